@@ -85,9 +85,109 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.CrmFieldData{},
 		&models.LeadInput{},
 		&models.LeadData{},
+		&models.LeadTouchpoint{},
+		&models.EmailSenderIdentity{},
+		&models.EmailMessage{},
+		&models.EmailEvent{},
+		&models.LeadConsent{},
+		&models.ConsentChange{},
+		&models.EmailSuppression{},
+		&models.EmailABTest{},
+		&models.EmailVariant{},
+		&models.CompanySettings{},
+		&models.Segment{},
+		&models.SegmentMember{},
+		&models.CampaignCost{},
+		&models.ExchangeRate{},
+		&models.Product{},
+		&models.PriceBook{},
+		&models.PriceBookEntry{},
+		&models.DealLineItem{},
+		&models.Quote{},
+		&models.QuoteLineItem{},
+		&models.QuoteTemplate{},
+		&models.CloseReason{},
+		&models.Account{},
+		&models.DealContact{},
+		&models.AccountContact{},
+		&models.SavedView{},
+		&models.AuditEntry{},
+		&models.BulkOperation{},
+		&models.BulkOperationResult{},
+		&models.Tag{},
+		&models.DealTag{},
+		&models.ContactTag{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
 	)
 }
 
+// MigrateNurtureCurrentStep moves nurture enrollments from the position of their next step among
+// the active steps, which they used to be tracked by, to that step's ID and drops the position
+// column. It does nothing once the column is gone.
+func MigrateNurtureCurrentStep(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.NurtureEnrollment{}, "current_step") {
+		return nil
+	}
+	if !migrator.HasColumn(&models.NurtureEnrollment{}, "CurrentStepID") {
+		if err := migrator.AddColumn(&models.NurtureEnrollment{}, "CurrentStepID"); err != nil {
+			return err
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Position 0 was the first step, which a nil step ID still means
+		var enrollments []struct {
+			ID          int
+			SequenceID  int
+			CurrentStep int
+		}
+		if err := tx.Table("nurture_enrollments").
+			Select("id, sequence_id, current_step").
+			Where("current_step > 0 AND current_step_id IS NULL").
+			Scan(&enrollments).Error; err != nil {
+			return err
+		}
+
+		activeSteps := make(map[int][]int)
+		for _, enrollment := range enrollments {
+			stepIDs, ok := activeSteps[enrollment.SequenceID]
+			if !ok {
+				if err := tx.Model(&models.NurtureStep{}).
+					Where("sequence_id = ? AND is_active = ?", enrollment.SequenceID, true).
+					Order("order_index, id").
+					Pluck("id", &stepIDs).Error; err != nil {
+					return err
+				}
+				activeSteps[enrollment.SequenceID] = stepIDs
+			}
+
+			query := tx.Table("nurture_enrollments").Where("id = ?", enrollment.ID)
+			if enrollment.CurrentStep < len(stepIDs) {
+				if err := query.Update("current_step_id", stepIDs[enrollment.CurrentStep]).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			// Past the last step the enrollment had run them all and would complete when next processed
+			if err := query.Where("status IN ?", []string{"active", "paused"}).Updates(map[string]interface{}{
+				"status":       "completed",
+				"completed_at": gorm.Expr("COALESCE(completed_at, last_step_at, ?)", time.Now()),
+				"next_run_at":  nil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return migrator.DropColumn(&models.NurtureEnrollment{}, "current_step")
+}
+
 // Helper function to get environment variable with default fallback
 func getEnvOrDefault(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type abTestVariantRequest struct {
+	Name         string `json:"name" binding:"required"`
+	TemplateID   int    `json:"template_id" binding:"required"`
+	SplitPercent int    `json:"split_percent"`
+}
+
+type abTestRequest struct {
+	TestPercent      *int                   `json:"test_percent"`
+	HoldoutPercent   int                    `json:"holdout_percent"`
+	WinnerMetric     string                 `json:"winner_metric"`
+	WinnerAfterHours int                    `json:"winner_after_hours"`
+	Variants         []abTestVariantRequest `json:"variants" binding:"required"`
+}
+
+// GetCampaignABTest returns the A/B test of a campaign with the stats of each variant
+func (h *CRMNurtureHandler) GetCampaignABTest(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	test, err := h.abTestRepo.GetTestByCampaign(campaign.ID)
+	h.respondABTest(c, test, err)
+}
+
+// SaveCampaignABTest creates or replaces the A/B test of a campaign
+func (h *CRMNurtureHandler) SaveCampaignABTest(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+	if campaign.Status == models.CampaignStatusCompleted || campaign.Status == models.CampaignStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaign has already finished"})
+		return
+	}
+
+	existing, err := h.abTestRepo.GetTestByCampaign(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch A/B test"})
+		return
+	}
+
+	test := &models.EmailABTest{CampaignID: &campaign.ID}
+	h.saveABTest(c, test, existing, campaign.CompanyId)
+}
+
+// DeleteCampaignABTest removes the A/B test of a campaign that has not started sending
+func (h *CRMNurtureHandler) DeleteCampaignABTest(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	test, err := h.abTestRepo.GetTestByCampaign(campaign.ID)
+	h.deleteABTest(c, test, err)
+}
+
+// GetStepABTest returns the A/B test of a nurture email step with the stats of each variant
+func (h *CRMNurtureHandler) GetStepABTest(c *gin.Context) {
+	step, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+
+	test, err := h.abTestRepo.GetTestByStep(step.ID)
+	h.respondABTest(c, test, err)
+}
+
+// SaveStepABTest creates or replaces the A/B test of a nurture email step
+func (h *CRMNurtureHandler) SaveStepABTest(c *gin.Context) {
+	step, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+	if step.Type != "email" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only email steps can be A/B tested"})
+		return
+	}
+
+	sequence, err := h.nurtureRepo.GetSequenceByID(step.SequenceID)
+	if err != nil || sequence == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequence"})
+		return
+	}
+
+	existing, err := h.abTestRepo.GetTestByStep(step.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch A/B test"})
+		return
+	}
+
+	test := &models.EmailABTest{StepID: &step.ID}
+	h.saveABTest(c, test, existing, sequence.CompanyId)
+}
+
+// DeleteStepABTest removes the A/B test of a nurture email step that has not started sending
+func (h *CRMNurtureHandler) DeleteStepABTest(c *gin.Context) {
+	step, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+
+	test, err := h.abTestRepo.GetTestByStep(step.ID)
+	h.deleteABTest(c, test, err)
+}
+
+func (h *CRMNurtureHandler) respondABTest(c *gin.Context, test *models.EmailABTest, err error) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch A/B test"})
+		return
+	}
+	if test == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "A/B test not found"})
+		return
+	}
+
+	results, err := h.abTestRepo.GetTestResults(test)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch A/B test stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *CRMNurtureHandler) saveABTest(c *gin.Context, test *models.EmailABTest, existing *models.EmailABTest, companyId int) {
+	if existing != nil && existing.TestStartedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A/B test has already started sending and can no longer be changed"})
+		return
+	}
+
+	var reqBody abTestRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	test.TestPercent = 100
+	if reqBody.TestPercent != nil {
+		test.TestPercent = *reqBody.TestPercent
+	}
+	test.HoldoutPercent = reqBody.HoldoutPercent
+	test.WinnerMetric = reqBody.WinnerMetric
+	if test.WinnerMetric == "" {
+		test.WinnerMetric = "open_rate"
+	}
+	test.WinnerAfterHours = reqBody.WinnerAfterHours
+	if test.WinnerAfterHours == 0 {
+		test.WinnerAfterHours = 24
+	}
+	test.Status = models.ABTestStatusTesting
+	test.CompanyId = companyId
+	for _, variant := range reqBody.Variants {
+		test.Variants = append(test.Variants, models.EmailVariant{
+			Name:         variant.Name,
+			TemplateID:   variant.TemplateID,
+			SplitPercent: variant.SplitPercent,
+		})
+	}
+
+	if msg := h.validateABTest(test); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if existing != nil {
+		test.ID = existing.ID
+		test.CreatedAt = existing.CreatedAt
+	}
+	if err := h.abTestRepo.SaveTest(test); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save A/B test"})
+		return
+	}
+
+	saved, err := h.abTestRepo.GetTestByID(test.ID)
+	h.respondABTest(c, saved, err)
+}
+
+// validateABTest checks an A/B test's shares, winner settings and variant templates
+func (h *CRMNurtureHandler) validateABTest(test *models.EmailABTest) string {
+	if test.HoldoutPercent < 0 || test.HoldoutPercent > 100 {
+		return "holdout_percent must be between 0 and 100"
+	}
+	if test.CampaignID != nil {
+		if test.TestPercent <= 0 || test.TestPercent > 100 {
+			return "test_percent must be between 1 and 100"
+		}
+		if test.HoldoutPercent+test.TestPercent > 100 {
+			return "holdout_percent and test_percent together cannot exceed 100"
+		}
+	} else if test.HoldoutPercent == 100 {
+		return "holdout_percent must leave recipients to test"
+	}
+	if !models.ABTestWinnerMetrics[test.WinnerMetric] {
+		return "winner_metric must be open_rate or click_rate"
+	}
+	if test.WinnerAfterHours <= 0 {
+		return "winner_after_hours must be positive"
+	}
+
+	if len(test.Variants) < 2 {
+		return "An A/B test needs at least two variants"
+	}
+	total := 0
+	for _, variant := range test.Variants {
+		if variant.SplitPercent <= 0 || variant.SplitPercent > 100 {
+			return "split_percent must be between 1 and 100"
+		}
+		total += variant.SplitPercent
+
+		template, err := h.nurtureRepo.GetTemplateByID(variant.TemplateID)
+		if err != nil || template == nil || template.CompanyId != test.CompanyId {
+			return fmt.Sprintf("Email template %d not found", variant.TemplateID)
+		}
+	}
+	if total != 100 {
+		return "Variant split_percent values must add up to 100"
+	}
+	return ""
+}
+
+func (h *CRMNurtureHandler) deleteABTest(c *gin.Context, test *models.EmailABTest, err error) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch A/B test"})
+		return
+	}
+	if test == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "A/B test not found"})
+		return
+	}
+	if test.TestStartedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A/B test has already started sending and cannot be deleted"})
+		return
+	}
+
+	if err := h.abTestRepo.DeleteTest(test.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete A/B test"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "A/B test deleted successfully"})
+}
+
+// findCampaign loads the campaign named by the :id parameter, writing an error response if it fails
+func (h *CRMNurtureHandler) findCampaign(c *gin.Context) (*models.Campaign, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+
+	campaign, err := h.nurtureRepo.GetCampaignByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign"})
+		return nil, false
+	}
+	if campaign == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+
+	return campaign, true
+}
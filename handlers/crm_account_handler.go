@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMAccountHandler handles requests for customer accounts
+type CRMAccountHandler struct {
+	accountRepo models.AccountRepository
+	contactRepo models.ContactRepository
+}
+
+// NewCRMAccountHandler creates a new account handler
+func NewCRMAccountHandler(repos *models.CRMRepositories) *CRMAccountHandler {
+	return &CRMAccountHandler{
+		accountRepo: repos.AccountRepo,
+		contactRepo: repos.ContactRepo,
+	}
+}
+
+// GetAccounts returns a company's accounts, optionally filtered by owner, industry or parent.
+// parent_account_id=none returns only top-level accounts.
+func (h *CRMAccountHandler) GetAccounts(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if ownerID, err := strconv.Atoi(c.Query("owner_id")); err == nil {
+		filters["owner_id"] = ownerID
+	}
+	if industry := c.Query("industry"); industry != "" {
+		filters["industry"] = industry
+	}
+	if parent := c.Query("parent_account_id"); parent == "none" {
+		filters["parent_account_id"] = nil
+	} else if parentID, err := strconv.Atoi(parent); err == nil {
+		filters["parent_account_id"] = parentID
+	}
+
+	accounts, err := h.accountRepo.GetAccounts(offset, limit, filters, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// SearchAccounts searches a company's accounts by name or domain
+func (h *CRMAccountHandler) SearchAccounts(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	accounts, err := h.accountRepo.SearchAccounts(query, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// GetAccount returns an account by ID
+func (h *CRMAccountHandler) GetAccount(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// CreateAccount creates a new account
+func (h *CRMAccountHandler) CreateAccount(c *gin.Context) {
+	var account models.Account
+	if err := c.ShouldBindJSON(&account); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.validateAccount(c, &account) {
+		return
+	}
+
+	if err := h.accountRepo.CreateAccount(&account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// UpdateAccount updates an account
+func (h *CRMAccountHandler) UpdateAccount(c *gin.Context) {
+	existingAccount, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	var account models.Account
+	if err := c.ShouldBindJSON(&account); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the existing account
+	account.ID = existingAccount.ID
+	account.CompanyId = existingAccount.CompanyId
+	account.CreatedAt = existingAccount.CreatedAt
+	if !h.validateAccount(c, &account) {
+		return
+	}
+
+	if err := h.accountRepo.UpdateAccount(&account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// DeleteAccount deletes an account; its child accounts move up to its parent
+func (h *CRMAccountHandler) DeleteAccount(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountRepo.DeleteAccount(account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// GetChildAccounts returns the accounts directly below an account
+func (h *CRMAccountHandler) GetChildAccounts(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	children, err := h.accountRepo.GetChildAccounts(account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch child accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, children)
+}
+
+// GetAccountContacts returns an account's contacts, and with include_children=true those of the
+// accounts below it
+func (h *CRMAccountHandler) GetAccountContacts(c *gin.Context) {
+	ids, ok := h.accountIDs(c)
+	if !ok {
+		return
+	}
+
+	contacts, err := h.accountRepo.GetAccountContacts(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+// GetAccountContactLinks returns the contacts linked to an account other than through their own
+// account, with their roles
+func (h *CRMAccountHandler) GetAccountContactLinks(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.accountRepo.GetAccountContactLinks(account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// LinkAccountContact links a contact to an account with a role
+func (h *CRMAccountHandler) LinkAccountContact(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		ContactID int    `json:"contact_id" binding:"required"`
+		Role      string `json:"role"`
+		IsPrimary bool   `json:"is_primary"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contact, err := h.contactRepo.FindByID(reqBody.ContactID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify contact"})
+		return
+	}
+	if contact == nil || contact.CompanyId != account.CompanyId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contact does not exist"})
+		return
+	}
+
+	existing, err := h.accountRepo.GetAccountContact(account.ID, contact.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account contact"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contact is already linked to this account"})
+		return
+	}
+
+	link := &models.AccountContact{
+		AccountID: account.ID,
+		ContactID: contact.ID,
+		Role:      strings.TrimSpace(reqBody.Role),
+		IsPrimary: reqBody.IsPrimary,
+		CompanyId: account.CompanyId,
+	}
+	if err := h.accountRepo.SaveAccountContact(link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link contact"})
+		return
+	}
+	link.Contact = contact
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// UpdateAccountContact changes a linked contact's role or makes it the account's primary contact
+func (h *CRMAccountHandler) UpdateAccountContact(c *gin.Context) {
+	link, ok := h.findAccountContact(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Role      string `json:"role"`
+		IsPrimary bool   `json:"is_primary"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link.Role = strings.TrimSpace(reqBody.Role)
+	link.IsPrimary = reqBody.IsPrimary
+	if err := h.accountRepo.SaveAccountContact(link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// UnlinkAccountContact removes a contact's link to an account
+func (h *CRMAccountHandler) UnlinkAccountContact(c *gin.Context) {
+	link, ok := h.findAccountContact(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountRepo.DeleteAccountContact(link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact unlinked from account successfully"})
+}
+
+// GetAccountDeals returns an account's deals, and with include_children=true those of the
+// accounts below it
+func (h *CRMAccountHandler) GetAccountDeals(c *gin.Context) {
+	ids, ok := h.accountIDs(c)
+	if !ok {
+		return
+	}
+
+	deals, err := h.accountRepo.GetAccountDeals(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deals)
+}
+
+// GetAccountRollup returns an account's open pipeline, won revenue and last activity, across the
+// accounts below it unless include_children=false
+func (h *CRMAccountHandler) GetAccountRollup(c *gin.Context) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return
+	}
+
+	rollup, err := h.accountRepo.GetAccountRollup(account, c.DefaultQuery("include_children", "true") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account rollup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rollup)
+}
+
+// validateAccount checks an account's fields and parent, normalizing its domain, and writes an
+// error response if it is invalid
+func (h *CRMAccountHandler) validateAccount(c *gin.Context, account *models.Account) bool {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return false
+	}
+	account.Domain = normalizeDomain(account.Domain)
+
+	if account.ParentAccountID == nil {
+		return true
+	}
+	if *account.ParentAccountID == account.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An account cannot be its own parent"})
+		return false
+	}
+	if !verifyAccount(c, h.accountRepo, account.ParentAccountID, account.CompanyId) {
+		return false
+	}
+	if account.ID != 0 {
+		descendants, err := h.accountRepo.GetDescendantIDs(account.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account hierarchy"})
+			return false
+		}
+		for _, id := range descendants {
+			if id == *account.ParentAccountID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "An account cannot be moved below one of its own child accounts"})
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// accountIDs returns the account named by :id, and the accounts below it when
+// include_children=true, writing an error response if it fails
+func (h *CRMAccountHandler) accountIDs(c *gin.Context) ([]int, bool) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return nil, false
+	}
+
+	ids := []int{account.ID}
+	if c.Query("include_children") == "true" {
+		descendants, err := h.accountRepo.GetDescendantIDs(account.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account hierarchy"})
+			return nil, false
+		}
+		ids = append(ids, descendants...)
+	}
+	return ids, true
+}
+
+// findAccount loads the account named by the :id parameter, writing an error response if it fails
+func (h *CRMAccountHandler) findAccount(c *gin.Context) (*models.Account, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return nil, false
+	}
+
+	account, err := h.accountRepo.GetAccountByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account"})
+		return nil, false
+	}
+	if account == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return nil, false
+	}
+
+	return account, true
+}
+
+// findAccountContact loads the link between the account named by :id and the contact named by
+// :contactId, writing an error response if it fails
+func (h *CRMAccountHandler) findAccountContact(c *gin.Context) (*models.AccountContact, bool) {
+	account, ok := h.findAccount(c)
+	if !ok {
+		return nil, false
+	}
+	contactID, err := strconv.Atoi(c.Param("contactId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return nil, false
+	}
+
+	link, err := h.accountRepo.GetAccountContact(account.ID, contactID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account contact"})
+		return nil, false
+	}
+	if link == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact is not linked to this account"})
+		return nil, false
+	}
+
+	return link, true
+}
+
+// verifyAccount checks that an optional account ID names an account of the company, writing an
+// error response if not
+func verifyAccount(c *gin.Context, accountRepo models.AccountRepository, accountID *int, companyId int) bool {
+	if accountID == nil {
+		return true
+	}
+
+	account, err := accountRepo.GetAccountByID(*accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify account"})
+		return false
+	}
+	if account == nil || account.CompanyId != companyId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account does not exist"})
+		return false
+	}
+	return true
+}
+
+// normalizeDomain reduces a website or domain to its lower-case host name without www
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if i := strings.Index(domain, "://"); i >= 0 {
+		domain = domain[i+3:]
+	}
+	if i := strings.IndexAny(domain, "/?#"); i >= 0 {
+		domain = domain[:i]
+	}
+	return strings.TrimPrefix(domain, "www.")
+}
@@ -357,3 +357,116 @@ func (h *CRMAnalyticsHandler) GetTargetAnalytics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetAttributionAnalytics returns multi-touch attribution of won revenue to campaigns and sources
+func (h *CRMAnalyticsHandler) GetAttributionAnalytics(c *gin.Context) {
+	filters, err := h.parseAnalyticsFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	model := c.DefaultQuery("model", models.AttributionLastTouch)
+	switch model {
+	case models.AttributionFirstTouch, models.AttributionLastTouch, models.AttributionLinear, models.AttributionTimeDecay:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribution model"})
+		return
+	}
+
+	analytics, err := h.analyticsService.GetAttributionAnalytics(filters, model, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attribution analytics"})
+		return
+	}
+
+	response := map[string]interface{}{
+		"period": map[string]interface{}{
+			"start_date": filters.StartDate.Format("2006-01-02"),
+			"end_date":   filters.EndDate.Format("2006-01-02"),
+		},
+		"data": analytics,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCampaignROIAnalytics compares spend, leads, opportunities, revenue and ROI across campaigns
+func (h *CRMAnalyticsHandler) GetCampaignROIAnalytics(c *gin.Context) {
+	filters, err := h.parseAnalyticsFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	analytics, err := h.analyticsService.GetCampaignROIComparison(filters, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign ROI analytics"})
+		return
+	}
+
+	response := map[string]interface{}{
+		"period": map[string]interface{}{
+			"start_date": filters.StartDate.Format("2006-01-02"),
+			"end_date":   filters.EndDate.Format("2006-01-02"),
+		},
+		"data": analytics,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLossAnalytics breaks down the deals lost in a date range by reason, competitor, the stage they
+// were lost at, owner and week, month or quarter
+func (h *CRMAnalyticsHandler) GetLossAnalytics(c *gin.Context) {
+	filters, err := h.parseAnalyticsFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	period := c.DefaultQuery("period", "month")
+	switch period {
+	case "week", "month", "quarter":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be week, month or quarter"})
+		return
+	}
+
+	analytics, err := h.analyticsService.GetLossAnalytics(filters, period, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loss analytics"})
+		return
+	}
+
+	response := map[string]interface{}{
+		"period": map[string]interface{}{
+			"start_date": filters.StartDate.Format("2006-01-02"),
+			"end_date":   filters.EndDate.Format("2006-01-02"),
+		},
+		"data": analytics,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMAuditHandler handles requests for the audit trail of changes to leads, deals and contacts
+type CRMAuditHandler struct {
+	auditRepo models.AuditRepository
+}
+
+// NewCRMAuditHandler creates a new audit trail handler
+func NewCRMAuditHandler(repos *models.CRMRepositories) *CRMAuditHandler {
+	return &CRMAuditHandler{
+		auditRepo: repos.AuditRepo,
+	}
+}
+
+// GetAuditEntries returns a company's audit trail newest first, optionally only that of an entity
+// type or, with entity_id, of one record
+func (h *CRMAuditHandler) GetAuditEntries(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	entityType := c.Query("entity_type")
+	entityID := 0
+	if idStr := c.Query("entity_id"); idStr != "" {
+		if entityType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type is required with entity_id"})
+			return
+		}
+		if entityID, err = strconv.Atoi(idStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity_id"})
+			return
+		}
+	}
+
+	entries, err := h.auditRepo.GetAuditEntries(entityType, entityID, offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit trail"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// recordAudit records a change the current user made to a record in the audit trail. The change
+// has been made, so a failure is logged rather than reported.
+func recordAudit(c *gin.Context, auditRepo models.AuditRepository, entityType string, entityID int, action string, companyId int) {
+	entry := models.AuditEntry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		UserID:     currentUserID(c),
+		CreatedAt:  time.Now(),
+		CompanyId:  companyId,
+	}
+	if err := auditRepo.RecordAudit([]models.AuditEntry{entry}); err != nil {
+		log.Printf("Failed to record %s of %s %d in the audit trail: %v", action, entityType, entityID, err)
+	}
+}
@@ -0,0 +1,624 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errBulkRecordNotFound is recorded for selected records that do not exist in the company
+var errBulkRecordNotFound = errors.New("record not found")
+
+// errBulkDealHasLineItems is recorded for deals whose amount a bulk edit cannot set, because it
+// is the total of their line items
+var errBulkDealHasLineItems = errors.New("deal amount is the total of its line items")
+
+// CRMBulkHandler handles bulk operations on leads and deals
+type CRMBulkHandler struct {
+	leadRepo        models.LeadRepository
+	dealRepo        models.DealRepository
+	viewRepo        models.SavedViewRepository
+	campaignRepo    models.CampaignRepository
+	nurtureRepo     models.NurtureRepository
+	closeReasonRepo models.CloseReasonRepository
+	bulkRepo        models.BulkOperationRepository
+	bulkService     *services.BulkService
+}
+
+// NewCRMBulkHandler creates a new bulk operation handler
+func NewCRMBulkHandler(repos *models.CRMRepositories) *CRMBulkHandler {
+	return &CRMBulkHandler{
+		leadRepo:        repos.LeadRepo,
+		dealRepo:        repos.DealRepo,
+		viewRepo:        repos.SavedViewRepo,
+		campaignRepo:    repos.CampaignRepo,
+		nurtureRepo:     repos.NurtureRepo,
+		closeReasonRepo: repos.CloseReasonRepo,
+		bulkRepo:        repos.BulkOperationRepo,
+		bulkService:     services.NewBulkService(repos),
+	}
+}
+
+// bulkRequest selects the records of a bulk operation, by ID, saved view or filters, and names
+// the action to apply to them
+type bulkRequest struct {
+	CompanyId int                 `json:"company_id" binding:"required"`
+	IDs       []int               `json:"ids"`
+	ViewID    *int                `json:"view_id"`
+	Filters   []models.ViewFilter `json:"filters"`
+	Action    string              `json:"action" binding:"required"`
+	bulkParams
+}
+
+// bulkParams are the parameters of a bulk action, recorded with the operation
+type bulkParams struct {
+	Field            string                `json:"field,omitempty"`            // set_field
+	Value            string                `json:"value,omitempty"`            // set_field
+	Status           string                `json:"status,omitempty"`           // set_status
+	Disqualification *leadDisqualification `json:"disqualification,omitempty"` // set_status to disqualified
+	Stage            string                `json:"stage,omitempty"`            // set_stage
+	Close            *dealCloseRequest     `json:"close,omitempty"`            // set_stage to won or lost
+	AssignedTo       *int                  `json:"assigned_to,omitempty"`      // reassign
+	Tags             []string              `json:"tags,omitempty"`             // add_tags and remove_tags
+	CampaignID       *int                  `json:"campaign_id,omitempty"`      // add_to_campaign
+	SequenceID       *int                  `json:"sequence_id,omitempty"`      // add_to_sequence
+}
+
+// BulkUpdateLeads applies an action to many leads: setting a field or the status, reassigning,
+// adding or removing tags, adding them to a campaign or sequence, or deleting them
+func (h *CRMBulkHandler) BulkUpdateLeads(c *gin.Context) {
+	h.runBulk(c, models.ViewEntityLead)
+}
+
+// BulkUpdateDeals applies an action to many deals: setting a field or the stage, reassigning, or
+// deleting them
+func (h *CRMBulkHandler) BulkUpdateDeals(c *gin.Context) {
+	h.runBulk(c, models.ViewEntityDeal)
+}
+
+// runBulk validates a bulk request, resolves the records it selects and runs it as a tracked
+// operation, responding with the operation and its result for each record
+func (h *CRMBulkHandler) runBulk(c *gin.Context, entity string) {
+	var req bulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.BulkActions[entity][req.Action] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported action %q for %ss", req.Action, entity)})
+		return
+	}
+
+	var apply services.BulkRecordFunc
+	var ok bool
+	if entity == models.ViewEntityLead {
+		apply, ok = h.leadAction(c, &req)
+	} else {
+		apply, ok = h.dealAction(c, &req)
+	}
+	if !ok {
+		return
+	}
+
+	ids, ok := h.bulkRecordIDs(c, entity, &req)
+	if !ok {
+		return
+	}
+
+	params, err := json.Marshal(req.bulkParams)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record bulk operation"})
+		return
+	}
+	operation := &models.BulkOperation{
+		Entity:    entity,
+		Action:    req.Action,
+		Params:    string(params),
+		CreatedBy: currentUserID(c),
+		CompanyId: req.CompanyId,
+	}
+	if err := h.bulkService.Run(operation, ids, apply); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record bulk operation", "operation_id": operation.ID})
+		return
+	}
+
+	c.JSON(http.StatusOK, operation)
+}
+
+// bulkRecordIDs resolves the records a bulk request selects: the IDs given, or those matching a
+// saved view or filters. It writes an error response if the selection is invalid or too large.
+func (h *CRMBulkHandler) bulkRecordIDs(c *gin.Context, entity string, req *bulkRequest) ([]int, bool) {
+	selections := 0
+	if len(req.IDs) > 0 {
+		selections++
+	}
+	if req.ViewID != nil {
+		selections++
+	}
+	if req.Filters != nil {
+		selections++
+	}
+	if selections != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give exactly one of ids, view_id or filters"})
+		return nil, false
+	}
+
+	// Explicit IDs are used as given; any not in the company fail as not found
+	if len(req.IDs) > 0 {
+		seen := make(map[int]bool, len(req.IDs))
+		ids := make([]int, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > models.MaxBulkRecords {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A bulk operation can change at most %d records", models.MaxBulkRecords)})
+			return nil, false
+		}
+		return ids, true
+	}
+
+	filters := req.Filters
+	if req.ViewID != nil {
+		view, ok := findVisibleView(c, h.viewRepo, *req.ViewID)
+		if !ok {
+			return nil, false
+		}
+		if view.Entity != entity || view.CompanyId != req.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Saved view does not list these records"})
+			return nil, false
+		}
+		var err error
+		if filters, err = services.ParseViewFilters(view.Filters); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid saved view filters"})
+			return nil, false
+		}
+	} else if err := services.ValidateViewFilters(entity, filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	ids, err := h.viewRepo.FindIDs(entity, filters, models.MaxBulkRecords+1, req.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find the selected records"})
+		return nil, false
+	}
+	if len(ids) > models.MaxBulkRecords {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The selection matches more than %d records", models.MaxBulkRecords)})
+		return nil, false
+	}
+	return ids, true
+}
+
+// leadAction checks the parameters of a bulk lead action and returns the function applying it to
+// one lead, writing an error response if the parameters are invalid
+func (h *CRMBulkHandler) leadAction(c *gin.Context, req *bulkRequest) (services.BulkRecordFunc, bool) {
+	params := req.bulkParams
+	findLead := func(id int) (*models.Lead, error) {
+		lead, err := h.leadRepo.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if lead == nil || lead.CompanyId != req.CompanyId {
+			return nil, errBulkRecordNotFound
+		}
+		return lead, nil
+	}
+
+	switch req.Action {
+	case models.BulkActionSetField:
+		if params.Field == "" || params.Field == "id" || params.Field == "status" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "field is required and cannot be id or status; use set_status to change the status"})
+			return nil, false
+		}
+		return func(id int) error {
+			if _, err := findLead(id); err != nil {
+				return err
+			}
+			values, err := h.leadRepo.GetFieldValues(id)
+			if err != nil {
+				return err
+			}
+			if current, ok := values[params.Field]; ok && current == params.Value {
+				return services.ErrBulkRecordSkipped
+			}
+			return h.leadRepo.SetFieldValue(id, params.Field, params.Value)
+		}, true
+
+	case models.BulkActionSetStatus:
+		if params.Status == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+			return nil, false
+		}
+		details := leadDisqualification{}
+		if params.Disqualification != nil {
+			details = *params.Disqualification
+		}
+		if params.Status == "disqualified" &&
+			!requireCloseReason(c, h.closeReasonRepo, models.CloseReasonTypeDisqualified, details.ReasonID, req.CompanyId) {
+			return nil, false
+		}
+		return func(id int) error {
+			lead, err := findLead(id)
+			if err != nil {
+				return err
+			}
+			if lead.Status == params.Status {
+				return services.ErrBulkRecordSkipped
+			}
+			setLeadStatus(lead, params.Status, details)
+			return h.leadRepo.Update(lead)
+		}, true
+
+	case models.BulkActionReassign:
+		if params.AssignedTo == nil || *params.AssignedTo <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assigned_to is required"})
+			return nil, false
+		}
+		assignedTo := uint(*params.AssignedTo)
+		return func(id int) error {
+			lead, err := findLead(id)
+			if err != nil {
+				return err
+			}
+			if lead.AssignedToID != nil && *lead.AssignedToID == assignedTo {
+				return services.ErrBulkRecordSkipped
+			}
+			lead.AssignedToID = &assignedTo
+			return h.leadRepo.Update(lead)
+		}, true
+
+	case models.BulkActionAddTags, models.BulkActionRemoveTags:
+		tags, ok := bulkTags(c, params.Tags)
+		if !ok {
+			return nil, false
+		}
+		return func(id int) error {
+			if _, err := findLead(id); err != nil {
+				return err
+			}
+			var changed int64
+			if req.Action == models.BulkActionAddTags {
+				added, err := h.leadRepo.AddTags(id, tags, req.CompanyId)
+				if err != nil {
+					return err
+				}
+				changed = int64(added)
+			} else {
+				removed, err := h.leadRepo.RemoveTags(id, tags)
+				if err != nil {
+					return err
+				}
+				changed = removed
+			}
+			if changed == 0 {
+				return services.ErrBulkRecordSkipped
+			}
+			return nil
+		}, true
+
+	case models.BulkActionAddToCampaign:
+		if params.CampaignID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "campaign_id is required"})
+			return nil, false
+		}
+		campaign, err := h.campaignRepo.GetCampaignByID(*params.CampaignID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign"})
+			return nil, false
+		}
+		if campaign == nil || campaign.CompanyId != req.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign does not exist"})
+			return nil, false
+		}
+		if campaign.Status == models.CampaignStatusCompleted || campaign.Status == models.CampaignStatusCancelled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign has already finished"})
+			return nil, false
+		}
+		return func(id int) error {
+			if _, err := findLead(id); err != nil {
+				return err
+			}
+			added, err := h.campaignRepo.AddLeadsToCampaign(campaign.ID, []int{id})
+			if err != nil {
+				return err
+			}
+			if added == 0 {
+				return services.ErrBulkRecordSkipped
+			}
+			return nil
+		}, true
+
+	case models.BulkActionAddToSequence:
+		if params.SequenceID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sequence_id is required"})
+			return nil, false
+		}
+		sequence, err := h.nurtureRepo.GetSequenceByID(*params.SequenceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequence"})
+			return nil, false
+		}
+		if sequence == nil || sequence.CompanyId != req.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sequence does not exist"})
+			return nil, false
+		}
+		if !sequence.IsActive {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sequence is not active"})
+			return nil, false
+		}
+		return func(id int) error {
+			if _, err := findLead(id); err != nil {
+				return err
+			}
+			enrolled, err := h.nurtureRepo.EnrollLeads(sequence.ID, []int{id})
+			if err != nil {
+				return err
+			}
+			if enrolled == 0 {
+				return services.ErrBulkRecordSkipped
+			}
+			return nil
+		}, true
+
+	case models.BulkActionDelete:
+		return func(id int) error {
+			if _, err := findLead(id); err != nil {
+				return err
+			}
+			return h.leadRepo.Delete(id)
+		}, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported action %q for leads", req.Action)})
+	return nil, false
+}
+
+// dealAction checks the parameters of a bulk deal action and returns the function applying it to
+// one deal, writing an error response if the parameters are invalid
+func (h *CRMBulkHandler) dealAction(c *gin.Context, req *bulkRequest) (services.BulkRecordFunc, bool) {
+	params := req.bulkParams
+	findDeal := func(id int) (*models.Deal, error) {
+		deal, err := h.dealRepo.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if deal == nil || deal.CompanyId != req.CompanyId {
+			return nil, errBulkRecordNotFound
+		}
+		return deal, nil
+	}
+
+	switch req.Action {
+	case models.BulkActionSetField:
+		if err := setDealField(&models.Deal{}, params.Field, params.Value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return func(id int) error {
+			deal, err := findDeal(id)
+			if err != nil {
+				return err
+			}
+			if params.Field == "amount" {
+				items, err := h.dealRepo.GetLineItems(deal.ID)
+				if err != nil {
+					return err
+				}
+				if len(items) > 0 {
+					return errBulkDealHasLineItems
+				}
+			}
+			if err := setDealField(deal, params.Field, params.Value); err != nil {
+				return err
+			}
+			return h.dealRepo.Update(deal)
+		}, true
+
+	case models.BulkActionSetStage:
+		if params.Stage == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stage is required"})
+			return nil, false
+		}
+		details := dealCloseRequest{}
+		if params.Close != nil {
+			details = *params.Close
+		}
+		if closeType := dealCloseType(params.Stage); closeType != "" &&
+			!requireCloseReason(c, h.closeReasonRepo, closeType, details.CloseReasonID, req.CompanyId) {
+			return nil, false
+		}
+		return func(id int) error {
+			deal, err := findDeal(id)
+			if err != nil {
+				return err
+			}
+			if deal.Stage == params.Stage {
+				return services.ErrBulkRecordSkipped
+			}
+			// The close reason was checked for the whole operation above
+			previous := *deal
+			applyDealStage(deal, params.Stage)
+			recordDealClose(deal, previous, details)
+			return h.dealRepo.Update(deal)
+		}, true
+
+	case models.BulkActionReassign:
+		if params.AssignedTo == nil || *params.AssignedTo <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assigned_to is required"})
+			return nil, false
+		}
+		return func(id int) error {
+			deal, err := findDeal(id)
+			if err != nil {
+				return err
+			}
+			if deal.AssignedTo != nil && *deal.AssignedTo == *params.AssignedTo {
+				return services.ErrBulkRecordSkipped
+			}
+			assignedTo := *params.AssignedTo
+			deal.AssignedTo = &assignedTo
+			return h.dealRepo.Update(deal)
+		}, true
+
+	case models.BulkActionAddTags, models.BulkActionRemoveTags:
+		tags, ok := bulkTags(c, params.Tags)
+		if !ok {
+			return nil, false
+		}
+		return func(id int) error {
+			if _, err := findDeal(id); err != nil {
+				return err
+			}
+			var changed int64
+			if req.Action == models.BulkActionAddTags {
+				added, err := h.dealRepo.AddTags(id, tags, req.CompanyId)
+				if err != nil {
+					return err
+				}
+				changed = int64(added)
+			} else {
+				removed, err := h.dealRepo.RemoveTags(id, tags)
+				if err != nil {
+					return err
+				}
+				changed = removed
+			}
+			if changed == 0 {
+				return services.ErrBulkRecordSkipped
+			}
+			return nil
+		}, true
+
+	case models.BulkActionDelete:
+		return func(id int) error {
+			if _, err := findDeal(id); err != nil {
+				return err
+			}
+			return h.dealRepo.Delete(id)
+		}, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported action %q for deals", req.Action)})
+	return nil, false
+}
+
+// setDealField sets one of the deal fields a bulk operation can set from its text value
+func setDealField(deal *models.Deal, field string, value string) error {
+	switch field {
+	case "title":
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("title cannot be empty")
+		}
+		deal.Title = strings.TrimSpace(value)
+	case "amount":
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 {
+			return fmt.Errorf("amount must be a non-negative number")
+		}
+		deal.Amount = amount
+	case "currency":
+		deal.Currency = strings.ToUpper(strings.TrimSpace(value))
+	case "probability":
+		probability, err := strconv.Atoi(value)
+		if err != nil || probability < 0 || probability > 100 {
+			return fmt.Errorf("probability must be a whole number from 0 to 100")
+		}
+		deal.Probability = probability
+	case "expected_close_date":
+		if value == "" {
+			deal.ExpectedCloseDate = nil
+			break
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("expected_close_date must be a date in the form 2006-01-02")
+		}
+		deal.ExpectedCloseDate = &date
+	case "notes":
+		deal.Notes = value
+	default:
+		return fmt.Errorf("field must be one of title, amount, currency, probability, expected_close_date or notes")
+	}
+	return nil
+}
+
+// bulkTags trims and checks the tags of a tag action, writing an error response if there are none
+// or one is too long
+func bulkTags(c *gin.Context, tags []string) ([]string, bool) {
+	var trimmed []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		trimmed = append(trimmed, tag)
+	}
+	if len(trimmed) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags are required"})
+		return nil, false
+	}
+	if msg := checkTagNames(trimmed); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil, false
+	}
+	return trimmed, true
+}
+
+// GetBulkOperations returns a company's bulk operations, newest first
+func (h *CRMBulkHandler) GetBulkOperations(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	operations, err := h.bulkRepo.GetBulkOperations(c.Query("entity"), offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk operations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operations)
+}
+
+// GetBulkOperation returns a bulk operation with its result for each record, optionally only the
+// results with a status
+func (h *CRMBulkHandler) GetBulkOperation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bulk operation ID"})
+		return
+	}
+
+	operation, err := h.bulkRepo.GetBulkOperationByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk operation"})
+		return
+	}
+	if operation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bulk operation not found"})
+		return
+	}
+
+	operation.Results, err = h.bulkRepo.GetBulkOperationResults(operation.ID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk operation results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operation)
+}
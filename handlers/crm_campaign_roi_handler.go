@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type campaignCostRequest struct {
+	Date    string  `json:"date" binding:"required"` // YYYY-MM-DD
+	Amount  float64 `json:"amount"`
+	Channel string  `json:"channel"`
+	Note    string  `json:"note"`
+}
+
+// GetCampaignCosts returns the cost entries of a campaign
+func (h *CRMNurtureHandler) GetCampaignCosts(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	costs, err := h.nurtureRepo.GetCampaignCosts(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign costs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, costs)
+}
+
+// CreateCampaignCost records money spent on a campaign
+func (h *CRMNurtureHandler) CreateCampaignCost(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	cost := &models.CampaignCost{
+		CampaignID: campaign.ID,
+		CreatedBy:  currentUserID(c),
+		CompanyId:  campaign.CompanyId,
+	}
+	if !bindCampaignCost(c, cost) {
+		return
+	}
+
+	if err := h.nurtureRepo.CreateCampaignCost(cost); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign cost"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cost)
+}
+
+// UpdateCampaignCost updates a cost entry of a campaign
+func (h *CRMNurtureHandler) UpdateCampaignCost(c *gin.Context) {
+	cost, ok := h.findCampaignCost(c)
+	if !ok {
+		return
+	}
+	if !bindCampaignCost(c, cost) {
+		return
+	}
+
+	if err := h.nurtureRepo.UpdateCampaignCost(cost); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign cost"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cost)
+}
+
+// DeleteCampaignCost deletes a cost entry of a campaign
+func (h *CRMNurtureHandler) DeleteCampaignCost(c *gin.Context) {
+	cost, ok := h.findCampaignCost(c)
+	if !ok {
+		return
+	}
+
+	if err := h.nurtureRepo.DeleteCampaignCost(cost.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete campaign cost"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign cost deleted successfully"})
+}
+
+// GetCampaignROI returns a campaign's spend, cost per lead and opportunity, revenue, pipeline
+// influenced and ROI
+func (h *CRMNurtureHandler) GetCampaignROI(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	roi, err := h.analyticsService.GetCampaignROI(campaign.ID, campaign.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign ROI"})
+		return
+	}
+	if roi == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roi)
+}
+
+// bindCampaignCost reads a cost entry from the request body into cost, writing an error response
+// if it is invalid
+func bindCampaignCost(c *gin.Context, cost *models.CampaignCost) bool {
+	var reqBody campaignCostRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	date, err := time.Parse("2006-01-02", reqBody.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return false
+	}
+	if reqBody.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return false
+	}
+
+	cost.Date = date
+	cost.Amount = reqBody.Amount
+	cost.Channel = reqBody.Channel
+	cost.Note = reqBody.Note
+	return true
+}
+
+// findCampaignCost loads the cost named by :costId, ensuring it belongs to the :id campaign
+func (h *CRMNurtureHandler) findCampaignCost(c *gin.Context) (*models.CampaignCost, bool) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return nil, false
+	}
+	costID, err := strconv.Atoi(c.Param("costId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cost ID"})
+		return nil, false
+	}
+
+	cost, err := h.nurtureRepo.GetCampaignCostByID(costID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign cost"})
+		return nil, false
+	}
+	if cost == nil || cost.CampaignID != campaignID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign cost not found"})
+		return nil, false
+	}
+
+	return cost, true
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type closeReasonRequest struct {
+	Type        string `json:"type" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"is_active"`
+	Position    int    `json:"position"`
+}
+
+// dealCloseRequest is why a deal was won or lost, given when it moves into a closing stage
+type dealCloseRequest struct {
+	CloseReasonID *int   `json:"close_reason_id"`
+	Competitor    string `json:"competitor"`
+	CloseNotes    string `json:"close_notes"`
+}
+
+// CRMCloseReasonHandler handles requests for a company's win, loss and disqualification reasons
+type CRMCloseReasonHandler struct {
+	closeReasonRepo models.CloseReasonRepository
+}
+
+// NewCRMCloseReasonHandler creates a new close reason handler
+func NewCRMCloseReasonHandler(repos *models.CRMRepositories) *CRMCloseReasonHandler {
+	return &CRMCloseReasonHandler{
+		closeReasonRepo: repos.CloseReasonRepo,
+	}
+}
+
+// GetCloseReasons returns a company's close reasons, optionally of one type or only active ones
+func (h *CRMCloseReasonHandler) GetCloseReasons(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	reasonType := c.Query("type")
+	if reasonType != "" && !validCloseReasonType(reasonType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be won, lost or disqualified"})
+		return
+	}
+	activeOnly := c.Query("active") == "true"
+
+	reasons, err := h.closeReasonRepo.GetCloseReasons(reasonType, activeOnly, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch close reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reasons)
+}
+
+// CreateCloseReason adds a reason to one of a company's reason lists
+func (h *CRMCloseReasonHandler) CreateCloseReason(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	reason := &models.CloseReason{CompanyId: companyId, IsActive: true}
+	if !h.bindCloseReason(c, reason) {
+		return
+	}
+
+	if err := h.closeReasonRepo.CreateCloseReason(reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create close reason"})
+		return
+	}
+	if !reason.IsActive {
+		// A false IsActive is not written on create because the column defaults to true
+		if err := h.closeReasonRepo.UpdateCloseReason(reason); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create close reason"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, reason)
+}
+
+// UpdateCloseReason renames, describes, reorders or deactivates a close reason
+func (h *CRMCloseReasonHandler) UpdateCloseReason(c *gin.Context) {
+	reason, ok := h.findCloseReason(c)
+	if !ok {
+		return
+	}
+	if !h.bindCloseReason(c, reason) {
+		return
+	}
+
+	if err := h.closeReasonRepo.UpdateCloseReason(reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update close reason"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reason)
+}
+
+// DeleteCloseReason deletes a close reason no deal or lead has been recorded with
+func (h *CRMCloseReasonHandler) DeleteCloseReason(c *gin.Context) {
+	reason, ok := h.findCloseReason(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.closeReasonRepo.CountCloseReasonUsage(reason.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check close reason usage"})
+		return
+	}
+	if usage > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Close reason is in use; deactivate it instead"})
+		return
+	}
+
+	if err := h.closeReasonRepo.DeleteCloseReason(reason.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete close reason"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Close reason deleted successfully"})
+}
+
+// bindCloseReason reads a close reason from the request body into reason, writing an error
+// response if it is invalid or its name is taken
+func (h *CRMCloseReasonHandler) bindCloseReason(c *gin.Context, reason *models.CloseReason) bool {
+	var reqBody closeReasonRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	reqBody.Type = strings.ToLower(reqBody.Type)
+	reqBody.Name = strings.TrimSpace(reqBody.Name)
+	if !validCloseReasonType(reqBody.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be won, lost or disqualified"})
+		return false
+	}
+	if reqBody.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return false
+	}
+
+	existing, err := h.closeReasonRepo.GetCloseReasons(reqBody.Type, false, reason.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch close reasons"})
+		return false
+	}
+	for _, other := range existing {
+		if other.ID != reason.ID && strings.EqualFold(other.Name, reqBody.Name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A " + reqBody.Type + " reason named " + other.Name + " already exists"})
+			return false
+		}
+	}
+
+	reason.Type = reqBody.Type
+	reason.Name = reqBody.Name
+	reason.Description = reqBody.Description
+	reason.Position = reqBody.Position
+	if reqBody.IsActive != nil {
+		reason.IsActive = *reqBody.IsActive
+	}
+	return true
+}
+
+// findCloseReason loads the close reason named by the :id parameter, writing an error response
+// if it fails
+func (h *CRMCloseReasonHandler) findCloseReason(c *gin.Context) (*models.CloseReason, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid close reason ID"})
+		return nil, false
+	}
+
+	reason, err := h.closeReasonRepo.GetCloseReasonByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch close reason"})
+		return nil, false
+	}
+	if reason == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Close reason not found"})
+		return nil, false
+	}
+
+	return reason, true
+}
+
+func validCloseReasonType(reasonType string) bool {
+	switch reasonType {
+	case models.CloseReasonTypeWon, models.CloseReasonTypeLost, models.CloseReasonTypeDisqualified:
+		return true
+	}
+	return false
+}
+
+// dealCloseType returns won or lost for a closing deal stage, or "" for an open one
+func dealCloseType(stage string) string {
+	for _, won := range models.DealStagesWon {
+		if stage == won {
+			return models.CloseReasonTypeWon
+		}
+	}
+	for _, lost := range models.DealStagesLost {
+		if stage == lost {
+			return models.CloseReasonTypeLost
+		}
+	}
+	return ""
+}
+
+// requireCloseReason checks that reasonID names an active reason of the given type in the
+// company's list, and that one is given if the list has any active reasons. It writes an error
+// response if not.
+func requireCloseReason(c *gin.Context, closeReasonRepo models.CloseReasonRepository, reasonType string, reasonID *int, companyId int) bool {
+	if reasonID == nil {
+		required, err := closeReasonRepo.HasActiveCloseReasons(reasonType, companyId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch close reasons"})
+			return false
+		}
+		if required {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A " + reasonType + " reason is required"})
+			return false
+		}
+		return true
+	}
+
+	reason, err := closeReasonRepo.GetCloseReasonByID(*reasonID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch close reason"})
+		return false
+	}
+	if reason == nil || reason.CompanyId != companyId || reason.Type != reasonType || !reason.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Close reason is not an active " + reasonType + " reason"})
+		return false
+	}
+	return true
+}
+
+// applyDealClose records why a deal whose stage changed from previous's was won or lost. A deal
+// that stays closed the same way keeps its close date and any details not given again; a deal
+// that is reopened loses them. It writes an error response if the reason is missing or invalid.
+func applyDealClose(c *gin.Context, closeReasonRepo models.CloseReasonRepository, deal *models.Deal, previous models.Deal, details dealCloseRequest) bool {
+	if reasonType := recordDealClose(deal, previous, details); reasonType != "" {
+		return requireCloseReason(c, closeReasonRepo, reasonType, deal.CloseReasonID, deal.CompanyId)
+	}
+	return true
+}
+
+// recordDealClose records why a deal was won or lost as applyDealClose does, without checking
+// the reason. It returns the type of reason to check, or "" when the deal is open or keeps the
+// reason it was closed with.
+func recordDealClose(deal *models.Deal, previous models.Deal, details dealCloseRequest) string {
+	closeType := dealCloseType(deal.Stage)
+	if closeType == "" {
+		deal.CloseReasonID = nil
+		deal.Competitor = ""
+		deal.CloseNotes = ""
+		deal.LostAtStage = ""
+		deal.ClosedAt = nil
+		return ""
+	}
+
+	// A deal already closed the same way keeps its reason without checking it again, so deals
+	// closed before the company set up its reasons, or with a reason since deactivated, can
+	// still be edited
+	checkReason := true
+	if closeType == dealCloseType(previous.Stage) {
+		if details.CloseReasonID == nil || sameCloseReason(details.CloseReasonID, previous.CloseReasonID) {
+			details.CloseReasonID = previous.CloseReasonID
+			checkReason = false
+		}
+		if details.Competitor == "" {
+			details.Competitor = previous.Competitor
+		}
+		if details.CloseNotes == "" {
+			details.CloseNotes = previous.CloseNotes
+		}
+		deal.ClosedAt = previous.ClosedAt
+		deal.LostAtStage = previous.LostAtStage
+	} else {
+		now := time.Now()
+		deal.ClosedAt = &now
+		deal.LostAtStage = ""
+		if closeType == models.CloseReasonTypeLost {
+			deal.LostAtStage = previous.Stage
+		}
+	}
+
+	deal.CloseReasonID = details.CloseReasonID
+	deal.Competitor = strings.TrimSpace(details.Competitor)
+	deal.CloseNotes = details.CloseNotes
+	if !checkReason {
+		return ""
+	}
+	return closeType
+}
+
+// dealCloseDetails returns the close details given in a deal's request body
+func dealCloseDetails(deal *models.Deal) dealCloseRequest {
+	return dealCloseRequest{
+		CloseReasonID: deal.CloseReasonID,
+		Competitor:    deal.Competitor,
+		CloseNotes:    deal.CloseNotes,
+	}
+}
+
+func sameCloseReason(a *int, b *int) bool {
+	return a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMCompanyHandler handles requests for company settings
+type CRMCompanyHandler struct {
+	companyRepo  models.CompanyRepository
+	phoneService *services.PhoneService
+}
+
+// NewCRMCompanyHandler creates a new company handler
+func NewCRMCompanyHandler(repos *models.CRMRepositories) *CRMCompanyHandler {
+	return &CRMCompanyHandler{
+		companyRepo:  repos.CompanyRepo,
+		phoneService: services.NewPhoneService(repos),
+	}
+}
+
+// GetSettings returns a company's settings, empty if none have been saved
+func (h *CRMCompanyHandler) GetSettings(c *gin.Context) {
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	settings, err := h.companyRepo.GetSettings(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company settings"})
+		return
+	}
+	if settings == nil {
+		settings = &models.CompanySettings{CompanyId: companyId, Timezone: "UTC", BaseCurrency: models.DefaultCurrency}
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings saves a company's settings
+func (h *CRMCompanyHandler) UpdateSettings(c *gin.Context) {
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	var settings models.CompanySettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings.CompanyId = companyId
+
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	settings.BaseCurrency = strings.ToUpper(settings.BaseCurrency)
+	if settings.BaseCurrency == "" {
+		settings.BaseCurrency = models.DefaultCurrency
+	}
+	if !currencyCodePattern.MatchString(settings.BaseCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid base_currency. Use a 3-letter ISO 4217 code"})
+		return
+	}
+
+	settings.DefaultCountry = strings.ToUpper(strings.TrimSpace(settings.DefaultCountry))
+	if settings.DefaultCountry != "" && !services.ValidPhoneCountry(settings.DefaultCountry) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid default_country. Use a 2-letter ISO 3166-1 region code"})
+		return
+	}
+
+	if err := h.companyRepo.SaveSettings(&settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save company settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// BackfillPhones normalizes the company's stored phone numbers that have not been normalized,
+// such as those saved before it set a default country, and reports those that are invalid
+func (h *CRMCompanyHandler) BackfillPhones(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	result, err := h.phoneService.Backfill(c.Request.Context(), companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to backfill phone numbers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// phoneNormalized checks the result of normalizing phone numbers, writing a 400 naming an invalid
+// number or a 500 if the numbers could not be checked
+func phoneNormalized(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var invalid *services.InvalidPhoneError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error(), "field": invalid.Field})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check phone number"})
+	return false
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage is the page the unsubscribe link in an email body opens. Unsubscribing takes a
+// POST so link scanners that follow every URL in a message cannot unsubscribe the recipient.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 48px auto; padding: 0 16px;">
+{{if .Done}}
+<p>{{.Email}} has been unsubscribed{{if .Company}} from {{.Company}}{{end}} and will no longer receive marketing email.</p>
+{{else}}
+<p>Stop marketing email{{if .Company}} from {{.Company}}{{end}} to {{.Email}}?</p>
+<form method="post"><button type="submit">Unsubscribe</button></form>
+{{end}}
+</body>
+</html>
+`))
+
+// CRMConsentHandler handles requests for communication consent, the suppression list and
+// the public preference center
+type CRMConsentHandler struct {
+	leadRepo       models.LeadRepository
+	consentRepo    models.ConsentRepository
+	consentService *services.ConsentService
+	unsubscribe    *services.UnsubscribeLinks
+}
+
+// NewCRMConsentHandler creates a new consent handler
+func NewCRMConsentHandler(repos *models.CRMRepositories) *CRMConsentHandler {
+	return &CRMConsentHandler{
+		leadRepo:       repos.LeadRepo,
+		consentRepo:    repos.ConsentRepo,
+		consentService: services.NewConsentService(repos),
+		unsubscribe:    services.NewUnsubscribeLinksFromEnv(),
+	}
+}
+
+// GetLeadConsent returns a lead's consent per channel and whether its address is suppressed
+func (h *CRMConsentHandler) GetLeadConsent(c *gin.Context) {
+	lead, ok := h.findLead(c)
+	if !ok {
+		return
+	}
+
+	consents, err := h.consentRepo.GetLeadConsents(int(lead.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent"})
+		return
+	}
+
+	var suppression *models.EmailSuppression
+	values, err := h.leadRepo.GetFieldValues(int(lead.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+		return
+	}
+	if email := values["email"]; email != "" {
+		suppression, err = h.consentRepo.FindSuppression(lead.CompanyId, services.NormalizeEmail(email))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppression list"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lead_id":     lead.ID,
+		"consents":    consents,
+		"suppression": suppression,
+	})
+}
+
+// UpdateLeadConsent sets a lead's consent for a channel
+func (h *CRMConsentHandler) UpdateLeadConsent(c *gin.Context) {
+	lead, ok := h.findLead(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Channel string `json:"channel" binding:"required"`
+		Status  string `json:"status" binding:"required"`
+		Source  string `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if reqBody.Source == "" {
+		reqBody.Source = "manual"
+	}
+
+	source := services.ConsentChangeSource{
+		Source:      reqBody.Source,
+		ChangedByID: currentUserID(c),
+		IPAddress:   c.ClientIP(),
+	}
+	consent, err := h.consentService.SetLeadConsent(int(lead.ID), lead.CompanyId, reqBody.Channel, reqBody.Status, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, consent)
+}
+
+// GetLeadConsentHistory returns the audit trail of a lead's consent changes
+func (h *CRMConsentHandler) GetLeadConsentHistory(c *gin.Context) {
+	lead, ok := h.findLead(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.consentRepo.GetConsentChanges(int(lead.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// GetSuppressions returns a company's suppression list, optionally searched by address
+func (h *CRMConsentHandler) GetSuppressions(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	suppressions, err := h.consentRepo.GetSuppressions(offset, limit, c.Query("q"), companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppression list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, suppressions)
+}
+
+// AddSuppression adds an address to a company's suppression list
+func (h *CRMConsentHandler) AddSuppression(c *gin.Context) {
+	var reqBody struct {
+		Email     string `json:"email" binding:"required"`
+		Reason    string `json:"reason"`
+		Notes     string `json:"notes"`
+		CompanyId int    `json:"company_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := services.NormalizeEmail(reqBody.Email)
+	if !isValidEmail(email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	if reqBody.Reason == "" {
+		reqBody.Reason = models.SuppressionReasonManual
+	}
+
+	source := services.ConsentChangeSource{
+		Source:      "suppression_list",
+		ChangedByID: currentUserID(c),
+		IPAddress:   c.ClientIP(),
+	}
+	suppression, err := h.consentService.Suppress(reqBody.CompanyId, email, reqBody.Reason, reqBody.Notes, source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add address to the suppression list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// DeleteSuppression removes an address from a company's suppression list
+func (h *CRMConsentHandler) DeleteSuppression(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suppression ID"})
+		return
+	}
+
+	suppression, err := h.consentRepo.GetSuppressionByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppression"})
+		return
+	}
+	if suppression == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suppression not found"})
+		return
+	}
+
+	source := services.ConsentChangeSource{
+		Source:      "suppression_list",
+		ChangedByID: currentUserID(c),
+		IPAddress:   c.ClientIP(),
+	}
+	if err := h.consentService.Unsuppress(suppression, source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove address from the suppression list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address removed from the suppression list"})
+}
+
+// GetPreferences returns the channels the recipient of a preference center link accepts
+func (h *CRMConsentHandler) GetPreferences(c *gin.Context) {
+	companyId, email, ok := h.verifyPreferencesToken(c)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.consentService.GetPreferences(companyId, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences saves the recipient's choices from the preference center
+func (h *CRMConsentHandler) UpdatePreferences(c *gin.Context) {
+	companyId, email, ok := h.verifyPreferencesToken(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Channels map[string]bool `json:"channels" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := services.ConsentChangeSource{Source: "preference_center", IPAddress: c.ClientIP()}
+	if err := h.consentService.UpdatePreferences(companyId, email, reqBody.Channels, source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preferences, err := h.consentService.GetPreferences(companyId, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// ShowUnsubscribe renders the unsubscribe confirmation page
+func (h *CRMConsentHandler) ShowUnsubscribe(c *gin.Context) {
+	h.renderUnsubscribe(c, false)
+}
+
+// Unsubscribe opts the recipient out of marketing email. Mail clients post here directly for
+// RFC 8058 one-click unsubscribe.
+func (h *CRMConsentHandler) Unsubscribe(c *gin.Context) {
+	h.renderUnsubscribe(c, true)
+}
+
+func (h *CRMConsentHandler) renderUnsubscribe(c *gin.Context, unsubscribe bool) {
+	companyId, email, ok := h.verifyPreferencesToken(c)
+	if !ok {
+		return
+	}
+
+	if unsubscribe {
+		source := services.ConsentChangeSource{Source: "unsubscribe_link", IPAddress: c.ClientIP()}
+		if err := h.consentService.Unsubscribe(companyId, email, source); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+			return
+		}
+	}
+
+	preferences, err := h.consentService.GetPreferences(companyId, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	unsubscribePage.Execute(c.Writer, gin.H{
+		"Email":   preferences.Email,
+		"Company": preferences.Company,
+		"Done":    unsubscribe,
+	})
+}
+
+// verifyPreferencesToken resolves a preference center token, writing an error response if invalid
+func (h *CRMConsentHandler) verifyPreferencesToken(c *gin.Context) (int, string, bool) {
+	if h.unsubscribe == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preference center is not configured"})
+		return 0, "", false
+	}
+	companyId, email, ok := h.unsubscribe.VerifyPreferences(c.Param("token"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preferences link"})
+		return 0, "", false
+	}
+	return companyId, email, true
+}
+
+// findLead loads the lead in the route, writing an error response if it cannot
+func (h *CRMConsentHandler) findLead(c *gin.Context) (*models.Lead, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return nil, false
+	}
+
+	lead, err := h.leadRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+		return nil, false
+	}
+	if lead == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return nil, false
+	}
+	return lead, true
+}
+
+// currentUserID returns the authenticated user's ID, or nil if there is none. The JWT
+// middleware stores the user_id claim as it was encoded in the token.
+func currentUserID(c *gin.Context) *int {
+	value, exists := c.Get("userId")
+	if !exists || value == nil {
+		return nil
+	}
+	id, err := strconv.Atoi(fmt.Sprint(value))
+	if err != nil {
+		return nil
+	}
+	return &id
+}
@@ -5,25 +5,34 @@ import (
 	"strconv"
 
 	"crm-app/backend/models"
+	"crm-app/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CRMContactHandler handles requests for contact management
 type CRMContactHandler struct {
-	contactRepo models.ContactRepository
-	leadRepo    models.LeadRepository
+	contactRepo  models.ContactRepository
+	leadRepo     models.LeadRepository
+	accountRepo  models.AccountRepository
+	dealRepo     models.DealRepository
+	auditRepo    models.AuditRepository
+	phoneService *services.PhoneService
 }
 
 // NewCRMContactHandler creates a new contact handler
 func NewCRMContactHandler(repos *models.CRMRepositories) *CRMContactHandler {
 	return &CRMContactHandler{
-		contactRepo: repos.ContactRepo,
-		leadRepo:    repos.LeadRepo,
+		contactRepo:  repos.ContactRepo,
+		leadRepo:     repos.LeadRepo,
+		accountRepo:  repos.AccountRepo,
+		dealRepo:     repos.DealRepo,
+		auditRepo:    repos.AuditRepo,
+		phoneService: services.NewPhoneService(repos),
 	}
 }
 
-// GetContacts returns all contacts with pagination
+// GetContacts returns all contacts with pagination, optionally only those with given tags
 func (h *CRMContactHandler) GetContacts(c *gin.Context) {
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
@@ -34,7 +43,12 @@ func (h *CRMContactHandler) GetContacts(c *gin.Context) {
 		return
 	}
 
-	contacts, err := h.contactRepo.List(offset, limit, companyId)
+	tags, ok := tagFilterQuery(c)
+	if !ok {
+		return
+	}
+
+	contacts, err := h.contactRepo.List(offset, limit, tags, companyId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
 		return
@@ -73,6 +87,10 @@ func (h *CRMContactHandler) CreateContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(contact.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// If lead_id is provided, verify that the lead exists
 	if contact.LeadID != nil {
@@ -86,6 +104,12 @@ func (h *CRMContactHandler) CreateContact(c *gin.Context) {
 			return
 		}
 	}
+	if !verifyAccount(c, h.accountRepo, contact.AccountID, contact.CompanyId) {
+		return
+	}
+	if !phoneNormalized(c, h.phoneService.NormalizeContact(&contact)) {
+		return
+	}
 
 	if err := h.contactRepo.Create(&contact); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
@@ -120,6 +144,10 @@ func (h *CRMContactHandler) UpdateContact(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(contact.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Ensure ID matches the URL parameter
 	contact.ID = id
@@ -136,6 +164,12 @@ func (h *CRMContactHandler) UpdateContact(c *gin.Context) {
 			return
 		}
 	}
+	if !verifyAccount(c, h.accountRepo, contact.AccountID, contact.CompanyId) {
+		return
+	}
+	if !phoneNormalized(c, h.phoneService.NormalizeContact(&contact)) {
+		return
+	}
 
 	if err := h.contactRepo.Update(&contact); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
@@ -169,6 +203,7 @@ func (h *CRMContactHandler) DeleteContact(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contact"})
 		return
 	}
+	recordAudit(c, h.auditRepo, models.TrashTypeContact, id, models.AuditActionDelete, existingContact.CompanyId)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
 }
@@ -213,3 +248,56 @@ func (h *CRMContactHandler) SearchContacts(c *gin.Context) {
 
 	c.JSON(http.StatusOK, contacts)
 }
+
+// GetContactDeals returns the deals a contact is on the buying committee of
+func (h *CRMContactHandler) GetContactDeals(c *gin.Context) {
+	contact, ok := h.findContact(c)
+	if !ok {
+		return
+	}
+
+	deals, err := h.dealRepo.GetContactDeals(contact.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deals)
+}
+
+// GetContactAccounts returns a contact's own account and the other accounts it is linked to
+func (h *CRMContactHandler) GetContactAccounts(c *gin.Context) {
+	contact, ok := h.findContact(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.accountRepo.GetContactAccounts(contact.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// findContact loads the contact named by the :id parameter, writing an error response if it fails
+func (h *CRMContactHandler) findContact(c *gin.Context) (*models.Contact, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return nil, false
+	}
+
+	contact, err := h.contactRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact"})
+		return nil, false
+	}
+	if contact == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return nil, false
+	}
+
+	return contact, true
+}
@@ -124,7 +124,7 @@ func (h *CRMDashboardHandler) GetTopDeals(c *gin.Context) {
 		return
 	}
 	// Get deals sorted by amount, limiting to 5 results
-	deals, err := h.dealRepo.List(0, limit, map[string]interface{}{}, companyId)
+	deals, err := h.dealRepo.List(0, limit, map[string]interface{}{}, nil, companyId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch top deals"})
 		return
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type dealContactRequest struct {
+	ContactID int    `json:"contact_id"` // only read when adding a contact
+	Role      string `json:"role" binding:"required"`
+	IsPrimary bool   `json:"is_primary"`
+	Notes     string `json:"notes"`
+}
+
+// GetDealContacts returns a deal's buying committee
+func (h *CRMDealHandler) GetDealContacts(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	dealContacts, err := h.dealRepo.GetDealContacts(deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dealContacts)
+}
+
+// AddDealContact adds a contact to a deal's buying committee with a role
+func (h *CRMDealHandler) AddDealContact(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	var reqBody dealContactRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validDealContactRole(c, reqBody.Role) {
+		return
+	}
+
+	contact, err := h.contactRepo.FindByID(reqBody.ContactID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify contact"})
+		return
+	}
+	if contact == nil || contact.CompanyId != deal.CompanyId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contact does not exist"})
+		return
+	}
+
+	existing, err := h.dealRepo.GetDealContact(deal.ID, contact.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal contact"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contact is already on this deal"})
+		return
+	}
+
+	dealContact := &models.DealContact{
+		DealID:    deal.ID,
+		ContactID: contact.ID,
+		Role:      reqBody.Role,
+		IsPrimary: reqBody.IsPrimary,
+		Notes:     reqBody.Notes,
+		CompanyId: deal.CompanyId,
+	}
+	if err := h.dealRepo.SaveDealContact(dealContact); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add deal contact"})
+		return
+	}
+	dealContact.Contact = contact
+
+	c.JSON(http.StatusCreated, dealContact)
+}
+
+// UpdateDealContact changes a contact's role on a deal or makes it the primary contact
+func (h *CRMDealHandler) UpdateDealContact(c *gin.Context) {
+	dealContact, ok := h.findDealContact(c)
+	if !ok {
+		return
+	}
+
+	var reqBody dealContactRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validDealContactRole(c, reqBody.Role) {
+		return
+	}
+
+	dealContact.Role = reqBody.Role
+	dealContact.IsPrimary = reqBody.IsPrimary
+	dealContact.Notes = reqBody.Notes
+	if err := h.dealRepo.SaveDealContact(dealContact); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deal contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dealContact)
+}
+
+// RemoveDealContact removes a contact from a deal's buying committee
+func (h *CRMDealHandler) RemoveDealContact(c *gin.Context) {
+	dealContact, ok := h.findDealContact(c)
+	if !ok {
+		return
+	}
+
+	if err := h.dealRepo.DeleteDealContact(dealContact); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove deal contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact removed from deal successfully"})
+}
+
+// findDealContact loads the buying committee entry of the deal named by :id for the contact named
+// by :contactId, writing an error response if it fails
+func (h *CRMDealHandler) findDealContact(c *gin.Context) (*models.DealContact, bool) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return nil, false
+	}
+	contactID, err := strconv.Atoi(c.Param("contactId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return nil, false
+	}
+
+	dealContact, err := h.dealRepo.GetDealContact(deal.ID, contactID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal contact"})
+		return nil, false
+	}
+	if dealContact == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact is not on this deal"})
+		return nil, false
+	}
+
+	return dealContact, true
+}
+
+// validDealContactRole checks a buying committee role, writing an error response if it is unknown
+func validDealContactRole(c *gin.Context, role string) bool {
+	for _, known := range models.DealContactRoles {
+		if role == known {
+			return true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of decision_maker, champion, influencer, economic_buyer or blocker"})
+	return false
+}
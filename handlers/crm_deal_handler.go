@@ -12,15 +12,25 @@ import (
 
 // CRMDealHandler handles requests for deal management
 type CRMDealHandler struct {
-	dealRepo models.DealRepository
-	leadRepo models.LeadRepository
+	dealRepo        models.DealRepository
+	leadRepo        models.LeadRepository
+	productRepo     models.ProductRepository
+	closeReasonRepo models.CloseReasonRepository
+	accountRepo     models.AccountRepository
+	contactRepo     models.ContactRepository
+	auditRepo       models.AuditRepository
 }
 
 // NewCRMDealHandler creates a new deal handler
 func NewCRMDealHandler(repos *models.CRMRepositories) *CRMDealHandler {
 	return &CRMDealHandler{
-		dealRepo: repos.DealRepo,
-		leadRepo: repos.LeadRepo,
+		dealRepo:        repos.DealRepo,
+		leadRepo:        repos.LeadRepo,
+		productRepo:     repos.ProductRepo,
+		closeReasonRepo: repos.CloseReasonRepo,
+		accountRepo:     repos.AccountRepo,
+		contactRepo:     repos.ContactRepo,
+		auditRepo:       repos.AuditRepo,
 	}
 }
 
@@ -53,6 +63,10 @@ func (h *CRMDealHandler) GetDeals(c *gin.Context) {
 		}
 	}
 
+	if accountID, err := strconv.Atoi(c.Query("account_id")); err == nil {
+		filters["account_id"] = accountID
+	}
+
 	if minAmountStr != "" {
 		minAmount, err := strconv.ParseFloat(minAmountStr, 64)
 		if err == nil {
@@ -67,7 +81,12 @@ func (h *CRMDealHandler) GetDeals(c *gin.Context) {
 		}
 	}
 
-	deals, err := h.dealRepo.List(offset, limit, filters, companyId)
+	tags, ok := tagFilterQuery(c)
+	if !ok {
+		return
+	}
+
+	deals, err := h.dealRepo.List(offset, limit, filters, tags, companyId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
 		return
@@ -96,6 +115,12 @@ func (h *CRMDealHandler) GetDeal(c *gin.Context) {
 		return
 	}
 
+	deal.BuyingCommittee, err = h.dealRepo.GetDealContacts(deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal contacts"})
+		return
+	}
+
 	c.JSON(http.StatusOK, deal)
 }
 
@@ -106,6 +131,10 @@ func (h *CRMDealHandler) CreateDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(deal.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Verify that the lead exists
 	lead, err := h.leadRepo.FindByID(deal.LeadID)
@@ -118,6 +147,15 @@ func (h *CRMDealHandler) CreateDeal(c *gin.Context) {
 		return
 	}
 
+	if !verifyAccount(c, h.accountRepo, deal.AccountID, deal.CompanyId) {
+		return
+	}
+
+	// Deals can be created already won or lost
+	if !applyDealClose(c, h.closeReasonRepo, &deal, models.Deal{}, dealCloseDetails(&deal)) {
+		return
+	}
+
 	if err := h.dealRepo.Create(&deal); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create deal"})
 		return
@@ -151,6 +189,10 @@ func (h *CRMDealHandler) UpdateDeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(deal.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Ensure ID matches the URL parameter
 	deal.ID = id
@@ -166,6 +208,15 @@ func (h *CRMDealHandler) UpdateDeal(c *gin.Context) {
 		return
 	}
 
+	if !verifyAccount(c, h.accountRepo, deal.AccountID, deal.CompanyId) {
+		return
+	}
+
+	// Record why the deal was won or lost if this closes it
+	if !applyDealClose(c, h.closeReasonRepo, &deal, *existingDeal, dealCloseDetails(&deal)) {
+		return
+	}
+
 	if err := h.dealRepo.Update(&deal); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deal"})
 		return
@@ -198,6 +249,7 @@ func (h *CRMDealHandler) DeleteDeal(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deal"})
 		return
 	}
+	recordAudit(c, h.auditRepo, models.TrashTypeDeal, id, models.AuditActionDelete, existingDeal.CompanyId)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Deal deleted successfully"})
 }
@@ -248,6 +300,7 @@ func (h *CRMDealHandler) UpdateDealStage(c *gin.Context) {
 
 	var reqBody struct {
 		Stage string `json:"stage" binding:"required"`
+		dealCloseRequest
 	}
 	if err := c.ShouldBindJSON(&reqBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -265,11 +318,56 @@ func (h *CRMDealHandler) UpdateDealStage(c *gin.Context) {
 		return
 	}
 
-	// Update the stage
-	deal.Stage = reqBody.Stage
+	if !moveDealToStage(c, h.dealRepo, h.closeReasonRepo, deal, reqBody.Stage, reqBody.dealCloseRequest) {
+		return
+	}
+
+	c.JSON(http.StatusOK, deal)
+}
+
+// findDealByParam loads the deal named by the :id parameter, writing an error response if it fails
+func findDealByParam(c *gin.Context, dealRepo models.DealRepository) (*models.Deal, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return nil, false
+	}
+
+	deal, err := dealRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal"})
+		return nil, false
+	}
+	if deal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return nil, false
+	}
+
+	return deal, true
+}
+
+// moveDealToStage moves a deal to a stage and saves it, recording why the deal was won or lost if
+// this closes it. It writes an error response and returns false if that fails.
+func moveDealToStage(c *gin.Context, dealRepo models.DealRepository, closeReasonRepo models.CloseReasonRepository, deal *models.Deal, stage string, details dealCloseRequest) bool {
+	previous := *deal
+	applyDealStage(deal, stage)
+	if !applyDealClose(c, closeReasonRepo, deal, previous, details) {
+		return false
+	}
+
+	if err := dealRepo.Update(deal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deal stage"})
+		return false
+	}
+	return true
+}
+
+// applyDealStage moves a deal to a stage, updating its probability for the stages that imply one
+func applyDealStage(deal *models.Deal, stage string) {
+	deal.Stage = stage
 
 	// Auto-update probability based on stage
-	switch reqBody.Stage {
+	switch stage {
 	case "prospecting":
 		deal.Probability = 10
 	case "qualification":
@@ -285,11 +383,4 @@ func (h *CRMDealHandler) UpdateDealStage(c *gin.Context) {
 	case "lost":
 		deal.Probability = 0
 	}
-
-	if err := h.dealRepo.Update(deal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deal stage"})
-		return
-	}
-
-	c.JSON(http.StatusOK, deal)
 }
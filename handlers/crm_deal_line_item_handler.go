@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type dealLineItemRequest struct {
+	ProductID       int      `json:"product_id" binding:"required"`
+	PriceBookID     *int     `json:"price_book_id"`
+	Quantity        float64  `json:"quantity"`
+	UnitPrice       *float64 `json:"unit_price"` // taken from the price book when omitted
+	DiscountPercent float64  `json:"discount_percent"`
+	TaxPercent      float64  `json:"tax_percent"`
+	Position        int      `json:"position"`
+}
+
+// GetDealLineItems returns a deal's line items and amount
+func (h *CRMDealHandler) GetDealLineItems(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	items, err := h.dealRepo.GetLineItems(deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch line items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deal_id":    deal.ID,
+		"currency":   deal.Currency,
+		"amount":     deal.Amount,
+		"line_items": items,
+	})
+}
+
+// CreateDealLineItem adds a product to a deal and recomputes the deal's amount
+func (h *CRMDealHandler) CreateDealLineItem(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	item := &models.DealLineItem{
+		DealID:    deal.ID,
+		CompanyId: deal.CompanyId,
+	}
+	if !h.bindDealLineItem(c, deal, item) {
+		return
+	}
+
+	updatedDeal, err := h.dealRepo.SaveLineItem(item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create line item"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"line_item": item,
+		"deal":      updatedDeal,
+	})
+}
+
+// UpdateDealLineItem updates a deal's line item and recomputes the deal's amount
+func (h *CRMDealHandler) UpdateDealLineItem(c *gin.Context) {
+	deal, item, ok := h.findDealLineItem(c)
+	if !ok {
+		return
+	}
+	if !h.bindDealLineItem(c, deal, item) {
+		return
+	}
+
+	updatedDeal, err := h.dealRepo.SaveLineItem(item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update line item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"line_item": item,
+		"deal":      updatedDeal,
+	})
+}
+
+// DeleteDealLineItem removes a line item from a deal and recomputes the deal's amount
+func (h *CRMDealHandler) DeleteDealLineItem(c *gin.Context) {
+	_, item, ok := h.findDealLineItem(c)
+	if !ok {
+		return
+	}
+
+	updatedDeal, err := h.dealRepo.DeleteLineItem(item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete line item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Line item deleted successfully",
+		"deal":    updatedDeal,
+	})
+}
+
+// bindDealLineItem reads a line item from the request body into item, pricing it from a price
+// book when no unit price is given, and writes an error response if it is invalid
+func (h *CRMDealHandler) bindDealLineItem(c *gin.Context, deal *models.Deal, item *models.DealLineItem) bool {
+	var reqBody dealLineItemRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if reqBody.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be positive"})
+		return false
+	}
+	if reqBody.DiscountPercent < 0 || reqBody.DiscountPercent > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "discount_percent must be between 0 and 100"})
+		return false
+	}
+	if reqBody.TaxPercent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tax_percent cannot be negative"})
+		return false
+	}
+
+	product, err := h.productRepo.GetProductByID(reqBody.ProductID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return false
+	}
+	if product == nil || product.CompanyId != deal.CompanyId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product does not exist"})
+		return false
+	}
+	if !product.IsActive && product.ID != item.ProductID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product is inactive"})
+		return false
+	}
+
+	priceBookID := reqBody.PriceBookID
+	if priceBookID == nil {
+		priceBookID = deal.PriceBookID
+	}
+	if reqBody.UnitPrice != nil {
+		if *reqBody.UnitPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unit_price cannot be negative"})
+			return false
+		}
+		item.UnitPrice = *reqBody.UnitPrice
+	} else {
+		entry, ok := h.lookupPrice(c, deal, product.ID, priceBookID)
+		if !ok {
+			return false
+		}
+		item.UnitPrice = entry.UnitPrice
+		priceBookID = &entry.PriceBookID
+	}
+
+	item.ProductID = product.ID
+	item.PriceBookID = priceBookID
+	item.Name = product.Name
+	item.SKU = product.SKU
+	item.Quantity = reqBody.Quantity
+	item.DiscountPercent = reqBody.DiscountPercent
+	item.TaxPercent = reqBody.TaxPercent
+	item.Position = reqBody.Position
+	return true
+}
+
+// lookupPrice returns a product's price in the deal's currency from a price book, or from the
+// company's default price book, writing an error response if there is none
+func (h *CRMDealHandler) lookupPrice(c *gin.Context, deal *models.Deal, productID int, priceBookID *int) (*models.PriceBookEntry, bool) {
+	var priceBook *models.PriceBook
+	var err error
+	if priceBookID != nil {
+		priceBook, err = h.productRepo.GetPriceBookByID(*priceBookID)
+	} else {
+		priceBook, err = h.productRepo.GetDefaultPriceBook(deal.CompanyId)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price book"})
+		return nil, false
+	}
+	if priceBook == nil || priceBook.CompanyId != deal.CompanyId || !priceBook.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit_price is required when no active price book applies"})
+		return nil, false
+	}
+
+	currency := strings.ToUpper(deal.Currency)
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	entry, err := h.productRepo.GetPrice(priceBook.ID, productID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price"})
+		return nil, false
+	}
+	if entry == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product has no " + currency + " price in price book " + priceBook.Name})
+		return nil, false
+	}
+	return entry, true
+}
+
+// findDealLineItem loads the deal named by :id and its line item named by :itemId, writing an
+// error response if either fails
+func (h *CRMDealHandler) findDealLineItem(c *gin.Context) (*models.Deal, *models.DealLineItem, bool) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return nil, nil, false
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid line item ID"})
+		return nil, nil, false
+	}
+
+	item, err := h.dealRepo.GetLineItemByID(itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch line item"})
+		return nil, nil, false
+	}
+	if item == nil || item.DealID != deal.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Line item not found"})
+		return nil, nil, false
+	}
+
+	return deal, item, true
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"strconv"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMEmailHandler handles requests for sender identities and outbound email
+type CRMEmailHandler struct {
+	emailRepo    models.EmailRepository
+	emailService *services.EmailService
+}
+
+// NewCRMEmailHandler creates a new email handler
+func NewCRMEmailHandler(repos *models.CRMRepositories) *CRMEmailHandler {
+	return &CRMEmailHandler{
+		emailRepo:    repos.EmailRepo,
+		emailService: services.NewEmailService(repos, nil),
+	}
+}
+
+// GetSenderIdentities returns a company's sender identities
+func (h *CRMEmailHandler) GetSenderIdentities(c *gin.Context) {
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	identities, err := h.emailRepo.GetSenderIdentities(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sender identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// CreateSenderIdentity creates a sender identity
+func (h *CRMEmailHandler) CreateSenderIdentity(c *gin.Context) {
+	var identity models.EmailSenderIdentity
+	if err := c.ShouldBindJSON(&identity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateSenderIdentity(&identity); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.emailRepo.CreateSenderIdentity(&identity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, identity)
+}
+
+// UpdateSenderIdentity updates a sender identity
+func (h *CRMEmailHandler) UpdateSenderIdentity(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender identity ID"})
+		return
+	}
+
+	existingIdentity, err := h.emailRepo.GetSenderIdentityByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sender identity"})
+		return
+	}
+	if existingIdentity == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sender identity not found"})
+		return
+	}
+
+	var identity models.EmailSenderIdentity
+	if err := c.ShouldBindJSON(&identity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored identity
+	identity.ID = id
+	identity.CompanyId = existingIdentity.CompanyId
+
+	if msg := validateSenderIdentity(&identity); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.emailRepo.UpdateSenderIdentity(&identity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// DeleteSenderIdentity deletes a sender identity
+func (h *CRMEmailHandler) DeleteSenderIdentity(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender identity ID"})
+		return
+	}
+
+	existingIdentity, err := h.emailRepo.GetSenderIdentityByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sender identity"})
+		return
+	}
+	if existingIdentity == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sender identity not found"})
+		return
+	}
+
+	if err := h.emailRepo.DeleteSenderIdentity(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sender identity deleted successfully"})
+}
+
+// GetMessages returns a company's outbound email, optionally filtered by status, lead or campaign
+func (h *CRMEmailHandler) GetMessages(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if leadID, err := strconv.Atoi(c.Query("lead_id")); err == nil {
+		filters["lead_id"] = leadID
+	}
+	if campaignID, err := strconv.Atoi(c.Query("campaign_id")); err == nil {
+		filters["campaign_id"] = campaignID
+	}
+
+	messages, err := h.emailRepo.GetMessages(offset, limit, filters, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// GetMessage returns an outbound email by ID
+func (h *CRMEmailHandler) GetMessage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	message, err := h.emailRepo.GetMessageByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message"})
+		return
+	}
+	if message == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// SendMessage queues a one-off email for delivery
+func (h *CRMEmailHandler) SendMessage(c *gin.Context) {
+	var message models.EmailMessage
+	if err := c.ShouldBindJSON(&message); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if message.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return
+	}
+
+	// Delivery state is owned by the send queue
+	message.ID = 0
+	message.FromName = ""
+	message.FromEmail = ""
+	message.EnrollmentID = nil
+	message.StepID = nil
+	message.Attempts = 0
+	message.ProviderMessageID = ""
+	message.SentAt = nil
+	message.BouncedAt = nil
+
+	if err := h.emailService.Queue(&message); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, message)
+}
+
+// HandleDeliveryWebhook receives bounce and complaint notifications from the email provider.
+// Requests must carry the EMAIL_WEBHOOK_SECRET in the X-Webhook-Secret header. Events identified
+// by address only need a company_id, which defaults to the companyId query parameter.
+func (h *CRMEmailHandler) HandleDeliveryWebhook(c *gin.Context) {
+	secret := os.Getenv("EMAIL_WEBHOOK_SECRET")
+	if secret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email webhook is not configured"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Webhook-Secret")), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var reqBody struct {
+		Events []models.EmailDeliveryEvent `json:"events" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	companyId := 0
+	if companyIdStr := c.Query("companyId"); companyIdStr != "" {
+		var err error
+		if companyId, err = strconv.Atoi(companyIdStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+			return
+		}
+	}
+
+	processed := 0
+	unmatched := 0
+	for _, event := range reqBody.Events {
+		if event.CompanyId == 0 {
+			event.CompanyId = companyId
+		}
+		message, err := h.emailService.HandleDeliveryEvent(event)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if message == nil {
+			unmatched++
+			continue
+		}
+		processed++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"processed": processed, "unmatched": unmatched})
+}
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackOpen records an email open and returns the tracking pixel. The pixel is returned even for
+// invalid tokens so mail clients never show a broken image.
+func (h *CRMEmailHandler) TrackOpen(c *gin.Context) {
+	if tracker := h.emailService.Tracker(); tracker != nil {
+		if messageID, ok := tracker.VerifyOpen(c.Param("token")); ok {
+			if err := h.emailService.RecordTrackingEvent(messageID, "open", "", c.ClientIP(), c.Request); err != nil {
+				log.Printf("Failed to record open of email %d: %v", messageID, err)
+			}
+		}
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Data(http.StatusOK, "image/gif", trackingPixel)
+}
+
+// TrackClick records a link click and redirects to the original URL. The signature covers the
+// target URL, so the endpoint cannot be used as an open redirect.
+func (h *CRMEmailHandler) TrackClick(c *gin.Context) {
+	tracker := h.emailService.Tracker()
+	if tracker == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Click tracking is not configured"})
+		return
+	}
+
+	target := c.Query("u")
+	messageID, ok := tracker.VerifyClick(c.Param("token"), target)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tracking link"})
+		return
+	}
+
+	if err := h.emailService.RecordTrackingEvent(messageID, "click", target, c.ClientIP(), c.Request); err != nil {
+		log.Printf("Failed to record click of email %d: %v", messageID, err)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// validateSenderIdentity checks a sender identity, returning an error message if invalid
+func validateSenderIdentity(identity *models.EmailSenderIdentity) string {
+	if identity.CompanyId == 0 {
+		return "company_id is required"
+	}
+	if identity.Name == "" {
+		return "Sender name is required"
+	}
+	if !isValidEmail(identity.Email) {
+		return "Invalid sender email"
+	}
+	if identity.ReplyTo != "" && !isValidEmail(identity.ReplyTo) {
+		return "Invalid reply-to email"
+	}
+	return ""
+}
+
+func isValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
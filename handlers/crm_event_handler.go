@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMEventHandler handles requests for the domain events in the outbox
+type CRMEventHandler struct {
+	outboxRepo models.OutboxRepository
+}
+
+// NewCRMEventHandler creates a new domain event handler
+func NewCRMEventHandler(repos *models.CRMRepositories) *CRMEventHandler {
+	return &CRMEventHandler{
+		outboxRepo: repos.OutboxRepo,
+	}
+}
+
+// GetEvents returns a company's domain events newest first, optionally only those in a status
+func (h *CRMEventHandler) GetEvents(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxEventPending, models.OutboxEventDispatched, models.OutboxEventFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, dispatched or failed"})
+		return
+	}
+
+	events, err := h.outboxRepo.GetEvents(status, offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetEvent returns a domain event with its payload, the subscribers that have handled it and its
+// latest error
+func (h *CRMEventHandler) GetEvent(c *gin.Context) {
+	event, ok := h.findEvent(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ReplayEvent dispatches a failed event again, to the subscribers that have not handled it
+func (h *CRMEventHandler) ReplayEvent(c *gin.Context) {
+	event, ok := h.findEvent(c)
+	if !ok {
+		return
+	}
+	if event.Status != models.OutboxEventFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only failed events can be replayed"})
+		return
+	}
+
+	if err := h.outboxRepo.ReplayEvent(event, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay event"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, event)
+}
+
+// findEvent loads the domain event named by the :id parameter, writing an error response if it
+// fails
+func (h *CRMEventHandler) findEvent(c *gin.Context) (*models.OutboxEvent, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, false
+	}
+
+	event, err := h.outboxRepo.GetEventByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return nil, false
+	}
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	return event, true
+}
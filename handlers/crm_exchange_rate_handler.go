@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currencyCodePattern matches an ISO 4217 currency code
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type exchangeRateRequest struct {
+	Currency      string  `json:"currency" binding:"required"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate string  `json:"effective_date" binding:"required"` // YYYY-MM-DD
+}
+
+// CRMExchangeRateHandler handles requests for the exchange rates used to convert amounts into a
+// company's base currency
+type CRMExchangeRateHandler struct {
+	exchangeRateRepo models.ExchangeRateRepository
+	companyRepo      models.CompanyRepository
+}
+
+// NewCRMExchangeRateHandler creates a new exchange rate handler
+func NewCRMExchangeRateHandler(repos *models.CRMRepositories) *CRMExchangeRateHandler {
+	return &CRMExchangeRateHandler{
+		exchangeRateRepo: repos.ExchangeRateRepo,
+		companyRepo:      repos.CompanyRepo,
+	}
+}
+
+// GetExchangeRates returns a company's exchange rates, optionally for one currency
+func (h *CRMExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	baseCurrency, ok := h.baseCurrency(c, companyId)
+	if !ok {
+		return
+	}
+
+	rates, err := h.exchangeRateRepo.GetExchangeRates(offset, limit, strings.ToUpper(c.Query("currency")), companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": baseCurrency,
+		"rates":         rates,
+	})
+}
+
+// CreateExchangeRate records the rate of a currency in the company's base currency from a date on
+func (h *CRMExchangeRateHandler) CreateExchangeRate(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	baseCurrency, ok := h.baseCurrency(c, companyId)
+	if !ok {
+		return
+	}
+
+	rate := &models.ExchangeRate{
+		BaseCurrency: baseCurrency,
+		Source:       models.ExchangeRateSourceManual,
+		CreatedBy:    currentUserID(c),
+		CompanyId:    companyId,
+	}
+	if !bindExchangeRate(c, rate) {
+		return
+	}
+
+	existing, err := h.exchangeRateRepo.GetRate(companyId, baseCurrency, rate.Currency, rate.EffectiveDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+	if existing != nil && existing.EffectiveDate.Format("2006-01-02") == rate.EffectiveDate.Format("2006-01-02") {
+		c.JSON(http.StatusConflict, gin.H{"error": "A rate for this currency and date already exists"})
+		return
+	}
+
+	if err := h.exchangeRateRepo.CreateExchangeRate(rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// UpdateExchangeRate updates an exchange rate
+func (h *CRMExchangeRateHandler) UpdateExchangeRate(c *gin.Context) {
+	rate, ok := h.findExchangeRate(c)
+	if !ok {
+		return
+	}
+	if !bindExchangeRate(c, rate) {
+		return
+	}
+	rate.Source = models.ExchangeRateSourceManual
+
+	if err := h.exchangeRateRepo.UpdateExchangeRate(rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteExchangeRate deletes an exchange rate
+func (h *CRMExchangeRateHandler) DeleteExchangeRate(c *gin.Context) {
+	rate, ok := h.findExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	if err := h.exchangeRateRepo.DeleteExchangeRate(rate.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// ImportExchangeRates imports rates into the company's base currency from an uploaded CSV file.
+// The file needs a header row with currency, rate and effective_date (YYYY-MM-DD) columns; rates
+// already recorded for a currency and date are replaced. Nothing is imported if any row is invalid.
+func (h *CRMExchangeRateHandler) ImportExchangeRates(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	baseCurrency, ok := h.baseCurrency(c, companyId)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	rates, rowErrors, err := parseExchangeRateCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rows in file", "errors": rowErrors})
+		return
+	}
+	if len(rates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File contains no rates"})
+		return
+	}
+
+	createdBy := currentUserID(c)
+	for i := range rates {
+		rates[i].BaseCurrency = baseCurrency
+		rates[i].Source = models.ExchangeRateSourceImport
+		rates[i].CreatedBy = createdBy
+		rates[i].CompanyId = companyId
+	}
+
+	result, err := h.exchangeRateRepo.ImportExchangeRates(rates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseExchangeRateCSV reads rates from CSV, returning the rows it could not parse as errors
+func parseExchangeRateCSV(r io.Reader) ([]models.ExchangeRate, []string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("file has no header row")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"currency", "rate", "effective_date"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	var rowErrors []string
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		var rate models.ExchangeRate
+		if msg := parseExchangeRate(&rate, record[columns["currency"]], record[columns["rate"]], record[columns["effective_date"]]); msg != "" {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: %s", line, msg))
+			continue
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rowErrors, nil
+}
+
+// parseExchangeRate validates a currency, rate and date into rate, returning an error message if invalid
+func parseExchangeRate(rate *models.ExchangeRate, currency string, value string, effectiveDate string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyCodePattern.MatchString(currency) {
+		return "currency must be a 3-letter ISO 4217 code"
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || amount <= 0 {
+		return "rate must be a positive number"
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(effectiveDate))
+	if err != nil {
+		return "Invalid effective_date format. Use YYYY-MM-DD"
+	}
+
+	rate.Currency = currency
+	rate.Rate = amount
+	rate.EffectiveDate = date
+	return ""
+}
+
+// bindExchangeRate reads a rate from the request body into rate, writing an error response if it
+// is invalid
+func bindExchangeRate(c *gin.Context, rate *models.ExchangeRate) bool {
+	var reqBody exchangeRateRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if msg := parseExchangeRate(rate, reqBody.Currency, strconv.FormatFloat(reqBody.Rate, 'f', -1, 64), reqBody.EffectiveDate); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false
+	}
+	if rate.Currency == rate.BaseCurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency must differ from the base currency"})
+		return false
+	}
+	return true
+}
+
+// baseCurrency returns a company's base currency, writing an error response if it fails
+func (h *CRMExchangeRateHandler) baseCurrency(c *gin.Context, companyId int) (string, bool) {
+	settings, err := h.companyRepo.GetSettings(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company settings"})
+		return "", false
+	}
+	if settings == nil || settings.BaseCurrency == "" {
+		return models.DefaultCurrency, true
+	}
+	return settings.BaseCurrency, true
+}
+
+// findExchangeRate loads the exchange rate named by the :id parameter, writing an error response if it fails
+func (h *CRMExchangeRateHandler) findExchangeRate(c *gin.Context) (*models.ExchangeRate, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return nil, false
+	}
+
+	rate, err := h.exchangeRateRepo.GetExchangeRateByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rate"})
+		return nil, false
+	}
+	if rate == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return nil, false
+	}
+
+	return rate, true
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kanban card page sizes
+const (
+	defaultKanbanCards = 20
+	maxKanbanCards     = 100
+)
+
+// CRMKanbanHandler handles requests for the deal and lead kanban boards
+type CRMKanbanHandler struct {
+	viewRepo        models.SavedViewRepository
+	dealRepo        models.DealRepository
+	leadRepo        models.LeadRepository
+	closeReasonRepo models.CloseReasonRepository
+}
+
+// NewCRMKanbanHandler creates a new kanban handler
+func NewCRMKanbanHandler(repos *models.CRMRepositories) *CRMKanbanHandler {
+	return &CRMKanbanHandler{
+		viewRepo:        repos.SavedViewRepo,
+		dealRepo:        repos.DealRepo,
+		leadRepo:        repos.LeadRepo,
+		closeReasonRepo: repos.CloseReasonRepo,
+	}
+}
+
+// GetDealBoard returns a company's deals grouped by stage
+func (h *CRMKanbanHandler) GetDealBoard(c *gin.Context) {
+	h.getBoard(c, models.ViewEntityDeal)
+}
+
+// GetLeadBoard returns a company's leads grouped by status
+func (h *CRMKanbanHandler) GetLeadBoard(c *gin.Context) {
+	h.getBoard(c, models.ViewEntityLead)
+}
+
+// getBoard returns a board of deals or leads, each column with its count, total and first page of
+// cards. view_id filters and sorts the board as a saved view does; column with offset pages
+// through the cards of a single column.
+func (h *CRMKanbanHandler) getBoard(c *gin.Context, entity string) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultKanbanCards)))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = defaultKanbanCards
+	}
+	if limit > maxKanbanCards {
+		limit = maxKanbanCards
+	}
+	var columns []string
+	if column := c.Query("column"); column != "" {
+		columns = []string{column}
+	}
+
+	filters := []models.ViewFilter{}
+	var order models.ViewSort
+	if viewIDStr := c.Query("view_id"); viewIDStr != "" {
+		viewID, err := strconv.Atoi(viewIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid view_id"})
+			return
+		}
+		view, ok := findVisibleView(c, h.viewRepo, viewID)
+		if !ok {
+			return
+		}
+		if view.Entity != entity || view.CompanyId != companyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Saved view does not list this board's records"})
+			return
+		}
+		if filters, err = services.ParseViewFilters(view.Filters); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid saved view filters"})
+			return
+		}
+		order = services.ViewSortOf(view)
+	}
+
+	var board *models.KanbanBoard
+	if entity == models.ViewEntityDeal {
+		board, err = h.viewRepo.GetDealBoard(filters, order, columns, offset, limit, companyId)
+	} else {
+		board, err = h.viewRepo.GetLeadBoard(filters, order, columns, offset, limit, companyId)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch board"})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// MoveDeal moves a deal to another stage column, as changing its stage does, and returns the
+// deal with the updated totals of the columns it left and joined
+func (h *CRMKanbanHandler) MoveDeal(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	var reqBody struct {
+		Stage string `json:"stage" binding:"required"`
+		dealCloseRequest
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deal, err := h.dealRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal"})
+		return
+	}
+	if deal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+
+	from := deal.Stage
+	if !moveDealToStage(c, h.dealRepo, h.closeReasonRepo, deal, reqBody.Stage, reqBody.dealCloseRequest) {
+		return
+	}
+
+	board, err := h.viewRepo.GetDealBoard(nil, models.ViewSort{}, []string{from, deal.Stage}, 0, 0, deal.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Deal moved, but failed to fetch its columns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deal": deal, "columns": board.Columns})
+}
+
+// MoveLead moves a lead to another status column, as qualifying or disqualifying it does, and
+// returns the lead with the updated totals of the columns it left and joined
+func (h *CRMKanbanHandler) MoveLead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return
+	}
+
+	var reqBody struct {
+		Status string `json:"status" binding:"required"`
+		leadDisqualification
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lead, err := h.leadRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+		return
+	}
+	if lead == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return
+	}
+
+	from := lead.Status
+	if !applyLeadStatus(c, h.closeReasonRepo, lead, reqBody.Status, reqBody.leadDisqualification) {
+		return
+	}
+	if err := h.leadRepo.Update(lead); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lead status"})
+		return
+	}
+
+	board, err := h.viewRepo.GetLeadBoard(nil, models.ViewSort{}, []string{from, lead.Status}, 0, 0, lead.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Lead moved, but failed to fetch its columns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lead": lead, "columns": board.Columns})
+}
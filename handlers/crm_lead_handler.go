@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"crm-app/backend/models"
+	"crm-app/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +17,11 @@ import (
 type CRMLeadHandler struct {
 	leadRepo        models.LeadRepository
 	fieldConfigRepo models.LeadFieldConfigRepository
+	attributionRepo models.AttributionRepository
+	closeReasonRepo models.CloseReasonRepository
+	auditRepo       models.AuditRepository
+	tagRepo         models.TagRepository
+	phoneService    *services.PhoneService
 }
 
 type CRMScoreHandler struct {
@@ -26,6 +33,11 @@ func NewCRMLeadHandler(repos *models.CRMRepositories) *CRMLeadHandler {
 	return &CRMLeadHandler{
 		leadRepo:        repos.LeadRepo,
 		fieldConfigRepo: repos.LeadFieldConfigRepo,
+		attributionRepo: repos.AttributionRepo,
+		closeReasonRepo: repos.CloseReasonRepo,
+		auditRepo:       repos.AuditRepo,
+		tagRepo:         repos.TagRepo,
+		phoneService:    services.NewPhoneService(repos),
 	}
 }
 
@@ -50,7 +62,7 @@ func (h *CRMScoreHandler) UpdateScore(c *gin.Context) {
 	c.JSON(http.StatusOK, config)
 }
 
-// GetLeads returns all leads
+// GetLeads returns all leads, optionally only those with given tags
 func (h *CRMLeadHandler) GetLeads(c *gin.Context) {
 	// Handle query parameters for filtering
 	status := c.Query("status")
@@ -62,6 +74,10 @@ func (h *CRMLeadHandler) GetLeads(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
 		return
 	}
+	tags, ok := tagFilterQuery(c)
+	if !ok {
+		return
+	}
 	var leads []models.GroupedLead
 
 	// Apply filters if provided
@@ -87,6 +103,25 @@ func (h *CRMLeadHandler) GetLeads(c *gin.Context) {
 		return
 	}
 
+	if tags != nil {
+		taggedIDs, err := h.tagRepo.GetTaggedIDs(models.TagEntityLead, *tags, companyId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leads"})
+			return
+		}
+		tagged := make(map[uint]bool, len(taggedIDs))
+		for _, id := range taggedIDs {
+			tagged[uint(id)] = true
+		}
+		filtered := []models.GroupedLead{}
+		for _, lead := range leads {
+			if tagged[lead.SubmitID] {
+				filtered = append(filtered, lead)
+			}
+		}
+		leads = filtered
+	}
+
 	c.JSON(http.StatusOK, leads)
 }
 
@@ -120,6 +155,10 @@ func (h *CRMLeadHandler) CreateLead(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(leadInput.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	userId, exists := c.Get("userId")
 	if !exists {
@@ -133,6 +172,24 @@ func (h *CRMLeadHandler) CreateLead(c *gin.Context) {
 		return
 	}
 
+	// Check the phone fields before creating the lead, so an invalid number leaves nothing behind
+	var records []models.CrmFieldData
+	now := time.Now()
+	for _, d := range leadInput.Datas {
+		records = append(records, models.CrmFieldData{
+			CompanyId:  leadInput.CompanyId,
+			CrmStageId: d.StageId,
+			CrmFieldId: d.FieldId,
+			FieldValue: d.FieldValue,
+			CreatedBy:  userIdValue,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+	if !phoneNormalized(c, h.phoneService.NormalizeLeadFields(records)) {
+		return
+	}
+
 	lead := models.Lead{
 
 		Status:    "new",
@@ -140,6 +197,9 @@ func (h *CRMLeadHandler) CreateLead(c *gin.Context) {
 		UpdatedAt: time.Now(),
 		CompanyId: leadInput.CompanyId,
 	}
+	if leadInput.Touchpoint != nil {
+		lead.Source = leadInput.Touchpoint.UtmSource
+	}
 	fmt.Println("hello")
 	if err := h.leadRepo.CreateMainLead(&lead); err != nil {
 		fmt.Println("check")
@@ -155,19 +215,8 @@ func (h *CRMLeadHandler) CreateLead(c *gin.Context) {
 	// newSubmitId := lastSubmitId + 1
 	newSubmitId := lead.ID
 	fmt.Println("leadInput", leadInput)
-	var records []models.CrmFieldData
-	now := time.Now()
-	for _, d := range leadInput.Datas {
-		records = append(records, models.CrmFieldData{
-			CompanyId:  leadInput.CompanyId,
-			CrmStageId: d.StageId,
-			CrmFieldId: d.FieldId,
-			FieldValue: d.FieldValue,
-			CreatedBy:  userIdValue,
-			SubmitId:   newSubmitId,
-			CreatedAt:  now,
-			UpdatedAt:  now,
-		})
+	for i := range records {
+		records[i].SubmitId = newSubmitId
 	}
 	fmt.Println("records", records)
 	if err := h.leadRepo.Create(records); err != nil {
@@ -175,6 +224,23 @@ func (h *CRMLeadHandler) CreateLead(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.leadRepo.AddTags(int(lead.ID), leadInput.Tags, leadInput.CompanyId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag lead: " + err.Error()})
+		return
+	}
+
+	// Capture the attribution touchpoint the lead arrived with
+	if leadInput.Touchpoint != nil {
+		touchpoint := *leadInput.Touchpoint
+		touchpoint.ID = 0
+		touchpoint.LeadID = int(lead.ID)
+		touchpoint.CompanyId = leadInput.CompanyId
+		if err := h.attributionRepo.RecordTouchpoint(&touchpoint); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record touchpoint: " + err.Error()})
+			return
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Records inserted successfully",
 		"count":   len(records),
@@ -207,10 +273,20 @@ func (h *CRMLeadHandler) UpdateLead(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := checkTagNames(lead.Tags); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Ensure ID matches the URL parameter
 	var input uint = uint(id)
 	lead.ID = input
+	if lead.CompanyId == 0 {
+		lead.CompanyId = existingLead.CompanyId
+	}
+	if !phoneNormalized(c, h.phoneService.NormalizeLead(&lead)) {
+		return
+	}
 
 	// Update the lead
 	if err := h.leadRepo.Update(&lead); err != nil {
@@ -246,6 +322,7 @@ func (h *CRMLeadHandler) DeleteLead(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lead"})
 		return
 	}
+	recordAudit(c, h.auditRepo, models.TrashTypeLead, id, models.AuditActionDelete, existingLead.CompanyId)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Lead deleted successfully"})
 }
@@ -270,8 +347,8 @@ func (h *CRMLeadHandler) QualifyLead(c *gin.Context) {
 		return
 	}
 
-	// Update the lead status
-	lead.Status = "qualified"
+	// Update the lead status, clearing any earlier disqualification
+	applyLeadStatus(c, h.closeReasonRepo, lead, "qualified", leadDisqualification{})
 
 	// Parse score from request if provided
 	var reqBody struct {
@@ -310,33 +387,18 @@ func (h *CRMLeadHandler) DisqualifyLead(c *gin.Context) {
 		return
 	}
 
-	// Update the lead status
-	lead.Status = "disqualified"
-
-	// Parse reason from request if provided
-	var reqBody struct {
-		Reason string `json:"reason"`
-	}
-	if err := c.ShouldBindJSON(&reqBody); err == nil {
-		// Fixed: Initialize CustomFields properly as a slice
-		if lead.CustomFields == nil {
-			lead.CustomFields = []models.LeadCustomField{}
-		}
-
-		// Add disqualification reason as a custom field
-		disqualificationReasonField := models.LeadCustomField{
-			LeadID:     lead.ID,
-			FieldName:  "disqualification_reason",
-			FieldValue: reqBody.Reason,
-		}
-
-		disqualifiedAtField := models.LeadCustomField{
-			LeadID:     lead.ID,
-			FieldName:  "disqualified_at",
-			FieldValue: time.Now().Format(time.RFC3339),
+	// Parse the disqualification reason from the request
+	var reqBody leadDisqualification
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+	}
 
-		lead.CustomFields = append(lead.CustomFields, disqualificationReasonField, disqualifiedAtField)
+	// Update the lead status
+	if !applyLeadStatus(c, h.closeReasonRepo, lead, "disqualified", reqBody) {
+		return
 	}
 
 	// Update the lead
@@ -348,6 +410,47 @@ func (h *CRMLeadHandler) DisqualifyLead(c *gin.Context) {
 	c.JSON(http.StatusOK, lead)
 }
 
+// leadDisqualification is why a lead was disqualified. Reason is free text kept from before
+// companies had reason lists and is recorded as the notes when none are given.
+type leadDisqualification struct {
+	ReasonID   *int   `json:"reason_id"`
+	Competitor string `json:"competitor"`
+	Notes      string `json:"notes"`
+	Reason     string `json:"reason"`
+}
+
+// applyLeadStatus moves a lead to a status. Disqualifying a lead records why, requiring a reason
+// when the company has disqualification reasons; any other status clears an earlier
+// disqualification. It writes an error response and returns false if the reason is invalid.
+func applyLeadStatus(c *gin.Context, closeReasonRepo models.CloseReasonRepository, lead *models.Lead, status string, details leadDisqualification) bool {
+	if status == "disqualified" && !requireCloseReason(c, closeReasonRepo, models.CloseReasonTypeDisqualified, details.ReasonID, lead.CompanyId) {
+		return false
+	}
+	setLeadStatus(lead, status, details)
+	return true
+}
+
+// setLeadStatus moves a lead to a status as applyLeadStatus does, without checking the reason
+func setLeadStatus(lead *models.Lead, status string, details leadDisqualification) {
+	lead.Status = status
+	if status != "disqualified" {
+		lead.DisqualificationReasonID = nil
+		lead.DisqualificationNotes = ""
+		lead.Competitor = ""
+		lead.DisqualifiedAt = nil
+		return
+	}
+
+	if details.Notes == "" {
+		details.Notes = details.Reason
+	}
+	now := time.Now()
+	lead.DisqualificationReasonID = details.ReasonID
+	lead.DisqualificationNotes = details.Notes
+	lead.Competitor = strings.TrimSpace(details.Competitor)
+	lead.DisqualifiedAt = &now
+}
+
 // AssignLead assigns a lead to a user
 func (h *CRMLeadHandler) AssignLead(c *gin.Context) {
 	idStr := c.Param("id")
@@ -389,6 +492,61 @@ func (h *CRMLeadHandler) AssignLead(c *gin.Context) {
 	c.JSON(http.StatusOK, lead)
 }
 
+// GetLeadTouchpoints returns the attribution touchpoints of a lead
+func (h *CRMLeadHandler) GetLeadTouchpoints(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return
+	}
+
+	touchpoints, err := h.attributionRepo.GetTouchpointsByLead(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch touchpoints"})
+		return
+	}
+
+	c.JSON(http.StatusOK, touchpoints)
+}
+
+// RecordTouchpoint records a new attribution touchpoint for a lead
+func (h *CRMLeadHandler) RecordTouchpoint(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+		return
+	}
+
+	lead, err := h.leadRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+		return
+	}
+	if lead == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return
+	}
+
+	var touchpoint models.LeadTouchpoint
+	if err := c.ShouldBindJSON(&touchpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	touchpoint.ID = 0
+	touchpoint.LeadID = id
+	touchpoint.CompanyId = lead.CompanyId
+
+	if err := h.attributionRepo.RecordTouchpoint(&touchpoint); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record touchpoint"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, touchpoint)
+}
+
 // func (h *CRMScoreHandler) UpdateScore(c *gin.Context) {
 // 	var config []models.ScoreType
 // 	if err := c.ShouldBindJSON(&config); err != nil {
@@ -462,6 +620,9 @@ func (h *CRMLeadHandler) BulkImportLeads(c *gin.Context) {
 			})
 		}
 	}
+	if !phoneNormalized(c, h.phoneService.NormalizeLeadFields(allRecords)) {
+		return
+	}
 	if err := h.leadRepo.Create(allRecords); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create leads: " + err.Error()})
 		return
@@ -1,25 +1,45 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"crm-app/backend/models"
+	"crm-app/backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CRMNurtureHandler handles requests for lead nurturing
 type CRMNurtureHandler struct {
-	nurtureRepo models.NurtureRepository
-	leadRepo    models.LeadRepository
+	nurtureRepo      models.NurtureRepository
+	leadRepo         models.LeadRepository
+	emailRepo        models.EmailRepository
+	abTestRepo       models.ABTestRepository
+	segmentRepo      models.SegmentRepository
+	viewRepo         models.SavedViewRepository
+	auditRepo        models.AuditRepository
+	templateService  *services.TemplateService
+	campaignService  *services.CampaignService
+	segmentService   *services.SegmentService
+	analyticsService *services.AnalyticsService
 }
 
 // NewCRMNurtureHandler creates a new nurturing handler
 func NewCRMNurtureHandler(repos *models.CRMRepositories) *CRMNurtureHandler {
 	return &CRMNurtureHandler{
-		nurtureRepo: repos.NurtureRepo,
-		leadRepo:    repos.LeadRepo,
+		nurtureRepo:      repos.NurtureRepo,
+		leadRepo:         repos.LeadRepo,
+		emailRepo:        repos.EmailRepo,
+		abTestRepo:       repos.ABTestRepo,
+		segmentRepo:      repos.SegmentRepo,
+		viewRepo:         repos.SavedViewRepo,
+		auditRepo:        repos.AuditRepo,
+		templateService:  services.NewTemplateService(repos),
+		campaignService:  services.NewCampaignService(repos, services.NewEmailService(repos, nil)),
+		segmentService:   services.NewSegmentService(repos),
+		analyticsService: services.NewAnalyticsService(repos),
 	}
 }
 
@@ -79,6 +99,22 @@ func (h *CRMNurtureHandler) CreateCampaign(c *gin.Context) {
 	userID := 1 // Placeholder
 	campaign.CreatedBy = userID
 
+	// Campaigns start as drafts and change status through the launch, pause, resume and cancel actions
+	campaign.Status = models.CampaignStatusDraft
+	campaign.ScheduledAt = nil
+	campaign.EndsAt = nil
+	campaign.LaunchedAt = nil
+	campaign.CompletedAt = nil
+
+	if msg := validateCampaign(&campaign); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if msg := h.validateSegmentTarget(campaign.SegmentID, campaign.CompanyId); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	if err := h.nurtureRepo.CreateCampaign(&campaign); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
 		return
@@ -119,6 +155,31 @@ func (h *CRMNurtureHandler) UpdateCampaign(c *gin.Context) {
 	// Preserve the created_by field
 	campaign.CreatedBy = existingCampaign.CreatedBy
 
+	// Execution state is owned by the campaign actions and scheduler
+	campaign.Status = existingCampaign.Status
+	campaign.ScheduledAt = existingCampaign.ScheduledAt
+	campaign.EndsAt = existingCampaign.EndsAt
+	campaign.LaunchedAt = existingCampaign.LaunchedAt
+	campaign.CompletedAt = existingCampaign.CompletedAt
+	campaign.LockedBy = existingCampaign.LockedBy
+	campaign.LockedUntil = existingCampaign.LockedUntil
+	if existingCampaign.Status != models.CampaignStatusDraft {
+		// What is sent and when it starts are fixed once a campaign is launched
+		campaign.TemplateID = existingCampaign.TemplateID
+		campaign.SenderIdentityID = existingCampaign.SenderIdentityID
+		campaign.StartDate = existingCampaign.StartDate
+		campaign.SegmentID = existingCampaign.SegmentID
+	}
+
+	if msg := validateCampaign(&campaign); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if msg := h.validateSegmentTarget(campaign.SegmentID, campaign.CompanyId); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	if err := h.nurtureRepo.UpdateCampaign(&campaign); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
 		return
@@ -155,8 +216,54 @@ func (h *CRMNurtureHandler) DeleteCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
 }
 
-// GetCampaignStats returns statistics for a campaign
+// GetCampaignStats returns statistics for a campaign, with its revenue and ROI
 func (h *CRMNurtureHandler) GetCampaignStats(c *gin.Context) {
+	campaign, ok := h.findCampaign(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.nurtureRepo.GetCampaignStats(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign statistics"})
+		return
+	}
+
+	roi, err := h.analyticsService.GetCampaignROI(campaign.ID, campaign.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign statistics"})
+		return
+	}
+	if roi != nil {
+		stats["revenue"] = roi.Revenue
+		stats["roi"] = roi.ROI
+		stats["exchange_rate_missing"] = roi.ExchangeRateMissing
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// LaunchCampaign schedules a draft campaign to send its template to its members
+func (h *CRMNurtureHandler) LaunchCampaign(c *gin.Context) {
+	h.changeCampaignStatus(c, h.campaignService.Launch)
+}
+
+// PauseCampaign stops a scheduled or running campaign from sending
+func (h *CRMNurtureHandler) PauseCampaign(c *gin.Context) {
+	h.changeCampaignStatus(c, h.campaignService.Pause)
+}
+
+// ResumeCampaign continues a paused campaign
+func (h *CRMNurtureHandler) ResumeCampaign(c *gin.Context) {
+	h.changeCampaignStatus(c, h.campaignService.Resume)
+}
+
+// CancelCampaign stops a campaign for good and cancels its unsent email
+func (h *CRMNurtureHandler) CancelCampaign(c *gin.Context) {
+	h.changeCampaignStatus(c, h.campaignService.Cancel)
+}
+
+func (h *CRMNurtureHandler) changeCampaignStatus(c *gin.Context, action func(*models.Campaign) error) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -164,13 +271,32 @@ func (h *CRMNurtureHandler) GetCampaignStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.nurtureRepo.GetCampaignStats(id)
+	campaign, err := h.nurtureRepo.GetCampaignByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign statistics"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign"})
+		return
+	}
+	if campaign == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	if err := action(campaign); err != nil {
+		if errors.Is(err, services.ErrInvalidCampaignTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Campaign is " + campaign.Status})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err = h.nurtureRepo.GetCampaignByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
 }
 
 // GetCampaignLeads returns the leads assigned to a campaign
@@ -322,6 +448,38 @@ func (h *CRMNurtureHandler) GetTemplate(c *gin.Context) {
 	c.JSON(http.StatusOK, template)
 }
 
+// GetTemplateStats returns delivery and engagement stats across all emails sent from a template
+func (h *CRMNurtureHandler) GetTemplateStats(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	template, err := h.nurtureRepo.GetTemplateByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template"})
+		return
+	}
+	if template == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	stats, err := h.emailRepo.GetEmailStats("template", template.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template statistics"})
+		return
+	}
+
+	result := models.EmailStats{}
+	if len(stats) > 0 {
+		result = stats[0]
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // CreateTemplate creates a new template
 func (h *CRMNurtureHandler) CreateTemplate(c *gin.Context) {
 	var template models.CampaignTemplate
@@ -330,6 +488,10 @@ func (h *CRMNurtureHandler) CreateTemplate(c *gin.Context) {
 		return
 	}
 
+	if !h.validateTemplateMergeFields(c, &template) {
+		return
+	}
+
 	// Set the created_by field to the current user ID
 	userID := 1 // Placeholder
 	template.CreatedBy = userID
@@ -373,6 +535,13 @@ func (h *CRMNurtureHandler) UpdateTemplate(c *gin.Context) {
 
 	// Preserve the created_by field
 	template.CreatedBy = existingTemplate.CreatedBy
+	if template.CompanyId == 0 {
+		template.CompanyId = existingTemplate.CompanyId
+	}
+
+	if !h.validateTemplateMergeFields(c, &template) {
+		return
+	}
 
 	if err := h.nurtureRepo.UpdateTemplate(&template); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
@@ -409,3 +578,129 @@ func (h *CRMNurtureHandler) DeleteTemplate(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
 }
+
+// GetTemplateMergeFields returns the merge fields available to a company's templates
+func (h *CRMNurtureHandler) GetTemplateMergeFields(c *gin.Context) {
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	catalog, err := h.templateService.MergeFieldCatalog(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merge fields"})
+		return
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
+
+// PreviewTemplate renders a template for a lead. An optional body with subject and content
+// previews unsaved changes instead of the stored template.
+func (h *CRMNurtureHandler) PreviewTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	leadID, err := strconv.Atoi(c.Query("lead_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead_id"})
+		return
+	}
+
+	template, err := h.nurtureRepo.GetTemplateByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template"})
+		return
+	}
+	if template == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	lead, err := h.leadRepo.FindByID(leadID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+		return
+	}
+	if lead == nil || lead.CompanyId != template.CompanyId {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+		return
+	}
+
+	var draft struct {
+		Subject *string `json:"subject"`
+		Content *string `json:"content"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&draft); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if draft.Subject != nil {
+		template.Subject = *draft.Subject
+	}
+	if draft.Content != nil {
+		template.Content = *draft.Content
+	}
+
+	rendered, err := h.templateService.RenderForLead(template, leadID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
+}
+
+// validateTemplateMergeFields rejects templates with invalid syntax or unknown merge fields
+func (h *CRMNurtureHandler) validateTemplateMergeFields(c *gin.Context, template *models.CampaignTemplate) bool {
+	unknown, err := h.templateService.ValidateTemplate(template.Subject, template.Content, template.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return false
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Template uses unknown merge fields",
+			"unknown_fields": unknown,
+		})
+		return false
+	}
+	return true
+}
+
+// validateSegmentTarget checks that a segment a campaign or sequence targets belongs to its company
+func (h *CRMNurtureHandler) validateSegmentTarget(segmentID *int, companyId int) string {
+	if segmentID == nil {
+		return ""
+	}
+	segment, err := h.segmentRepo.GetSegmentByID(*segmentID)
+	if err != nil || segment == nil || segment.CompanyId != companyId {
+		return "Segment not found"
+	}
+	return ""
+}
+
+// validateCampaign checks a campaign's sending settings, returning an error message if invalid
+func validateCampaign(campaign *models.Campaign) string {
+	if campaign.HourlyLimit < 0 {
+		return "hourly_limit cannot be negative"
+	}
+	if !services.ValidClockTime(campaign.QuietHoursStart) || !services.ValidClockTime(campaign.QuietHoursEnd) {
+		return "Quiet hours must be in HH:MM format"
+	}
+	if (campaign.QuietHoursStart == "") != (campaign.QuietHoursEnd == "") {
+		return "Quiet hours need both a start and an end"
+	}
+	if campaign.StartDate != nil && campaign.EndDate != nil && campaign.EndDate.Before(*campaign.StartDate) {
+		return "end_date must be after start_date"
+	}
+	return ""
+}
@@ -0,0 +1,711 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// enrollmentRequest selects leads for bulk enroll/unenroll by ID, by filter or by a saved lead view
+type enrollmentRequest struct {
+	LeadIDs []int                  `json:"lead_ids"`
+	Filters map[string]interface{} `json:"filters"`
+	ViewID  *int                   `json:"view_id"`
+}
+
+// GetSequences returns nurture sequences with pagination
+func (h *CRMNurtureHandler) GetSequences(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyIdStr := c.Query("companyId")
+	companyId, err := strconv.Atoi(companyIdStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	sequences, err := h.nurtureRepo.GetSequences(offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sequences)
+}
+
+// GetSequence returns a nurture sequence with its steps
+func (h *CRMNurtureHandler) GetSequence(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, sequence)
+}
+
+// CreateSequence creates a nurture sequence, optionally with its steps
+func (h *CRMNurtureHandler) CreateSequence(c *gin.Context) {
+	var sequence models.NurtureSequence
+	if err := c.ShouldBindJSON(&sequence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if sequence.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return
+	}
+
+	if msg := h.validateSegmentTarget(sequence.SegmentID, sequence.CompanyId); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	for i := range sequence.Steps {
+		if msg := validateNurtureStep(&sequence.Steps[i]); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg, "step": i})
+			return
+		}
+		if sequence.Steps[i].OrderIndex == 0 {
+			sequence.Steps[i].OrderIndex = i + 1
+		}
+	}
+
+	if err := h.nurtureRepo.CreateSequence(&sequence); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sequence"})
+		return
+	}
+	if !h.enrollSegment(c, &sequence) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, sequence)
+}
+
+// UpdateSequence updates a nurture sequence; steps are managed through the steps endpoints
+func (h *CRMNurtureHandler) UpdateSequence(c *gin.Context) {
+	existingSequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	var sequence models.NurtureSequence
+	if err := c.ShouldBindJSON(&sequence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored sequence
+	sequence.ID = existingSequence.ID
+	sequence.CompanyId = existingSequence.CompanyId
+	sequence.Steps = nil
+
+	if msg := h.validateSegmentTarget(sequence.SegmentID, sequence.CompanyId); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.nurtureRepo.UpdateSequence(&sequence); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sequence"})
+		return
+	}
+	if !h.enrollSegment(c, &sequence) {
+		return
+	}
+
+	c.JSON(http.StatusOK, sequence)
+}
+
+// DeleteSequence deletes a nurture sequence
+func (h *CRMNurtureHandler) DeleteSequence(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	if err := h.nurtureRepo.DeleteSequence(sequence.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sequence"})
+		return
+	}
+	recordAudit(c, h.auditRepo, models.TrashTypeSequence, sequence.ID, models.AuditActionDelete, sequence.CompanyId)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sequence deleted successfully"})
+}
+
+// ActivateSequence resumes processing of a sequence's enrollments
+func (h *CRMNurtureHandler) ActivateSequence(c *gin.Context) {
+	h.setSequenceActive(c, true)
+}
+
+// DeactivateSequence holds all of a sequence's enrollments at their current step
+func (h *CRMNurtureHandler) DeactivateSequence(c *gin.Context) {
+	h.setSequenceActive(c, false)
+}
+
+func (h *CRMNurtureHandler) setSequenceActive(c *gin.Context, active bool) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	sequence.IsActive = active
+	sequence.Steps = nil
+	if err := h.nurtureRepo.UpdateSequence(sequence); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sequence"})
+		return
+	}
+	if !h.enrollSegment(c, sequence) {
+		return
+	}
+
+	c.JSON(http.StatusOK, sequence)
+}
+
+// GetSequenceSteps returns the steps of a sequence in order
+func (h *CRMNurtureHandler) GetSequenceSteps(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	steps, err := h.nurtureRepo.GetStepsBySequence(sequence.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, steps)
+}
+
+// CreateSequenceStep adds a step to a sequence, appending it when no order is given
+func (h *CRMNurtureHandler) CreateSequenceStep(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	var step models.NurtureStep
+	if err := c.ShouldBindJSON(&step); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateNurtureStep(&step); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	step.SequenceID = sequence.ID
+	if step.OrderIndex == 0 {
+		step.OrderIndex = len(sequence.Steps) + 1
+	}
+
+	if err := h.nurtureRepo.CreateStep(&step); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create step"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, step)
+}
+
+// UpdateSequenceStep updates a step of a sequence
+func (h *CRMNurtureHandler) UpdateSequenceStep(c *gin.Context) {
+	existingStep, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+
+	// Fields left out of the request keep their current values
+	step := *existingStep
+	if err := c.ShouldBindJSON(&step); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateNurtureStep(&step); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	step.ID = existingStep.ID
+	step.SequenceID = existingStep.SequenceID
+	step.CreatedAt = existingStep.CreatedAt
+	if step.OrderIndex == 0 {
+		step.OrderIndex = existingStep.OrderIndex
+	}
+
+	if err := h.nurtureRepo.UpdateStep(&step); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update step"})
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// DeleteSequenceStep deletes a step of a sequence
+func (h *CRMNurtureHandler) DeleteSequenceStep(c *gin.Context) {
+	step, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+
+	if err := h.nurtureRepo.DeleteStep(step.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete step"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Step deleted successfully"})
+}
+
+// ActivateSequenceStep includes a step when enrollments run
+func (h *CRMNurtureHandler) ActivateSequenceStep(c *gin.Context) {
+	h.setStepActive(c, true)
+}
+
+// DeactivateSequenceStep skips a step when enrollments run
+func (h *CRMNurtureHandler) DeactivateSequenceStep(c *gin.Context) {
+	h.setStepActive(c, false)
+}
+
+func (h *CRMNurtureHandler) setStepActive(c *gin.Context, active bool) {
+	step, ok := h.findSequenceStep(c)
+	if !ok {
+		return
+	}
+
+	step.IsActive = active
+	if err := h.nurtureRepo.UpdateStep(step); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update step"})
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// ReorderSequenceSteps sets the order of a sequence's steps
+func (h *CRMNurtureHandler) ReorderSequenceSteps(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		StepIDs []int `json:"step_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(reqBody.StepIDs) != len(sequence.Steps) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_ids must list every step of the sequence"})
+		return
+	}
+
+	if err := h.nurtureRepo.ReorderSteps(sequence.ID, reqBody.StepIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Steps reordered successfully"})
+}
+
+// GetSequenceEnrollments returns the enrollments of a sequence
+func (h *CRMNurtureHandler) GetSequenceEnrollments(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	enrollments, err := h.nurtureRepo.GetEnrollments(sequence.ID, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// EnrollLeads enrolls leads in a sequence by ID or by lead filter
+func (h *CRMNurtureHandler) EnrollLeads(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	leadIDs, ok := h.resolveEnrollmentLeads(c, sequence)
+	if !ok {
+		return
+	}
+
+	enrolled, err := h.nurtureRepo.EnrollLeads(sequence.ID, leadIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll leads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Leads enrolled successfully",
+		"enrolled": enrolled,
+		"skipped":  len(leadIDs) - enrolled,
+	})
+}
+
+// UnenrollLeads cancels the enrollments of leads in a sequence by ID or by lead filter
+func (h *CRMNurtureHandler) UnenrollLeads(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	leadIDs, ok := h.resolveEnrollmentLeads(c, sequence)
+	if !ok {
+		return
+	}
+
+	unenrolled, err := h.nurtureRepo.UnenrollLeads(sequence.ID, leadIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unenroll leads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Leads unenrolled successfully",
+		"unenrolled": unenrolled,
+	})
+}
+
+// GetSequenceStats returns the enrollment funnel of a sequence and the email engagement of its steps
+func (h *CRMNurtureHandler) GetSequenceStats(c *gin.Context) {
+	sequence, ok := h.findSequence(c)
+	if !ok {
+		return
+	}
+
+	counts, err := h.nurtureRepo.GetEnrollmentCounts(sequence.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequence statistics"})
+		return
+	}
+
+	emailStats, err := h.emailRepo.GetEmailStats("sequence", sequence.ID, "step_id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequence statistics"})
+		return
+	}
+	emailStatsByStep := make(map[int]models.EmailStats, len(emailStats))
+	for _, stats := range emailStats {
+		if stats.GroupID != nil {
+			emailStatsByStep[*stats.GroupID] = stats
+		}
+	}
+
+	// Funnel positions count active steps only. An enrollment at an inactive step is counted at
+	// the next active step, where the nurture engine moves it.
+	var steps []models.NurtureStep
+	positions := make(map[int]int, len(sequence.Steps))
+	for _, step := range sequence.Steps {
+		if step.IsActive {
+			steps = append(steps, step)
+		}
+	}
+	next := len(steps)
+	for i := len(sequence.Steps) - 1; i >= 0; i-- {
+		if sequence.Steps[i].IsActive {
+			next--
+		}
+		positions[sequence.Steps[i].ID] = next
+	}
+
+	totals := map[string]int64{"enrolled": 0, "active": 0, "paused": 0, "completed": 0, "exited": 0, "cancelled": 0}
+	funnel := make([]map[string]interface{}, len(steps))
+	for i, step := range steps {
+		funnel[i] = map[string]interface{}{
+			"step_id":   step.ID,
+			"name":      step.Name,
+			"type":      step.Type,
+			"reached":   int64(0),
+			"waiting":   int64(0),
+			"completed": int64(0),
+			"exited":    int64(0),
+		}
+		if stats, ok := emailStatsByStep[step.ID]; ok {
+			funnel[i]["email"] = stats
+		}
+	}
+
+	for _, count := range counts {
+		totals["enrolled"] += count.Count
+		totals[count.Status] += count.Count
+
+		// Enrollments at a deleted step appear in the totals only
+		position := 0
+		if count.CurrentStepID != nil {
+			var ok bool
+			if position, ok = positions[*count.CurrentStepID]; !ok && count.Status != "completed" {
+				continue
+			}
+		}
+
+		for i := range funnel {
+			switch {
+			case count.Status == "completed" || position > i:
+				funnel[i]["reached"] = funnel[i]["reached"].(int64) + count.Count
+				funnel[i]["completed"] = funnel[i]["completed"].(int64) + count.Count
+			case position == i:
+				funnel[i]["reached"] = funnel[i]["reached"].(int64) + count.Count
+				if count.Status == "active" || count.Status == "paused" {
+					funnel[i]["waiting"] = funnel[i]["waiting"].(int64) + count.Count
+				} else {
+					funnel[i]["exited"] = funnel[i]["exited"].(int64) + count.Count
+				}
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sequence_id": sequence.ID,
+		"totals":      totals,
+		"steps":       funnel,
+	})
+}
+
+// GetEnrollmentActivity returns the activity recorded for an enrollment
+func (h *CRMNurtureHandler) GetEnrollmentActivity(c *gin.Context) {
+	enrollment, ok := h.findEnrollment(c)
+	if !ok {
+		return
+	}
+
+	activities, err := h.nurtureRepo.GetEnrollmentActivity(enrollment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollment activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, activities)
+}
+
+// PauseEnrollment stops an active enrollment at its current step
+func (h *CRMNurtureHandler) PauseEnrollment(c *gin.Context) {
+	h.changeEnrollmentStatus(c, []string{"active"}, "paused")
+}
+
+// ResumeEnrollment continues a paused enrollment from its current step
+func (h *CRMNurtureHandler) ResumeEnrollment(c *gin.Context) {
+	h.changeEnrollmentStatus(c, []string{"paused"}, "active")
+}
+
+// CancelEnrollment ends an active or paused enrollment
+func (h *CRMNurtureHandler) CancelEnrollment(c *gin.Context) {
+	h.changeEnrollmentStatus(c, []string{"active", "paused"}, "cancelled")
+}
+
+func (h *CRMNurtureHandler) changeEnrollmentStatus(c *gin.Context, fromStatuses []string, status string) {
+	enrollment, ok := h.findEnrollment(c)
+	if !ok {
+		return
+	}
+
+	changed, err := h.nurtureRepo.SetEnrollmentStatus(enrollment.ID, fromStatuses, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update enrollment"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Enrollment cannot be changed from its current status",
+			"status": enrollment.Status,
+		})
+		return
+	}
+
+	enrollment, err = h.nurtureRepo.GetEnrollmentByID(enrollment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// findSequence loads the sequence named by the :id parameter, writing an error response if it fails
+func (h *CRMNurtureHandler) findSequence(c *gin.Context) (*models.NurtureSequence, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sequence ID"})
+		return nil, false
+	}
+
+	sequence, err := h.nurtureRepo.GetSequenceByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sequence"})
+		return nil, false
+	}
+	if sequence == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sequence not found"})
+		return nil, false
+	}
+
+	return sequence, true
+}
+
+// findSequenceStep loads the step named by :stepId, ensuring it belongs to the :id sequence
+func (h *CRMNurtureHandler) findSequenceStep(c *gin.Context) (*models.NurtureStep, bool) {
+	sequenceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sequence ID"})
+		return nil, false
+	}
+	stepID, err := strconv.Atoi(c.Param("stepId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid step ID"})
+		return nil, false
+	}
+
+	step, err := h.nurtureRepo.GetStepByID(stepID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch step"})
+		return nil, false
+	}
+	if step == nil || step.SequenceID != sequenceID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Step not found"})
+		return nil, false
+	}
+
+	return step, true
+}
+
+// findEnrollment loads the enrollment named by the :id parameter
+func (h *CRMNurtureHandler) findEnrollment(c *gin.Context) (*models.NurtureEnrollment, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid enrollment ID"})
+		return nil, false
+	}
+
+	enrollment, err := h.nurtureRepo.GetEnrollmentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollment"})
+		return nil, false
+	}
+	if enrollment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enrollment not found"})
+		return nil, false
+	}
+
+	return enrollment, true
+}
+
+// enrollSegment enrolls the members of the segment an active sequence targets, refreshing the
+// segment first if it has never been evaluated
+func (h *CRMNurtureHandler) enrollSegment(c *gin.Context, sequence *models.NurtureSequence) bool {
+	if sequence.SegmentID == nil || !sequence.IsActive {
+		return true
+	}
+
+	segment, err := h.segmentRepo.GetSegmentByID(*sequence.SegmentID)
+	if err == nil && segment != nil && segment.LastRefreshedAt == nil {
+		_, err = h.segmentService.Refresh(segment, time.Now())
+	}
+	if err == nil {
+		_, err = h.segmentService.SyncSequence(sequence)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll segment members"})
+		return false
+	}
+	return true
+}
+
+// resolveEnrollmentLeads returns the lead IDs selected by an enrollment request body
+func (h *CRMNurtureHandler) resolveEnrollmentLeads(c *gin.Context, sequence *models.NurtureSequence) ([]int, bool) {
+	var reqBody enrollmentRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if len(reqBody.LeadIDs) == 0 && len(reqBody.Filters) == 0 && reqBody.ViewID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lead_ids, filters or view_id is required"})
+		return nil, false
+	}
+
+	leadIDs := reqBody.LeadIDs
+	if len(reqBody.Filters) > 0 {
+		matched, err := h.leadRepo.FindIDsByFilter(sequence.CompanyId, reqBody.Filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve lead filters"})
+			return nil, false
+		}
+		leadIDs = append(leadIDs, matched...)
+	}
+	if reqBody.ViewID != nil {
+		view, ok := findVisibleView(c, h.viewRepo, *reqBody.ViewID)
+		if !ok {
+			return nil, false
+		}
+		if view.Entity != models.ViewEntityLead || view.CompanyId != sequence.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Saved view does not list leads of this company"})
+			return nil, false
+		}
+		filters, err := services.ParseViewFilters(view.Filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid saved view filters"})
+			return nil, false
+		}
+		matched, err := h.viewRepo.FindIDs(models.ViewEntityLead, filters, -1, sequence.CompanyId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve saved view"})
+			return nil, false
+		}
+		leadIDs = append(leadIDs, matched...)
+	}
+
+	// Verify that each lead exists and belongs to the sequence's company
+	for _, leadID := range reqBody.LeadIDs {
+		lead, err := h.leadRepo.FindByID(leadID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify leads"})
+			return nil, false
+		}
+		if lead == nil || lead.CompanyId != sequence.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Lead not found",
+				"id":    leadID,
+			})
+			return nil, false
+		}
+	}
+
+	return leadIDs, true
+}
+
+// validateNurtureStep checks a step's type and condition JSON, returning an error message if invalid
+func validateNurtureStep(step *models.NurtureStep) string {
+	if step.Name == "" {
+		return "Step name is required"
+	}
+	if !models.NurtureStepTypes[step.Type] {
+		return "Unsupported step type: " + step.Type
+	}
+	if step.Delay < 0 {
+		return "Step delay cannot be negative"
+	}
+	if step.Conditions != "" {
+		var conditions models.NurtureConditions
+		if err := json.Unmarshal([]byte(step.Conditions), &conditions); err != nil {
+			return "Invalid step conditions"
+		}
+	}
+	return ""
+}
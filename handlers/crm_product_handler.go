@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type priceBookEntryRequest struct {
+	ProductID int     `json:"product_id" binding:"required"`
+	Currency  string  `json:"currency" binding:"required"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+// CRMProductHandler handles requests for the product catalog and price books
+type CRMProductHandler struct {
+	productRepo models.ProductRepository
+}
+
+// NewCRMProductHandler creates a new product handler
+func NewCRMProductHandler(repos *models.CRMRepositories) *CRMProductHandler {
+	return &CRMProductHandler{
+		productRepo: repos.ProductRepo,
+	}
+}
+
+// GetProducts returns a company's products, optionally matching a name or SKU
+func (h *CRMProductHandler) GetProducts(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	products, err := h.productRepo.GetProducts(offset, limit, c.Query("search"), c.Query("active") == "true", companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// GetProduct returns a product with its prices
+func (h *CRMProductHandler) GetProduct(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	prices, err := h.productRepo.GetProductPrices(product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product": product,
+		"prices":  prices,
+	})
+}
+
+// CreateProduct creates a new product
+func (h *CRMProductHandler) CreateProduct(c *gin.Context) {
+	var product models.Product
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateProduct(c, &product) {
+		return
+	}
+
+	if err := h.productRepo.CreateProduct(&product); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// UpdateProduct updates a product
+func (h *CRMProductHandler) UpdateProduct(c *gin.Context) {
+	existingProduct, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored product
+	product.ID = existingProduct.ID
+	product.CompanyId = existingProduct.CompanyId
+	product.CreatedAt = existingProduct.CreatedAt
+
+	if !h.validateProduct(c, &product) {
+		return
+	}
+
+	if err := h.productRepo.UpdateProduct(&product); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct deletes a product; deals keep their line items for it
+func (h *CRMProductHandler) DeleteProduct(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	if err := h.productRepo.DeleteProduct(product.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
+
+// GetPriceBooks returns a company's price books
+func (h *CRMProductHandler) GetPriceBooks(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	priceBooks, err := h.productRepo.GetPriceBooks(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceBooks)
+}
+
+// GetPriceBook returns a price book with its prices
+func (h *CRMProductHandler) GetPriceBook(c *gin.Context) {
+	priceBook, ok := h.findPriceBook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, priceBook)
+}
+
+// CreatePriceBook creates a new price book
+func (h *CRMProductHandler) CreatePriceBook(c *gin.Context) {
+	var priceBook models.PriceBook
+	if err := c.ShouldBindJSON(&priceBook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validatePriceBook(&priceBook); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	priceBook.Entries = nil
+
+	if err := h.productRepo.CreatePriceBook(&priceBook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price book"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, priceBook)
+}
+
+// UpdatePriceBook updates a price book's name, description and flags; prices are set through
+// its entries
+func (h *CRMProductHandler) UpdatePriceBook(c *gin.Context) {
+	existingPriceBook, ok := h.findPriceBook(c)
+	if !ok {
+		return
+	}
+
+	var priceBook models.PriceBook
+	if err := c.ShouldBindJSON(&priceBook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored price book
+	priceBook.ID = existingPriceBook.ID
+	priceBook.CompanyId = existingPriceBook.CompanyId
+	priceBook.CreatedAt = existingPriceBook.CreatedAt
+	priceBook.Entries = nil
+
+	if msg := validatePriceBook(&priceBook); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.productRepo.UpdatePriceBook(&priceBook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price book"})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceBook)
+}
+
+// DeletePriceBook deletes a price book and its prices
+func (h *CRMProductHandler) DeletePriceBook(c *gin.Context) {
+	priceBook, ok := h.findPriceBook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.productRepo.DeletePriceBook(priceBook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price book"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price book deleted successfully"})
+}
+
+// SavePriceBookEntries sets the unit prices of products in a price book, one per currency
+func (h *CRMProductHandler) SavePriceBookEntries(c *gin.Context) {
+	priceBook, ok := h.findPriceBook(c)
+	if !ok {
+		return
+	}
+
+	var reqBody []priceBookEntryRequest
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries := make([]models.PriceBookEntry, 0, len(reqBody))
+	for _, req := range reqBody {
+		currency := strings.ToUpper(req.Currency)
+		if !currencyCodePattern.MatchString(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be a 3-letter ISO 4217 code"})
+			return
+		}
+		if req.UnitPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unit_price cannot be negative"})
+			return
+		}
+
+		product, err := h.productRepo.GetProductByID(req.ProductID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+			return
+		}
+		if product == nil || product.CompanyId != priceBook.CompanyId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Product " + strconv.Itoa(req.ProductID) + " does not exist"})
+			return
+		}
+
+		entries = append(entries, models.PriceBookEntry{
+			ProductID: req.ProductID,
+			Currency:  currency,
+			UnitPrice: req.UnitPrice,
+		})
+	}
+
+	if err := h.productRepo.SavePriceBookEntries(priceBook.ID, entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save prices"})
+		return
+	}
+
+	h.GetPriceBook(c)
+}
+
+// DeletePriceBookEntry removes a price from a price book
+func (h *CRMProductHandler) DeletePriceBookEntry(c *gin.Context) {
+	priceBook, ok := h.findPriceBook(c)
+	if !ok {
+		return
+	}
+	entryID, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
+	}
+
+	found := false
+	for _, entry := range priceBook.Entries {
+		if entry.ID == entryID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price not found"})
+		return
+	}
+
+	if err := h.productRepo.DeletePriceBookEntry(priceBook.ID, entryID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price deleted successfully"})
+}
+
+// validateProduct checks a product's required fields and that its SKU is unique within the
+// company, writing an error response if invalid
+func (h *CRMProductHandler) validateProduct(c *gin.Context, product *models.Product) bool {
+	product.SKU = strings.TrimSpace(product.SKU)
+	if product.SKU == "" || product.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sku and name are required"})
+		return false
+	}
+	if product.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return false
+	}
+
+	existing, err := h.productRepo.GetProductBySKU(product.SKU, product.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify SKU"})
+		return false
+	}
+	if existing != nil && existing.ID != product.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "A product with this SKU already exists"})
+		return false
+	}
+	return true
+}
+
+// validatePriceBook checks a price book's required fields, returning an error message if invalid
+func validatePriceBook(priceBook *models.PriceBook) string {
+	if priceBook.Name == "" {
+		return "Price book name is required"
+	}
+	if priceBook.CompanyId == 0 {
+		return "company_id is required"
+	}
+	return ""
+}
+
+// findProduct loads the product named by the :id parameter, writing an error response if it fails
+func (h *CRMProductHandler) findProduct(c *gin.Context) (*models.Product, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return nil, false
+	}
+
+	product, err := h.productRepo.GetProductByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return nil, false
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return nil, false
+	}
+
+	return product, true
+}
+
+// findPriceBook loads the price book named by the :id parameter, writing an error response if it fails
+func (h *CRMProductHandler) findPriceBook(c *gin.Context) (*models.PriceBook, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price book ID"})
+		return nil, false
+	}
+
+	priceBook, err := h.productRepo.GetPriceBookByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price book"})
+		return nil, false
+	}
+	if priceBook == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price book not found"})
+		return nil, false
+	}
+
+	return priceBook, true
+}
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxQuoteLogoSize is the largest logo accepted for quote PDFs
+const maxQuoteLogoSize = 1 << 20
+
+var accentColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+type quoteRequest struct {
+	Title      string `json:"title"`
+	ValidFrom  string `json:"valid_from"`  // YYYY-MM-DD
+	ValidUntil string `json:"valid_until"` // YYYY-MM-DD
+	Terms      string `json:"terms"`
+	Notes      string `json:"notes"`
+}
+
+// CRMQuoteHandler handles requests for deal quotes and the company's quote template
+type CRMQuoteHandler struct {
+	quoteRepo       models.QuoteRepository
+	dealRepo        models.DealRepository
+	closeReasonRepo models.CloseReasonRepository
+	quoteService    *services.QuoteService
+}
+
+// NewCRMQuoteHandler creates a new quote handler
+func NewCRMQuoteHandler(repos *models.CRMRepositories) *CRMQuoteHandler {
+	return &CRMQuoteHandler{
+		quoteRepo:       repos.QuoteRepo,
+		dealRepo:        repos.DealRepo,
+		closeReasonRepo: repos.CloseReasonRepo,
+		quoteService:    services.NewQuoteService(repos),
+	}
+}
+
+// GetDealQuotes returns every quote version of a deal, newest first
+func (h *CRMQuoteHandler) GetDealQuotes(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	if _, err := h.quoteRepo.ExpireQuotes(deal.ID, today()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to expire quotes"})
+		return
+	}
+
+	quotes, err := h.quoteRepo.GetDealQuotes(deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quotes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quotes)
+}
+
+// CreateDealQuote creates a new draft quote version from a deal's current line items
+func (h *CRMQuoteHandler) CreateDealQuote(c *gin.Context) {
+	deal, ok := findDealByParam(c, h.dealRepo)
+	if !ok {
+		return
+	}
+
+	quote := &models.Quote{CreatedBy: currentUserID(c)}
+	if !bindQuote(c, quote) {
+		return
+	}
+
+	if err := h.quoteService.CreateQuote(deal, quote, today()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quote"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, quote)
+}
+
+// GetQuote returns a quote with its line items
+func (h *CRMQuoteHandler) GetQuote(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// UpdateQuote updates the title, validity, terms and notes of a draft quote
+func (h *CRMQuoteHandler) UpdateQuote(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuoteStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft quotes can be edited"})
+		return
+	}
+
+	if !bindQuote(c, quote) {
+		return
+	}
+
+	if err := h.quoteRepo.UpdateQuote(quote); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// SendQuote marks a draft quote as sent to the customer
+func (h *CRMQuoteHandler) SendQuote(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuoteStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft quotes can be sent"})
+		return
+	}
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusSent
+	quote.SentAt = &now
+	if err := h.quoteRepo.UpdateQuote(quote); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// AcceptQuote accepts a draft or sent quote and moves its deal to won. The body may give the win
+// reason, which is required once the company has set up win reasons.
+func (h *CRMQuoteHandler) AcceptQuote(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuoteStatusDraft && quote.Status != models.QuoteStatusSent {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quote is " + quote.Status + " and cannot be accepted"})
+		return
+	}
+
+	var reqBody dealCloseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	quotes, err := h.quoteRepo.GetDealQuotes(quote.DealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quotes"})
+		return
+	}
+	for _, other := range quotes {
+		if other.Status == models.QuoteStatusAccepted {
+			c.JSON(http.StatusConflict, gin.H{"error": "Quote " + other.Number + " has already been accepted for this deal"})
+			return
+		}
+	}
+
+	deal, err := h.dealRepo.FindByID(quote.DealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deal"})
+		return
+	}
+	if deal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+
+	previous := *deal
+	applyDealStage(deal, "won")
+	if !applyDealClose(c, h.closeReasonRepo, deal, previous, reqBody) {
+		return
+	}
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusAccepted
+	quote.AcceptedAt = &now
+	if err := h.quoteRepo.AcceptQuote(quote, deal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quote": quote,
+		"deal":  deal,
+	})
+}
+
+// RejectQuote records the customer's rejection of a draft or sent quote
+func (h *CRMQuoteHandler) RejectQuote(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuoteStatusDraft && quote.Status != models.QuoteStatusSent {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quote is " + quote.Status + " and cannot be rejected"})
+		return
+	}
+
+	var reqBody struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusRejected
+	quote.RejectedAt = &now
+	quote.RejectionNote = reqBody.Note
+	if err := h.quoteRepo.UpdateQuote(quote); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// GetQuotePDF renders a quote as a PDF with the company's quote template
+func (h *CRMQuoteHandler) GetQuotePDF(c *gin.Context) {
+	quote, ok := h.findQuote(c)
+	if !ok {
+		return
+	}
+
+	pdf, err := h.quoteService.RenderPDF(quote)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render quote: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", quote.Number+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetQuoteTemplate returns a company's quote template, or the defaults if none has been saved
+func (h *CRMQuoteHandler) GetQuoteTemplate(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	template, err := h.quoteTemplate(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quote template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateQuoteTemplate saves a company's quote template text, validity and colour, keeping its logo
+func (h *CRMQuoteHandler) UpdateQuoteTemplate(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	var reqBody struct {
+		Header       string `json:"header"`
+		Footer       string `json:"footer"`
+		DefaultTerms string `json:"default_terms"`
+		ValidityDays int    `json:"validity_days"`
+		AccentColor  string `json:"accent_color"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for field, text := range map[string]string{
+		"header":        reqBody.Header,
+		"footer":        reqBody.Footer,
+		"default_terms": reqBody.DefaultTerms,
+	} {
+		if _, err := services.ParseTemplate(text); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + field + ": " + err.Error()})
+			return
+		}
+	}
+	if reqBody.ValidityDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validity_days cannot be negative"})
+		return
+	}
+	if reqBody.AccentColor != "" && !accentColorPattern.MatchString(reqBody.AccentColor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "accent_color must be a #RRGGBB colour"})
+		return
+	}
+
+	template, err := h.quoteTemplate(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quote template"})
+		return
+	}
+
+	template.Header = reqBody.Header
+	template.Footer = reqBody.Footer
+	template.DefaultTerms = reqBody.DefaultTerms
+	if reqBody.ValidityDays > 0 {
+		template.ValidityDays = reqBody.ValidityDays
+	}
+	if reqBody.AccentColor != "" {
+		template.AccentColor = reqBody.AccentColor
+	}
+	if err := h.quoteRepo.SaveQuoteTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save quote template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UploadQuoteLogo replaces the logo printed on a company's quote PDFs with a PNG or JPEG upload
+func (h *CRMQuoteHandler) UploadQuoteLogo(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A logo file is required"})
+		return
+	}
+	if fileHeader.Size > maxQuoteLogoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be at most 1MB"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read logo"})
+		return
+	}
+	defer file.Close()
+
+	logo, err := io.ReadAll(io.LimitReader(file, maxQuoteLogoSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read logo"})
+		return
+	}
+	if len(logo) > maxQuoteLogoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be at most 1MB"})
+		return
+	}
+	contentType := http.DetectContentType(logo)
+	if contentType != "image/png" && contentType != "image/jpeg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be a PNG or JPEG image"})
+		return
+	}
+
+	template, err := h.quoteTemplate(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quote template"})
+		return
+	}
+
+	template.Logo = logo
+	template.LogoContentType = contentType
+	if err := h.quoteRepo.SaveQuoteTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save quote logo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// quoteTemplate returns a company's saved quote template or an unsaved one with the defaults
+func (h *CRMQuoteHandler) quoteTemplate(companyId int) (*models.QuoteTemplate, error) {
+	template, err := h.quoteRepo.GetQuoteTemplate(companyId)
+	if err != nil || template != nil {
+		return template, err
+	}
+	return &models.QuoteTemplate{
+		ValidityDays: 30,
+		AccentColor:  "#1F4E79",
+		CompanyId:    companyId,
+	}, nil
+}
+
+// bindQuote reads a quote's editable fields from the request body, writing an error response if
+// they are invalid
+func bindQuote(c *gin.Context, quote *models.Quote) bool {
+	var reqBody quoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+		dest  *time.Time
+	}{
+		{"valid_from", reqBody.ValidFrom, &quote.ValidFrom},
+		{"valid_until", reqBody.ValidUntil, &quote.ValidUntil},
+	} {
+		if field.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", field.value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + field.name + ", expected YYYY-MM-DD"})
+			return false
+		}
+		*field.dest = date
+	}
+	if !quote.ValidUntil.IsZero() && quote.ValidUntil.Before(quote.ValidFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until must not be before valid_from"})
+		return false
+	}
+	if reqBody.Title != "" {
+		quote.Title = reqBody.Title
+	}
+	if reqBody.Terms != "" {
+		quote.Terms = reqBody.Terms
+	}
+	quote.Notes = reqBody.Notes
+	return true
+}
+
+// findQuote loads the quote named by the :id parameter, expiring it if its validity has ended,
+// and writes an error response if it fails
+func (h *CRMQuoteHandler) findQuote(c *gin.Context) (*models.Quote, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote ID"})
+		return nil, false
+	}
+
+	quote, err := h.quoteRepo.GetQuoteByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quote"})
+		return nil, false
+	}
+	if quote == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return nil, false
+	}
+
+	if (quote.Status == models.QuoteStatusDraft || quote.Status == models.QuoteStatusSent) &&
+		quote.ValidUntil.Before(today()) {
+		quote.Status = models.QuoteStatusExpired
+		if err := h.quoteRepo.UpdateQuote(quote); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to expire quote"})
+			return nil, false
+		}
+	}
+
+	return quote, true
+}
+
+// today returns the start of the current day in UTC, the zone quote dates are stored in
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMSavedViewHandler handles requests for saved list views of leads, deals and contacts
+type CRMSavedViewHandler struct {
+	viewRepo models.SavedViewRepository
+}
+
+// NewCRMSavedViewHandler creates a new saved view handler
+func NewCRMSavedViewHandler(repos *models.CRMRepositories) *CRMSavedViewHandler {
+	return &CRMSavedViewHandler{
+		viewRepo: repos.SavedViewRepo,
+	}
+}
+
+// GetSavedViews returns the current user's views and those shared with the company, optionally
+// of one entity
+func (h *CRMSavedViewHandler) GetSavedViews(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	entity := c.Query("entity")
+	if entity != "" && !models.ViewEntities[entity] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity must be lead, deal or contact"})
+		return
+	}
+
+	userID := 0
+	if id := currentUserID(c); id != nil {
+		userID = *id
+	}
+	views, err := h.viewRepo.GetSavedViews(entity, userID, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved views"})
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// GetSavedView returns a saved view by ID
+func (h *CRMSavedViewHandler) GetSavedView(c *gin.Context) {
+	view, ok := h.findView(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// CreateSavedView creates a view owned by the current user
+func (h *CRMSavedViewHandler) CreateSavedView(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "userId not found in context"})
+		return
+	}
+
+	var view models.SavedView
+	if err := c.ShouldBindJSON(&view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view.ID = 0
+	view.OwnerID = *userID
+
+	if msg := validateSavedView(&view); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.viewRepo.CreateSavedView(&view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create saved view"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateSavedView updates a view; only its owner can change it
+func (h *CRMSavedViewHandler) UpdateSavedView(c *gin.Context) {
+	existingView, ok := h.findOwnView(c)
+	if !ok {
+		return
+	}
+
+	var view models.SavedView
+	if err := c.ShouldBindJSON(&view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID, company and owner match the stored view
+	view.ID = existingView.ID
+	view.CompanyId = existingView.CompanyId
+	view.OwnerID = existingView.OwnerID
+	view.CreatedAt = existingView.CreatedAt
+
+	if msg := validateSavedView(&view); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.viewRepo.UpdateSavedView(&view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update saved view"})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteSavedView deletes a view; only its owner can delete it
+func (h *CRMSavedViewHandler) DeleteSavedView(c *gin.Context) {
+	view, ok := h.findOwnView(c)
+	if !ok {
+		return
+	}
+
+	if err := h.viewRepo.DeleteSavedView(view.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved view"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved view deleted successfully"})
+}
+
+// GetSavedViewRecords returns a page of the records a view lists, filtered and sorted as the view
+// says. The page size is the view's unless page_size is given.
+func (h *CRMSavedViewHandler) GetSavedViewRecords(c *gin.Context) {
+	view, ok := h.findView(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+	pageSize := view.PageSize
+	if pageSize <= 0 {
+		pageSize = models.DefaultViewPageSize
+	}
+	if sizeStr := c.Query("page_size"); sizeStr != "" {
+		pageSize, err = strconv.Atoi(sizeStr)
+		if err != nil || pageSize < 1 || pageSize > models.MaxViewPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size"})
+			return
+		}
+	}
+
+	filters, err := services.ParseViewFilters(view.Filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid saved view filters"})
+		return
+	}
+	columns, err := services.ParseViewColumns(view.Columns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid saved view columns"})
+		return
+	}
+
+	result := models.ViewPage{
+		ViewID:   view.ID,
+		Entity:   view.Entity,
+		Columns:  columns,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	order := services.ViewSortOf(view)
+	offset := (page - 1) * pageSize
+	switch view.Entity {
+	case models.ViewEntityLead:
+		result.Records, result.Total, err = h.viewRepo.ListLeads(filters, order, offset, pageSize, view.CompanyId)
+	case models.ViewEntityDeal:
+		result.Records, result.Total, err = h.viewRepo.ListDeals(filters, order, offset, pageSize, view.CompanyId)
+	case models.ViewEntityContact:
+		result.Records, result.Total, err = h.viewRepo.ListContacts(filters, order, offset, pageSize, view.CompanyId)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved view records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// findView loads the saved view named by the :id parameter, writing an error response if it
+// fails or the current user cannot see it
+func (h *CRMSavedViewHandler) findView(c *gin.Context) (*models.SavedView, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved view ID"})
+		return nil, false
+	}
+	return findVisibleView(c, h.viewRepo, id)
+}
+
+// findOwnView loads the saved view named by the :id parameter, writing an error response if it
+// fails or the current user does not own it
+func (h *CRMSavedViewHandler) findOwnView(c *gin.Context) (*models.SavedView, bool) {
+	view, ok := h.findView(c)
+	if !ok {
+		return nil, false
+	}
+	if userID := currentUserID(c); userID == nil || *userID != view.OwnerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can change a saved view"})
+		return nil, false
+	}
+	return view, true
+}
+
+// findVisibleView loads a saved view the current user can see, writing an error response if it
+// fails. Views that are neither shared nor the user's own are reported as not found.
+func findVisibleView(c *gin.Context, viewRepo models.SavedViewRepository, id int) (*models.SavedView, bool) {
+	view, err := viewRepo.GetSavedViewByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved view"})
+		return nil, false
+	}
+	if view != nil && !view.IsShared {
+		if userID := currentUserID(c); userID == nil || *userID != view.OwnerID {
+			view = nil
+		}
+	}
+	if view == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved view not found"})
+		return nil, false
+	}
+	return view, true
+}
+
+// validateSavedView checks a view's name, company, page size, filters and columns, defaulting the
+// page size, and returns an error message if invalid
+func validateSavedView(view *models.SavedView) string {
+	if view.Name == "" {
+		return "Saved view name is required"
+	}
+	if view.CompanyId == 0 {
+		return "company_id is required"
+	}
+	if err := services.ValidateSavedView(view); err != nil {
+		return err.Error()
+	}
+	if view.PageSize == 0 {
+		view.PageSize = models.DefaultViewPageSize
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchLimit caps how many hits one search request returns
+const maxSearchLimit = 100
+
+// CRMSearchHandler handles global search across leads, contacts, deals and accounts
+type CRMSearchHandler struct {
+	searchRepo models.SearchRepository
+}
+
+// NewCRMSearchHandler creates a new search handler
+func NewCRMSearchHandler(repos *models.CRMRepositories) *CRMSearchHandler {
+	return &CRMSearchHandler{
+		searchRepo: repos.SearchRepo,
+	}
+}
+
+// Search returns a company's records matching q, tolerating typos and matching partial words.
+// types=lead,deal limits the hits to some record types; the facets still count every type.
+func (h *CRMSearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var types []string
+	if typesParam := c.Query("types"); typesParam != "" {
+		for _, searchType := range strings.Split(typesParam, ",") {
+			searchType = strings.TrimSpace(strings.ToLower(searchType))
+			if !validSearchType(searchType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "types must be lead, contact, deal or account"})
+				return
+			}
+			types = append(types, searchType)
+		}
+	}
+
+	results, err := h.searchRepo.Search(query, types, offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// Reindex rebuilds a company's search index from its records
+func (h *CRMSearchHandler) Reindex(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	indexed, err := h.searchRepo.Reindex(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild search index"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"indexed": indexed})
+}
+
+func validSearchType(searchType string) bool {
+	for _, valid := range models.SearchTypes {
+		if searchType == valid {
+			return true
+		}
+	}
+	return false
+}
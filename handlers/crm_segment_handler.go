@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const segmentPreviewSample = 20
+
+// CRMSegmentHandler handles requests for saved lead segments
+type CRMSegmentHandler struct {
+	segmentRepo    models.SegmentRepository
+	segmentService *services.SegmentService
+}
+
+// NewCRMSegmentHandler creates a new segment handler
+func NewCRMSegmentHandler(repos *models.CRMRepositories) *CRMSegmentHandler {
+	return &CRMSegmentHandler{
+		segmentRepo:    repos.SegmentRepo,
+		segmentService: services.NewSegmentService(repos),
+	}
+}
+
+// GetSegments returns a company's segments with pagination
+func (h *CRMSegmentHandler) GetSegments(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	segments, err := h.segmentRepo.GetSegments(offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, segments)
+}
+
+// GetSegment returns a segment by ID
+func (h *CRMSegmentHandler) GetSegment(c *gin.Context) {
+	segment, ok := h.findSegment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, segment)
+}
+
+// CreateSegment creates a segment and evaluates its members
+func (h *CRMSegmentHandler) CreateSegment(c *gin.Context) {
+	var segment models.Segment
+	if err := c.ShouldBindJSON(&segment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := validateSegment(&segment); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if userID := currentUserID(c); userID != nil {
+		segment.CreatedBy = *userID
+	}
+	segment.MemberCount = 0
+	segment.LastRefreshedAt = nil
+	segment.NextRefreshAt = nil
+
+	if err := h.segmentRepo.CreateSegment(&segment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create segment"})
+		return
+	}
+
+	h.respondRefreshed(c, http.StatusCreated, &segment)
+}
+
+// UpdateSegment updates a segment and re-evaluates its members
+func (h *CRMSegmentHandler) UpdateSegment(c *gin.Context) {
+	existingSegment, ok := h.findSegment(c)
+	if !ok {
+		return
+	}
+
+	var segment models.Segment
+	if err := c.ShouldBindJSON(&segment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored segment; refresh state is owned by the refresh
+	segment.ID = existingSegment.ID
+	segment.CompanyId = existingSegment.CompanyId
+	segment.CreatedBy = existingSegment.CreatedBy
+	segment.CreatedAt = existingSegment.CreatedAt
+	segment.MemberCount = existingSegment.MemberCount
+	segment.LastRefreshedAt = existingSegment.LastRefreshedAt
+	segment.NextRefreshAt = existingSegment.NextRefreshAt
+
+	if msg := validateSegment(&segment); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.segmentRepo.UpdateSegment(&segment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update segment"})
+		return
+	}
+
+	h.respondRefreshed(c, http.StatusOK, &segment)
+}
+
+// DeleteSegment deletes a segment; campaigns and sequences targeting it keep their members
+func (h *CRMSegmentHandler) DeleteSegment(c *gin.Context) {
+	segment, ok := h.findSegment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.segmentRepo.DeleteSegment(segment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete segment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Segment deleted successfully"})
+}
+
+// PreviewSegment counts the leads a filter matches without saving it. The body has the shape of
+// a segment; only company_id and filters are used.
+func (h *CRMSegmentHandler) PreviewSegment(c *gin.Context) {
+	var segment models.Segment
+	if err := c.ShouldBindJSON(&segment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if segment.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return
+	}
+
+	filter, err := services.ParseSegmentFilter(segment.Filters)
+	if err == nil {
+		err = services.ValidateSegmentFilter(filter)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, sample, err := h.segmentRepo.PreviewSegment(segment.CompanyId, filter, segmentPreviewSample)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate segment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":           count,
+		"sample_lead_ids": sample,
+	})
+}
+
+// GetSegmentLeads returns a segment's members as of its last refresh, or evaluates the segment
+// now when live=true
+func (h *CRMSegmentHandler) GetSegmentLeads(c *gin.Context) {
+	segment, ok := h.findSegment(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("live") == "true" {
+		leadIDs, err := h.segmentService.Evaluate(segment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate segment"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"count":    len(leadIDs),
+			"lead_ids": leadIDs,
+		})
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	members, err := h.segmentRepo.GetSegmentMembers(segment.ID, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segment members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":             segment.MemberCount,
+		"last_refreshed_at": segment.LastRefreshedAt,
+		"members":           members,
+	})
+}
+
+// RefreshSegment re-evaluates a segment now and adds new members to the campaigns and sequences
+// targeting it
+func (h *CRMSegmentHandler) RefreshSegment(c *gin.Context) {
+	segment, ok := h.findSegment(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.segmentService.Refresh(segment, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh segment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondRefreshed refreshes a saved segment and responds with it and the refresh result
+func (h *CRMSegmentHandler) respondRefreshed(c *gin.Context, status int, segment *models.Segment) {
+	result, err := h.segmentService.Refresh(segment, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Segment saved but could not be evaluated"})
+		return
+	}
+
+	refreshed, err := h.segmentRepo.GetSegmentByID(segment.ID)
+	if err != nil || refreshed == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segment"})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"segment": refreshed,
+		"refresh": result,
+	})
+}
+
+// findSegment loads the segment named by the :id parameter, writing an error response if it fails
+func (h *CRMSegmentHandler) findSegment(c *gin.Context) (*models.Segment, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment ID"})
+		return nil, false
+	}
+
+	segment, err := h.segmentRepo.GetSegmentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch segment"})
+		return nil, false
+	}
+	if segment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+		return nil, false
+	}
+
+	return segment, true
+}
+
+// validateSegment checks a segment's name, refresh interval and filters, returning an error message if invalid
+func validateSegment(segment *models.Segment) string {
+	if segment.Name == "" {
+		return "Segment name is required"
+	}
+	if segment.CompanyId == 0 {
+		return "company_id is required"
+	}
+	if segment.RefreshMinutes < 0 {
+		return "refresh_minutes cannot be negative"
+	}
+
+	filter, err := services.ParseSegmentFilter(segment.Filters)
+	if err != nil {
+		return "Invalid segment filters"
+	}
+	if err := services.ValidateSegmentFilter(filter); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"crm-app/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tagColorPattern matches a tag color, #RRGGBB
+var tagColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// CRMTagHandler handles requests for company tag definitions
+type CRMTagHandler struct {
+	tagRepo models.TagRepository
+}
+
+// NewCRMTagHandler creates a new tag handler
+func NewCRMTagHandler(repos *models.CRMRepositories) *CRMTagHandler {
+	return &CRMTagHandler{
+		tagRepo: repos.TagRepo,
+	}
+}
+
+// GetTags returns a company's tags by name
+func (h *CRMTagHandler) GetTags(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	tags, err := h.tagRepo.GetTags(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetTagUsage returns how many leads, deals and contacts each of a company's tags is on, most
+// used first
+func (h *CRMTagHandler) GetTagUsage(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	usage, err := h.tagRepo.GetTagUsage(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// CreateTag creates a tag
+func (h *CRMTagHandler) CreateTag(c *gin.Context) {
+	var tag models.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag.ID = 0
+
+	if !h.validateTag(c, &tag) {
+		return
+	}
+
+	if err := h.tagRepo.CreateTag(&tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag updates a tag's name and color; renaming it renames it on every record tagged with it
+func (h *CRMTagHandler) UpdateTag(c *gin.Context) {
+	existingTag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ensure ID and company match the stored tag
+	tag.ID = existingTag.ID
+	tag.CompanyId = existingTag.CompanyId
+	tag.CreatedAt = existingTag.CreatedAt
+
+	if !h.validateTag(c, &tag) {
+		return
+	}
+
+	if err := h.tagRepo.UpdateTag(&tag, existingTag.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// MergeTag moves every record tagged with a tag to another tag, then deletes it
+func (h *CRMTagHandler) MergeTag(c *gin.Context) {
+	source, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		TargetID int `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := h.tagRepo.GetTagByID(reqBody.TargetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag"})
+		return
+	}
+	if target == nil || target.CompanyId != source.CompanyId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target tag does not exist"})
+		return
+	}
+	if target.ID == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A tag cannot be merged into itself"})
+		return
+	}
+
+	if err := h.tagRepo.MergeTag(source, target); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// DeleteTag deletes a tag and removes it from every record tagged with it
+func (h *CRMTagHandler) DeleteTag(c *gin.Context) {
+	tag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tagRepo.DeleteTag(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// findTag loads the tag named by the :id parameter, writing an error response if it fails
+func (h *CRMTagHandler) findTag(c *gin.Context) (*models.Tag, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return nil, false
+	}
+
+	tag, err := h.tagRepo.GetTagByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag"})
+		return nil, false
+	}
+	if tag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return nil, false
+	}
+	return tag, true
+}
+
+// validateTag checks a tag's name, color and company, defaulting the color, and that no other
+// tag of the company has its name, writing an error response if not
+func (h *CRMTagHandler) validateTag(c *gin.Context, tag *models.Tag) bool {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required"})
+		return false
+	}
+	if msg := checkTagNames([]string{tag.Name}); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false
+	}
+	if tag.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return false
+	}
+	if tag.Color == "" {
+		tag.Color = models.DefaultTagColor
+	}
+	if !tagColorPattern.MatchString(tag.Color) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "color must be in the form #RRGGBB"})
+		return false
+	}
+
+	existing, err := h.tagRepo.GetTagByName(tag.Name, tag.CompanyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check tag name"})
+		return false
+	}
+	if existing != nil && existing.ID != tag.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists; merge the tags instead", "tag_id": existing.ID})
+		return false
+	}
+	return true
+}
+
+// checkTagNames checks the tags given for a record, returning an error message if one is too long
+func checkTagNames(tags []string) string {
+	for _, tag := range tags {
+		if len(strings.TrimSpace(tag)) > models.MaxTagLength {
+			return fmt.Sprintf("Tags can be at most %d characters", models.MaxTagLength)
+		}
+	}
+	return ""
+}
+
+// tagFilterQuery parses the tags and tag_match query parameters of a list endpoint, writing an
+// error response if they are invalid. tags is a comma-separated list; records match if they have
+// any of them, or every one with tag_match=all. The filter is nil if no tags are given.
+func tagFilterQuery(c *gin.Context) (*models.TagFilter, bool) {
+	var tags []string
+	seen := map[string]bool{}
+	for _, param := range c.QueryArray("tags") {
+		for _, tag := range strings.Split(param, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && !seen[strings.ToLower(tag)] {
+				seen[strings.ToLower(tag)] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, true
+	}
+
+	filter := &models.TagFilter{Tags: tags}
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_match must be any or all"})
+		return nil, false
+	}
+	return filter, true
+}
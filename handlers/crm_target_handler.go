@@ -12,12 +12,14 @@ import (
 // CRMTargetHandler handles requests for sales targets
 type CRMTargetHandler struct {
 	targetRepo models.TargetRepository
+	auditRepo  models.AuditRepository
 }
 
 // NewCRMTargetHandler creates a new target handler
 func NewCRMTargetHandler(repos *models.CRMRepositories) *CRMTargetHandler {
 	return &CRMTargetHandler{
 		targetRepo: repos.TargetRepo,
+		auditRepo:  repos.AuditRepo,
 	}
 }
 
@@ -178,6 +180,7 @@ func (h *CRMTargetHandler) DeleteTarget(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete target"})
 		return
 	}
+	recordAudit(c, h.auditRepo, models.TrashTypeTarget, id, models.AuditActionDelete, existingTarget.CompanyId)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Target deleted successfully"})
 }
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMTrashHandler handles requests for the recycle bin of deleted records
+type CRMTrashHandler struct {
+	trashService *services.TrashService
+}
+
+// NewCRMTrashHandler creates a new recycle bin handler
+func NewCRMTrashHandler(repos *models.CRMRepositories) *CRMTrashHandler {
+	return &CRMTrashHandler{
+		trashService: services.NewTrashService(repos),
+	}
+}
+
+// GetTrash returns a company's deleted records newest first, optionally of one type, with who
+// deleted each, when, and when it will be purged for good
+func (h *CRMTrashHandler) GetTrash(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+	recordType := c.Query("type")
+	if recordType != "" && !validTrashType(recordType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be lead, contact, deal, target or nurture_sequence"})
+		return
+	}
+
+	items, total, err := h.trashService.List(recordType, offset, limit, companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recycle bin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"records": items, "total": total})
+}
+
+// RestoreTrashItem restores a deleted record along with the children deleted with it
+func (h *CRMTrashHandler) RestoreTrashItem(c *gin.Context) {
+	item, ok := h.findTrashItem(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Restore(item, currentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Record restored successfully", "type": item.Type, "id": item.ID})
+}
+
+// PurgeTrashItem permanently deletes a record in the recycle bin, without waiting for the
+// retention period to pass
+func (h *CRMTrashHandler) PurgeTrashItem(c *gin.Context) {
+	item, ok := h.findTrashItem(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.Purge(item, currentUserID(c)); err != nil {
+		var blocked *models.PurgeBlockedError
+		if errors.As(err, &blocked) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "Record cannot be purged while other records belong to it; reassign or purge them first",
+				"blocked_by": blocked.Blockers,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Record permanently deleted"})
+}
+
+// findTrashItem loads the deleted record named by the :type and :id parameters, writing an error
+// response if it fails or the record is not in the recycle bin
+func (h *CRMTrashHandler) findTrashItem(c *gin.Context) (*models.TrashItem, bool) {
+	recordType := c.Param("type")
+	if !validTrashType(recordType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be lead, contact, deal, target or nurture_sequence"})
+		return nil, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return nil, false
+	}
+
+	item, err := h.trashService.Get(recordType, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted record"})
+		return nil, false
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted record not found"})
+		return nil, false
+	}
+	return item, true
+}
+
+// validTrashType reports whether the recycle bin holds records of a type
+func validTrashType(recordType string) bool {
+	for _, t := range models.TrashTypes {
+		if t == recordType {
+			return true
+		}
+	}
+	return false
+}
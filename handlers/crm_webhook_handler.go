@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-app/backend/models"
+	"crm-app/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRMWebhookHandler handles requests for webhook subscriptions and their delivery log
+type CRMWebhookHandler struct {
+	webhookRepo    models.WebhookRepository
+	webhookService *services.WebhookService
+}
+
+// NewCRMWebhookHandler creates a new webhook handler
+func NewCRMWebhookHandler(repos *models.CRMRepositories) *CRMWebhookHandler {
+	return &CRMWebhookHandler{
+		webhookRepo:    repos.WebhookRepo,
+		webhookService: services.NewWebhookService(repos),
+	}
+}
+
+// webhookRequest is the body of a webhook create or update. Secret is generated on create if not
+// given, and kept on update if not given.
+type webhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required"`
+	Secret      string   `json:"secret"`
+	Active      *bool    `json:"active"`
+	CompanyId   int      `json:"company_id"`
+}
+
+// GetWebhooks returns a company's webhook subscriptions
+func (h *CRMWebhookHandler) GetWebhooks(c *gin.Context) {
+	companyId, err := strconv.Atoi(c.Query("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid companyId"})
+		return
+	}
+
+	subscriptions, err := h.webhookRepo.GetSubscriptions(companyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// GetWebhook returns a webhook subscription by ID
+func (h *CRMWebhookHandler) GetWebhook(c *gin.Context) {
+	subscription, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	subscription.Secret = ""
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// CreateWebhook creates a webhook subscription. The response carries its signing secret, which is
+// not returned again.
+func (h *CRMWebhookHandler) CreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CompanyId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return
+	}
+
+	subscription := models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      req.Secret,
+		Active:      req.Active == nil || *req.Active,
+		CompanyId:   req.CompanyId,
+	}
+	if !subscription.Active {
+		now := time.Now()
+		subscription.DisabledAt = &now
+		subscription.DisabledReason = "disabled by user"
+	}
+	if err := services.ValidateSubscription(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.webhookRepo.CreateSubscription(&subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// UpdateWebhook updates a webhook subscription. Enabling a disabled webhook resumes the
+// deliveries waiting for it; a new secret is returned in the response.
+func (h *CRMWebhookHandler) UpdateWebhook(c *gin.Context) {
+	subscription, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription.URL = req.URL
+	subscription.Description = req.Description
+	subscription.Events = req.Events
+	newSecret := req.Secret != ""
+	if newSecret {
+		subscription.Secret = req.Secret
+	}
+	if req.Active != nil && *req.Active != subscription.Active {
+		subscription.Active = *req.Active
+		if subscription.Active {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledAt = nil
+			subscription.DisabledReason = ""
+		} else {
+			now := time.Now()
+			subscription.DisabledAt = &now
+			subscription.DisabledReason = "disabled by user"
+		}
+	}
+	if err := services.ValidateSubscription(subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.webhookRepo.UpdateSubscription(subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	if !newSecret {
+		subscription.Secret = ""
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook deletes a webhook subscription and its delivery log
+func (h *CRMWebhookHandler) DeleteWebhook(c *gin.Context) {
+	subscription, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookRepo.DeleteSubscription(subscription.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns a webhook's delivery log newest first, optionally only the
+// deliveries in a status
+func (h *CRMWebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	subscription, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	status := c.Query("status")
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, succeeded or failed"})
+		return
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveries(subscription.ID, status, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery returns a webhook delivery with its payload and latest response
+func (h *CRMWebhookHandler) GetWebhookDelivery(c *gin.Context) {
+	delivery, ok := h.findDelivery(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery sends a delivery's event to its webhook again, as a new delivery with the
+// same event ID and payload
+func (h *CRMWebhookHandler) ReplayWebhookDelivery(c *gin.Context) {
+	subscription, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	delivery, ok := h.findDelivery(c)
+	if !ok {
+		return
+	}
+	if !subscription.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook is disabled; enable it to replay deliveries"})
+		return
+	}
+
+	replay, err := h.webhookService.Replay(delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay webhook delivery"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, replay)
+}
+
+// findWebhook loads the webhook subscription named by the :id parameter, writing an error response
+// if it fails
+func (h *CRMWebhookHandler) findWebhook(c *gin.Context) (*models.WebhookSubscription, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	subscription, err := h.webhookRepo.GetSubscriptionByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		return nil, false
+	}
+	if subscription == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return subscription, true
+}
+
+// findDelivery loads the delivery named by the :deliveryId parameter, which must belong to the
+// webhook named by :id, writing an error response if it fails
+func (h *CRMWebhookHandler) findDelivery(c *gin.Context) (*models.WebhookDelivery, bool) {
+	subscriptionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+	id, err := strconv.Atoi(c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return nil, false
+	}
+
+	delivery, err := h.webhookRepo.GetDeliveryByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook delivery"})
+		return nil, false
+	}
+	if delivery == nil || delivery.SubscriptionID != subscriptionID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+		return nil, false
+	}
+	return delivery, true
+}
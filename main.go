@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"crm-app/backend/db"
 	"crm-app/backend/models"
 	"crm-app/backend/repositories"
 	"crm-app/backend/routes"
+	"crm-app/backend/services"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -29,6 +32,14 @@ func main() {
 	// if err := db.AutoMigrate(database); err != nil {
 	// 	log.Fatalf("Failed to migrate database schema: %v", err)
 	// }
+	if err := db.MigrateNurtureCurrentStep(database); err != nil {
+		log.Fatalf("Failed to migrate nurture enrollment steps: %v", err)
+	}
+
+	// Open the full-text index behind global search, kept in memory unless a path is configured
+	if err := repositories.OpenSearchIndex(os.Getenv("SEARCH_INDEX_PATH")); err != nil {
+		log.Fatalf("Failed to open search index: %v", err)
+	}
 
 	// Initialize repositories
 	repos := repositories.NewRepositoriesInit(database)
@@ -57,14 +68,81 @@ func main() {
 		CampaignRepo:        repos.CampaignRepo,
 		DashboardRepo:       repos.DashboardRepo,
 		// AnalyticsRepo:       repos.AnalyticsRepo,
-		AnalyticsRepo: repositories.NewAnalyticsRepository(database),
-		TargetRepo:    repos.TargetRepo,
-		NurtureRepo:   repos.NurtureRepo,
-		UserRepo:      repos.UserRepo,
-		LeadScoreType: repos.ScoreRepo,
+		AnalyticsRepo:     repositories.NewAnalyticsRepository(database),
+		TargetRepo:        repos.TargetRepo,
+		NurtureRepo:       repos.NurtureRepo,
+		UserRepo:          repos.UserRepo,
+		LeadScoreType:     repos.ScoreRepo,
+		AttributionRepo:   repos.AttributionRepo,
+		EmailRepo:         repos.EmailRepo,
+		CompanyRepo:       repos.CompanyRepo,
+		ConsentRepo:       repos.ConsentRepo,
+		ABTestRepo:        repos.ABTestRepo,
+		SegmentRepo:       repos.SegmentRepo,
+		ExchangeRateRepo:  repos.ExchangeRateRepo,
+		ProductRepo:       repos.ProductRepo,
+		QuoteRepo:         repos.QuoteRepo,
+		CloseReasonRepo:   repos.CloseReasonRepo,
+		AccountRepo:       repos.AccountRepo,
+		SearchRepo:        repos.SearchRepo,
+		PhoneRepo:         repos.PhoneRepo,
+		SavedViewRepo:     repos.SavedViewRepo,
+		AuditRepo:         repos.AuditRepo,
+		BulkOperationRepo: repos.BulkOperationRepo,
+		TrashRepo:         repos.TrashRepo,
+		TagRepo:           repos.TagRepo,
+		WebhookRepo:       repos.WebhookRepo,
+		OutboxRepo:        repos.OutboxRepo,
 	}
 	routes.SetupCRMRoutes(r, crmRepos)
 
+	// Build the search index in the background if it is new or kept in memory
+	go func() {
+		if err := crmRepos.SearchRepo.EnsureIndexed(); err != nil {
+			log.Printf("Failed to build search index: %v", err)
+		}
+	}()
+
+	// Start the email send queue, the campaign, segment and nurture sequence schedulers, the
+	// phone number backfill, the recycle bin purge, webhook delivery and the domain event bus
+	emailService := services.NewEmailService(crmRepos, services.NewEmailProviderFromEnv())
+	if services.NewUnsubscribeLinksFromEnv() == nil {
+		log.Println("UNSUBSCRIBE_BASE_URL and UNSUBSCRIBE_SECRET are not set; campaign and nurture email will not be sent")
+	}
+	if os.Getenv("EMAIL_QUEUE_DISABLED") != "true" {
+		go emailService.Start(context.Background(), durationFromEnv("EMAIL_QUEUE_INTERVAL", 30*time.Second))
+	}
+	if os.Getenv("CAMPAIGN_SCHEDULER_DISABLED") != "true" {
+		campaignService := services.NewCampaignService(crmRepos, emailService)
+		go campaignService.Start(context.Background(), durationFromEnv("CAMPAIGN_SCHEDULER_INTERVAL", time.Minute))
+	}
+	if os.Getenv("SEGMENT_SCHEDULER_DISABLED") != "true" {
+		segmentService := services.NewSegmentService(crmRepos)
+		go segmentService.Start(context.Background(), durationFromEnv("SEGMENT_SCHEDULER_INTERVAL", time.Minute))
+	}
+	if os.Getenv("PHONE_BACKFILL_DISABLED") != "true" {
+		phoneService := services.NewPhoneService(crmRepos)
+		go phoneService.Start(context.Background(), durationFromEnv("PHONE_BACKFILL_INTERVAL", time.Hour))
+	}
+	if os.Getenv("TRASH_PURGE_DISABLED") != "true" {
+		trashService := services.NewTrashService(crmRepos)
+		go trashService.Start(context.Background(), durationFromEnv("TRASH_PURGE_INTERVAL", time.Hour))
+	}
+	webhookService := services.NewWebhookService(crmRepos)
+	if os.Getenv("WEBHOOK_DELIVERY_DISABLED") != "true" {
+		go webhookService.Start(context.Background(), durationFromEnv("WEBHOOK_DELIVERY_INTERVAL", 15*time.Second))
+	}
+	if os.Getenv("EVENT_BUS_DISABLED") != "true" {
+		eventBus := services.NewEventBus(crmRepos)
+		eventBus.Subscribe("webhooks", webhookService.DomainEventHandler(), webhookService.DomainEventTypes()...)
+		go eventBus.Start(context.Background(), durationFromEnv("EVENT_BUS_INTERVAL", 2*time.Second))
+	}
+	if os.Getenv("NURTURE_SCHEDULER_DISABLED") != "true" {
+		nurtureEngine := services.NewNurtureEngine(crmRepos)
+		nurtureEngine.RegisterExecutor("email", emailService.NurtureEmailExecutor())
+		go nurtureEngine.Start(context.Background(), durationFromEnv("NURTURE_SCHEDULER_INTERVAL", time.Minute))
+	}
+
 	// Setup Lead Capture routes - these will be at /api/leads/...
 	routes.SetupLeadCaptureRoutes(api, repos)
 
@@ -82,3 +160,17 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// durationFromEnv parses a duration such as "30s" from the environment, falling back to a default
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// A/B test statuses
+const (
+	ABTestStatusTesting        = "testing"
+	ABTestStatusWinnerSelected = "winner_selected"
+)
+
+// ABTestWinnerMetrics are the rates a winning variant can be chosen by
+var ABTestWinnerMetrics = map[string]bool{
+	"open_rate":  true,
+	"click_rate": true,
+}
+
+// EmailABTest splits the recipients of a campaign or nurture email step between template variants.
+// A holdout share receives nothing; a test share is split between the variants and, once
+// WinnerAfterHours have passed since the first test send, the variant with the best
+// WinnerMetric is sent to the remaining recipients.
+type EmailABTest struct {
+	ID               int            `json:"id" gorm:"primaryKey"`
+	CampaignID       *int           `json:"campaign_id" gorm:"uniqueIndex"`
+	StepID           *int           `json:"step_id" gorm:"uniqueIndex"`
+	TestPercent      int            `json:"test_percent" gorm:"not null;default:100"` // share of recipients in the test, campaigns only
+	HoldoutPercent   int            `json:"holdout_percent" gorm:"not null;default:0"`
+	WinnerMetric     string         `json:"winner_metric" gorm:"size:20;not null;default:'open_rate'"`
+	WinnerAfterHours int            `json:"winner_after_hours" gorm:"not null;default:24"`
+	Status           string         `json:"status" gorm:"size:20;not null;default:'testing';index"`
+	WinnerVariantID  *int           `json:"winner_variant_id"`
+	TestStartedAt    *time.Time     `json:"test_started_at"`
+	WinnerSelectedAt *time.Time     `json:"winner_selected_at"`
+	Variants         []EmailVariant `json:"variants" gorm:"foreignKey:TestID"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	CompanyId        int            `json:"company_id" gorm:"not null;index"`
+}
+
+// EmailVariant is one template being tested in an A/B test
+type EmailVariant struct {
+	ID           int       `json:"id" gorm:"primaryKey"`
+	TestID       int       `json:"test_id" gorm:"not null;index"`
+	Name         string    `json:"name" gorm:"size:100;not null"`
+	TemplateID   int       `json:"template_id" gorm:"not null"`
+	SplitPercent int       `json:"split_percent" gorm:"not null"` // share of the test group
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
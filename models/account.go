@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Account is a customer organization. Accounts form hierarchies through ParentAccountID, and
+// contacts and deals belong to an account through their AccountID.
+type Account struct {
+	ID              int            `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name" gorm:"size:255;not null;index"`
+	Domain          string         `json:"domain" gorm:"size:255;index"` // e.g. example.com
+	Industry        string         `json:"industry" gorm:"size:100"`
+	Size            string         `json:"size" gorm:"size:50"` // employee band, e.g. 51-200
+	Phone           string         `json:"phone" gorm:"size:50"`
+	Street          string         `json:"street" gorm:"size:255"`
+	City            string         `json:"city" gorm:"size:100"`
+	State           string         `json:"state" gorm:"size:100"`
+	PostalCode      string         `json:"postal_code" gorm:"size:20"`
+	Country         string         `json:"country" gorm:"size:100"`
+	ParentAccountID *int           `json:"parent_account_id" gorm:"index"`
+	OwnerID         *int           `json:"owner_id" gorm:"index"`
+	Notes           string         `json:"notes,omitempty" gorm:"type:text"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	CompanyId       int            `json:"company_id" gorm:"not null;index"`
+}
+
+// AccountRollup totals an account's deals and activity, including those of its child accounts
+// when IncludesChildren is set. Amounts are in the company's reporting currency.
+type AccountRollup struct {
+	AccountID              int              `json:"account_id"`
+	IncludesChildren       bool             `json:"includes_children"`
+	AccountIDs             []int            `json:"account_ids"`
+	Contacts               int64            `json:"contacts"`
+	OpenDeals              int64            `json:"open_deals"`
+	OpenPipeline           float64          `json:"open_pipeline"`
+	OpenPipelineByCurrency []CurrencyAmount `json:"open_pipeline_by_currency"`
+	WonDeals               int64            `json:"won_deals"`
+	WonRevenue             float64          `json:"won_revenue"`
+	WonRevenueByCurrency   []CurrencyAmount `json:"won_revenue_by_currency"`
+	Currency               string           `json:"currency"`
+	LastActivityAt         *time.Time       `json:"last_activity_at"`
+	LastActivityType       string           `json:"last_activity_type,omitempty"` // deal, contact or email
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// Attribution models supported by the attribution report
+const (
+	AttributionFirstTouch = "first_touch"
+	AttributionLastTouch  = "last_touch"
+	AttributionLinear     = "linear"
+	AttributionTimeDecay  = "time_decay"
+)
+
+// LeadTouchpoint records a single marketing interaction for a lead
+type LeadTouchpoint struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	LeadID      int       `json:"lead_id" gorm:"not null;index"`
+	CampaignID  *int      `json:"campaign_id" gorm:"index"`
+	UtmSource   string    `json:"utm_source" gorm:"size:255"`
+	UtmMedium   string    `json:"utm_medium" gorm:"size:255"`
+	UtmCampaign string    `json:"utm_campaign" gorm:"size:255"`
+	UtmTerm     string    `json:"utm_term" gorm:"size:255"`
+	UtmContent  string    `json:"utm_content" gorm:"size:255"`
+	Referrer    string    `json:"referrer" gorm:"type:text"`
+	LandingPage string    `json:"landing_page" gorm:"type:text"`
+	TouchedAt   time.Time `json:"touched_at" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	CompanyId   int       `json:"company_id" gorm:"not null;index"`
+}
+
+// AttributionConversion is a won deal together with the touchpoints of its lead. Amount is in
+// the company's base currency, and is 0 with ExchangeRateMissing set when the deal's currency has
+// no rate.
+type AttributionConversion struct {
+	DealID              int              `json:"deal_id"`
+	LeadID              int              `json:"lead_id"`
+	Amount              float64          `json:"amount"`
+	ExchangeRateMissing bool             `json:"exchange_rate_missing"`
+	ConvertedAt         time.Time        `json:"converted_at"`
+	Touchpoints         []LeadTouchpoint `json:"touchpoints"`
+}
+
+// CampaignLeadCount holds the number of distinct leads touched by a campaign
+type CampaignLeadCount struct {
+	CampaignID int     `json:"campaign_id"`
+	Name       string  `json:"name"`
+	Budget     float64 `json:"budget"`
+	Currency   string  `json:"currency"`
+	Leads      int64   `json:"leads"`
+}
+
+// SourceLeadCount holds the number of distinct leads touched by a source
+type SourceLeadCount struct {
+	Source string `json:"source"`
+	Leads  int64  `json:"leads"`
+}
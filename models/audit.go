@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AuditEntry records a change made to a lead, deal or contact: what was done, by whom and when.
+// Changes made by a bulk operation name the operation.
+type AuditEntry struct {
+	ID              int       `json:"id" gorm:"primaryKey"`
+	EntityType      string    `json:"entity_type" gorm:"size:20;not null;index:idx_audit_entity"`
+	EntityID        int       `json:"entity_id" gorm:"not null;index:idx_audit_entity"`
+	Action          string    `json:"action" gorm:"size:50;not null"`
+	Details         string    `json:"details" gorm:"type:text"` // JSON describing the change
+	UserID          *int      `json:"user_id"`
+	BulkOperationID *int      `json:"bulk_operation_id" gorm:"index"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+	CompanyId       int       `json:"company_id" gorm:"not null;index"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Bulk operation actions
+const (
+	BulkActionSetField      = "set_field"
+	BulkActionSetStatus     = "set_status" // leads
+	BulkActionSetStage      = "set_stage"  // deals
+	BulkActionReassign      = "reassign"
+	BulkActionAddTags       = "add_tags"
+	BulkActionRemoveTags    = "remove_tags"
+	BulkActionAddToCampaign = "add_to_campaign"
+	BulkActionAddToSequence = "add_to_sequence"
+	BulkActionDelete        = "delete"
+)
+
+// BulkActions lists the actions bulk operations on each entity accept
+var BulkActions = map[string]map[string]bool{
+	ViewEntityLead: {
+		BulkActionSetField: true, BulkActionSetStatus: true, BulkActionReassign: true,
+		BulkActionAddTags: true, BulkActionRemoveTags: true,
+		BulkActionAddToCampaign: true, BulkActionAddToSequence: true, BulkActionDelete: true,
+	},
+	ViewEntityDeal: {
+		BulkActionSetField: true, BulkActionSetStage: true, BulkActionReassign: true,
+		BulkActionAddTags: true, BulkActionRemoveTags: true, BulkActionDelete: true,
+	},
+}
+
+// Bulk operation statuses
+const (
+	BulkStatusRunning   = "running"
+	BulkStatusCompleted = "completed"
+	BulkStatusFailed    = "failed"
+)
+
+// Bulk operation record results
+const (
+	BulkRecordSucceeded = "succeeded"
+	BulkRecordSkipped   = "skipped" // the record needed no change
+	BulkRecordFailed    = "failed"
+)
+
+// MaxBulkRecords caps how many records a bulk operation can change
+const MaxBulkRecords = 5000
+
+// BulkOperation is an action applied to many leads or deals at once, with counts of the records
+// it changed, skipped and failed on
+type BulkOperation struct {
+	ID          int                   `json:"id" gorm:"primaryKey"`
+	Entity      string                `json:"entity" gorm:"size:20;not null"`
+	Action      string                `json:"action" gorm:"size:30;not null"`
+	Params      string                `json:"params" gorm:"type:text"` // JSON of the action's parameters
+	Status      string                `json:"status" gorm:"size:20;not null;default:'running'"`
+	Total       int                   `json:"total"`
+	Succeeded   int                   `json:"succeeded"`
+	Skipped     int                   `json:"skipped"`
+	Failed      int                   `json:"failed"`
+	Error       string                `json:"error,omitempty" gorm:"type:text"` // why a failed operation stopped
+	CreatedBy   *int                  `json:"created_by"`
+	StartedAt   time.Time             `json:"started_at"`
+	CompletedAt *time.Time            `json:"completed_at"`
+	Results     []BulkOperationResult `json:"results,omitempty" gorm:"-"` // loaded for the operation detail
+	CompanyId   int                   `json:"company_id" gorm:"not null;index"`
+}
+
+// BulkOperationResult is what a bulk operation did to one record
+type BulkOperationResult struct {
+	ID          int    `json:"id" gorm:"primaryKey"`
+	OperationID int    `json:"operation_id" gorm:"not null;index"`
+	RecordID    int    `json:"record_id"`
+	Status      string `json:"status" gorm:"size:20;not null"`
+	Error       string `json:"error,omitempty" gorm:"size:500"`
+}
@@ -4,30 +4,78 @@ import (
 	"time"
 )
 
+// Campaign statuses. A launched campaign is scheduled until its start time, then running until
+// every member has been processed.
+const (
+	CampaignStatusDraft     = "draft"
+	CampaignStatusScheduled = "scheduled"
+	CampaignStatusRunning   = "running"
+	CampaignStatusPaused    = "paused"
+	CampaignStatusCompleted = "completed"
+	CampaignStatusCancelled = "cancelled"
+)
+
 // Campaign represents a marketing campaign in the CRM system
 type Campaign struct {
-	ID           int        `json:"id" gorm:"primaryKey"`
-	Name         string     `json:"name" gorm:"size:255;not null"`
-	Description  string     `json:"description" gorm:"type:text"`
-	CampaignType string     `json:"campaign_type" gorm:"size:50;not null"`
-	Status       string     `json:"status" gorm:"size:50;not null;default:'draft'"`
-	StartDate    *time.Time `json:"start_date"`
-	EndDate      *time.Time `json:"end_date"`
-	Budget       float64    `json:"budget"`
-	Currency     string     `json:"currency" gorm:"size:3;default:'USD'"`
-	CreatedBy    int        `json:"created_by" gorm:"not null"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	CompanyId    int        `json:"company_id" gorm:"not null"`
+	ID               int        `json:"id" gorm:"primaryKey"`
+	Name             string     `json:"name" gorm:"size:255;not null"`
+	Description      string     `json:"description" gorm:"type:text"`
+	CampaignType     string     `json:"campaign_type" gorm:"size:50;not null"`
+	Status           string     `json:"status" gorm:"size:50;not null;default:'draft';index"`
+	StartDate        *time.Time `json:"start_date"` // wall clock time in the company timezone
+	EndDate          *time.Time `json:"end_date"`   // wall clock time in the company timezone
+	Budget           float64    `json:"budget"`
+	Currency         string     `json:"currency" gorm:"size:3;default:'USD'"`
+	TemplateID       *int       `json:"template_id"`
+	SenderIdentityID *int       `json:"sender_identity_id"`
+	SegmentID        *int       `json:"segment_id" gorm:"index"`         // new segment members are added while the campaign runs
+	HourlyLimit      int        `json:"hourly_limit" gorm:"default:0"`   // maximum sends per hour, 0 for no limit
+	QuietHoursStart  string     `json:"quiet_hours_start" gorm:"size:5"` // HH:MM in the company timezone
+	QuietHoursEnd    string     `json:"quiet_hours_end" gorm:"size:5"`   // HH:MM in the company timezone
+	ScheduledAt      *time.Time `json:"scheduled_at" gorm:"index"`       // StartDate resolved at launch
+	EndsAt           *time.Time `json:"ends_at"`                         // EndDate resolved at launch
+	LaunchedAt       *time.Time `json:"launched_at"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	LockedBy         string     `json:"-" gorm:"size:100"`
+	LockedUntil      *time.Time `json:"-"`
+	CreatedBy        int        `json:"created_by" gorm:"not null"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	CompanyId        int        `json:"company_id" gorm:"not null"`
 }
 
+// Campaign member statuses
+const (
+	CampaignLeadPending    = "pending"
+	CampaignLeadQueued     = "queued"
+	CampaignLeadSent       = "sent"
+	CampaignLeadSkipped    = "skipped" // no email address or no consent
+	CampaignLeadFailed     = "failed"
+	CampaignLeadBounced    = "bounced"
+	CampaignLeadComplained = "complained"
+	CampaignLeadSuppressed = "suppressed"
+	CampaignLeadCancelled  = "cancelled"
+	CampaignLeadHoldout    = "holdout"         // A/B test control group, never sent to
+	CampaignLeadAwaiting   = "awaiting_winner" // sent the A/B test winner once it is selected
+)
+
 // CampaignLead represents the relationship between campaigns and leads
 type CampaignLead struct {
-	CampaignID int       `json:"campaign_id" gorm:"primaryKey"`
-	LeadID     int       `json:"lead_id" gorm:"primaryKey"`
-	Status     string    `json:"status" gorm:"size:50;default:'active'"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	CampaignID  int        `json:"campaign_id" gorm:"primaryKey"`
+	LeadID      int        `json:"lead_id" gorm:"primaryKey"`
+	Status      string     `json:"status" gorm:"size:50;default:'pending';index"`
+	VariantID   *int       `json:"variant_id"`
+	MessageID   *int       `json:"message_id"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	ProcessedAt *time.Time `json:"processed_at" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CampaignLeadStatusCount is the number of campaign members in a status
+type CampaignLeadStatusCount struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
 }
 
 // CampaignTemplate represents an email template for campaigns
@@ -43,3 +91,39 @@ type CampaignTemplate struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 	CompanyId    int       `json:"company_id" gorm:"not null"`
 }
+
+// CampaignCost is money spent on a campaign, in the campaign currency
+type CampaignCost struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	CampaignID int       `json:"campaign_id" gorm:"not null;index"`
+	Date       time.Time `json:"date" gorm:"type:date;not null"`
+	Amount     float64   `json:"amount" gorm:"not null"`
+	Channel    string    `json:"channel" gorm:"size:50"` // e.g. email, paid_search, social, events
+	Note       string    `json:"note" gorm:"type:text"`
+	CreatedBy  *int      `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	CompanyId  int       `json:"company_id" gorm:"not null;index"`
+}
+
+// CampaignROI is a campaign's spend against the leads, deals and revenue it produced. Deals count
+// towards a campaign when they belong to a campaign member and were created after the lead joined.
+type CampaignROI struct {
+	CampaignID          int                `json:"campaign_id"`
+	Name                string             `json:"name"`
+	Status              string             `json:"status"`
+	Currency            string             `json:"currency"`
+	Budget              float64            `json:"budget"`
+	Cost                float64            `json:"cost"`
+	BudgetRemaining     float64            `json:"budget_remaining"`
+	CostByChannel       map[string]float64 `json:"cost_by_channel"`
+	Leads               int64              `json:"leads"`
+	Opportunities       int64              `json:"opportunities"`
+	WonDeals            int64              `json:"won_deals"`
+	Revenue             *float64           `json:"revenue"`             // nil when an exchange rate is missing
+	PipelineInfluenced  *float64           `json:"pipeline_influenced"` // open deal value; nil when an exchange rate is missing
+	CostPerLead         float64            `json:"cost_per_lead"`
+	CostPerOpportunity  float64            `json:"cost_per_opportunity"`
+	ROI                 *float64           `json:"roi"`                   // percent; nil without revenue
+	ExchangeRateMissing bool               `json:"exchange_rate_missing"` // deal values could not be converted into Currency
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Close reason types
+const (
+	CloseReasonTypeWon          = "won"
+	CloseReasonTypeLost         = "lost"
+	CloseReasonTypeDisqualified = "disqualified"
+)
+
+// CloseReason is an entry in a company's list of reasons deals are won or lost and leads are
+// disqualified. Once a company has active reasons of a type, closing a deal or disqualifying a lead
+// requires one of them.
+type CloseReason struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	Type        string    `json:"type" gorm:"size:20;not null;uniqueIndex:idx_close_reason"`
+	Name        string    `json:"name" gorm:"size:100;not null;uniqueIndex:idx_close_reason"`
+	Description string    `json:"description" gorm:"type:text"`
+	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CompanyId   int       `json:"company_id" gorm:"not null;uniqueIndex:idx_close_reason,priority:1"`
+}
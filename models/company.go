@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CompanySettings holds a company's profile and defaults
+type CompanySettings struct {
+	ID           int       `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"size:255"`
+	Website      string    `json:"website" gorm:"size:255"`
+	Phone        string    `json:"phone" gorm:"size:50"`
+	Address      string    `json:"address" gorm:"type:text"`
+	Timezone     string    `json:"timezone" gorm:"size:64;default:'UTC'"`     // IANA name, e.g. Europe/Berlin
+	BaseCurrency string    `json:"base_currency" gorm:"size:3;default:'USD'"` // ISO 4217 reporting currency
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	CompanyId    int       `json:"company_id" gorm:"not null;uniqueIndex"`
+
+	// ISO 3166-1 region, e.g. DE, that phone numbers written without a country code are read in
+	DefaultCountry string `json:"default_country" gorm:"size:2"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Communication channels a lead can consent to
+const (
+	ConsentChannelEmail = "email"
+	ConsentChannelSMS   = "sms"
+	ConsentChannelPhone = "phone"
+)
+
+// Consent statuses. A lead without a consent record for a channel has not opted out.
+const (
+	ConsentStatusOptedIn  = "opted_in"
+	ConsentStatusOptedOut = "opted_out"
+)
+
+// ConsentChannels are the channels consent is tracked for
+var ConsentChannels = map[string]bool{
+	ConsentChannelEmail: true,
+	ConsentChannelSMS:   true,
+	ConsentChannelPhone: true,
+}
+
+// LeadConsent is a lead's current consent for one communication channel
+type LeadConsent struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	LeadID    int       `json:"lead_id" gorm:"not null;uniqueIndex:idx_lead_consent_channel"`
+	Channel   string    `json:"channel" gorm:"size:20;not null;uniqueIndex:idx_lead_consent_channel"`
+	Status    string    `json:"status" gorm:"size:20;not null"`
+	Source    string    `json:"source" gorm:"size:100"` // e.g. web_form, import, preference_center, unsubscribe_link
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;index"`
+}
+
+// ConsentChange is an audit record of a consent change
+type ConsentChange struct {
+	ID             int       `json:"id" gorm:"primaryKey"`
+	LeadID         *int      `json:"lead_id" gorm:"index"`
+	Email          string    `json:"email" gorm:"size:255;index"`
+	Channel        string    `json:"channel" gorm:"size:20;not null"`
+	PreviousStatus string    `json:"previous_status" gorm:"size:20"`
+	Status         string    `json:"status" gorm:"size:20;not null"`
+	Source         string    `json:"source" gorm:"size:100"`
+	ChangedByID    *int      `json:"changed_by_id"` // nil when changed by the recipient or the system
+	IPAddress      string    `json:"ip_address" gorm:"size:64"`
+	CreatedAt      time.Time `json:"created_at"`
+	CompanyId      int       `json:"company_id" gorm:"not null;index"`
+}
+
+// Suppression reasons
+const (
+	SuppressionReasonUnsubscribe = "unsubscribe"
+	SuppressionReasonBounce      = "bounce"
+	SuppressionReasonComplaint   = "complaint"
+	SuppressionReasonManual      = "manual"
+)
+
+// EmailSuppression is an address a company must not send marketing email to
+type EmailSuppression struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"size:255;not null;uniqueIndex:idx_suppression_company_email"`
+	Reason    string    `json:"reason" gorm:"size:50;not null"`
+	Notes     string    `json:"notes" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;uniqueIndex:idx_suppression_company_email"`
+}
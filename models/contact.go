@@ -10,6 +10,7 @@ import (
 type Contact struct {
 	ID        int            `json:"id" gorm:"primaryKey"`
 	LeadID    *int           `json:"lead_id" gorm:"index"`
+	AccountID *int           `json:"account_id" gorm:"index"`
 	Name      string         `json:"name" gorm:"size:255;not null"`
 	Email     string         `json:"email" gorm:"size:255"`
 	Phone     string         `json:"phone" gorm:"size:50"`
@@ -20,4 +21,9 @@ type Contact struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	CompanyId int            `json:"company_id" gorm:"not null"`
+	Tags      []string       `json:"tags" gorm:"-"` // Handled through a separate table
+
+	// Display forms of Phone, which is stored in E.164
+	PhoneNational      string `json:"phone_national" gorm:"size:50"`
+	PhoneInternational string `json:"phone_international" gorm:"size:50"`
 }
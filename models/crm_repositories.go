@@ -13,4 +13,24 @@ type CRMRepositories struct {
 	NurtureRepo         NurtureRepository
 	UserRepo            UserRepository
 	LeadScoreType       ScoreRepository
+	AttributionRepo     AttributionRepository
+	EmailRepo           EmailRepository
+	CompanyRepo         CompanyRepository
+	ConsentRepo         ConsentRepository
+	ABTestRepo          ABTestRepository
+	SegmentRepo         SegmentRepository
+	ExchangeRateRepo    ExchangeRateRepository
+	ProductRepo         ProductRepository
+	QuoteRepo           QuoteRepository
+	CloseReasonRepo     CloseReasonRepository
+	AccountRepo         AccountRepository
+	SearchRepo          SearchRepository
+	PhoneRepo           PhoneRepository
+	SavedViewRepo       SavedViewRepository
+	AuditRepo           AuditRepository
+	BulkOperationRepo   BulkOperationRepository
+	TrashRepo           TrashRepository
+	TagRepo             TagRepository
+	WebhookRepo         WebhookRepository
+	OutboxRepo          OutboxRepository
 }
@@ -6,21 +6,40 @@ import (
 	"gorm.io/gorm"
 )
 
+// Closing deal stages. Older records use the closed_ prefixed names.
+var (
+	DealStagesWon  = []string{"won", "closed_won"}
+	DealStagesLost = []string{"lost", "closed_lost"}
+)
+
+// DealPipelineStages are the stages of the deal pipeline in order. Deals can also be in stages
+// outside it, which boards list after these.
+var DealPipelineStages = []string{"prospecting", "qualification", "needs_analysis", "proposal", "negotiation", "won", "lost"}
+
 // Deal represents a deal in the CRM system
 type Deal struct {
 	ID                int            `json:"id" gorm:"primaryKey"`
 	LeadID            int            `json:"lead_id" gorm:"not null"`
+	AccountID         *int           `json:"account_id" gorm:"index"`
 	Title             string         `json:"title" gorm:"size:255;not null"`
 	Amount            float64        `json:"amount"`
+	ManualAmount      float64        `json:"-"` // the amount entered for the deal, restored when its last line item is removed
 	Currency          string         `json:"currency" gorm:"size:20;default:'USD'"`
 	Stage             string         `json:"stage" gorm:"size:50;not null"`
 	Probability       int            `json:"probability"` // 0-100 percent
 	ExpectedCloseDate *time.Time     `json:"expected_close_date"`
+	PriceBookID       *int           `json:"price_book_id"` // prices line items added without a unit price
 	AssignedTo        *int           `json:"assigned_to"`
 	Notes             string         `json:"notes,omitempty"`
+	CloseReasonID     *int           `json:"close_reason_id" gorm:"index"`
+	Competitor        string         `json:"competitor" gorm:"size:255"` // who the deal was lost to, or won against
+	CloseNotes        string         `json:"close_notes" gorm:"type:text"`
+	LostAtStage       string         `json:"lost_at_stage" gorm:"size:50"` // the stage the deal was in when it was lost
+	ClosedAt          *time.Time     `json:"closed_at"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	Tags              []string       `json:"tags,omitempty" gorm:"-"`
+	BuyingCommittee   []DealContact  `json:"buying_committee,omitempty" gorm:"-"` // loaded for the deal detail
 	CompanyId         int            `json:"company_id" gorm:"not null"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Buying committee roles of a contact on a deal
+const (
+	DealContactRoleDecisionMaker = "decision_maker"
+	DealContactRoleChampion      = "champion"
+	DealContactRoleInfluencer    = "influencer"
+	DealContactRoleEconomicBuyer = "economic_buyer"
+	DealContactRoleBlocker       = "blocker"
+)
+
+// DealContactRoles lists the buying committee roles
+var DealContactRoles = []string{
+	DealContactRoleDecisionMaker,
+	DealContactRoleChampion,
+	DealContactRoleInfluencer,
+	DealContactRoleEconomicBuyer,
+	DealContactRoleBlocker,
+}
+
+// DealContact puts a contact on a deal's buying committee. A deal has at most one primary contact.
+type DealContact struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	DealID    int       `json:"deal_id" gorm:"not null;uniqueIndex:idx_deal_contact"`
+	ContactID int       `json:"contact_id" gorm:"not null;uniqueIndex:idx_deal_contact;index"`
+	Role      string    `json:"role" gorm:"size:30;not null"`
+	IsPrimary bool      `json:"is_primary" gorm:"default:false"`
+	Notes     string    `json:"notes,omitempty" gorm:"type:text"`
+	Contact   *Contact  `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;index"`
+}
+
+// AccountContact links a contact to an account other than its own, such as a consultant or board
+// member, with a free-form role. An account has at most one primary contact.
+type AccountContact struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	AccountID int       `json:"account_id" gorm:"not null;uniqueIndex:idx_account_contact"`
+	ContactID int       `json:"contact_id" gorm:"not null;uniqueIndex:idx_account_contact;index"`
+	Role      string    `json:"role" gorm:"size:100"`
+	IsPrimary bool      `json:"is_primary" gorm:"default:false"`
+	Contact   *Contact  `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;index"`
+}
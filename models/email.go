@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Email message statuses
+const (
+	EmailStatusQueued     = "queued"
+	EmailStatusSent       = "sent"
+	EmailStatusFailed     = "failed"
+	EmailStatusBounced    = "bounced"
+	EmailStatusComplained = "complained"
+	EmailStatusSuppressed = "suppressed" // not sent because the recipient opted out
+	EmailStatusCancelled  = "cancelled"  // not sent because its campaign was cancelled
+)
+
+// EmailSenderIdentity is a from address a company sends email as
+type EmailSenderIdentity struct {
+	ID        int            `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"size:255;not null"`
+	Email     string         `json:"email" gorm:"size:255;not null"`
+	ReplyTo   string         `json:"reply_to" gorm:"size:255"`
+	IsDefault bool           `json:"is_default" gorm:"default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	CompanyId int            `json:"company_id" gorm:"not null;index"`
+}
+
+// EmailMessage is an outbound email and its delivery state. Nurture sends also record each
+// outcome as NurtureActivity of their enrollment, and campaign sends as the status of their
+// campaign member; one-off sends are tracked on the message alone.
+type EmailMessage struct {
+	ID                int        `json:"id" gorm:"primaryKey"`
+	LeadID            *int       `json:"lead_id" gorm:"index"`
+	EnrollmentID      *int       `json:"enrollment_id" gorm:"index"`
+	StepID            *int       `json:"step_id"`
+	CampaignID        *int       `json:"campaign_id" gorm:"index"`
+	TemplateID        *int       `json:"template_id"`
+	VariantID         *int       `json:"variant_id" gorm:"index"`
+	SenderIdentityID  *int       `json:"sender_identity_id"`
+	FromName          string     `json:"from_name" gorm:"size:255"`
+	FromEmail         string     `json:"from_email" gorm:"size:255;not null"`
+	ReplyTo           string     `json:"reply_to" gorm:"size:255"`
+	ToEmail           string     `json:"to_email" gorm:"size:255;not null;index"`
+	Subject           string     `json:"subject" gorm:"size:255"`
+	HTMLBody          string     `json:"html_body" gorm:"type:longtext"`
+	TextBody          string     `json:"text_body" gorm:"type:longtext"`
+	Headers           string     `json:"headers" gorm:"type:text"` // JSON object of extra headers
+	Status            string     `json:"status" gorm:"size:50;not null;default:'queued';index"`
+	Attempts          int        `json:"attempts" gorm:"default:0"`
+	MaxAttempts       int        `json:"max_attempts" gorm:"default:5"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError         string     `json:"last_error" gorm:"type:text"`
+	ProviderMessageID string     `json:"provider_message_id" gorm:"size:255;index"`
+	SentAt            *time.Time `json:"sent_at"`
+	BouncedAt         *time.Time `json:"bounced_at"`
+	LockedBy          string     `json:"-" gorm:"size:100"`
+	LockedUntil       *time.Time `json:"-" gorm:"index"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	CompanyId         int        `json:"company_id" gorm:"not null;index"`
+}
+
+// EmailDeliveryEvent is a bounce or complaint reported by the email provider
+type EmailDeliveryEvent struct {
+	Type              string `json:"type"` // bounce or complaint
+	ProviderMessageID string `json:"message_id"`
+	Email             string `json:"email"`
+	CompanyId         int    `json:"company_id"`  // required to match by email address
+	BounceType        string `json:"bounce_type"` // hard or soft
+	Reason            string `json:"reason"`
+}
+
+// EmailEvent is an open or click recorded by the tracking endpoints
+type EmailEvent struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	MessageID int       `json:"message_id" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"size:20;not null"` // open or click
+	URL       string    `json:"url" gorm:"type:text"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	IPAddress string    `json:"ip_address" gorm:"size:64"`
+	IsBot     bool      `json:"is_bot" gorm:"default:false"` // prefetches and link scanners, excluded from stats
+	CreatedAt time.Time `json:"created_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;index"`
+}
+
+// EmailStats are delivery and engagement counts for a set of messages.
+// GroupID is the template or step ID when stats are broken down.
+type EmailStats struct {
+	GroupID         *int    `json:"group_id,omitempty"`
+	Sent            int64   `json:"sent"`
+	Delivered       int64   `json:"delivered"`
+	Bounced         int64   `json:"bounced"`
+	Complained      int64   `json:"complained"`
+	Opens           int64   `json:"opens"`
+	Clicks          int64   `json:"clicks"`
+	UniqueOpens     int64   `json:"unique_opens"`
+	UniqueClicks    int64   `json:"unique_clicks"`
+	OpenRate        float64 `json:"open_rate" gorm:"-"`
+	ClickRate       float64 `json:"click_rate" gorm:"-"`
+	ClickToOpenRate float64 `json:"click_to_open_rate" gorm:"-"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// DefaultCurrency is the reporting currency of a company that has not chosen one
+const DefaultCurrency = "USD"
+
+// Exchange rate sources
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateSourceImport = "import"
+)
+
+// ExchangeRate is the value of one unit of Currency in a company's base currency from
+// EffectiveDate on. Amounts are converted with the latest rate effective on the amount's date,
+// or the earliest later rate when none is.
+type ExchangeRate struct {
+	ID            int       `json:"id" gorm:"primaryKey"`
+	BaseCurrency  string    `json:"base_currency" gorm:"size:3;not null;uniqueIndex:idx_exchange_rate"`
+	Currency      string    `json:"currency" gorm:"size:3;not null;uniqueIndex:idx_exchange_rate"`
+	Rate          float64   `json:"rate" gorm:"type:decimal(20,10);not null"`
+	EffectiveDate time.Time `json:"effective_date" gorm:"type:date;not null;uniqueIndex:idx_exchange_rate"`
+	Source        string    `json:"source" gorm:"size:20;default:'manual'"`
+	CreatedBy     *int      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	CompanyId     int       `json:"company_id" gorm:"not null;uniqueIndex:idx_exchange_rate,priority:1"`
+}
+
+// CurrencyAmount is a total in its original currency next to the same total converted to the
+// reporting currency. ConvertedAmount is nil when no exchange rate is known for the currency.
+type CurrencyAmount struct {
+	Currency        string   `json:"currency"`
+	Amount          float64  `json:"amount"`
+	ConvertedAmount *float64 `json:"converted_amount"`
+}
+
+// ExchangeRateImportResult counts the rates an import created and updated
+type ExchangeRateImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Errors  []string `json:"errors,omitempty"`
+}
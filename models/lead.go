@@ -6,6 +6,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// LeadStatuses are the statuses a lead moves through, in order. Leads can also have statuses
+// outside them, which boards list after these.
+var LeadStatuses = []string{"new", "qualified", "disqualified"}
+
 // Lead represents a lead in the CRM system
 type Lead struct {
 	ID           uint              `json:"id" gorm:"primaryKey"`
@@ -26,6 +30,16 @@ type Lead struct {
 	CustomFields []LeadCustomField `json:"custom_fields" gorm:"foreignKey:LeadID"`
 	Type         string            `json:"type" gorm:"default:null"`
 	CompanyId    int               `json:"company_id" gorm:"not null"`
+
+	// Set when the lead is disqualified
+	DisqualificationReasonID *int       `json:"disqualification_reason_id" gorm:"index"`
+	DisqualificationNotes    string     `json:"disqualification_notes" gorm:"type:text"`
+	Competitor               string     `json:"competitor" gorm:"size:255"`
+	DisqualifiedAt           *time.Time `json:"disqualified_at"`
+
+	// Display forms of Phone, which is stored in E.164
+	PhoneNational      string `json:"phone_national" gorm:"size:50"`
+	PhoneInternational string `json:"phone_international" gorm:"size:50"`
 }
 
 // LeadTag represents a tag associated with a lead
@@ -44,8 +58,10 @@ type LeadData struct {
 }
 
 type LeadInput struct {
-	CompanyId int        `json:"company_id"`
-	Datas     []LeadData `json:"data"`
+	CompanyId  int             `json:"company_id"`
+	Datas      []LeadData      `json:"data"`
+	Tags       []string        `json:"tags,omitempty"`
+	Touchpoint *LeadTouchpoint `json:"touchpoint,omitempty" gorm:"-"`
 }
 
 type CrmFieldData struct {
@@ -57,6 +73,13 @@ type CrmFieldData struct {
 	CreatedBy  int       `json:"createdBy" gorm:"column:created_by"`
 	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at"`
 	UpdatedAt  time.Time `json:"updatedAt" gorm:"column:updated_at"`
+
+	// Display forms of the value of a phone field, which is stored in E.164
+	PhoneNational      string `json:"phoneNational" gorm:"column:phone_national;size:50"`
+	PhoneInternational string `json:"phoneInternational" gorm:"column:phone_international;size:50"`
+
+	// Set when the lead is deleted, to the lead's deletion time
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
 }
 
 type GroupedLead struct {
@@ -1,9 +1,8 @@
-
 package models
 
 import (
 	"time"
-	
+
 	"gorm.io/gorm"
 )
 
@@ -17,38 +16,54 @@ type NurtureSequence struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	Steps       []NurtureStep  `json:"steps" gorm:"foreignKey:SequenceID"`
+	SegmentID   *int           `json:"segment_id" gorm:"index"` // segment members are enrolled automatically
+	CompanyId   int            `json:"company_id" gorm:"not null;index"`
 }
 
-// NurtureStep represents a single step in a nurture sequence
-type NurtureStep struct {
-	ID          int            `json:"id" gorm:"primaryKey"`
-	SequenceID  int            `json:"sequence_id" gorm:"not null"`
-	Name        string         `json:"name" gorm:"size:100;not null"`
-	Type        string         `json:"type" gorm:"size:50;not null"` // email, task, notification, etc.
-	Content     string         `json:"content" gorm:"type:text"`
-	Delay       int            `json:"delay" gorm:"default:0"`       // delay in hours from previous step
-	OrderIndex  int            `json:"order_index" gorm:"not null"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	Conditions  string         `json:"conditions" gorm:"type:text"`  // JSON for conditions
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+// NurtureStepTypes lists the step types the nurture engine can execute
+var NurtureStepTypes = map[string]bool{
+	"email":        true,
+	"task":         true,
+	"notification": true,
+	"field_update": true,
+	"wait":         true,
 }
 
-// NurtureEnrollment represents a lead enrolled in a nurture sequence
-type NurtureEnrollment struct {
+// NurtureStep represents a single step in a nurture sequence
+type NurtureStep struct {
 	ID         int            `json:"id" gorm:"primaryKey"`
 	SequenceID int            `json:"sequence_id" gorm:"not null"`
-	LeadID     int            `json:"lead_id" gorm:"not null"`
-	Status     string         `json:"status" gorm:"size:50;default:'active'"`
-	CurrentStep int           `json:"current_step"`
-	StartedAt  time.Time      `json:"started_at"`
-	CompletedAt *time.Time    `json:"completed_at"`
+	Name       string         `json:"name" gorm:"size:100;not null"`
+	Type       string         `json:"type" gorm:"size:50;not null"` // email, task, notification, etc.
+	Content    string         `json:"content" gorm:"type:text"`
+	Delay      int            `json:"delay" gorm:"default:0"` // delay in hours from previous step
+	OrderIndex int            `json:"order_index" gorm:"not null"`
+	IsActive   bool           `json:"is_active" gorm:"default:true"`
+	Conditions string         `json:"conditions" gorm:"type:text"` // JSON for conditions
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// NurtureEnrollment represents a lead enrolled in a nurture sequence
+type NurtureEnrollment struct {
+	ID            int            `json:"id" gorm:"primaryKey"`
+	SequenceID    int            `json:"sequence_id" gorm:"not null"`
+	LeadID        int            `json:"lead_id" gorm:"not null"`
+	Status        string         `json:"status" gorm:"size:50;default:'active'"` // active, paused, completed, exited, cancelled
+	CurrentStepID *int           `json:"current_step_id"`                        // step waiting to run; nil before the first one
+	StepAttempts  int            `json:"step_attempts" gorm:"default:0"`         // failed attempts at the current step
+	StartedAt     time.Time      `json:"started_at"`
+	CompletedAt   *time.Time     `json:"completed_at"`
+	LastStepAt    *time.Time     `json:"last_step_at"`
+	NextRunAt     *time.Time     `json:"next_run_at" gorm:"index"`
+	LockedBy      string         `json:"-" gorm:"size:100"`
+	LockedUntil   *time.Time     `json:"-" gorm:"index"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
 // NurtureActivity represents activity within a nurture sequence
 type NurtureActivity struct {
 	ID           int       `json:"id" gorm:"primaryKey"`
@@ -58,3 +73,26 @@ type NurtureActivity struct {
 	Details      string    `json:"details" gorm:"type:text"`     // JSON for additional details
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// NurtureConditions describes when a step may run; stored as JSON in NurtureStep.Conditions
+type NurtureConditions struct {
+	Match  string                 `json:"match"`   // all (default) or any
+	OnFail string                 `json:"on_fail"` // skip (default), exit or wait
+	Rules  []NurtureConditionRule `json:"rules"`
+}
+
+// NurtureConditionRule is a single condition on a lead field or prior enrollment activity
+type NurtureConditionRule struct {
+	Field    string `json:"field,omitempty"`    // lead field name, e.g. status or company
+	Activity string `json:"activity,omitempty"` // activity type, e.g. opened or clicked
+	StepID   *int   `json:"step_id,omitempty"`  // restrict an activity rule to one step
+	Operator string `json:"operator"`           // eq, neq, contains, gt, lt, exists, not_exists
+	Value    string `json:"value,omitempty"`
+}
+
+// NurtureEnrollmentCount is the number of enrollments in a status at a step
+type NurtureEnrollmentCount struct {
+	Status        string `json:"status"`
+	CurrentStepID *int   `json:"current_step_id"`
+	Count         int64  `json:"count"`
+}
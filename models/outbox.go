@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types
+const (
+	EventLeadCreated      = "LeadCreated"
+	EventLeadUpdated      = "LeadUpdated"
+	EventLeadDeleted      = "LeadDeleted"
+	EventLeadQualified    = "LeadQualified"
+	EventLeadAssigned     = "LeadAssigned"
+	EventDealCreated      = "DealCreated"
+	EventDealUpdated      = "DealUpdated"
+	EventDealDeleted      = "DealDeleted"
+	EventDealStageChanged = "DealStageChanged"
+	EventDealWon          = "DealWon"
+	EventContactCreated   = "ContactCreated"
+	EventContactUpdated   = "ContactUpdated"
+	EventContactDeleted   = "ContactDeleted"
+	EventTargetAchieved   = "TargetAchieved"
+)
+
+// Domain event aggregate types, the types of record events are about
+const (
+	AggregateLead    = "lead"
+	AggregateDeal    = "deal"
+	AggregateContact = "contact"
+	AggregateTarget  = "target"
+)
+
+// DomainEventAggregates maps each domain event type to the type of record it is about
+var DomainEventAggregates = map[string]string{
+	EventLeadCreated:      AggregateLead,
+	EventLeadUpdated:      AggregateLead,
+	EventLeadDeleted:      AggregateLead,
+	EventLeadQualified:    AggregateLead,
+	EventLeadAssigned:     AggregateLead,
+	EventDealCreated:      AggregateDeal,
+	EventDealUpdated:      AggregateDeal,
+	EventDealDeleted:      AggregateDeal,
+	EventDealStageChanged: AggregateDeal,
+	EventDealWon:          AggregateDeal,
+	EventContactCreated:   AggregateContact,
+	EventContactUpdated:   AggregateContact,
+	EventContactDeleted:   AggregateContact,
+	EventTargetAchieved:   AggregateTarget,
+}
+
+// Outbox event statuses
+const (
+	OutboxEventPending    = "pending"
+	OutboxEventDispatched = "dispatched" // every subscriber has handled it
+	OutboxEventFailed     = "failed"     // a subscriber failed on every attempt; replaying it retries
+)
+
+// OutboxEvent is a domain event, written in the same transaction as the change it records and
+// then dispatched to the event bus subscribers. An event is retried until every subscriber has
+// handled it or it runs out of attempts, so subscribers can see it more than once.
+type OutboxEvent struct {
+	ID            int             `json:"id" gorm:"primaryKey"`
+	EventID       string          `json:"event_id" gorm:"size:36;not null;uniqueIndex"`
+	Type          string          `json:"type" gorm:"size:50;not null;index"`
+	AggregateType string          `json:"aggregate_type" gorm:"size:30;not null"` // lead, deal, contact or target
+	AggregateID   int             `json:"aggregate_id" gorm:"not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:text;not null"` // JSON of the event's data
+	Status        string          `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Attempts      int             `json:"attempts" gorm:"default:0"`
+	MaxAttempts   int             `json:"max_attempts" gorm:"default:20"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at" gorm:"index"`
+	HandledBy     []string        `json:"handled_by" gorm:"serializer:json;type:text"` // subscribers that have handled it
+	LastError     string          `json:"last_error,omitempty" gorm:"type:text"`
+	DispatchedAt  *time.Time      `json:"dispatched_at" gorm:"index"`
+	LockedBy      string          `json:"-" gorm:"size:100"`
+	LockedUntil   *time.Time      `json:"-" gorm:"index"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CompanyId     int             `json:"company_id" gorm:"not null;index"`
+}
+
+// LeadEvent is the payload of the lead events
+type LeadEvent struct {
+	LeadID               int    `json:"lead_id"`
+	Status               string `json:"status"`
+	PreviousStatus       string `json:"previous_status,omitempty"`
+	Source               string `json:"source,omitempty"`
+	AssignedToID         *uint  `json:"assigned_to_id"`
+	PreviousAssignedToID *uint  `json:"previous_assigned_to_id,omitempty"`
+}
+
+// DealEvent is the payload of the deal events
+type DealEvent struct {
+	DealID        int     `json:"deal_id"`
+	LeadID        int     `json:"lead_id"`
+	Stage         string  `json:"stage"`
+	PreviousStage string  `json:"previous_stage,omitempty"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	AssignedTo    *int    `json:"assigned_to"`
+}
+
+// ContactEvent is the payload of the contact events
+type ContactEvent struct {
+	ContactID int    `json:"contact_id"`
+	LeadID    *int   `json:"lead_id"`
+	AccountID *int   `json:"account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+}
+
+// TargetEvent is the payload of TargetAchieved
+type TargetEvent struct {
+	TargetID    int     `json:"target_id"`
+	Name        string  `json:"name"`
+	TargetType  string  `json:"target_type"`
+	TargetValue float64 `json:"target_value"`
+	ActualValue float64 `json:"actual_value"`
+	UserId      *int    `json:"user_id"`
+	TeamId      *int    `json:"team_id"`
+}
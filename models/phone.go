@@ -0,0 +1,32 @@
+package models
+
+// Records whose phone numbers are normalized to E.164
+const (
+	PhoneRecordContact   = "contact"
+	PhoneRecordLead      = "lead"
+	PhoneRecordLeadField = "lead_field" // a phone-type lead field value
+)
+
+// PhoneRecordTypes lists every kind of record with normalized phone numbers
+var PhoneRecordTypes = []string{PhoneRecordContact, PhoneRecordLead, PhoneRecordLeadField}
+
+// PhoneRecord is a stored phone number and the record it belongs to. For lead fields RecordID is
+// the lead and FieldID the lead field config.
+type PhoneRecord struct {
+	Type               string `json:"type"`
+	RecordID           int    `json:"record_id"`
+	FieldID            int    `json:"field_id,omitempty"`
+	CompanyId          int    `json:"company_id"`
+	Phone              string `json:"phone"`
+	PhoneNational      string `json:"-"`
+	PhoneInternational string `json:"-"`
+	Reason             string `json:"reason,omitempty"` // why the number could not be normalized
+}
+
+// PhoneBackfillResult reports a run of the phone number backfill. Invalid lists a sample of the
+// numbers that could not be normalized, which are left as they were.
+type PhoneBackfillResult struct {
+	Normalized   int           `json:"normalized"`
+	InvalidCount int           `json:"invalid_count"`
+	Invalid      []PhoneRecord `json:"invalid"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Product is an item in a company's catalog that can be sold on deals
+type Product struct {
+	ID          int            `json:"id" gorm:"primaryKey"`
+	SKU         string         `json:"sku" gorm:"size:64;not null;uniqueIndex:idx_product_sku"`
+	Name        string         `json:"name" gorm:"size:255;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	TaxCategory string         `json:"tax_category" gorm:"size:50"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	CompanyId   int            `json:"company_id" gorm:"not null;uniqueIndex:idx_product_sku,priority:1"`
+}
+
+// PriceBook is a named list of product prices. A company's default price book prices deals that
+// do not name one.
+type PriceBook struct {
+	ID          int              `json:"id" gorm:"primaryKey"`
+	Name        string           `json:"name" gorm:"size:100;not null"`
+	Description string           `json:"description" gorm:"type:text"`
+	IsDefault   bool             `json:"is_default" gorm:"default:false"`
+	IsActive    bool             `json:"is_active" gorm:"default:true"`
+	Entries     []PriceBookEntry `json:"entries,omitempty" gorm:"foreignKey:PriceBookID"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	CompanyId   int              `json:"company_id" gorm:"not null;index"`
+}
+
+// PriceBookEntry is the unit price of a product in one currency within a price book
+type PriceBookEntry struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	PriceBookID int       `json:"price_book_id" gorm:"not null;uniqueIndex:idx_price_book_entry"`
+	ProductID   int       `json:"product_id" gorm:"not null;uniqueIndex:idx_price_book_entry;index"`
+	Currency    string    `json:"currency" gorm:"size:3;not null;uniqueIndex:idx_price_book_entry"`
+	UnitPrice   float64   `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DealLineItem is a product sold on a deal. Subtotal is the discounted price of the quantity
+// and Total adds tax; a deal with line items has the sum of their subtotals as its Amount.
+type DealLineItem struct {
+	ID              int       `json:"id" gorm:"primaryKey"`
+	DealID          int       `json:"deal_id" gorm:"not null;index"`
+	ProductID       int       `json:"product_id" gorm:"not null;index"`
+	PriceBookID     *int      `json:"price_book_id"`
+	Name            string    `json:"name" gorm:"size:255"` // product name when the item was added
+	SKU             string    `json:"sku" gorm:"size:64"`
+	Quantity        float64   `json:"quantity" gorm:"type:decimal(15,4);not null"`
+	UnitPrice       float64   `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	DiscountPercent float64   `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+	TaxPercent      float64   `json:"tax_percent" gorm:"type:decimal(5,2);default:0"`
+	Subtotal        float64   `json:"subtotal" gorm:"type:decimal(15,2)"`
+	TaxAmount       float64   `json:"tax_amount" gorm:"type:decimal(15,2)"`
+	Total           float64   `json:"total" gorm:"type:decimal(15,2)"`
+	Position        int       `json:"position" gorm:"default:0"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	CompanyId       int       `json:"company_id" gorm:"not null;index"`
+}
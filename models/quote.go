@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Quote statuses
+const (
+	QuoteStatusDraft    = "draft"
+	QuoteStatusSent     = "sent"
+	QuoteStatusAccepted = "accepted"
+	QuoteStatusRejected = "rejected"
+	QuoteStatusExpired  = "expired"
+)
+
+// Quote is a priced offer for a deal. Each quote for a deal is a new version with the deal's line
+// items copied at the time it was created; draft and sent quotes expire after ValidUntil.
+type Quote struct {
+	ID            int             `json:"id" gorm:"primaryKey"`
+	DealID        int             `json:"deal_id" gorm:"not null;uniqueIndex:idx_quote_version"`
+	Version       int             `json:"version" gorm:"not null;uniqueIndex:idx_quote_version"`
+	Number        string          `json:"number" gorm:"size:50;not null"` // e.g. Q-42-3 for version 3 of deal 42
+	Title         string          `json:"title" gorm:"size:255"`
+	Status        string          `json:"status" gorm:"size:20;default:'draft';index"`
+	Currency      string          `json:"currency" gorm:"size:3;not null"`
+	Subtotal      float64         `json:"subtotal" gorm:"type:decimal(15,2)"`
+	TaxTotal      float64         `json:"tax_total" gorm:"type:decimal(15,2)"`
+	Total         float64         `json:"total" gorm:"type:decimal(15,2)"`
+	ValidFrom     time.Time       `json:"valid_from" gorm:"type:date;not null"`
+	ValidUntil    time.Time       `json:"valid_until" gorm:"type:date;not null"`
+	Terms         string          `json:"terms" gorm:"type:text"`
+	Notes         string          `json:"notes" gorm:"type:text"`
+	LineItems     []QuoteLineItem `json:"line_items,omitempty" gorm:"foreignKey:QuoteID"`
+	SentAt        *time.Time      `json:"sent_at"`
+	AcceptedAt    *time.Time      `json:"accepted_at"`
+	RejectedAt    *time.Time      `json:"rejected_at"`
+	RejectionNote string          `json:"rejection_note" gorm:"type:text"`
+	CreatedBy     *int            `json:"created_by"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	CompanyId     int             `json:"company_id" gorm:"not null;index"`
+}
+
+// QuoteLineItem is a deal line item as it was when the quote was created
+type QuoteLineItem struct {
+	ID              int     `json:"id" gorm:"primaryKey"`
+	QuoteID         int     `json:"quote_id" gorm:"not null;index"`
+	ProductID       int     `json:"product_id"`
+	Name            string  `json:"name" gorm:"size:255"`
+	SKU             string  `json:"sku" gorm:"size:64"`
+	Quantity        float64 `json:"quantity" gorm:"type:decimal(15,4)"`
+	UnitPrice       float64 `json:"unit_price" gorm:"type:decimal(15,2)"`
+	DiscountPercent float64 `json:"discount_percent" gorm:"type:decimal(5,2)"`
+	TaxPercent      float64 `json:"tax_percent" gorm:"type:decimal(5,2)"`
+	Subtotal        float64 `json:"subtotal" gorm:"type:decimal(15,2)"`
+	TaxAmount       float64 `json:"tax_amount" gorm:"type:decimal(15,2)"`
+	Total           float64 `json:"total" gorm:"type:decimal(15,2)"`
+	Position        int     `json:"position"`
+}
+
+// QuoteTemplate is a company's layout for quote PDFs. Header, footer and default terms may use
+// the merge tags of campaign templates with company, deal, lead and quote data.
+type QuoteTemplate struct {
+	ID              int       `json:"id" gorm:"primaryKey"`
+	Header          string    `json:"header" gorm:"type:text"` // printed beside the logo
+	Footer          string    `json:"footer" gorm:"type:text"` // printed at the bottom of every page
+	DefaultTerms    string    `json:"default_terms" gorm:"type:text"`
+	ValidityDays    int       `json:"validity_days" gorm:"default:30"`
+	AccentColor     string    `json:"accent_color" gorm:"size:7;default:'#1F4E79'"` // #RRGGBB
+	Logo            []byte    `json:"-" gorm:"type:mediumblob"`
+	LogoContentType string    `json:"logo_content_type" gorm:"size:50"`
+	HasLogo         bool      `json:"has_logo" gorm:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	CompanyId       int       `json:"company_id" gorm:"not null;uniqueIndex"`
+}
@@ -18,6 +18,26 @@ type Repositories struct {
 	CampaignRepo        CampaignRepository
 	AnalyticsRepo       AnalyticsRepository
 	ScoreRepo           ScoreRepository
+	AttributionRepo     AttributionRepository
+	EmailRepo           EmailRepository
+	CompanyRepo         CompanyRepository
+	ConsentRepo         ConsentRepository
+	ABTestRepo          ABTestRepository
+	SegmentRepo         SegmentRepository
+	ExchangeRateRepo    ExchangeRateRepository
+	ProductRepo         ProductRepository
+	QuoteRepo           QuoteRepository
+	CloseReasonRepo     CloseReasonRepository
+	AccountRepo         AccountRepository
+	SearchRepo          SearchRepository
+	PhoneRepo           PhoneRepository
+	SavedViewRepo       SavedViewRepository
+	AuditRepo           AuditRepository
+	BulkOperationRepo   BulkOperationRepository
+	TrashRepo           TrashRepository
+	TagRepo             TagRepository
+	WebhookRepo         WebhookRepository
+	OutboxRepo          OutboxRepository
 }
 
 // NewRepositories initializes repositories
@@ -16,6 +16,12 @@ type LeadRepository interface {
 	Delete(id int) error
 	ValidateLeadFields(lead *Lead, requiredFields []string) error
 	GetLastSubmitId() (int, error)
+	GetFieldValues(leadID int) (map[string]string, error)
+	SetFieldValue(leadID int, fieldName string, value string) error
+	FindIDsByFilter(companyId int, filters map[string]interface{}) ([]int, error)
+	FindIDsByEmail(companyId int, email string) ([]int, error)
+	AddTags(leadID int, tags []string, companyId int) (int, error)
+	RemoveTags(leadID int, tags []string) (int64, error)
 }
 
 // LeadFieldConfigRepository interface for lead field configuration
@@ -45,7 +51,7 @@ type ScoreRepository interface {
 // ContactRepository interface for contact operations
 type ContactRepository interface {
 	FindByID(id int) (*Contact, error)
-	List(offset int, limit int, companyId int) ([]Contact, error)
+	List(offset int, limit int, tags *TagFilter, companyId int) ([]Contact, error)
 	FindByLead(leadID int) ([]Contact, error)
 	Create(contact *Contact) error
 	Update(contact *Contact) error
@@ -56,12 +62,23 @@ type ContactRepository interface {
 // DealRepository interface for deal operations
 type DealRepository interface {
 	FindByID(id int) (*Deal, error)
-	List(offset int, limit int, filters map[string]interface{}, companyId int) ([]Deal, error)
+	List(offset int, limit int, filters map[string]interface{}, tags *TagFilter, companyId int) ([]Deal, error)
 	FindByLead(leadID int) ([]Deal, error)
 	Create(deal *Deal) error
 	Update(deal *Deal) error
 	Delete(id int) error
 	GetDealPipeline(companyId int) ([]map[string]interface{}, error)
+	GetLineItems(dealID int) ([]DealLineItem, error)
+	GetLineItemByID(id int) (*DealLineItem, error)
+	SaveLineItem(item *DealLineItem) (*Deal, error)
+	DeleteLineItem(item *DealLineItem) (*Deal, error)
+	GetDealContacts(dealID int) ([]DealContact, error)
+	GetDealContact(dealID int, contactID int) (*DealContact, error)
+	SaveDealContact(dealContact *DealContact) error
+	DeleteDealContact(dealContact *DealContact) error
+	GetContactDeals(contactID int) ([]Deal, error)
+	AddTags(dealID int, tags []string, companyId int) (int, error)
+	RemoveTags(dealID int, tags []string) (int64, error)
 }
 
 // CampaignRepository interface for campaign operations
@@ -75,6 +92,7 @@ type CampaignRepository interface {
 	GetLeadsForCampaign(id int) ([]Lead, error)
 	AssignLeadsToCampaign(campaignID int, leadIDs []int) error
 	RemoveLeadsFromCampaign(campaignID int, leadIDs []int) error
+	AddLeadsToCampaign(campaignID int, leadIDs []int) (int, error)
 	GetTemplates(offset int, limit int) ([]CampaignTemplate, error)
 	GetTemplateByID(id int) (*CampaignTemplate, error)
 	CreateTemplate(template *CampaignTemplate) error
@@ -99,6 +117,7 @@ type AnalyticsRepository interface {
 	GetPerformanceByUser(startDate time.Time, endDate time.Time, companyId int) (map[string]interface{}, error)
 	GetFunnelAnalytics(companyId int) (map[string]interface{}, error)
 	GetTargetAnalytics(startDate time.Time, endDate time.Time, userId *uint, companyId int) (map[string]interface{}, error)
+	GetLossAnalytics(startDate time.Time, endDate time.Time, period string, companyId int) (map[string]interface{}, error)
 }
 
 // TargetRepository interface for sales target operations
@@ -114,20 +133,30 @@ type TargetRepository interface {
 
 // NurtureRepository interface for nurture sequences
 type NurtureRepository interface {
-	GetSequences(offset int, limit int) ([]NurtureSequence, error)
+	GetSequences(offset int, limit int, companyId int) ([]NurtureSequence, error)
 	GetSequenceByID(id int) (*NurtureSequence, error)
 	CreateSequence(sequence *NurtureSequence) error
 	UpdateSequence(sequence *NurtureSequence) error
 	DeleteSequence(id int) error
 	GetStepsBySequence(sequenceID int) ([]NurtureStep, error)
+	GetStepsIncludingDeleted(sequenceID int) ([]NurtureStep, error)
 	CreateStep(step *NurtureStep) error
 	UpdateStep(step *NurtureStep) error
 	DeleteStep(id int) error
+	GetStepByID(id int) (*NurtureStep, error)
+	ReorderSteps(sequenceID int, stepIDs []int) error
 	GetEnrollments(sequenceID int, offset int, limit int) ([]NurtureEnrollment, error)
+	GetEnrollmentByID(id int) (*NurtureEnrollment, error)
 	EnrollLead(enrollment *NurtureEnrollment) error
+	EnrollLeads(sequenceID int, leadIDs []int) (int, error)
+	UnenrollLeads(sequenceID int, leadIDs []int) (int64, error)
 	UpdateEnrollment(enrollment *NurtureEnrollment) error
+	SetEnrollmentStatus(id int, fromStatuses []string, status string) (bool, error)
+	GetEnrollmentCounts(sequenceID int) ([]NurtureEnrollmentCount, error)
 	GetEnrollmentActivity(enrollmentID int) ([]NurtureActivity, error)
 	RecordActivity(activity *NurtureActivity) error
+	ClaimDueEnrollments(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]NurtureEnrollment, error)
+	ReleaseEnrollment(enrollment *NurtureEnrollment, workerID string) error
 
 	// Campaign related methods
 	GetCampaigns(offset int, limit int, companyId int) ([]Campaign, error)
@@ -139,11 +168,109 @@ type NurtureRepository interface {
 	GetLeadsForCampaign(id int) ([]Lead, error)
 	AssignLeadsToCampaign(campaignID int, leadIDs []int) error
 	RemoveLeadsFromCampaign(campaignID int, leadIDs []int) error
+	SetCampaignStatus(id int, fromStatuses []string, status string) (bool, error)
+	LaunchCampaign(id int, scheduledAt time.Time, endsAt *time.Time) (bool, error)
+	StartDueCampaigns(now time.Time) (int64, error)
+	ClaimRunningCampaigns(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]Campaign, error)
+	ReleaseCampaign(campaign *Campaign, workerID string) error
+	GetPendingCampaignLeads(campaignID int, limit int) ([]CampaignLead, error)
+	UpdateCampaignLead(member *CampaignLead) error
+	SetCampaignLeadStatus(campaignID int, leadID int, status string, lastError string) error
+	CancelPendingCampaignLeads(campaignID int) error
+	ReleaseAwaitingCampaignLeads(campaignID int, variantID int) error
+	CountCampaignLeadsProcessedSince(campaignID int, since time.Time) (int64, error)
+	GetCampaignLeadCounts(campaignID int) ([]CampaignLeadStatusCount, error)
 	GetTemplates(offset int, limit int, companyId int) ([]CampaignTemplate, error)
 	GetTemplateByID(id int) (*CampaignTemplate, error)
 	CreateTemplate(template *CampaignTemplate) error
 	UpdateTemplate(template *CampaignTemplate) error
 	DeleteTemplate(id int) error
+	GetCampaignsBySegment(segmentID int) ([]Campaign, error)
+	GetSequencesBySegment(segmentID int) ([]NurtureSequence, error)
+	AddSegmentMembersToCampaign(campaignID int, segmentID int) (int, error)
+	EnrollSegmentMembers(sequenceID int, segmentID int) (int, error)
+	GetCampaignCosts(campaignID int) ([]CampaignCost, error)
+	GetCampaignCostByID(id int) (*CampaignCost, error)
+	CreateCampaignCost(cost *CampaignCost) error
+	UpdateCampaignCost(cost *CampaignCost) error
+	DeleteCampaignCost(id int) error
+	GetCampaignROI(companyId int, campaignID *int, startDate *time.Time, endDate *time.Time) ([]CampaignROI, error)
+}
+
+// AttributionRepository interface for marketing touchpoints and attribution
+type AttributionRepository interface {
+	RecordTouchpoint(touchpoint *LeadTouchpoint) error
+	GetTouchpointsByLead(leadID int) ([]LeadTouchpoint, error)
+	GetConversions(startDate time.Time, endDate time.Time, companyId int) ([]AttributionConversion, string, error)
+	GetCampaignLeadCounts(startDate time.Time, endDate time.Time, companyId int) ([]CampaignLeadCount, error)
+	GetSourceLeadCounts(startDate time.Time, endDate time.Time, companyId int) ([]SourceLeadCount, error)
+}
+
+// EmailRepository interface for sender identities and outbound email
+type EmailRepository interface {
+	GetSenderIdentities(companyId int) ([]EmailSenderIdentity, error)
+	GetSenderIdentityByID(id int) (*EmailSenderIdentity, error)
+	GetDefaultSenderIdentity(companyId int) (*EmailSenderIdentity, error)
+	CreateSenderIdentity(identity *EmailSenderIdentity) error
+	UpdateSenderIdentity(identity *EmailSenderIdentity) error
+	DeleteSenderIdentity(id int) error
+	GetMessages(offset int, limit int, filters map[string]interface{}, companyId int) ([]EmailMessage, error)
+	GetMessageByID(id int) (*EmailMessage, error)
+	FindMessageByProviderID(providerMessageID string) (*EmailMessage, error)
+	FindLatestMessageTo(email string, companyId int) (*EmailMessage, error)
+	QueueMessage(message *EmailMessage) error
+	UpdateMessage(message *EmailMessage) error
+	ClaimDueMessages(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]EmailMessage, error)
+	ReleaseMessage(message *EmailMessage, workerID string) error
+	CancelQueuedMessages(campaignID int) (int64, error)
+	RecordEvent(event *EmailEvent) error
+	GetEmailStats(scope string, scopeID int, groupBy string) ([]EmailStats, error)
+}
+
+// CompanyRepository interface for company settings
+type CompanyRepository interface {
+	GetSettings(companyId int) (*CompanySettings, error)
+	SaveSettings(settings *CompanySettings) error
+}
+
+// ConsentRepository interface for communication consent and the email suppression list
+type ConsentRepository interface {
+	GetLeadConsents(leadID int) ([]LeadConsent, error)
+	SetLeadConsent(consent *LeadConsent, change *ConsentChange) (bool, error)
+	GetConsentChanges(leadID int) ([]ConsentChange, error)
+	GetSuppressions(offset int, limit int, search string, companyId int) ([]EmailSuppression, error)
+	GetSuppressionByID(id int) (*EmailSuppression, error)
+	FindSuppression(companyId int, email string) (*EmailSuppression, error)
+	AddSuppression(suppression *EmailSuppression, change *ConsentChange) (bool, error)
+	RemoveSuppression(suppression *EmailSuppression, change *ConsentChange) error
+}
+
+// ABTestRepository interface for A/B tests of campaign and nurture email
+type ABTestRepository interface {
+	GetTestByID(id int) (*EmailABTest, error)
+	GetTestByCampaign(campaignID int) (*EmailABTest, error)
+	GetTestByStep(stepID int) (*EmailABTest, error)
+	SaveTest(test *EmailABTest) error
+	DeleteTest(id int) error
+	MarkTestStarted(id int, startedAt time.Time) error
+	GetStartedTests() ([]EmailABTest, error)
+	SelectWinner(id int, variantID int, selectedAt time.Time) (bool, error)
+	GetVariantStats(id int) ([]EmailStats, error)
+	GetTestResults(test *EmailABTest) (map[string]interface{}, error)
+}
+
+// SegmentRepository interface for saved lead segments
+type SegmentRepository interface {
+	GetSegments(offset int, limit int, companyId int) ([]Segment, error)
+	GetSegmentByID(id int) (*Segment, error)
+	CreateSegment(segment *Segment) error
+	UpdateSegment(segment *Segment) error
+	DeleteSegment(id int) error
+	EvaluateSegment(companyId int, filter *SegmentFilter) ([]int, error)
+	PreviewSegment(companyId int, filter *SegmentFilter, sample int) (int64, []int, error)
+	GetSegmentMembers(segmentID int, offset int, limit int) ([]SegmentMember, error)
+	ReplaceSegmentMembers(segment *Segment, leadIDs []int, refreshedAt time.Time) (int, int, error)
+	ClaimDueSegments(now time.Time, limit int) ([]Segment, error)
 }
 
 // UserRepository interface for user operations
@@ -155,3 +282,174 @@ type UserRepository interface {
 	Delete(id int) error
 	List() ([]User, error)
 }
+
+// ExchangeRateRepository interface for dated exchange rates into a company's base currency
+type ExchangeRateRepository interface {
+	GetExchangeRates(offset int, limit int, currency string, companyId int) ([]ExchangeRate, error)
+	GetExchangeRateByID(id int) (*ExchangeRate, error)
+	CreateExchangeRate(rate *ExchangeRate) error
+	UpdateExchangeRate(rate *ExchangeRate) error
+	DeleteExchangeRate(id int) error
+	ImportExchangeRates(rates []ExchangeRate) (*ExchangeRateImportResult, error)
+	GetRate(companyId int, baseCurrency string, currency string, date time.Time) (*ExchangeRate, error)
+}
+
+// ProductRepository interface for the product catalog and price books
+type ProductRepository interface {
+	GetProducts(offset int, limit int, search string, activeOnly bool, companyId int) ([]Product, error)
+	GetProductByID(id int) (*Product, error)
+	GetProductBySKU(sku string, companyId int) (*Product, error)
+	CreateProduct(product *Product) error
+	UpdateProduct(product *Product) error
+	DeleteProduct(id int) error
+	GetProductPrices(productID int) ([]PriceBookEntry, error)
+	GetPriceBooks(companyId int) ([]PriceBook, error)
+	GetPriceBookByID(id int) (*PriceBook, error)
+	GetDefaultPriceBook(companyId int) (*PriceBook, error)
+	CreatePriceBook(priceBook *PriceBook) error
+	UpdatePriceBook(priceBook *PriceBook) error
+	DeletePriceBook(id int) error
+	SavePriceBookEntries(priceBookID int, entries []PriceBookEntry) error
+	DeletePriceBookEntry(priceBookID int, entryID int) error
+	GetPrice(priceBookID int, productID int, currency string) (*PriceBookEntry, error)
+}
+
+// QuoteRepository interface for deal quotes and the quote PDF template
+type QuoteRepository interface {
+	GetDealQuotes(dealID int) ([]Quote, error)
+	GetQuoteByID(id int) (*Quote, error)
+	CreateQuoteVersion(quote *Quote) error
+	UpdateQuote(quote *Quote) error
+	AcceptQuote(quote *Quote, deal *Deal) error
+	ExpireQuotes(dealID int, today time.Time) (int64, error)
+	GetQuoteTemplate(companyId int) (*QuoteTemplate, error)
+	SaveQuoteTemplate(template *QuoteTemplate) error
+}
+
+// CloseReasonRepository interface for the win, loss and disqualification reason lists
+type CloseReasonRepository interface {
+	GetCloseReasons(reasonType string, activeOnly bool, companyId int) ([]CloseReason, error)
+	GetCloseReasonByID(id int) (*CloseReason, error)
+	CreateCloseReason(reason *CloseReason) error
+	UpdateCloseReason(reason *CloseReason) error
+	DeleteCloseReason(id int) error
+	CountCloseReasonUsage(id int) (int64, error)
+	HasActiveCloseReasons(reasonType string, companyId int) (bool, error)
+}
+
+// AccountRepository interface for customer accounts and their hierarchy
+type AccountRepository interface {
+	GetAccounts(offset int, limit int, filters map[string]interface{}, companyId int) ([]Account, error)
+	SearchAccounts(query string, limit int, companyId int) ([]Account, error)
+	GetAccountByID(id int) (*Account, error)
+	CreateAccount(account *Account) error
+	UpdateAccount(account *Account) error
+	DeleteAccount(account *Account) error
+	GetChildAccounts(id int) ([]Account, error)
+	GetDescendantIDs(id int) ([]int, error)
+	GetAccountContacts(accountIDs []int) ([]Contact, error)
+	GetAccountDeals(accountIDs []int) ([]Deal, error)
+	GetAccountContactLinks(accountID int) ([]AccountContact, error)
+	GetAccountContact(accountID int, contactID int) (*AccountContact, error)
+	SaveAccountContact(accountContact *AccountContact) error
+	DeleteAccountContact(accountContact *AccountContact) error
+	GetContactAccounts(contactID int) ([]Account, error)
+	GetAccountRollup(account *Account, includeChildren bool) (*AccountRollup, error)
+}
+
+// SearchRepository interface for the full-text index behind global search. The lead, contact,
+// deal and account repositories keep the index in step as they write.
+type SearchRepository interface {
+	Search(query string, types []string, offset int, limit int, companyId int) (*SearchResults, error)
+	Reindex(companyId int) (int, error)
+	EnsureIndexed() error
+}
+
+// PhoneRepository interface for phone numbers stored before they were normalized to E.164
+type PhoneRepository interface {
+	GetUnnormalizedPhones(recordType string, after PhoneRecord, limit int, companyId int) ([]PhoneRecord, error)
+	SaveNormalizedPhone(record *PhoneRecord) error
+}
+
+// SavedViewRepository interface for saved list views and the lists and kanban boards they filter
+type SavedViewRepository interface {
+	GetSavedViews(entity string, userID int, companyId int) ([]SavedView, error)
+	GetSavedViewByID(id int) (*SavedView, error)
+	CreateSavedView(view *SavedView) error
+	UpdateSavedView(view *SavedView) error
+	DeleteSavedView(id int) error
+	ListLeads(filters []ViewFilter, order ViewSort, offset int, limit int, companyId int) ([]map[string]string, int64, error)
+	ListDeals(filters []ViewFilter, order ViewSort, offset int, limit int, companyId int) ([]Deal, int64, error)
+	ListContacts(filters []ViewFilter, order ViewSort, offset int, limit int, companyId int) ([]Contact, int64, error)
+	GetDealBoard(filters []ViewFilter, order ViewSort, columns []string, offset int, limit int, companyId int) (*KanbanBoard, error)
+	GetLeadBoard(filters []ViewFilter, order ViewSort, columns []string, offset int, limit int, companyId int) (*KanbanBoard, error)
+	FindIDs(entity string, filters []ViewFilter, limit int, companyId int) ([]int, error)
+}
+
+// AuditRepository interface for the audit trail of changes to leads, deals and contacts
+type AuditRepository interface {
+	RecordAudit(entries []AuditEntry) error
+	GetAuditEntries(entityType string, entityID int, offset int, limit int, companyId int) ([]AuditEntry, error)
+}
+
+// BulkOperationRepository interface for bulk operations on leads and deals and their results
+type BulkOperationRepository interface {
+	GetBulkOperations(entity string, offset int, limit int, companyId int) ([]BulkOperation, error)
+	GetBulkOperationByID(id int) (*BulkOperation, error)
+	GetBulkOperationResults(operationID int, status string) ([]BulkOperationResult, error)
+	CreateBulkOperation(operation *BulkOperation) error
+	UpdateBulkOperation(operation *BulkOperation) error
+	AddBulkOperationResults(results []BulkOperationResult) error
+}
+
+// TrashRepository interface for the recycle bin of deleted leads, contacts, deals, targets and
+// nurture sequences
+type TrashRepository interface {
+	GetTrash(recordType string, offset int, limit int, companyId int) ([]TrashItem, int64, error)
+	GetTrashItem(recordType string, id int) (*TrashItem, error)
+	RestoreRecord(recordType string, id int) error
+	PurgeRecord(recordType string, id int) error
+	GetExpiredTrash(recordType string, deletedBefore time.Time, limit int) ([]int, error)
+}
+
+// TagRepository interface for company tag definitions and the records tagged with them
+type TagRepository interface {
+	GetTags(companyId int) ([]Tag, error)
+	GetTagByID(id int) (*Tag, error)
+	GetTagByName(name string, companyId int) (*Tag, error)
+	CreateTag(tag *Tag) error
+	UpdateTag(tag *Tag, previousName string) error
+	MergeTag(source *Tag, target *Tag) error
+	DeleteTag(tag *Tag) error
+	GetTaggedIDs(entity string, filter TagFilter, companyId int) ([]int, error)
+	GetTagUsage(companyId int) ([]TagUsage, error)
+}
+
+// WebhookRepository interface for webhook subscriptions and the delivery of events to them
+type WebhookRepository interface {
+	GetSubscriptions(companyId int) ([]WebhookSubscription, error)
+	GetSubscriptionByID(id int) (*WebhookSubscription, error)
+	CreateSubscription(subscription *WebhookSubscription) error
+	UpdateSubscription(subscription *WebhookSubscription) error
+	DeleteSubscription(id int) error
+	RecordDeliveryResult(subscriptionID int, succeeded bool, disableAfter int, reason string, now time.Time) (bool, error)
+	HasSubscribers(companyId int, eventType string) (bool, error)
+	QueueEvent(event *WebhookEvent) (int, error)
+	GetEventRecord(aggregateType string, id int) (interface{}, error)
+
+	GetDeliveries(subscriptionID int, status string, offset int, limit int) ([]WebhookDelivery, error)
+	GetDeliveryByID(id int) (*WebhookDelivery, error)
+	ReplayDelivery(delivery *WebhookDelivery, now time.Time) (*WebhookDelivery, error)
+	ClaimDueDeliveries(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]WebhookDelivery, error)
+	ReleaseDelivery(delivery *WebhookDelivery, workerID string) error
+}
+
+// OutboxRepository interface for dispatching the domain events in the outbox
+type OutboxRepository interface {
+	GetEvents(status string, offset int, limit int, companyId int) ([]OutboxEvent, error)
+	GetEventByID(id int) (*OutboxEvent, error)
+	ReplayEvent(event *OutboxEvent, now time.Time) error
+	ClaimDueEvents(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]OutboxEvent, error)
+	ReleaseEvent(event *OutboxEvent, workerID string) error
+	PurgeDispatchedEvents(dispatchedBefore time.Time, limit int) (int64, error)
+}
@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// Entities a saved view can list
+const (
+	ViewEntityLead    = "lead"
+	ViewEntityDeal    = "deal"
+	ViewEntityContact = "contact"
+)
+
+// ViewEntities lists the entities a saved view can list
+var ViewEntities = map[string]bool{
+	ViewEntityLead:    true,
+	ViewEntityDeal:    true,
+	ViewEntityContact: true,
+}
+
+// ViewFields lists the columns each entity's views can filter and sort on. Lead views can also
+// use any lead field by name.
+var ViewFields = map[string]map[string]bool{
+	ViewEntityLead: {
+		"id": true, "name": true, "email": true, "phone": true, "company": true, "source": true,
+		"status": true, "score": true, "assigned_to_id": true, "type": true,
+		"created_at": true, "updated_at": true,
+	},
+	ViewEntityDeal: {
+		"id": true, "title": true, "amount": true, "currency": true, "stage": true, "probability": true,
+		"expected_close_date": true, "assigned_to": true, "account_id": true, "lead_id": true,
+		"close_reason_id": true, "competitor": true, "closed_at": true, "created_at": true, "updated_at": true,
+	},
+	ViewEntityContact: {
+		"id": true, "name": true, "email": true, "phone": true, "position": true, "is_primary": true,
+		"account_id": true, "lead_id": true, "created_at": true, "updated_at": true,
+	},
+}
+
+// ViewFilterTags is the filter field matching records by tag: with any of Values (in), none of
+// them (not_in), no tags (empty) or any tag (not_empty)
+const ViewFilterTags = "tags"
+
+// ViewFilterOperators lists the operators a view filter accepts
+var ViewFilterOperators = map[string]bool{
+	"eq": true, "neq": true, "contains": true, "starts_with": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+	"in": true, "not_in": true, "empty": true, "not_empty": true,
+}
+
+// Saved view limits
+const (
+	DefaultViewPageSize = 25
+	MaxViewPageSize     = 200
+)
+
+// SavedView is a user's saved list of leads, deals or contacts: the filters, sort, visible
+// columns and page size to list them with. Shared views are visible to the whole company but can
+// only be changed by their owner.
+type SavedView struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	Entity    string    `json:"entity" gorm:"size:20;not null;index"`
+	OwnerID   int       `json:"owner_id" gorm:"index"`
+	IsShared  bool      `json:"is_shared" gorm:"default:false"`
+	Filters   string    `json:"filters" gorm:"type:text"` // JSON []ViewFilter, all of which must match
+	SortBy    string    `json:"sort_by" gorm:"size:100"`  // a column or, for leads, a lead field name
+	SortDir   string    `json:"sort_dir" gorm:"size:4"`   // asc or desc
+	Columns   string    `json:"columns" gorm:"type:text"` // JSON []string of the visible columns
+	PageSize  int       `json:"page_size" gorm:"default:25"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;index"`
+}
+
+// ViewFilter is a single condition of a saved view; stored as JSON in SavedView.Filters
+type ViewFilter struct {
+	Field    string   `json:"field"` // a column or, for leads, a lead field name
+	Operator string   `json:"operator"`
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"` // in and not_in
+}
+
+// ViewSort orders the records of a view
+type ViewSort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// ViewPage is one page of the records a saved view lists
+type ViewPage struct {
+	ViewID   int         `json:"view_id"`
+	Entity   string      `json:"entity"`
+	Columns  []string    `json:"columns"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int64       `json:"total"`
+	Records  interface{} `json:"records"` // []map[string]string for leads, []Deal or []Contact
+}
+
+// KanbanBoard groups deals by stage, or leads by status, into columns
+type KanbanBoard struct {
+	Entity   string         `json:"entity"`
+	GroupBy  string         `json:"group_by"`
+	Currency string         `json:"currency"` // the reporting currency of the column totals
+	Columns  []KanbanColumn `json:"columns"`
+}
+
+// KanbanColumn is one stage or status of a board with a page of its cards. Total is the deal
+// value of the column in the reporting currency: the deals' amounts, or for leads the amounts of
+// their open deals.
+type KanbanColumn struct {
+	Key             string           `json:"key"`
+	Count           int64            `json:"count"`
+	Total           float64          `json:"total"`
+	TotalByCurrency []CurrencyAmount `json:"total_by_currency"`
+	Cards           interface{}      `json:"cards"` // []Deal, or []map[string]string for leads
+	HasMore         bool             `json:"has_more"`
+}
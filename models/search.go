@@ -0,0 +1,30 @@
+package models
+
+// Record types covered by global search
+const (
+	SearchTypeLead    = "lead"
+	SearchTypeContact = "contact"
+	SearchTypeDeal    = "deal"
+	SearchTypeAccount = "account"
+)
+
+// SearchTypes lists every record type covered by global search
+var SearchTypes = []string{SearchTypeLead, SearchTypeContact, SearchTypeDeal, SearchTypeAccount}
+
+// SearchHit is one record matching a global search
+type SearchHit struct {
+	Type     string  `json:"type"`
+	ID       int     `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle"`
+	Score    float64 `json:"score"`
+}
+
+// SearchResults is a page of global search hits. Facets count the matches of each record type,
+// whichever types the hits were limited to.
+type SearchResults struct {
+	Query  string           `json:"query"`
+	Total  uint64           `json:"total"`
+	Hits   []SearchHit      `json:"hits"`
+	Facets map[string]int64 `json:"facets"`
+}
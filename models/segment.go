@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Segment rule types
+const (
+	SegmentRuleField    = "field"    // a lead column or EAV field
+	SegmentRuleStatus   = "status"   // the lead status
+	SegmentRuleScore    = "score"    // a score band or numeric score range
+	SegmentRuleTag      = "tag"      // lead tags
+	SegmentRuleActivity = "activity" // recent email or touchpoint activity
+	SegmentRuleCampaign = "campaign" // membership and engagement in a campaign
+)
+
+// SegmentRuleOperators lists the operators each segment rule type accepts
+var SegmentRuleOperators = map[string]map[string]bool{
+	SegmentRuleField: {
+		"eq": true, "neq": true, "contains": true, "starts_with": true,
+		"gt": true, "lt": true, "exists": true, "not_exists": true,
+	},
+	SegmentRuleStatus: {"in": true, "not_in": true},
+	SegmentRuleScore:  {"in": true, "not_in": true, "between": true},
+	SegmentRuleTag:    {"has_any": true, "has_all": true, "has_none": true},
+	SegmentRuleActivity: {
+		"within": true, "not_within": true,
+	},
+	SegmentRuleCampaign: {
+		"member": true, "not_member": true, "sent": true, "not_sent": true,
+		"opened": true, "not_opened": true, "clicked": true, "not_clicked": true,
+	},
+}
+
+// SegmentActivities lists the activity types a segment activity rule can look for
+var SegmentActivities = map[string]bool{
+	"email_sent":    true,
+	"email_opened":  true,
+	"email_clicked": true,
+	"touchpoint":    true,
+}
+
+// Segment is a saved lead filter. Its members are evaluated on demand and, when RefreshMinutes
+// is set, refreshed on a schedule; campaigns and nurture sequences targeting the segment pick up
+// new members on every refresh.
+type Segment struct {
+	ID              int        `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"size:100;not null"`
+	Description     string     `json:"description" gorm:"type:text"`
+	Filters         string     `json:"filters" gorm:"type:text;not null"` // JSON SegmentFilter
+	RefreshMinutes  int        `json:"refresh_minutes" gorm:"default:0"`  // 0 to refresh on demand only
+	MemberCount     int64      `json:"member_count" gorm:"default:0"`
+	LastRefreshedAt *time.Time `json:"last_refreshed_at"`
+	NextRefreshAt   *time.Time `json:"next_refresh_at" gorm:"index"`
+	CreatedBy       int        `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompanyId       int        `json:"company_id" gorm:"not null;index"`
+}
+
+// SegmentMember is a lead that matched a segment when it was last refreshed
+type SegmentMember struct {
+	SegmentID int       `json:"segment_id" gorm:"primaryKey;autoIncrement:false"`
+	LeadID    int       `json:"lead_id" gorm:"primaryKey;autoIncrement:false;index"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// SegmentFilter describes which leads belong to a segment; stored as JSON in Segment.Filters
+type SegmentFilter struct {
+	Match string        `json:"match"` // all (default) or any
+	Rules []SegmentRule `json:"rules"`
+}
+
+// SegmentRule is a single condition of a segment filter
+type SegmentRule struct {
+	Type       string   `json:"type"`
+	Field      string   `json:"field,omitempty"`    // field rules
+	Activity   string   `json:"activity,omitempty"` // activity rules, one of SegmentActivities
+	CampaignID *int     `json:"campaign_id,omitempty"`
+	Operator   string   `json:"operator"`
+	Value      string   `json:"value,omitempty"`
+	Values     []string `json:"values,omitempty"` // status, tag and score band lists
+	Min        *int     `json:"min,omitempty"`    // score rules with the between operator
+	Max        *int     `json:"max,omitempty"`
+	Days       int      `json:"days,omitempty"` // activity rules: the look-back window
+}
+
+// SegmentSyncResult counts the leads a segment refresh added to the campaigns and sequences
+// targeting it
+type SegmentSyncResult struct {
+	Members            int64 `json:"members"`
+	Added              int   `json:"added"`
+	Removed            int   `json:"removed"`
+	CampaignLeadsAdded int   `json:"campaign_leads_added"`
+	LeadsEnrolled      int   `json:"leads_enrolled"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Record types that can be tagged
+const (
+	TagEntityLead    = "lead"
+	TagEntityDeal    = "deal"
+	TagEntityContact = "contact"
+)
+
+// TagEntities lists the record types that can be tagged
+var TagEntities = []string{TagEntityLead, TagEntityDeal, TagEntityContact}
+
+// DefaultTagColor is the color of tags created by tagging a record with a new name
+const DefaultTagColor = "#6B7280"
+
+// MaxTagLength is the longest a tag name can be
+const MaxTagLength = 50
+
+// Tag is a company's tag definition. Records are tagged by name, so renaming, merging or
+// deleting a tag updates the records tagged with it and the segments and saved views filtering on it.
+type Tag struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:50;not null;uniqueIndex:idx_tag_company_name"`
+	Color     string    `json:"color" gorm:"size:7;not null"` // #RRGGBB
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyId int       `json:"company_id" gorm:"not null;uniqueIndex:idx_tag_company_name"`
+}
+
+// DealTag represents a tag associated with a deal
+type DealTag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	DealID    int       `json:"deal_id" gorm:"not null;index"`
+	Tag       string    `json:"tag" gorm:"size:50;index"`
+	CreatedAt time.Time `json:"created_at"`
+	CompanyId int       `json:"company_id" gorm:"not null"`
+}
+
+// ContactTag represents a tag associated with a contact
+type ContactTag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ContactID int       `json:"contact_id" gorm:"not null;index"`
+	Tag       string    `json:"tag" gorm:"size:50;index"`
+	CreatedAt time.Time `json:"created_at"`
+	CompanyId int       `json:"company_id" gorm:"not null"`
+}
+
+// TagFilter limits a list to records tagged with any, or with MatchAll every, one of Tags
+type TagFilter struct {
+	Tags     []string
+	MatchAll bool
+}
+
+// TagUsage counts the live leads, deals and contacts tagged with a tag
+type TagUsage struct {
+	TagID    int    `json:"tag_id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Leads    int64  `json:"leads"`
+	Deals    int64  `json:"deals"`
+	Contacts int64  `json:"contacts"`
+	Total    int64  `json:"total"`
+}
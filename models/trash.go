@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Record types the recycle bin holds
+const (
+	TrashTypeLead     = "lead"
+	TrashTypeContact  = "contact"
+	TrashTypeDeal     = "deal"
+	TrashTypeTarget   = "target"
+	TrashTypeSequence = "nurture_sequence"
+)
+
+// TrashTypes lists the record types the recycle bin holds
+var TrashTypes = []string{TrashTypeLead, TrashTypeContact, TrashTypeDeal, TrashTypeTarget, TrashTypeSequence}
+
+// Audit trail actions recording a record's deletion, restoration and permanent purge. Bulk
+// operations record deletions as "bulk_delete".
+const (
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// DeleteAuditActions are the audit trail actions recording a record's deletion
+var DeleteAuditActions = []string{AuditActionDelete, "bulk_" + BulkActionDelete}
+
+// TrashItem is a deleted record in the recycle bin: who deleted it, when, and when it will be
+// purged for good
+type TrashItem struct {
+	Type      string     `json:"type"`
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy *int       `json:"deleted_by"` // from the audit trail; nil if not recorded
+	PurgeAt   *time.Time `json:"purge_at" gorm:"-"`
+	CompanyId int        `json:"company_id"`
+}
+
+// TrashPurgeResult reports how many deleted records of each type a purge removed for good
+type TrashPurgeResult struct {
+	Purged map[string]int `json:"purged"`
+	Total  int            `json:"total"`
+}
+
+// TrashBlocker is a record, deleted or not, that keeps a deleted record from being purged
+type TrashBlocker struct {
+	Type    string `json:"type"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+}
+
+// PurgeBlockedError is returned when a deleted record cannot be purged because other records
+// still belong to it, such as the deals of a lead
+type PurgeBlockedError struct {
+	Type     string
+	ID       int
+	Blockers []TrashBlocker
+}
+
+func (e *PurgeBlockedError) Error() string {
+	return fmt.Sprintf("%s %d still has %d records belonging to it", e.Type, e.ID, len(e.Blockers))
+}
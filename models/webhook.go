@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventLeadCreated      = "lead.created"
+	WebhookEventLeadUpdated      = "lead.updated"
+	WebhookEventLeadDeleted      = "lead.deleted"
+	WebhookEventLeadQualified    = "lead.qualified"
+	WebhookEventLeadAssigned     = "lead.assigned"
+	WebhookEventDealCreated      = "deal.created"
+	WebhookEventDealUpdated      = "deal.updated"
+	WebhookEventDealStageChanged = "deal.stage_changed"
+	WebhookEventDealDeleted      = "deal.deleted"
+	WebhookEventDealWon          = "deal.won"
+	WebhookEventContactCreated   = "contact.created"
+	WebhookEventContactUpdated   = "contact.updated"
+	WebhookEventContactDeleted   = "contact.deleted"
+	WebhookEventTargetAchieved   = "target.achieved"
+)
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []string{
+	WebhookEventLeadCreated, WebhookEventLeadUpdated, WebhookEventLeadDeleted,
+	WebhookEventLeadQualified, WebhookEventLeadAssigned,
+	WebhookEventDealCreated, WebhookEventDealUpdated, WebhookEventDealStageChanged, WebhookEventDealDeleted,
+	WebhookEventDealWon,
+	WebhookEventContactCreated, WebhookEventContactUpdated, WebhookEventContactDeleted,
+	WebhookEventTargetAchieved,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // every attempt failed
+)
+
+// WebhookSubscription sends a company's events of the subscribed types to a URL as signed JSON.
+// It is disabled after repeated failed deliveries; deliveries wait until it is enabled again.
+type WebhookSubscription struct {
+	ID                  int        `json:"id" gorm:"primaryKey"`
+	URL                 string     `json:"url" gorm:"size:2048;not null"`
+	Description         string     `json:"description" gorm:"size:255"`
+	Events              []string   `json:"events" gorm:"serializer:json;type:text"`
+	Secret              string     `json:"secret,omitempty" gorm:"size:100;not null"` // signs payloads; only returned when set
+	Active              bool       `json:"active" gorm:"not null;default:true"`
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"` // failed attempts since the last success
+	DisabledAt          *time.Time `json:"disabled_at"`
+	DisabledReason      string     `json:"disabled_reason,omitempty" gorm:"size:255"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	CompanyId           int        `json:"company_id" gorm:"not null;index"`
+}
+
+// Subscribes reports whether the subscription receives events of a type
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the JSON body posted to a webhook. ID identifies the event, and is the same on
+// every attempt and replay of its delivery, so receivers can ignore duplicates.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	CompanyId int         `json:"company_id"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery is the delivery of one event to one webhook, with the outcome of its latest
+// attempt. Replaying a delivery creates a new one with the same event.
+type WebhookDelivery struct {
+	ID             int             `json:"id" gorm:"primaryKey"`
+	SubscriptionID int             `json:"subscription_id" gorm:"not null;index"`
+	EventID        string          `json:"event_id" gorm:"size:36;not null;index"`
+	EventType      string          `json:"event_type" gorm:"size:50;not null"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:longtext;not null"` // the exact body posted
+	Status         string          `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Attempts       int             `json:"attempts" gorm:"default:0"`
+	MaxAttempts    int             `json:"max_attempts" gorm:"default:10"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at" gorm:"index"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty" gorm:"size:1000"` // truncated
+	LastError      string          `json:"last_error,omitempty" gorm:"type:text"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	ReplayOf       *int            `json:"replay_of,omitempty"` // the delivery this one replays
+	LockedBy       string          `json:"-" gorm:"size:100"`
+	LockedUntil    *time.Time      `json:"-" gorm:"index"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	CompanyId      int             `json:"company_id" gorm:"not null;index"`
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetTestByID returns an A/B test with its variants
+func (r *gormABTestRepository) GetTestByID(id int) (*models.EmailABTest, error) {
+	return r.findTest(r.db.Where("id = ?", id))
+}
+
+// GetTestByCampaign returns the A/B test of a campaign, or nil if it has none
+func (r *gormABTestRepository) GetTestByCampaign(campaignID int) (*models.EmailABTest, error) {
+	return r.findTest(r.db.Where("campaign_id = ?", campaignID))
+}
+
+// GetTestByStep returns the A/B test of a nurture step, or nil if it has none
+func (r *gormABTestRepository) GetTestByStep(stepID int) (*models.EmailABTest, error) {
+	return r.findTest(r.db.Where("step_id = ?", stepID))
+}
+
+func (r *gormABTestRepository) findTest(query *gorm.DB) (*models.EmailABTest, error) {
+	var test models.EmailABTest
+	err := query.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&test).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &test, nil
+}
+
+// SaveTest creates or replaces an A/B test and its variants
+func (r *gormABTestRepository) SaveTest(test *models.EmailABTest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		variants := test.Variants
+		test.Variants = nil
+		defer func() { test.Variants = variants }()
+
+		if test.ID == 0 {
+			if err := tx.Create(test).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Omit("CreatedAt").Save(test).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("test_id = ?", test.ID).Delete(&models.EmailVariant{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range variants {
+			variants[i].ID = 0
+			variants[i].TestID = test.ID
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		return tx.Create(&variants).Error
+	})
+}
+
+// DeleteTest deletes an A/B test and its variants
+func (r *gormABTestRepository) DeleteTest(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("test_id = ?", id).Delete(&models.EmailVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.EmailABTest{}, id).Error
+	})
+}
+
+// MarkTestStarted records the first test send, which starts the winner selection clock
+func (r *gormABTestRepository) MarkTestStarted(id int, startedAt time.Time) error {
+	return r.db.Model(&models.EmailABTest{}).
+		Where("id = ? AND test_started_at IS NULL", id).
+		Update("test_started_at", startedAt).Error
+}
+
+// GetStartedTests returns the tests still waiting for a winner that have begun sending
+func (r *gormABTestRepository) GetStartedTests() ([]models.EmailABTest, error) {
+	var tests []models.EmailABTest
+	err := r.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("status = ? AND test_started_at IS NOT NULL", models.ABTestStatusTesting).Find(&tests).Error
+	return tests, err
+}
+
+// SelectWinner records the winning variant of a test that is still testing
+func (r *gormABTestRepository) SelectWinner(id int, variantID int, selectedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.EmailABTest{}).
+		Where("id = ? AND status = ?", id, models.ABTestStatusTesting).
+		Updates(map[string]interface{}{
+			"status":             models.ABTestStatusWinnerSelected,
+			"winner_variant_id":  variantID,
+			"winner_selected_at": selectedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetVariantStats returns delivery and engagement stats per variant of a test
+func (r *gormABTestRepository) GetVariantStats(id int) ([]models.EmailStats, error) {
+	return queryEmailStats(r.db, "ab_test", id, "variant_id")
+}
+
+// GetTestResults summarizes a test's settings and outcome with the stats of each variant
+func (r *gormABTestRepository) GetTestResults(test *models.EmailABTest) (map[string]interface{}, error) {
+	return abTestResults(r.db, test)
+}
+
+// abTestResults summarizes a test's settings and outcome with the stats of each variant
+func abTestResults(db *gorm.DB, test *models.EmailABTest) (map[string]interface{}, error) {
+	stats, err := queryEmailStats(db, "ab_test", test.ID, "variant_id")
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[int]models.EmailStats, len(stats))
+	for _, variantStats := range stats {
+		if variantStats.GroupID != nil {
+			byVariant[*variantStats.GroupID] = variantStats
+		}
+	}
+
+	variants := make([]map[string]interface{}, 0, len(test.Variants))
+	for _, variant := range test.Variants {
+		variants = append(variants, map[string]interface{}{
+			"variant_id":    variant.ID,
+			"name":          variant.Name,
+			"template_id":   variant.TemplateID,
+			"split_percent": variant.SplitPercent,
+			"is_winner":     test.WinnerVariantID != nil && *test.WinnerVariantID == variant.ID,
+			"stats":         byVariant[variant.ID],
+		})
+	}
+
+	return map[string]interface{}{
+		"id":                 test.ID,
+		"status":             test.Status,
+		"test_percent":       test.TestPercent,
+		"holdout_percent":    test.HoldoutPercent,
+		"winner_metric":      test.WinnerMetric,
+		"winner_after_hours": test.WinnerAfterHours,
+		"winner_variant_id":  test.WinnerVariantID,
+		"test_started_at":    test.TestStartedAt,
+		"winner_selected_at": test.WinnerSelectedAt,
+		"variants":           variants,
+	}, nil
+}
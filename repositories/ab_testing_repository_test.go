@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSelectWinner(t *testing.T) {
+	selectedAt := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		want         bool
+	}{
+		{"test still testing", 1, true},
+		// Another worker selected a winner first
+		{"winner already selected", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := &gormABTestRepository{db: db}
+			mock.ExpectExec("UPDATE `email_ab_tests` SET `status`=\\?,`winner_selected_at`=\\?,`winner_variant_id`=\\?,`updated_at`=\\? "+
+				"WHERE id = \\? AND status = \\?").
+				WithArgs(models.ABTestStatusWinnerSelected, selectedAt, 8, sqlmock.AnyArg(), 3, models.ABTestStatusTesting).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			selected, err := repo.SelectWinner(3, 8, selectedAt)
+			if err != nil || selected != tt.want {
+				t.Errorf("SelectWinner = (%v, %v), want (%v, nil)", selected, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetAccountContactLinks returns the contacts linked to an account other than through their own
+// account, primary contact first. Deleted contacts are left out.
+func (r *gormAccountRepository) GetAccountContactLinks(accountID int) ([]models.AccountContact, error) {
+	var links []models.AccountContact
+	err := r.db.InnerJoins("Contact").
+		Where("account_contacts.account_id = ?", accountID).
+		Order("account_contacts.is_primary DESC, Contact.name").
+		Find(&links).Error
+	return links, err
+}
+
+// GetAccountContact returns a contact's link to an account, or nil if it has none
+func (r *gormAccountRepository) GetAccountContact(accountID int, contactID int) (*models.AccountContact, error) {
+	var link models.AccountContact
+	err := r.db.Where("account_id = ? AND contact_id = ?", accountID, contactID).First(&link).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// SaveAccountContact links a contact to an account or updates its role. Making it the primary
+// contact demotes the account's previous primary contact.
+func (r *gormAccountRepository) SaveAccountContact(link *models.AccountContact) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if link.IsPrimary {
+			if err := tx.Model(&models.AccountContact{}).
+				Where("account_id = ? AND contact_id <> ? AND is_primary = ?", link.AccountID, link.ContactID, true).
+				Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+		if link.ID == 0 {
+			return tx.Omit("Contact").Create(link).Error
+		}
+		return tx.Omit("Contact", "CreatedAt").Save(link).Error
+	})
+}
+
+// DeleteAccountContact unlinks a contact from an account
+func (r *gormAccountRepository) DeleteAccountContact(link *models.AccountContact) error {
+	return r.db.Delete(link).Error
+}
+
+// GetContactAccounts returns a contact's own account and the accounts it is linked to
+func (r *gormAccountRepository) GetContactAccounts(contactID int) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Where("id IN (?) OR id IN (?)",
+		r.db.Model(&models.Contact{}).Select("account_id").Where("id = ? AND account_id IS NOT NULL", contactID),
+		r.db.Model(&models.AccountContact{}).Select("account_id").Where("contact_id = ?", contactID)).
+		Order("name").
+		Find(&accounts).Error
+	return accounts, err
+}
@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetAccounts returns a company's accounts with pagination. A nil filter value matches NULL.
+func (r *gormAccountRepository) GetAccounts(offset int, limit int, filters map[string]interface{}, companyId int) ([]models.Account, error) {
+	var accounts []models.Account
+	query := r.db.Where("company_id = ?", companyId)
+	for key, value := range filters {
+		if value == nil {
+			query = query.Where(key + " IS NULL")
+		} else {
+			query = query.Where(key+" = ?", value)
+		}
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// SearchAccounts returns a company's accounts whose name contains the query or whose domain
+// starts with it
+func (r *gormAccountRepository) SearchAccounts(query string, limit int, companyId int) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Where("(name LIKE ? OR domain LIKE ?) AND company_id = ?", "%"+query+"%", query+"%", companyId).
+		Order("name").Limit(limit).Find(&accounts).Error
+	return accounts, err
+}
+
+// GetAccountByID returns an account by ID
+func (r *gormAccountRepository) GetAccountByID(id int) (*models.Account, error) {
+	var account models.Account
+	err := r.db.First(&account, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// CreateAccount creates a new account
+func (r *gormAccountRepository) CreateAccount(account *models.Account) error {
+	if err := r.db.Create(account).Error; err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeAccount, account.ID)
+	return nil
+}
+
+// UpdateAccount updates an account
+func (r *gormAccountRepository) UpdateAccount(account *models.Account) error {
+	if err := r.db.Omit("CreatedAt").Save(account).Error; err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeAccount, account.ID)
+	return nil
+}
+
+// DeleteAccount deletes an account, moving its child accounts up to its parent and unlinking its
+// contacts and deals
+func (r *gormAccountRepository) DeleteAccount(account *models.Account) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Account{}).Where("parent_account_id = ?", account.ID).
+			Update("parent_account_id", account.ParentAccountID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Contact{}).Where("account_id = ?", account.ID).
+			Update("account_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Deal{}).Where("account_id = ?", account.ID).
+			Update("account_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.AccountContact{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeAccount, account.ID)
+	return nil
+}
+
+// GetChildAccounts returns the accounts directly below an account
+func (r *gormAccountRepository) GetChildAccounts(id int) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Where("parent_account_id = ?", id).Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// GetDescendantIDs returns the IDs of every account below an account in its hierarchy
+func (r *gormAccountRepository) GetDescendantIDs(id int) ([]int, error) {
+	var descendants []int
+	seen := map[int]bool{id: true}
+	parents := []int{id}
+	for len(parents) > 0 {
+		var children []int
+		if err := r.db.Model(&models.Account{}).Where("parent_account_id IN ?", parents).
+			Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+
+		parents = parents[:0]
+		for _, child := range children {
+			if !seen[child] {
+				seen[child] = true
+				descendants = append(descendants, child)
+				parents = append(parents, child)
+			}
+		}
+	}
+	return descendants, nil
+}
+
+// GetAccountContacts returns the contacts of a set of accounts, whether their own account or
+// linked to it
+func (r *gormAccountRepository) GetAccountContacts(accountIDs []int) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.accountContacts(accountIDs).Order("name").Find(&contacts).Error
+	return contacts, err
+}
+
+// accountContacts selects the contacts of a set of accounts, whether their own account or linked
+// to it
+func (r *gormAccountRepository) accountContacts(accountIDs []int) *gorm.DB {
+	return r.db.Model(&models.Contact{}).Where("account_id IN ? OR id IN (?)", accountIDs,
+		r.db.Model(&models.AccountContact{}).Select("contact_id").Where("account_id IN ?", accountIDs))
+}
+
+// GetAccountDeals returns the deals of a set of accounts, newest first
+func (r *gormAccountRepository) GetAccountDeals(accountIDs []int) ([]models.Deal, error) {
+	var deals []models.Deal
+	err := r.db.Where("account_id IN ?", accountIDs).Order("created_at DESC").Find(&deals).Error
+	return deals, err
+}
+
+// GetAccountRollup totals an account's open pipeline, won revenue, contacts and last activity,
+// optionally across its whole hierarchy. Deal amounts are converted into the company's reporting
+// currency at the rate effective when each deal was created.
+func (r *gormAccountRepository) GetAccountRollup(account *models.Account, includeChildren bool) (*models.AccountRollup, error) {
+	rollup := &models.AccountRollup{
+		AccountID:        account.ID,
+		IncludesChildren: includeChildren,
+		AccountIDs:       []int{account.ID},
+	}
+	if includeChildren {
+		descendants, err := r.GetDescendantIDs(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		rollup.AccountIDs = append(rollup.AccountIDs, descendants...)
+	}
+	ids := rollup.AccountIDs
+
+	if err := r.accountContacts(ids).Count(&rollup.Contacts).Error; err != nil {
+		return nil, err
+	}
+
+	closedStages := append(append([]string{}, models.DealStagesWon...), models.DealStagesLost...)
+	openDeals := func() *gorm.DB {
+		return r.db.Model(&models.Deal{}).Where("account_id IN ? AND stage NOT IN ?", ids, closedStages)
+	}
+	wonDeals := func() *gorm.DB {
+		return r.db.Model(&models.Deal{}).Where("account_id IN ? AND stage IN ?", ids, models.DealStagesWon)
+	}
+	if err := openDeals().Count(&rollup.OpenDeals).Error; err != nil {
+		return nil, err
+	}
+	if err := wonDeals().Count(&rollup.WonDeals).Error; err != nil {
+		return nil, err
+	}
+
+	conversion, err := reportingCurrency(r.db, account.CompanyId)
+	if err != nil {
+		return nil, err
+	}
+	rollup.Currency = conversion.currency
+	pipeline, err := conversion.sumDealAmounts(openDeals(), "''", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+	rollup.OpenPipeline = pipeline.total("")
+	rollup.OpenPipelineByCurrency = pipeline.byCurrency("")
+	revenue, err := conversion.sumDealAmounts(wonDeals(), "''", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+	rollup.WonRevenue = revenue.total("")
+	rollup.WonRevenueByCurrency = revenue.byCurrency("")
+
+	// The last activity is the latest change to a deal or contact, or email sent to their leads
+	accountLeads := r.db.Model(&models.Deal{}).Select("lead_id").Where("account_id IN ?", ids)
+	contactLeads := r.accountContacts(ids).Select("lead_id").Where("lead_id IS NOT NULL")
+	activities := []struct {
+		activityType string
+		query        *gorm.DB
+	}{
+		{"deal", r.db.Model(&models.Deal{}).Select("MAX(updated_at) AS at").Where("account_id IN ?", ids)},
+		{"contact", r.accountContacts(ids).Select("MAX(updated_at) AS at")},
+		{"email", r.db.Model(&models.EmailMessage{}).Select("MAX(sent_at) AS at").
+			Where("lead_id IN (?) OR lead_id IN (?)", accountLeads, contactLeads)},
+	}
+	for _, activity := range activities {
+		var latest struct {
+			At *time.Time
+		}
+		if err := activity.query.Scan(&latest).Error; err != nil {
+			return nil, err
+		}
+		if latest.At != nil && (rollup.LastActivityAt == nil || latest.At.After(*rollup.LastActivityAt)) {
+			rollup.LastActivityAt = latest.At
+			rollup.LastActivityType = activity.activityType
+		}
+	}
+
+	return rollup, nil
+}
@@ -2,7 +2,11 @@ package repositories
 
 import (
 	"crm-app/backend/models"
+	"sort"
+	"strconv"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // type gormAnalyticsRepository struct {
@@ -136,13 +140,18 @@ func (r *gormAnalyticsRepository) GetDealAnalytics(startDate time.Time, endDate
 		return nil, err
 	}
 
-	// Get total revenue from won deals
-	if err := r.db.Model(&models.Deal{}).
-		Where("created_at BETWEEN ? AND ? AND stage = ? AND company_id = ?", startDate, endDate, "won", companyId).
-		Select("COALESCE(SUM(amount), 0)").
-		Scan(&totalRevenue).Error; err != nil {
+	// Get total revenue from won deals, converted into the company's reporting currency
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
+		Where("created_at BETWEEN ? AND ? AND stage = ? AND company_id = ?", startDate, endDate, "won", companyId),
+		"''", "deals.amount", "deals.created_at")
+	if err != nil {
 		return nil, err
 	}
+	totalRevenue = revenue.total("")
 
 	// Calculate average deal size
 	if wonDeals > 0 {
@@ -151,17 +160,28 @@ func (r *gormAnalyticsRepository) GetDealAnalytics(startDate time.Time, endDate
 
 	// Get deals by stage
 	var dealsByStage []struct {
-		Stage string  `json:"stage"`
-		Count int64   `json:"count"`
-		Value float64 `json:"value"`
+		Stage      string                  `json:"stage"`
+		Count      int64                   `json:"count"`
+		Value      float64                 `json:"value"`
+		ByCurrency []models.CurrencyAmount `json:"value_by_currency" gorm:"-"`
 	}
 	if err := r.db.Model(&models.Deal{}).
-		Select("stage, COUNT(*) as count, COALESCE(SUM(amount), 0) as value").
+		Select("stage, COUNT(*) as count").
 		Where("created_at BETWEEN ? AND ? AND company_id = ?", startDate, endDate, companyId).
 		Group("stage").
 		Scan(&dealsByStage).Error; err != nil {
 		return nil, err
 	}
+	stageValues, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
+		Where("created_at BETWEEN ? AND ? AND company_id = ?", startDate, endDate, companyId),
+		"deals.stage", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+	for i := range dealsByStage {
+		dealsByStage[i].Value = stageValues.total(dealsByStage[i].Stage)
+		dealsByStage[i].ByCurrency = stageValues.byCurrency(dealsByStage[i].Stage)
+	}
 
 	// Calculate win rate
 	winRate := float64(0)
@@ -171,33 +191,91 @@ func (r *gormAnalyticsRepository) GetDealAnalytics(startDate time.Time, endDate
 	}
 
 	// Get monthly revenue trend
-	var revenueTrend []struct {
-		Month   string  `json:"month"`
-		Revenue float64 `json:"revenue"`
+	monthlyRevenue, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
+		Where("created_at BETWEEN ? AND ? AND stage = ? AND company_id = ?", startDate, endDate, "won", companyId),
+		"DATE_FORMAT(deals.created_at, '%Y-%m')", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
 	}
-	if err := r.db.Model(&models.Deal{}).
-		Select("DATE_FORMAT(created_at, '%Y-%m') as month, COALESCE(SUM(amount), 0) as revenue").
-		Where("created_at BETWEEN ? AND ? AND stage = ? AND company_id = ?", startDate, endDate, "won", companyId).
-		Group("DATE_FORMAT(created_at, '%Y-%m')").
-		Order("month").
-		Scan(&revenueTrend).Error; err != nil {
+	revenueTrend := make([]map[string]interface{}, 0, len(monthlyRevenue.groups))
+	for _, month := range monthlyRevenue.groups {
+		revenueTrend = append(revenueTrend, map[string]interface{}{
+			"month":               month,
+			"revenue":             monthlyRevenue.total(month),
+			"revenue_by_currency": monthlyRevenue.byCurrency(month),
+		})
+	}
+
+	// Get revenue by product from the line items of won deals
+	revenueByProduct, err := r.getRevenueByProduct(conversion, startDate, endDate, companyId)
+	if err != nil {
 		return nil, err
 	}
 
 	return map[string]interface{}{
-		"total_deals":        totalDeals,
-		"deals_won":          wonDeals,
-		"deals_lost":         lostDeals,
-		"deals_by_stage":     dealsByStage,
-		"deals_by_value":     dealsByStage, // Same data structure
-		"total_revenue":      totalRevenue,
-		"average_deal_value": avgDealSize,
-		"win_rate":           winRate,
-		"deal_velocity":      0, // Would need time-based calculation
-		"revenue_trend":      revenueTrend,
+		"total_deals":         totalDeals,
+		"deals_won":           wonDeals,
+		"deals_lost":          lostDeals,
+		"deals_by_stage":      dealsByStage,
+		"deals_by_value":      dealsByStage, // Same data structure
+		"total_revenue":       totalRevenue,
+		"revenue_by_currency": revenue.byCurrency(""),
+		"currency":            conversion.currency,
+		"average_deal_value":  avgDealSize,
+		"win_rate":            winRate,
+		"deal_velocity":       0, // Would need time-based calculation
+		"revenue_trend":       revenueTrend,
+		"revenue_by_product":  revenueByProduct,
 	}, nil
 }
 
+// getRevenueByProduct returns the quantity sold and revenue, before tax, of each product on deals
+// won in a period, highest revenue first
+func (r *gormAnalyticsRepository) getRevenueByProduct(conversion *currencyConversion, startDate time.Time, endDate time.Time, companyId int) ([]map[string]interface{}, error) {
+	wonLineItems := func() *gorm.DB {
+		return r.db.Model(&models.DealLineItem{}).
+			Joins("JOIN deals ON deals.id = deal_line_items.deal_id AND deals.deleted_at IS NULL").
+			Where("deals.created_at BETWEEN ? AND ? AND deals.stage IN ? AND deals.company_id = ?", startDate, endDate, models.DealStagesWon, companyId)
+	}
+
+	var products []struct {
+		ProductID int
+		Name      string
+		SKU       string
+		Quantity  float64
+		Deals     int64
+	}
+	if err := wonLineItems().
+		Select("deal_line_items.product_id, MAX(deal_line_items.name) as name, MAX(deal_line_items.sku) as sku, SUM(deal_line_items.quantity) as quantity, COUNT(DISTINCT deal_line_items.deal_id) as deals").
+		Group("deal_line_items.product_id").
+		Scan(&products).Error; err != nil {
+		return nil, err
+	}
+
+	revenue, err := conversion.sumDealAmounts(wonLineItems(), "deal_line_items.product_id", "deal_line_items.subtotal", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]map[string]interface{}, 0, len(products))
+	for _, product := range products {
+		key := strconv.Itoa(product.ProductID)
+		output = append(output, map[string]interface{}{
+			"product_id":          product.ProductID,
+			"name":                product.Name,
+			"sku":                 product.SKU,
+			"quantity":            product.Quantity,
+			"deals":               product.Deals,
+			"revenue":             revenue.total(key),
+			"revenue_by_currency": revenue.byCurrency(key),
+		})
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i]["revenue"].(float64) > output[j]["revenue"].(float64)
+	})
+	return output, nil
+}
+
 // GetSalesActivity returns sales activity analytics
 func (r *gormAnalyticsRepository) GetSalesActivity(startDate time.Time, endDate time.Time, companyId int) (map[string]interface{}, error) {
 	// Note: This would need an activities table to be fully implemented
@@ -270,29 +348,41 @@ func (r *gormAnalyticsRepository) GetPerformanceByUser(startDate time.Time, endD
 		ConversionRate float64 `json:"conversion"`
 	}
 
-	query := `SELECT l.assigned_to_id AS user_id,COALESCE(l.lead_count,0)AS lead_count,COALESCE(d.deal_count,0)AS deal_count,COALESCE(d.total_revenue,0)AS total_revenue,CASE WHEN COALESCE(l.lead_count,0)>0 THEN(COALESCE(d.deal_count,0)*100.0/l.lead_count)ELSE 0 END AS conversion_rate FROM(SELECT assigned_to_id,COUNT(*)AS lead_count FROM leads WHERE created_at BETWEEN ? AND ? AND company_id= ? AND assigned_to_id IS NOT NULL GROUP BY assigned_to_id)l LEFT JOIN(SELECT assigned_to,COUNT(*)AS deal_count,COALESCE(SUM(amount),0)AS total_revenue FROM deals WHERE created_at BETWEEN ? AND ? AND assigned_to IS NOT NULL AND stage='won' AND company_id= ? GROUP BY assigned_to)d ON l.assigned_to_id=d.assigned_to UNION SELECT d.assigned_to AS user_id,0 AS lead_count,d.deal_count,d.total_revenue,0 AS conversion_rate FROM(SELECT assigned_to,COUNT(*)AS deal_count,COALESCE(SUM(amount),0)AS total_revenue FROM deals WHERE created_at BETWEEN ? AND ? AND assigned_to IS NOT NULL AND stage='won' AND company_id= ? GROUP BY assigned_to)d WHERE d.assigned_to NOT IN(SELECT assigned_to_id FROM leads WHERE created_at BETWEEN ? AND ? AND company_id= ? AND assigned_to_id IS NOT NULL)`
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	revenueSQL, revenueArgs := conversion.dealAmountSQL("deals.amount", "deals.created_at")
+
+	query := `SELECT l.assigned_to_id AS user_id,COALESCE(l.lead_count,0)AS lead_count,COALESCE(d.deal_count,0)AS deal_count,COALESCE(d.total_revenue,0)AS total_revenue,CASE WHEN COALESCE(l.lead_count,0)>0 THEN(COALESCE(d.deal_count,0)*100.0/l.lead_count)ELSE 0 END AS conversion_rate FROM(SELECT assigned_to_id,COUNT(*)AS lead_count FROM leads WHERE created_at BETWEEN ? AND ? AND company_id= ? AND assigned_to_id IS NOT NULL GROUP BY assigned_to_id)l LEFT JOIN(SELECT assigned_to,COUNT(*)AS deal_count,COALESCE(SUM(` + revenueSQL + `),0)AS total_revenue FROM deals WHERE created_at BETWEEN ? AND ? AND assigned_to IS NOT NULL AND stage='won' AND company_id= ? GROUP BY assigned_to)d ON l.assigned_to_id=d.assigned_to UNION SELECT d.assigned_to AS user_id,0 AS lead_count,d.deal_count,d.total_revenue,0 AS conversion_rate FROM(SELECT assigned_to,COUNT(*)AS deal_count,COALESCE(SUM(` + revenueSQL + `),0)AS total_revenue FROM deals WHERE created_at BETWEEN ? AND ? AND assigned_to IS NOT NULL AND stage='won' AND company_id= ? GROUP BY assigned_to)d WHERE d.assigned_to NOT IN(SELECT assigned_to_id FROM leads WHERE created_at BETWEEN ? AND ? AND company_id= ? AND assigned_to_id IS NOT NULL)`
 
-	if err := r.db.Raw(query, startDate, endDate, companyId, startDate, endDate, companyId, startDate, endDate, companyId, startDate, endDate, companyId).
-		Scan(&userPerformance).Error; err != nil {
+	args := []interface{}{startDate, endDate, companyId}
+	args = append(args, revenueArgs...)
+	args = append(args, startDate, endDate, companyId)
+	args = append(args, revenueArgs...)
+	args = append(args, startDate, endDate, companyId, startDate, endDate, companyId)
+	if err := r.db.Raw(query, args...).Scan(&userPerformance).Error; err != nil {
 		return nil, err
 	}
 
 	return map[string]interface{}{
-		"users": userPerformance,
+		"users":    userPerformance,
+		"currency": conversion.currency,
 	}, nil
 }
 
 // GetFunnelAnalytics returns sales funnel analytics
 func (r *gormAnalyticsRepository) GetFunnelAnalytics(companyId int) (map[string]interface{}, error) {
 	var funnelData []struct {
-		Stage string  `json:"stage"`
-		Count int64   `json:"count"`
-		Value float64 `json:"value"`
+		Stage      string                  `json:"stage"`
+		Count      int64                   `json:"count"`
+		Value      float64                 `json:"value"`
+		ByCurrency []models.CurrencyAmount `json:"value_by_currency" gorm:"-"`
 	}
 
 	// Get funnel stages with counts and values
 	if err := r.db.Model(&models.Deal{}).
-		Select("stage, COUNT(*) as count, COALESCE(SUM(amount), 0) as value").
+		Select("stage, COUNT(*) as count").
 		Group("stage").
 		Order("FIELD(stage, 'lead', 'qualified', 'proposal', 'negotiation', 'won', 'lost')").
 		Where("company_id = ? ", companyId).
@@ -300,6 +390,21 @@ func (r *gormAnalyticsRepository) GetFunnelAnalytics(companyId int) (map[string]
 		return nil, err
 	}
 
+	// Convert stage values into the company's reporting currency
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	stageValues, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).Where("company_id = ?", companyId),
+		"deals.stage", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+	for i := range funnelData {
+		funnelData[i].Value = stageValues.total(funnelData[i].Stage)
+		funnelData[i].ByCurrency = stageValues.byCurrency(funnelData[i].Stage)
+	}
+
 	// Calculate conversion rates between stages
 	conversionRates := make([]map[string]interface{}, 0)
 	for i := 0; i < len(funnelData)-1; i++ {
@@ -316,6 +421,7 @@ func (r *gormAnalyticsRepository) GetFunnelAnalytics(companyId int) (map[string]
 	return map[string]interface{}{
 		"stages":           funnelData,
 		"conversion_rates": conversionRates,
+		"currency":         conversion.currency,
 	}, nil
 }
 
@@ -376,7 +482,7 @@ func (r *gormAnalyticsRepository) GetTargetAnalytics(startDate time.Time, endDat
 
 	// Calculate actual values for each target based on current data
 	for i := range targets {
-		actualValue, err := r.calculateActualTargetValue(targets[i].ID, targets[i].TargetType, startDate, endDate, companyId)
+		actualValue, err := r.calculateActualTargetValue(targets[i].ID, targets[i].TargetType, targets[i].Currency, startDate, endDate, companyId)
 		if err == nil {
 			targets[i].ActualValue = actualValue
 			// Recalculate percent complete with updated actual value
@@ -470,22 +576,25 @@ func (r *gormAnalyticsRepository) GetTargetAnalytics(startDate time.Time, endDat
 }
 
 // Helper method to calculate actual target value based on target type
-func (r *gormAnalyticsRepository) calculateActualTargetValue(targetID int, targetType string, startDate, endDate time.Time, companyId int) (float64, error) {
+func (r *gormAnalyticsRepository) calculateActualTargetValue(targetID int, targetType string, currency string, startDate, endDate time.Time, companyId int) (float64, error) {
 	var actualValue float64
 
 	switch targetType {
 	case "revenue":
-		// Sum revenue from won deals in the period
-		var result struct {
-			Value float64
+		// Sum revenue from won deals in the period and convert it into the target's currency
+		conversion, err := reportingCurrency(r.db, companyId)
+		if err != nil {
+			return 0, err
 		}
-		if err := r.db.Model(&models.Deal{}).
-			Select("COALESCE(SUM(amount), 0) as value").
-			Where("stage = ? AND created_at BETWEEN ? AND ? AND company_id = ?", "won", startDate, endDate, companyId).
-			Scan(&result).Error; err != nil {
+		totals, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
+			Where("stage IN ? AND created_at BETWEEN ? AND ? AND company_id = ?", models.DealStagesWon, startDate, endDate, companyId),
+			"''", "deals.amount", "deals.created_at")
+		if err != nil {
+			return 0, err
+		}
+		if actualValue, _, err = conversion.convertTotal(r.db, totals.total(""), currency, targetRateDate(endDate)); err != nil {
 			return 0, err
 		}
-		actualValue = result.Value
 
 	case "leads":
 		// Count leads created in the period
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecordTouchpoint stores a marketing touchpoint for a lead
+func (r *gormAttributionRepository) RecordTouchpoint(touchpoint *models.LeadTouchpoint) error {
+	if touchpoint.TouchedAt.IsZero() {
+		touchpoint.TouchedAt = time.Now()
+	}
+
+	// Resolve the campaign from utm_campaign when no campaign id was supplied
+	if touchpoint.CampaignID == nil && touchpoint.UtmCampaign != "" {
+		var campaign models.Campaign
+		err := r.db.Select("id").
+			Where("company_id = ? AND name = ?", touchpoint.CompanyId, touchpoint.UtmCampaign).
+			First(&campaign).Error
+		if err == nil {
+			touchpoint.CampaignID = &campaign.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	return r.db.Create(touchpoint).Error
+}
+
+// GetTouchpointsByLead returns the touchpoints of a lead in chronological order
+func (r *gormAttributionRepository) GetTouchpointsByLead(leadID int) ([]models.LeadTouchpoint, error) {
+	var touchpoints []models.LeadTouchpoint
+	err := r.db.Where("lead_id = ?", leadID).Order("touched_at, id").Find(&touchpoints).Error
+	return touchpoints, err
+}
+
+// GetConversions returns the deals won in the period, with their amounts in the company's base
+// currency, and the touchpoints that preceded them. A deal converts when it is closed; deals
+// closed before closed_at was recorded fall back to their last update.
+func (r *gormAttributionRepository) GetConversions(startDate time.Time, endDate time.Time, companyId int) ([]models.AttributionConversion, string, error) {
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	const convertedAtSQL = "COALESCE(deals.closed_at, deals.updated_at)"
+	amountSQL, amountArgs := conversion.dealAmountSQL("deals.amount", convertedAtSQL)
+	var deals []struct {
+		ID          int
+		LeadID      int
+		Amount      *float64
+		ConvertedAt time.Time
+	}
+	if err := r.db.Model(&models.Deal{}).
+		Select("deals.id, deals.lead_id, "+amountSQL+" AS amount, "+convertedAtSQL+" AS converted_at", amountArgs...).
+		Where("deals.company_id = ? AND deals.stage IN ?", companyId, models.DealStagesWon).
+		Where(convertedAtSQL+" BETWEEN ? AND ?", startDate, endDate).
+		Scan(&deals).Error; err != nil {
+		return nil, "", err
+	}
+	if len(deals) == 0 {
+		return []models.AttributionConversion{}, conversion.currency, nil
+	}
+
+	leadIDs := make([]int, 0, len(deals))
+	for _, deal := range deals {
+		leadIDs = append(leadIDs, deal.LeadID)
+	}
+
+	var touchpoints []models.LeadTouchpoint
+	if err := r.db.Where("lead_id IN ? AND company_id = ?", leadIDs, companyId).
+		Order("touched_at, id").
+		Find(&touchpoints).Error; err != nil {
+		return nil, "", err
+	}
+
+	byLead := make(map[int][]models.LeadTouchpoint)
+	for _, tp := range touchpoints {
+		byLead[tp.LeadID] = append(byLead[tp.LeadID], tp)
+	}
+
+	conversions := make([]models.AttributionConversion, 0, len(deals))
+	for _, deal := range deals {
+		var preceding []models.LeadTouchpoint
+		for _, tp := range byLead[deal.LeadID] {
+			if !tp.TouchedAt.After(deal.ConvertedAt) {
+				preceding = append(preceding, tp)
+			}
+		}
+		converted := models.AttributionConversion{
+			DealID:              deal.ID,
+			LeadID:              deal.LeadID,
+			ExchangeRateMissing: deal.Amount == nil,
+			ConvertedAt:         deal.ConvertedAt,
+			Touchpoints:         preceding,
+		}
+		if deal.Amount != nil {
+			converted.Amount = *deal.Amount
+		}
+		conversions = append(conversions, converted)
+	}
+
+	return conversions, conversion.currency, nil
+}
+
+// GetCampaignLeadCounts returns the distinct leads touched by each campaign in the period
+func (r *gormAttributionRepository) GetCampaignLeadCounts(startDate time.Time, endDate time.Time, companyId int) ([]models.CampaignLeadCount, error) {
+	var results []models.CampaignLeadCount
+	err := r.db.Table("campaigns").
+		Select("campaigns.id as campaign_id, campaigns.name, campaigns.budget, campaigns.currency, COUNT(DISTINCT lead_touchpoints.lead_id) as leads").
+		Joins("LEFT JOIN lead_touchpoints ON lead_touchpoints.campaign_id = campaigns.id AND lead_touchpoints.touched_at BETWEEN ? AND ?", startDate, endDate).
+		Where("campaigns.company_id = ?", companyId).
+		Group("campaigns.id, campaigns.name, campaigns.budget, campaigns.currency").
+		Scan(&results).Error
+	return results, err
+}
+
+// GetSourceLeadCounts returns the distinct leads touched by each utm_source in the period
+func (r *gormAttributionRepository) GetSourceLeadCounts(startDate time.Time, endDate time.Time, companyId int) ([]models.SourceLeadCount, error) {
+	var results []models.SourceLeadCount
+	err := r.db.Model(&models.LeadTouchpoint{}).
+		Select("utm_source as source, COUNT(DISTINCT lead_id) as leads").
+		Where("touched_at BETWEEN ? AND ? AND company_id = ?", startDate, endDate, companyId).
+		Group("utm_source").
+		Scan(&results).Error
+	return results, err
+}
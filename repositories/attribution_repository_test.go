@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetConversions(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormAttributionRepository{db: db}
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 30, 23, 59, 59, 0, time.UTC)
+	closedAt := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+
+	expectBaseCurrency(mock, 1, "eur")
+	// Won deals of either won stage, in the period they were closed in, converted to EUR
+	mock.ExpectQuery("SELECT deals.id, deals.lead_id, \\(CASE WHEN .* SELECT er.rate FROM exchange_rates er .* AS amount, "+
+		"COALESCE\\(deals.closed_at, deals.updated_at\\) AS converted_at FROM `deals` "+
+		"WHERE \\(deals.company_id = \\? AND deals.stage IN \\(\\?,\\?\\)\\) "+
+		"AND \\(COALESCE\\(deals.closed_at, deals.updated_at\\) BETWEEN \\? AND \\?\\) AND `deals`.`deleted_at` IS NULL").
+		WithArgs("EUR", "EUR", 1, "EUR", 1, "won", "closed_won", start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "lead_id", "amount", "converted_at"}).
+			AddRow(7, 70, 150.5, closedAt).
+			AddRow(8, 80, nil, closedAt))
+	mock.ExpectQuery("SELECT \\* FROM `lead_touchpoints` WHERE lead_id IN \\(\\?,\\?\\) AND company_id = \\? ORDER BY touched_at, id").
+		WithArgs(70, 80, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "lead_id", "touched_at"}).
+			AddRow(1, 70, closedAt.AddDate(0, 0, -3)).
+			AddRow(2, 70, closedAt).
+			AddRow(3, 70, closedAt.Add(time.Hour)).
+			AddRow(4, 80, closedAt.AddDate(0, 0, -1)))
+
+	conversions, currency, err := repo.GetConversions(start, end, 1)
+	if err != nil {
+		t.Fatalf("GetConversions error: %v", err)
+	}
+	if currency != "EUR" {
+		t.Errorf("currency = %q, want EUR", currency)
+	}
+	if len(conversions) != 2 {
+		t.Fatalf("got %d conversions, want 2", len(conversions))
+	}
+
+	won := conversions[0]
+	if won.Amount != 150.5 || won.ExchangeRateMissing || !won.ConvertedAt.Equal(closedAt) {
+		t.Errorf("conversion = %+v, want 150.5 EUR converted at %v", won, closedAt)
+	}
+	// touchpoints after the deal closed do not count toward it
+	if len(won.Touchpoints) != 2 || won.Touchpoints[0].ID != 1 || won.Touchpoints[1].ID != 2 {
+		t.Errorf("touchpoints = %+v, want 1 and 2", won.Touchpoints)
+	}
+
+	unconverted := conversions[1]
+	if unconverted.Amount != 0 || !unconverted.ExchangeRateMissing || len(unconverted.Touchpoints) != 1 {
+		t.Errorf("conversion without a rate = %+v, want no amount and exchange_rate_missing", unconverted)
+	}
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+)
+
+// RecordAudit adds entries to the audit trail
+func (r *gormAuditRepository) RecordAudit(entries []models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(entries, 500).Error
+}
+
+// GetAuditEntries returns a company's audit trail newest first, optionally only that of an entity
+// type or of one record
+func (r *gormAuditRepository) GetAuditEntries(entityType string, entityID int, offset int, limit int, companyId int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	query := r.db.Where("company_id = ?", companyId)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID > 0 {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetBulkOperations returns a company's bulk operations newest first, optionally only those on
+// one entity
+func (r *gormBulkOperationRepository) GetBulkOperations(entity string, offset int, limit int, companyId int) ([]models.BulkOperation, error) {
+	var operations []models.BulkOperation
+	query := r.db.Where("company_id = ?", companyId)
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&operations).Error
+	return operations, err
+}
+
+// GetBulkOperationByID returns a bulk operation by ID
+func (r *gormBulkOperationRepository) GetBulkOperationByID(id int) (*models.BulkOperation, error) {
+	var operation models.BulkOperation
+	err := r.db.First(&operation, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetBulkOperationResults returns a bulk operation's record results, optionally only those with
+// a status
+func (r *gormBulkOperationRepository) GetBulkOperationResults(operationID int, status string) ([]models.BulkOperationResult, error) {
+	results := []models.BulkOperationResult{}
+	query := r.db.Where("operation_id = ?", operationID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id").Find(&results).Error
+	return results, err
+}
+
+// CreateBulkOperation creates a new bulk operation
+func (r *gormBulkOperationRepository) CreateBulkOperation(operation *models.BulkOperation) error {
+	return r.db.Create(operation).Error
+}
+
+// UpdateBulkOperation updates a bulk operation's status and counts
+func (r *gormBulkOperationRepository) UpdateBulkOperation(operation *models.BulkOperation) error {
+	return r.db.Save(operation).Error
+}
+
+// AddBulkOperationResults stores record results of a bulk operation
+func (r *gormBulkOperationRepository) AddBulkOperationResults(results []models.BulkOperationResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(results, 500).Error
+}
@@ -96,6 +96,20 @@ func (r *gormCampaignRepository) AssignLeadsToCampaign(campaignID int, leadIDs [
 	return tx.Commit().Error
 }
 
+// AddLeadsToCampaign adds the leads that are not yet members to a campaign, returning how many
+// were added
+func (r *gormCampaignRepository) AddLeadsToCampaign(campaignID int, leadIDs []int) (int, error) {
+	if len(leadIDs) == 0 {
+		return 0, nil
+	}
+	result := r.db.Exec(`INSERT INTO campaign_leads (campaign_id, lead_id, status, created_at, updated_at)
+		SELECT ?, leads.id, ?, NOW(), NOW() FROM leads
+		WHERE leads.id IN ?
+		AND NOT EXISTS (SELECT 1 FROM campaign_leads existing WHERE existing.campaign_id = ? AND existing.lead_id = leads.id)`,
+		campaignID, models.CampaignLeadPending, leadIDs, campaignID)
+	return int(result.RowsAffected), result.Error
+}
+
 // RemoveLeadsFromCampaign removes leads from a campaign
 func (r *gormCampaignRepository) RemoveLeadsFromCampaign(campaignID int, leadIDs []int) error {
 	return r.db.Where("campaign_id = ? AND lead_id IN ?", campaignID, leadIDs).Delete("campaign_leads").Error
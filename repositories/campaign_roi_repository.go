@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetCampaignCosts returns a campaign's cost entries, newest first
+func (r *gormNurtureRepository) GetCampaignCosts(campaignID int) ([]models.CampaignCost, error) {
+	var costs []models.CampaignCost
+	err := r.db.Where("campaign_id = ?", campaignID).Order("date DESC, id DESC").Find(&costs).Error
+	return costs, err
+}
+
+// GetCampaignCostByID returns a campaign cost entry by ID
+func (r *gormNurtureRepository) GetCampaignCostByID(id int) (*models.CampaignCost, error) {
+	var cost models.CampaignCost
+	err := r.db.First(&cost, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cost, nil
+}
+
+// CreateCampaignCost records money spent on a campaign
+func (r *gormNurtureRepository) CreateCampaignCost(cost *models.CampaignCost) error {
+	return r.db.Create(cost).Error
+}
+
+// UpdateCampaignCost updates a campaign cost entry
+func (r *gormNurtureRepository) UpdateCampaignCost(cost *models.CampaignCost) error {
+	return r.db.Omit("CreatedAt").Save(cost).Error
+}
+
+// DeleteCampaignCost deletes a campaign cost entry
+func (r *gormNurtureRepository) DeleteCampaignCost(id int) error {
+	return r.db.Delete(&models.CampaignCost{}, id).Error
+}
+
+// GetCampaignROI returns the spend, members and deal outcomes of a company's campaigns, or of
+// one campaign. When a period is given only costs dated, members added and deals created in it
+// are counted.
+func (r *gormNurtureRepository) GetCampaignROI(companyId int, campaignID *int, startDate *time.Time, endDate *time.Time) ([]models.CampaignROI, error) {
+	campaignQuery := r.db.Model(&models.Campaign{}).Where("company_id = ?", companyId)
+	if campaignID != nil {
+		campaignQuery = campaignQuery.Where("id = ?", *campaignID)
+	}
+	var campaigns []models.Campaign
+	if err := campaignQuery.Order("id").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	if len(campaigns) == 0 {
+		return []models.CampaignROI{}, nil
+	}
+
+	ids := make([]int, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.ID)
+	}
+	inPeriod := func(query *gorm.DB, column string) *gorm.DB {
+		if startDate != nil && endDate != nil {
+			query = query.Where(column+" BETWEEN ? AND ?", *startDate, *endDate)
+		}
+		return query
+	}
+
+	var costs []struct {
+		CampaignID int
+		Channel    string
+		Amount     float64
+	}
+	if err := inPeriod(r.db.Model(&models.CampaignCost{}), "date").
+		Select("campaign_id, channel, SUM(amount) as amount").
+		Where("campaign_id IN ?", ids).
+		Group("campaign_id, channel").
+		Scan(&costs).Error; err != nil {
+		return nil, err
+	}
+
+	var members []struct {
+		CampaignID int
+		Leads      int64
+	}
+	if err := inPeriod(r.db.Table("campaign_leads"), "created_at").
+		Select("campaign_id, COUNT(*) as leads").
+		Where("campaign_id IN ?", ids).
+		Group("campaign_id").
+		Scan(&members).Error; err != nil {
+		return nil, err
+	}
+
+	// Deal amounts are converted into the reporting currency here and into each campaign's
+	// currency below
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	amountSQL, amountArgs := conversion.dealAmountSQL("deals.amount", "deals.created_at")
+
+	closed := append(append([]string{}, models.DealStagesWon...), models.DealStagesLost...)
+	args := append([]interface{}{models.DealStagesWon, models.DealStagesWon}, amountArgs...)
+	args = append(args, closed)
+	args = append(args, amountArgs...)
+	var outcomes []struct {
+		CampaignID    int
+		Opportunities int64
+		WonDeals      int64
+		Revenue       float64
+		Pipeline      float64
+	}
+	if err := inPeriod(r.db.Table("campaign_leads"), "deals.created_at").
+		Select(`campaign_leads.campaign_id,
+			COUNT(DISTINCT deals.id) as opportunities,
+			COUNT(DISTINCT CASE WHEN deals.stage IN ? THEN deals.id END) as won_deals,
+			COALESCE(SUM(CASE WHEN deals.stage IN ? THEN `+amountSQL+` ELSE 0 END), 0) as revenue,
+			COALESCE(SUM(CASE WHEN deals.stage NOT IN ? THEN `+amountSQL+` ELSE 0 END), 0) as pipeline`,
+			args...).
+		Joins("INNER JOIN deals ON deals.lead_id = campaign_leads.lead_id AND deals.deleted_at IS NULL AND deals.created_at >= campaign_leads.created_at").
+		Where("campaign_leads.campaign_id IN ?", ids).
+		Group("campaign_leads.campaign_id").
+		Scan(&outcomes).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]models.CampaignROI, len(campaigns))
+	byID := make(map[int]*models.CampaignROI, len(campaigns))
+	for i, campaign := range campaigns {
+		results[i] = models.CampaignROI{
+			CampaignID:         campaign.ID,
+			Name:               campaign.Name,
+			Status:             campaign.Status,
+			Currency:           campaign.Currency,
+			Budget:             campaign.Budget,
+			CostByChannel:      make(map[string]float64),
+			Revenue:            new(float64),
+			PipelineInfluenced: new(float64),
+		}
+		byID[campaign.ID] = &results[i]
+	}
+	for _, cost := range costs {
+		roi := byID[cost.CampaignID]
+		channel := cost.Channel
+		if channel == "" {
+			channel = "other"
+		}
+		roi.Cost += cost.Amount
+		roi.CostByChannel[channel] += cost.Amount
+	}
+	for _, member := range members {
+		byID[member.CampaignID].Leads = member.Leads
+	}
+	rateDate := time.Now()
+	if endDate != nil && endDate.Before(rateDate) {
+		rateDate = *endDate
+	}
+	for _, outcome := range outcomes {
+		roi := byID[outcome.CampaignID]
+		roi.Opportunities = outcome.Opportunities
+		roi.WonDeals = outcome.WonDeals
+		revenue, revenueOK, err := conversion.convertTotal(r.db, outcome.Revenue, roi.Currency, rateDate)
+		if err != nil {
+			return nil, err
+		}
+		pipeline, pipelineOK, err := conversion.convertTotal(r.db, outcome.Pipeline, roi.Currency, rateDate)
+		if err != nil {
+			return nil, err
+		}
+		// Deal values that could not be converted into the campaign's currency are not reported
+		if (!revenueOK && outcome.Revenue != 0) || (!pipelineOK && outcome.Pipeline != 0) {
+			roi.ExchangeRateMissing = true
+			roi.Revenue, roi.PipelineInfluenced = nil, nil
+			continue
+		}
+		roi.Revenue, roi.PipelineInfluenced = &revenue, &pipeline
+	}
+
+	return results, nil
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetCloseReasons returns a company's close reasons in display order, optionally of one type
+func (r *gormCloseReasonRepository) GetCloseReasons(reasonType string, activeOnly bool, companyId int) ([]models.CloseReason, error) {
+	var reasons []models.CloseReason
+	query := r.db.Where("company_id = ?", companyId)
+	if reasonType != "" {
+		query = query.Where("type = ?", reasonType)
+	}
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("type, position, name").Find(&reasons).Error
+	return reasons, err
+}
+
+// GetCloseReasonByID returns a close reason by ID
+func (r *gormCloseReasonRepository) GetCloseReasonByID(id int) (*models.CloseReason, error) {
+	var reason models.CloseReason
+	err := r.db.First(&reason, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// CreateCloseReason creates a new close reason
+func (r *gormCloseReasonRepository) CreateCloseReason(reason *models.CloseReason) error {
+	return r.db.Create(reason).Error
+}
+
+// UpdateCloseReason updates a close reason
+func (r *gormCloseReasonRepository) UpdateCloseReason(reason *models.CloseReason) error {
+	return r.db.Omit("CreatedAt").Save(reason).Error
+}
+
+// DeleteCloseReason deletes a close reason
+func (r *gormCloseReasonRepository) DeleteCloseReason(id int) error {
+	return r.db.Delete(&models.CloseReason{}, id).Error
+}
+
+// CountCloseReasonUsage counts the deals, including deleted ones, and leads recorded with a reason
+func (r *gormCloseReasonRepository) CountCloseReasonUsage(id int) (int64, error) {
+	var deals, leads int64
+	if err := r.db.Unscoped().Model(&models.Deal{}).Where("close_reason_id = ?", id).Count(&deals).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Unscoped().Model(&models.Lead{}).Where("disqualification_reason_id = ?", id).Count(&leads).Error; err != nil {
+		return 0, err
+	}
+	return deals + leads, nil
+}
+
+// HasActiveCloseReasons reports whether a company has any active close reason of a type
+func (r *gormCloseReasonRepository) HasActiveCloseReasons(reasonType string, companyId int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CloseReason{}).
+		Where("type = ? AND is_active = ? AND company_id = ?", reasonType, true, companyId).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetSettings returns a company's settings, or nil if none have been saved
+func (r *gormCompanyRepository) GetSettings(companyId int) (*models.CompanySettings, error) {
+	var settings models.CompanySettings
+	err := r.db.Where("company_id = ?", companyId).First(&settings).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings creates or replaces a company's settings
+func (r *gormCompanyRepository) SaveSettings(settings *models.CompanySettings) error {
+	existing, err := r.GetSettings(settings.CompanyId)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		settings.ID = 0
+		return r.db.Create(settings).Error
+	}
+
+	settings.ID = existing.ID
+	settings.CreatedAt = existing.CreatedAt
+	return r.db.Save(settings).Error
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSaveSettings(t *testing.T) {
+	createdAt := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		existing      *sqlmock.Rows
+		wantSQL       string
+		wantID        int
+		wantCreatedAt time.Time
+	}{
+		{"first settings are created", sqlmock.NewRows([]string{"id"}), "INSERT INTO `company_settings`", 6, time.Time{}},
+		// A replaced row keeps its ID and creation time whatever the caller sent
+		{"existing settings are replaced", sqlmock.NewRows([]string{"id", "company_id", "created_at"}).AddRow(4, 1, createdAt), "UPDATE `company_settings` SET", 4, createdAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := &gormCompanyRepository{db: db}
+			mock.ExpectQuery("SELECT \\* FROM `company_settings` WHERE company_id = \\? ORDER BY `company_settings`.`id` LIMIT 1").
+				WithArgs(1).
+				WillReturnRows(tt.existing)
+			mock.ExpectExec(tt.wantSQL).WillReturnResult(sqlmock.NewResult(6, 1))
+
+			settings := &models.CompanySettings{ID: 99, CompanyId: 1}
+			if err := repo.SaveSettings(settings); err != nil {
+				t.Fatalf("SaveSettings error: %v", err)
+			}
+			if settings.ID != tt.wantID || (!tt.wantCreatedAt.IsZero() && !settings.CreatedAt.Equal(tt.wantCreatedAt)) {
+				t.Errorf("settings ID %d created at %v, want ID %d created at %v", settings.ID, settings.CreatedAt, tt.wantID, tt.wantCreatedAt)
+			}
+		})
+	}
+}
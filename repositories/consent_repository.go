@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetLeadConsents returns a lead's current consent per channel
+func (r *gormConsentRepository) GetLeadConsents(leadID int) ([]models.LeadConsent, error) {
+	var consents []models.LeadConsent
+	err := r.db.Where("lead_id = ?", leadID).Order("channel").Find(&consents).Error
+	return consents, err
+}
+
+// SetLeadConsent records a lead's consent for a channel together with its audit record.
+// It reports false without writing anything when the status is unchanged.
+func (r *gormConsentRepository) SetLeadConsent(consent *models.LeadConsent, change *models.ConsentChange) (bool, error) {
+	changed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.LeadConsent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("lead_id = ? AND channel = ?", consent.LeadID, consent.Channel).
+			First(&existing).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			consent.ID = 0
+			if err := tx.Create(consent).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case existing.Status == consent.Status:
+			*consent = existing
+			return nil
+		default:
+			change.PreviousStatus = existing.Status
+			consent.ID = existing.ID
+			if err := tx.Save(consent).Error; err != nil {
+				return err
+			}
+		}
+
+		changed = true
+		return tx.Create(change).Error
+	})
+	return changed, err
+}
+
+// GetConsentChanges returns the consent audit trail of a lead, newest first
+func (r *gormConsentRepository) GetConsentChanges(leadID int) ([]models.ConsentChange, error) {
+	var changes []models.ConsentChange
+	err := r.db.Where("lead_id = ?", leadID).Order("created_at desc, id desc").Find(&changes).Error
+	return changes, err
+}
+
+// GetSuppressions returns a company's suppressed addresses, optionally matching a search term
+func (r *gormConsentRepository) GetSuppressions(offset int, limit int, search string, companyId int) ([]models.EmailSuppression, error) {
+	var suppressions []models.EmailSuppression
+	query := r.db.Where("company_id = ?", companyId)
+	if search != "" {
+		query = query.Where("email LIKE ?", "%"+search+"%")
+	}
+	err := query.Order("created_at desc").Offset(offset).Limit(limit).Find(&suppressions).Error
+	return suppressions, err
+}
+
+// GetSuppressionByID returns a suppression list entry by ID
+func (r *gormConsentRepository) GetSuppressionByID(id int) (*models.EmailSuppression, error) {
+	var suppression models.EmailSuppression
+	err := r.db.First(&suppression, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// FindSuppression returns the suppression list entry for an address, or nil if it is not suppressed
+func (r *gormConsentRepository) FindSuppression(companyId int, email string) (*models.EmailSuppression, error) {
+	var suppression models.EmailSuppression
+	err := r.db.Where("company_id = ? AND email = ?", companyId, email).First(&suppression).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// AddSuppression adds an address to the suppression list with an optional audit record.
+// It reports false when the address was already suppressed.
+func (r *gormConsentRepository) AddSuppression(suppression *models.EmailSuppression, change *models.ConsentChange) (bool, error) {
+	added := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(suppression)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		added = true
+		if change == nil {
+			return nil
+		}
+		return tx.Create(change).Error
+	})
+	return added, err
+}
+
+// RemoveSuppression removes an address from the suppression list with an optional audit record
+func (r *gormConsentRepository) RemoveSuppression(suppression *models.EmailSuppression, change *models.ConsentChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.EmailSuppression{}, suppression.ID).Error; err != nil {
+			return err
+		}
+		if change == nil {
+			return nil
+		}
+		return tx.Create(change).Error
+	})
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSetLeadConsent(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		status             string
+		wantChanged        bool
+		wantPreviousStatus string
+	}{
+		{"unchanged status writes nothing", models.ConsentStatusOptedIn, false, ""},
+		{"changed status is saved with the previous one", models.ConsentStatusOptedOut, true, models.ConsentStatusOptedIn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := &gormConsentRepository{db: db}
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT \\* FROM `lead_consents` WHERE lead_id = \\? AND channel = \\? ORDER BY `lead_consents`.`id` LIMIT 1 FOR UPDATE").
+				WithArgs(4, models.ConsentChannelEmail).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "lead_id", "channel", "status", "updated_at", "company_id"}).
+					AddRow(12, 4, models.ConsentChannelEmail, models.ConsentStatusOptedIn, updatedAt, 1))
+			if tt.wantChanged {
+				mock.ExpectExec("UPDATE `lead_consents` SET").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `consent_changes`").
+					WillReturnResult(sqlmock.NewResult(30, 1))
+			}
+			mock.ExpectCommit()
+
+			consent := &models.LeadConsent{LeadID: 4, Channel: models.ConsentChannelEmail, Status: tt.status, CompanyId: 1}
+			change := &models.ConsentChange{Channel: models.ConsentChannelEmail, Status: tt.status, CompanyId: 1}
+			changed, err := repo.SetLeadConsent(consent, change)
+			if err != nil {
+				t.Fatalf("SetLeadConsent error: %v", err)
+			}
+			if changed != tt.wantChanged || change.PreviousStatus != tt.wantPreviousStatus || consent.ID != 12 {
+				t.Errorf("changed = %v, previous status %q, consent ID %d; want %v, %q, 12",
+					changed, change.PreviousStatus, consent.ID, tt.wantChanged, tt.wantPreviousStatus)
+			}
+		})
+	}
+}
@@ -27,14 +27,24 @@ func (r *GormContactRepository) FindByID(id int) (*models.Contact, error) {
 		}
 		return nil, result.Error
 	}
+
+	tags, err := loadRecordTags(r.db, models.TagEntityContact, []int{contact.ID})
+	if err != nil {
+		return nil, err
+	}
+	contact.Tags = tags[contact.ID]
 	return &contact, nil
 }
 
-// List returns contacts with pagination
-func (r *GormContactRepository) List(offset int, limit int, companyId int) ([]models.Contact, error) {
+// List returns contacts with pagination, optionally only those matching a tag filter
+func (r *GormContactRepository) List(offset int, limit int, tags *models.TagFilter, companyId int) ([]models.Contact, error) {
 	var contacts []models.Contact
 	query := r.db
 
+	if tags != nil {
+		query = query.Where("id IN (?)", taggedRecords(r.db, models.TagEntityContact, *tags, companyId))
+	}
+
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -45,6 +55,18 @@ func (r *GormContactRepository) List(offset int, limit int, companyId int) ([]mo
 	if err := query.Where("company_id = ?", companyId).Find(&contacts).Error; err != nil {
 		return nil, err
 	}
+
+	ids := make([]int, len(contacts))
+	for i := range contacts {
+		ids[i] = contacts[i].ID
+	}
+	contactTags, err := loadRecordTags(r.db, models.TagEntityContact, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contacts {
+		contacts[i].Tags = contactTags[contacts[i].ID]
+	}
 	return contacts, nil
 }
 
@@ -59,17 +81,85 @@ func (r *GormContactRepository) FindByLead(leadID int) ([]models.Contact, error)
 
 // Create creates a new contact
 func (r *GormContactRepository) Create(contact *models.Contact) error {
-	return r.db.Create(contact).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(contact).Error; err != nil {
+			return err
+		}
+		if _, err := addRecordTags(tx, models.TagEntityContact, contact.ID, contact.Tags, contact.CompanyId); err != nil {
+			return err
+		}
+		return recordDomainEvent(tx, models.EventContactCreated, contact.ID, contact.CompanyId, contactEvent(contact))
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeContact, contact.ID)
+	return nil
+}
+
+// contactEvent returns the payload of a contact's domain events
+func contactEvent(contact *models.Contact) models.ContactEvent {
+	return models.ContactEvent{
+		ContactID: contact.ID,
+		LeadID:    contact.LeadID,
+		AccountID: contact.AccountID,
+		Name:      contact.Name,
+		Email:     contact.Email,
+	}
 }
 
-// Update updates an existing contact
+// contactEventColumns are the contact columns its events are made from
+const contactEventColumns = "id, lead_id, account_id, name, email, company_id"
+
+// findContactForEvent reads the contact columns its events carry, returning a contact with no ID
+// if there is no such contact
+func findContactForEvent(tx *gorm.DB, id int) (*models.Contact, error) {
+	var contact models.Contact
+	err := tx.Select(contactEventColumns).Where("id = ?", id).Limit(1).Find(&contact).Error
+	return &contact, err
+}
+
+// Update updates an existing contact; its tags are replaced unless Tags is nil
 func (r *GormContactRepository) Update(contact *models.Contact) error {
-	return r.db.Save(contact).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(contact).Error; err != nil {
+			return err
+		}
+		if err := recordDomainEvent(tx, models.EventContactUpdated, contact.ID, contact.CompanyId, contactEvent(contact)); err != nil {
+			return err
+		}
+		if contact.Tags == nil {
+			return nil
+		}
+		return setRecordTags(tx, models.TagEntityContact, contact.ID, contact.Tags, contact.CompanyId)
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeContact, contact.ID)
+	return nil
 }
 
 // Delete deletes a contact
 func (r *GormContactRepository) Delete(id int) error {
-	return r.db.Delete(&models.Contact{}, id).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		contact, err := findContactForEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Contact{}, id).Error; err != nil {
+			return err
+		}
+		if contact.ID == 0 {
+			return nil
+		}
+		return recordDomainEvent(tx, models.EventContactDeleted, id, contact.CompanyId, contactEvent(contact))
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeContact, id)
+	return nil
 }
 
 // Search searches for contacts
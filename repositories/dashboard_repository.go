@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"crm-app/backend/models"
+	"strconv"
 	"time"
 )
 
@@ -61,23 +62,34 @@ func (r *gormDashboardRepository) GetDashboardSummary(companyId int) (map[string
 		summary["conversion_rate"] = 0.0
 	}
 
-	// Get revenue metrics
-	var totalRevenue float64
-	if err := r.db.Model(&models.Deal{}).Where("stage = ? AND company_id = ?", "won", companyId).Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalRevenue); err != nil {
+	// Get revenue metrics, converted into the company's reporting currency
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
 		return nil, err
 	}
-	summary["total_revenue"] = totalRevenue
+	summary["currency"] = conversion.currency
 
-	var forecastedRevenue float64
-	if err := r.db.Model(&models.Deal{}).Where("stage NOT IN (?, ?) AND company_id = ?", "won", "lost", companyId).
-		Select("COALESCE(SUM(amount * probability / 100), 0)").Row().Scan(&forecastedRevenue); err != nil {
+	revenue, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).Where("stage = ? AND company_id = ?", "won", companyId),
+		"''", "deals.amount", "deals.created_at")
+	if err != nil {
 		return nil, err
 	}
-	summary["forecasted_revenue"] = forecastedRevenue
+	summary["total_revenue"] = revenue.total("")
+	summary["revenue_by_currency"] = revenue.byCurrency("")
+
+	forecast, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).Where("stage NOT IN (?, ?) AND company_id = ?", "won", "lost", companyId),
+		"''", "deals.amount * deals.probability / 100", "CURDATE()")
+	if err != nil {
+		return nil, err
+	}
+	summary["forecasted_revenue"] = forecast.total("")
+	summary["forecast_by_currency"] = forecast.byCurrency("")
 
 	// Get average deal size
 	var avgDealSize float64
-	if err := r.db.Model(&models.Deal{}).Where("amount > 0").Select("COALESCE(AVG(amount), 0)").Row().Scan(&avgDealSize); err != nil {
+	amountSQL, amountArgs := conversion.dealAmountSQL("deals.amount", "deals.created_at")
+	if err := r.db.Model(&models.Deal{}).Where("amount > 0 AND company_id = ?", companyId).
+		Select("COALESCE(AVG("+amountSQL+"), 0)", amountArgs...).Row().Scan(&avgDealSize); err != nil {
 		return nil, err
 	}
 	summary["average_deal_size"] = avgDealSize
@@ -85,7 +97,7 @@ func (r *gormDashboardRepository) GetDashboardSummary(companyId int) (map[string
 	// Get average sales cycle
 	// DATEDIFF in MySQL calculates days between dates
 	var avgSalesCycle float64
-	err := r.db.Raw(`
+	err = r.db.Raw(`
 		SELECT COALESCE(AVG(DATEDIFF(deals.created_at, leads.created_at)), 0) 
 		FROM deals 
 		JOIN leads ON deals.lead_id = leads.id 
@@ -155,62 +167,60 @@ func (r *gormDashboardRepository) GetLeadsByStatus(companyId int) ([]map[string]
 	return output, nil
 }
 
-// GetRevenueByMonth retrieves monthly revenue for a given year
+// GetRevenueByMonth retrieves monthly revenue for a given year in the company's reporting currency
 func (r *gormDashboardRepository) GetRevenueByMonth(year int, companyId int) ([]map[string]interface{}, error) {
-	var results []struct {
-		Month   int
-		Revenue float64
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := r.db.Model(&models.Deal{}).
-		Select("MONTH(created_at) as month, SUM(amount) as revenue").
-		Where("YEAR(created_at) = ? AND stage = ? AND company_id= ?", year, "won", companyId).
-		Group("MONTH(created_at)").
-		Order("month").
-		Find(&results).Error; err != nil {
+	revenue, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
+		Where("YEAR(created_at) = ? AND stage = ? AND company_id= ?", year, "won", companyId),
+		"DATE_FORMAT(deals.created_at, '%m')", "deals.amount", "deals.created_at")
+	if err != nil {
 		return nil, err
 	}
 
 	var output []map[string]interface{}
-	for _, result := range results {
+	for _, month := range revenue.groups {
 		// Convert month number to month name
-		monthName := time.Month(result.Month).String()
+		monthNumber, _ := strconv.Atoi(month)
 		output = append(output, map[string]interface{}{
-			"month":   monthName,
-			"revenue": result.Revenue,
+			"month":               time.Month(monthNumber).String(),
+			"revenue":             revenue.total(month),
+			"currency":            conversion.currency,
+			"revenue_by_currency": revenue.byCurrency(month),
 		})
 	}
 
 	return output, nil
 }
 
-// GetSalesForecast retrieves sales forecast for the coming months
+// GetSalesForecast retrieves sales forecast for the coming months in the company's reporting
+// currency, converting at today's rates
 func (r *gormDashboardRepository) GetSalesForecast(months int, companyId int) ([]map[string]interface{}, error) {
-	var results []struct {
-		Year             int
-		Month            int
-		ForecastedAmount float64
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
 	}
 
 	// This is a simplified forecast based on probability-weighted deals
-	if err := r.db.Model(&models.Deal{}).
-		Select("YEAR(expected_close_date) as year, MONTH(expected_close_date) as month, SUM(amount * probability / 100) as forecasted_amount").
+	forecast, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).
 		Where("company_id = ? AND stage NOT IN (?, ?) AND expected_close_date IS NOT NULL AND expected_close_date <= DATE_ADD(CURDATE(), INTERVAL ? MONTH)",
-			companyId, "won", "lost", months).
-		Group("YEAR(expected_close_date), MONTH(expected_close_date)").
-		Order("year, month").
-		Find(&results).Error; err != nil {
+			companyId, "won", "lost", months),
+		"DATE_FORMAT(deals.expected_close_date, '%Y-%m')", "deals.amount * deals.probability / 100", "CURDATE()")
+	if err != nil {
 		return nil, err
 	}
 
 	var output []map[string]interface{}
-	for _, result := range results {
-		// Format as YYYY-MM
-		date := time.Date(result.Year, time.Month(result.Month), 1, 0, 0, 0, 0, time.UTC)
-		key := date.Format("2006-01")
+	for _, period := range forecast.groups {
+		// Periods are formatted as YYYY-MM
 		output = append(output, map[string]interface{}{
-			"period": key,
-			"amount": result.ForecastedAmount,
+			"period":             period,
+			"amount":             forecast.total(period),
+			"currency":           conversion.currency,
+			"amount_by_currency": forecast.byCurrency(period),
 		})
 	}
 
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetDealContacts returns a deal's buying committee with each contact, primary contact first.
+// Deleted contacts are left out.
+func (r *gormDealRepository) GetDealContacts(dealID int) ([]models.DealContact, error) {
+	var dealContacts []models.DealContact
+	err := r.db.InnerJoins("Contact").
+		Where("deal_contacts.deal_id = ?", dealID).
+		Order("deal_contacts.is_primary DESC, Contact.name").
+		Find(&dealContacts).Error
+	return dealContacts, err
+}
+
+// GetDealContact returns a contact's place on a deal's buying committee, or nil if it has none
+func (r *gormDealRepository) GetDealContact(dealID int, contactID int) (*models.DealContact, error) {
+	var dealContact models.DealContact
+	err := r.db.Where("deal_id = ? AND contact_id = ?", dealID, contactID).First(&dealContact).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dealContact, nil
+}
+
+// SaveDealContact adds a contact to a deal's buying committee or updates its role. Making it the
+// primary contact demotes the deal's previous primary contact.
+func (r *gormDealRepository) SaveDealContact(dealContact *models.DealContact) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if dealContact.IsPrimary {
+			if err := tx.Model(&models.DealContact{}).
+				Where("deal_id = ? AND contact_id <> ? AND is_primary = ?", dealContact.DealID, dealContact.ContactID, true).
+				Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+		if dealContact.ID == 0 {
+			return tx.Omit("Contact").Create(dealContact).Error
+		}
+		return tx.Omit("Contact", "CreatedAt").Save(dealContact).Error
+	})
+}
+
+// DeleteDealContact removes a contact from a deal's buying committee
+func (r *gormDealRepository) DeleteDealContact(dealContact *models.DealContact) error {
+	return r.db.Delete(dealContact).Error
+}
+
+// GetContactDeals returns the deals a contact is on the buying committee of, newest first
+func (r *gormDealRepository) GetContactDeals(contactID int) ([]models.Deal, error) {
+	var deals []models.Deal
+	err := r.db.Where("id IN (?)", r.db.Model(&models.DealContact{}).Select("deal_id").Where("contact_id = ?", contactID)).
+		Order("created_at DESC").
+		Find(&deals).Error
+	return deals, err
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GetLineItems returns a deal's line items in order
+func (r *gormDealRepository) GetLineItems(dealID int) ([]models.DealLineItem, error) {
+	var items []models.DealLineItem
+	err := r.db.Where("deal_id = ?", dealID).Order("position, id").Find(&items).Error
+	return items, err
+}
+
+// GetLineItemByID returns a line item by ID
+func (r *gormDealRepository) GetLineItemByID(id int) (*models.DealLineItem, error) {
+	var item models.DealLineItem
+	err := r.db.First(&item, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SaveLineItem calculates and stores a line item, then recomputes the deal's amount. It returns
+// the updated deal.
+func (r *gormDealRepository) SaveLineItem(item *models.DealLineItem) (*models.Deal, error) {
+	calculateLineItem(item)
+
+	var deal models.Deal
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if item.ID == 0 {
+			// The amount entered for the deal is kept aside when it gets its first line item
+			if err := tx.Model(&models.Deal{}).
+				Where("id = ? AND NOT EXISTS (SELECT 1 FROM deal_line_items WHERE deal_id = ?)", item.DealID, item.DealID).
+				Update("manual_amount", gorm.Expr("amount")).Error; err != nil {
+				return err
+			}
+			err = tx.Create(item).Error
+		} else {
+			err = tx.Omit("CreatedAt").Save(item).Error
+		}
+		if err != nil {
+			return err
+		}
+		return recalculateDealAmount(tx, item.DealID, &deal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deal, nil
+}
+
+// DeleteLineItem removes a line item and recomputes the deal's amount. It returns the updated deal.
+func (r *gormDealRepository) DeleteLineItem(item *models.DealLineItem) (*models.Deal, error) {
+	var deal models.Deal
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.DealLineItem{}, item.ID).Error; err != nil {
+			return err
+		}
+		return recalculateDealAmount(tx, item.DealID, &deal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deal, nil
+}
+
+// calculateLineItem sets an item's subtotal, tax and total from its quantity, unit price,
+// discount and tax
+func calculateLineItem(item *models.DealLineItem) {
+	gross := item.Quantity * item.UnitPrice
+	item.Subtotal = roundCents(gross * (1 - item.DiscountPercent/100))
+	item.TaxAmount = roundCents(item.Subtotal * item.TaxPercent / 100)
+	item.Total = roundCents(item.Subtotal + item.TaxAmount)
+}
+
+// roundCents rounds an amount to two decimal places, half away from zero. It rounds the shortest
+// decimal form of the amount, so 1.005 rounds up to 1.01 even though its float value is just below it.
+func roundCents(amount float64) float64 {
+	whole, fraction, _ := strings.Cut(strconv.FormatFloat(math.Abs(amount), 'f', -1, 64), ".")
+	fraction += "000"
+	cents, err := strconv.ParseFloat(whole+fraction[:2], 64)
+	if err != nil {
+		return amount
+	}
+	if fraction[2] >= '5' {
+		cents++
+	}
+	return math.Copysign(cents/100, amount)
+}
+
+// recalculateDealAmount sets a deal's amount to the sum of its line item subtotals, or back to its
+// manual amount once it has none, and loads the deal into deal, recording DealUpdated for the
+// change to its items
+func recalculateDealAmount(tx *gorm.DB, dealID int, deal *models.Deal) error {
+	amount, hasItems, err := lineItemsAmount(tx, dealID)
+	if err != nil {
+		return err
+	}
+	var value interface{} = amount
+	if !hasItems {
+		value = gorm.Expr("manual_amount")
+	}
+	if err := tx.Model(&models.Deal{}).Where("id = ?", dealID).Update("amount", value).Error; err != nil {
+		return err
+	}
+	if err := tx.First(deal, dealID).Error; err != nil {
+		return err
+	}
+	if err := recordDomainEvent(tx, models.EventDealUpdated, deal.ID, deal.CompanyId, dealEvent(deal)); err != nil {
+		return err
+	}
+	return refreshDealTargets(tx, deal.CompanyId, deal.CreatedAt, false, deal.Stage)
+}
+
+// lineItemsAmount returns the sum of a deal's line item subtotals and whether it has any
+func lineItemsAmount(db *gorm.DB, dealID int) (float64, bool, error) {
+	var result struct {
+		Items  int64
+		Amount float64
+	}
+	if err := db.Model(&models.DealLineItem{}).
+		Select("COUNT(*) as items, COALESCE(SUM(subtotal), 0) as amount").
+		Where("deal_id = ?", dealID).
+		Scan(&result).Error; err != nil {
+		return 0, false, err
+	}
+	return result.Amount, result.Items > 0, nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCalculateLineItem(t *testing.T) {
+	tests := []struct {
+		name          string
+		item          models.DealLineItem
+		wantSubtotal  float64
+		wantTaxAmount float64
+		wantTotal     float64
+	}{
+		{"quantity times price", models.DealLineItem{Quantity: 3, UnitPrice: 19.99}, 59.97, 0, 59.97},
+		{"discount", models.DealLineItem{Quantity: 2, UnitPrice: 100, DiscountPercent: 15}, 170, 0, 170},
+		{"tax on the discounted subtotal", models.DealLineItem{Quantity: 1, UnitPrice: 200, DiscountPercent: 10, TaxPercent: 20}, 180, 36, 216},
+		{"fractional quantity", models.DealLineItem{Quantity: 2.5, UnitPrice: 9.99}, 24.98, 0, 24.98},
+		{"subtotal rounded to cents", models.DealLineItem{Quantity: 1, UnitPrice: 10, DiscountPercent: 33.33}, 6.67, 0, 6.67},
+		{"tax rounded to cents", models.DealLineItem{Quantity: 1, UnitPrice: 10.01, TaxPercent: 7.5}, 10.01, 0.75, 10.76},
+		{"cents add up exactly", models.DealLineItem{Quantity: 1, UnitPrice: 0.1, TaxPercent: 200}, 0.1, 0.2, 0.3},
+		{"full discount", models.DealLineItem{Quantity: 4, UnitPrice: 50, DiscountPercent: 100, TaxPercent: 20}, 0, 0, 0},
+		{"zero quantity", models.DealLineItem{Quantity: 0, UnitPrice: 50, TaxPercent: 20}, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			calculateLineItem(&item)
+			if item.Subtotal != tt.wantSubtotal || item.TaxAmount != tt.wantTaxAmount || item.Total != tt.wantTotal {
+				t.Errorf("got subtotal %v, tax %v, total %v; want %v, %v, %v",
+					item.Subtotal, item.TaxAmount, item.Total, tt.wantSubtotal, tt.wantTaxAmount, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestRoundCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   float64
+	}{
+		{0, 0},
+		{1.234, 1.23},
+		{1.235, 1.24},
+		{1.005, 1.01},
+		{0.285, 0.29},
+		{-2.345, -2.35},
+		{1999.999, 2000},
+	}
+
+	for _, tt := range tests {
+		if got := roundCents(tt.amount); got != tt.want {
+			t.Errorf("roundCents(%v) = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteLastLineItemRestoresManualAmount(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormDealRepository{db: db}
+	createdAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `deal_line_items` WHERE `deal_line_items`.`id` = \\?").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) as items, COALESCE\\(SUM\\(subtotal\\), 0\\) as amount FROM `deal_line_items` WHERE deal_id = \\?").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"items", "amount"}).AddRow(0, 0))
+	// With no items left the deal goes back to the amount entered for it
+	mock.ExpectExec("UPDATE `deals` SET `amount`=manual_amount,`updated_at`=\\? WHERE id = \\? AND `deals`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM `deals` WHERE `deals`.`id` = \\? AND `deals`.`deleted_at` IS NULL ORDER BY `deals`.`id` LIMIT 1").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stage", "amount", "manual_amount", "created_at", "company_id"}).
+			AddRow(9, "proposal", 1200, 1200, createdAt, 1))
+	mock.ExpectExec("INSERT INTO `outbox_events`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	deal, err := repo.DeleteLineItem(&models.DealLineItem{ID: 5, DealID: 9})
+	if err != nil {
+		t.Fatalf("DeleteLineItem error: %v", err)
+	}
+	if deal.ID != 9 || deal.Amount != 1200 {
+		t.Errorf("deal = %+v, want deal 9 with its manual amount of 1200", deal)
+	}
+}
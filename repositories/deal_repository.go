@@ -3,6 +3,7 @@ package repositories
 import (
 	"crm-app/backend/models"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -27,11 +28,17 @@ func (r *gormDealRepository) FindByID(id int) (*models.Deal, error) {
 		}
 		return nil, result.Error
 	}
+
+	tags, err := loadRecordTags(r.db, models.TagEntityDeal, []int{deal.ID})
+	if err != nil {
+		return nil, err
+	}
+	deal.Tags = tags[deal.ID]
 	return &deal, nil
 }
 
-// List returns deals with pagination and filters
-func (r *gormDealRepository) List(offset int, limit int, filters map[string]interface{}, companyId int) ([]models.Deal, error) {
+// List returns deals with pagination and filters, optionally only those matching a tag filter
+func (r *gormDealRepository) List(offset int, limit int, filters map[string]interface{}, tags *models.TagFilter, companyId int) ([]models.Deal, error) {
 	var deals []models.Deal
 	query := r.db
 
@@ -41,6 +48,9 @@ func (r *gormDealRepository) List(offset int, limit int, filters map[string]inte
 			query = query.Where(key+" = ?", value)
 		}
 	}
+	if tags != nil {
+		query = query.Where("id IN (?)", taggedRecords(r.db, models.TagEntityDeal, *tags, companyId))
+	}
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -52,6 +62,18 @@ func (r *gormDealRepository) List(offset int, limit int, filters map[string]inte
 	if err := query.Where("company_id=?", companyId).Find(&deals).Error; err != nil {
 		return nil, err
 	}
+
+	ids := make([]int, len(deals))
+	for i := range deals {
+		ids[i] = deals[i].ID
+	}
+	dealTags, err := loadRecordTags(r.db, models.TagEntityDeal, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deals {
+		deals[i].Tags = dealTags[deals[i].ID]
+	}
 	return deals, nil
 }
 
@@ -66,31 +88,209 @@ func (r *gormDealRepository) FindByLead(leadID int) ([]models.Deal, error) {
 
 // Create creates a new deal
 func (r *gormDealRepository) Create(deal *models.Deal) error {
-	return r.db.Create(deal).Error
+	deal.ManualAmount = deal.Amount
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(deal).Error; err != nil {
+			return err
+		}
+		if _, err := addRecordTags(tx, models.TagEntityDeal, deal.ID, deal.Tags, deal.CompanyId); err != nil {
+			return err
+		}
+		if err := recordDomainEvent(tx, models.EventDealCreated, deal.ID, deal.CompanyId, dealEvent(deal)); err != nil {
+			return err
+		}
+		if err := recordDealChangeEvents(tx, "", deal); err != nil {
+			return err
+		}
+		return refreshDealTargets(tx, deal.CompanyId, deal.CreatedAt, true, deal.Stage)
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeDeal, deal.ID)
+	return nil
+}
+
+// dealEvent returns the payload of a deal's domain events
+func dealEvent(deal *models.Deal) models.DealEvent {
+	return models.DealEvent{
+		DealID:     deal.ID,
+		LeadID:     deal.LeadID,
+		Stage:      deal.Stage,
+		Amount:     deal.Amount,
+		Currency:   deal.Currency,
+		AssignedTo: deal.AssignedTo,
+	}
+}
+
+// dealEventColumns are the deal columns its events are made from
+const dealEventColumns = "id, lead_id, stage, amount, currency, assigned_to, created_at, company_id"
+
+// findDealForEvent reads the deal columns its events carry, returning a deal with no ID if there
+// is no such deal
+func findDealForEvent(tx *gorm.DB, id int) (*models.Deal, error) {
+	var deal models.Deal
+	err := tx.Select(dealEventColumns).Where("id = ?", id).Limit(1).Find(&deal).Error
+	return &deal, err
+}
+
+// recordDealChangeEvents records the domain events of a deal moving from a stage, empty for a new
+// deal, to its current one: DealStageChanged for an existing deal, and DealWon when it is won
+func recordDealChangeEvents(tx *gorm.DB, previousStage string, deal *models.Deal) error {
+	if deal.Stage == previousStage {
+		return nil
+	}
+	event := dealEvent(deal)
+	event.PreviousStage = previousStage
+	if previousStage != "" {
+		if err := recordDomainEvent(tx, models.EventDealStageChanged, deal.ID, deal.CompanyId, event); err != nil {
+			return err
+		}
+	}
+	if isWonStage(deal.Stage) && !isWonStage(previousStage) {
+		return recordDomainEvent(tx, models.EventDealWon, deal.ID, deal.CompanyId, event)
+	}
+	return nil
+}
+
+// refreshDealTargets refreshes the targets a change to a deal created at a time can move: deal
+// count targets when it is created or deleted, and revenue targets when it is or was won
+func refreshDealTargets(tx *gorm.DB, companyId int, createdAt time.Time, createdOrDeleted bool, stages ...string) error {
+	var targetTypes []string
+	if createdOrDeleted {
+		targetTypes = append(targetTypes, "deals")
+	}
+	for _, stage := range stages {
+		if isWonStage(stage) {
+			targetTypes = append(targetTypes, "revenue")
+			break
+		}
+	}
+	if len(targetTypes) == 0 {
+		return nil
+	}
+	return refreshTargets(tx, companyId, createdAt, targetTypes...)
+}
+
+// isWonStage reports whether a deal stage is a won one
+func isWonStage(stage string) bool {
+	for _, won := range models.DealStagesWon {
+		if stage == won {
+			return true
+		}
+	}
+	return false
 }
 
-// Update updates an existing deal
+// Update updates an existing deal. A deal with line items keeps the amount computed from them;
+// otherwise the amount given is also its manual amount. Its tags are replaced unless Tags is nil.
 func (r *gormDealRepository) Update(deal *models.Deal) error {
-	return r.db.Omit("CreatedAt").Save(deal).Error
+	amount, hasItems, err := lineItemsAmount(r.db, deal.ID)
+	if err != nil {
+		return err
+	}
+	// The amount entered is kept aside while the deal has line items
+	omit := []string{"CreatedAt"}
+	if hasItems {
+		deal.Amount = amount
+		omit = append(omit, "ManualAmount")
+	} else {
+		deal.ManualAmount = deal.Amount
+	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := findDealForEvent(tx, deal.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit(omit...).Save(deal).Error; err != nil {
+			return err
+		}
+		if previous.ID != 0 {
+			if err := recordDomainEvent(tx, models.EventDealUpdated, deal.ID, deal.CompanyId, dealEvent(deal)); err != nil {
+				return err
+			}
+			if err := recordDealChangeEvents(tx, previous.Stage, deal); err != nil {
+				return err
+			}
+			if err := refreshDealTargets(tx, deal.CompanyId, previous.CreatedAt, false, previous.Stage, deal.Stage); err != nil {
+				return err
+			}
+		}
+		if deal.Tags == nil {
+			return nil
+		}
+		return setRecordTags(tx, models.TagEntityDeal, deal.ID, deal.Tags, deal.CompanyId)
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeDeal, deal.ID)
+	return nil
 }
 
 // Delete deletes a deal
 func (r *gormDealRepository) Delete(id int) error {
-	return r.db.Delete(&models.Deal{}, id).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		deal, err := findDealForEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Deal{}, id).Error; err != nil {
+			return err
+		}
+		if deal.ID == 0 {
+			return nil
+		}
+		if err := recordDomainEvent(tx, models.EventDealDeleted, id, deal.CompanyId, dealEvent(deal)); err != nil {
+			return err
+		}
+		return refreshDealTargets(tx, deal.CompanyId, deal.CreatedAt, true, deal.Stage)
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeDeal, id)
+	return nil
 }
 
-// GetDealPipeline returns the deal pipeline statistics
+// AddTags tags a deal, returning how many of the tags it did not have already
+func (r *gormDealRepository) AddTags(dealID int, tags []string, companyId int) (int, error) {
+	var added int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if added, err = addRecordTags(tx, models.TagEntityDeal, dealID, tags, companyId); err != nil || added == 0 {
+			return err
+		}
+		return recordUpdatedEvents(tx, models.AggregateDeal, []int{dealID})
+	})
+	return added, err
+}
+
+// RemoveTags removes tags from a deal, returning how many of them it had
+func (r *gormDealRepository) RemoveTags(dealID int, tags []string) (int64, error) {
+	var removed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if removed, err = removeRecordTags(tx, models.TagEntityDeal, dealID, tags); err != nil || removed == 0 {
+			return err
+		}
+		return recordUpdatedEvents(tx, models.AggregateDeal, []int{dealID})
+	})
+	return removed, err
+}
+
+// GetDealPipeline returns the deal pipeline statistics, with stage values in the company's
+// reporting currency
 func (r *gormDealRepository) GetDealPipeline(companyId int) ([]map[string]interface{}, error) {
 	type PipelineStage struct {
-		Stage      string  `json:"stage"`
-		Count      int     `json:"count"`
-		TotalValue float64 `json:"total_value"`
+		Stage string `json:"stage"`
+		Count int    `json:"count"`
 	}
 
 	var results []PipelineStage
 
 	if err := r.db.Model(&models.Deal{}).
-		Select("stage, COUNT(*) as count, SUM(amount) as total_value").
+		Select("stage, COUNT(*) as count").
 		Where("company_id = ?", companyId).
 		Group("stage").
 		Order("FIELD(stage, 'lead', 'qualified', 'proposal', 'negotiation', 'closed_won', 'closed_lost')").
@@ -98,13 +298,25 @@ func (r *gormDealRepository) GetDealPipeline(companyId int) ([]map[string]interf
 		return nil, err
 	}
 
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	values, err := conversion.sumDealAmounts(r.db.Model(&models.Deal{}).Where("company_id = ?", companyId),
+		"deals.stage", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to map[string]interface{} for flexibility
 	pipeline := make([]map[string]interface{}, len(results))
 	for i, stage := range results {
 		pipeline[i] = map[string]interface{}{
-			"stage":       stage.Stage,
-			"count":       stage.Count,
-			"total_value": stage.TotalValue,
+			"stage":             stage.Stage,
+			"count":             stage.Count,
+			"total_value":       values.total(stage.Stage),
+			"currency":          conversion.currency,
+			"value_by_currency": values.byCurrency(stage.Stage),
 		}
 	}
 
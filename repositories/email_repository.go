@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetSenderIdentities returns a company's sender identities
+func (r *gormEmailRepository) GetSenderIdentities(companyId int) ([]models.EmailSenderIdentity, error) {
+	var identities []models.EmailSenderIdentity
+	err := r.db.Where("company_id = ?", companyId).Order("is_default desc, name").Find(&identities).Error
+	return identities, err
+}
+
+// GetSenderIdentityByID returns a sender identity by ID
+func (r *gormEmailRepository) GetSenderIdentityByID(id int) (*models.EmailSenderIdentity, error) {
+	var identity models.EmailSenderIdentity
+	err := r.db.First(&identity, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetDefaultSenderIdentity returns a company's default sender identity, falling back to its first one
+func (r *gormEmailRepository) GetDefaultSenderIdentity(companyId int) (*models.EmailSenderIdentity, error) {
+	var identity models.EmailSenderIdentity
+	err := r.db.Where("company_id = ?", companyId).Order("is_default desc, id").First(&identity).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateSenderIdentity creates a sender identity, clearing the previous default if it is the new default
+func (r *gormEmailRepository) CreateSenderIdentity(identity *models.EmailSenderIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if identity.IsDefault {
+			if err := clearDefaultSenderIdentity(tx, identity.CompanyId); err != nil {
+				return err
+			}
+		}
+		return tx.Create(identity).Error
+	})
+}
+
+// UpdateSenderIdentity updates a sender identity, clearing the previous default if it is the new default
+func (r *gormEmailRepository) UpdateSenderIdentity(identity *models.EmailSenderIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if identity.IsDefault {
+			if err := clearDefaultSenderIdentity(tx, identity.CompanyId); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("CreatedAt").Save(identity).Error
+	})
+}
+
+func clearDefaultSenderIdentity(tx *gorm.DB, companyId int) error {
+	return tx.Model(&models.EmailSenderIdentity{}).
+		Where("company_id = ? AND is_default = ?", companyId, true).
+		Update("is_default", false).Error
+}
+
+// DeleteSenderIdentity deletes a sender identity
+func (r *gormEmailRepository) DeleteSenderIdentity(id int) error {
+	return r.db.Delete(&models.EmailSenderIdentity{}, id).Error
+}
+
+// GetMessages returns a company's email messages, newest first
+func (r *gormEmailRepository) GetMessages(offset int, limit int, filters map[string]interface{}, companyId int) ([]models.EmailMessage, error) {
+	var messages []models.EmailMessage
+	query := r.db.Where("company_id = ?", companyId)
+
+	for key, value := range filters {
+		query = query.Where(key+" = ?", value)
+	}
+
+	err := query.Order("created_at desc").Offset(offset).Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// GetMessageByID returns an email message by ID
+func (r *gormEmailRepository) GetMessageByID(id int) (*models.EmailMessage, error) {
+	var message models.EmailMessage
+	err := r.db.First(&message, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindMessageByProviderID returns the message the provider knows by the given ID
+func (r *gormEmailRepository) FindMessageByProviderID(providerMessageID string) (*models.EmailMessage, error) {
+	var message models.EmailMessage
+	err := r.db.Where("provider_message_id = ?", providerMessageID).First(&message).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindLatestMessageTo returns a company's most recent sent message to an address
+func (r *gormEmailRepository) FindLatestMessageTo(email string, companyId int) (*models.EmailMessage, error) {
+	var message models.EmailMessage
+	err := r.db.Where("to_email = ? AND company_id = ? AND sent_at IS NOT NULL", email, companyId).Order("sent_at desc").First(&message).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// QueueMessage stores a message for the send queue to deliver
+func (r *gormEmailRepository) QueueMessage(message *models.EmailMessage) error {
+	now := time.Now()
+	message.Status = models.EmailStatusQueued
+	if message.NextAttemptAt == nil {
+		message.NextAttemptAt = &now
+	}
+	if message.MaxAttempts == 0 {
+		message.MaxAttempts = 5
+	}
+	return r.db.Create(message).Error
+}
+
+// UpdateMessage updates an email message
+func (r *gormEmailRepository) UpdateMessage(message *models.EmailMessage) error {
+	return r.db.Omit("CreatedAt").Save(message).Error
+}
+
+// ClaimDueMessages leases queued messages that are due for a delivery attempt to a single worker
+func (r *gormEmailRepository) ClaimDueMessages(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]models.EmailMessage, error) {
+	candidates := r.db.Model(&models.EmailMessage{}).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at")
+	return claimLeased[models.EmailMessage](r.db, candidates, models.EmailStatusQueued, workerID, now, leaseUntil, limit)
+}
+
+// ReleaseMessage saves the outcome of a delivery attempt and gives up the worker's lease
+func (r *gormEmailRepository) ReleaseMessage(message *models.EmailMessage, workerID string) error {
+	message.LockedBy = ""
+	message.LockedUntil = nil
+	return r.db.Model(&models.EmailMessage{}).
+		Where("id = ? AND locked_by = ?", message.ID, workerID).
+		Updates(map[string]interface{}{
+			"status":              message.Status,
+			"attempts":            message.Attempts,
+			"next_attempt_at":     message.NextAttemptAt,
+			"last_error":          message.LastError,
+			"provider_message_id": message.ProviderMessageID,
+			"sent_at":             message.SentAt,
+			"locked_by":           "",
+			"locked_until":        nil,
+		}).Error
+}
+
+// CancelQueuedMessages cancels a campaign's messages that have not been sent yet
+func (r *gormEmailRepository) CancelQueuedMessages(campaignID int) (int64, error) {
+	result := r.db.Model(&models.EmailMessage{}).
+		Where("campaign_id = ? AND status = ?", campaignID, models.EmailStatusQueued).
+		Updates(map[string]interface{}{"status": models.EmailStatusCancelled, "next_attempt_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// RecordEvent stores an open or click event
+func (r *gormEmailRepository) RecordEvent(event *models.EmailEvent) error {
+	return r.db.Create(event).Error
+}
+
+// emailStatsScopes maps a stats scope to the condition selecting its messages
+var emailStatsScopes = map[string]string{
+	"campaign": "m.campaign_id = ?",
+	"template": "m.template_id = ?",
+	"sequence": "m.step_id IN (SELECT id FROM nurture_steps WHERE sequence_id = ?)",
+	"ab_test":  "m.variant_id IN (SELECT id FROM email_variants WHERE test_id = ?)",
+}
+
+// emailStatsGroups are the columns stats can be broken down by
+var emailStatsGroups = map[string]string{
+	"template_id": "m.template_id",
+	"step_id":     "m.step_id",
+	"variant_id":  "m.variant_id",
+}
+
+// GetEmailStats returns delivery and engagement counts for the messages of a campaign, template
+// sequence or A/B test, optionally grouped by template_id, step_id or variant_id. Bot events are excluded.
+func (r *gormEmailRepository) GetEmailStats(scope string, scopeID int, groupBy string) ([]models.EmailStats, error) {
+	return queryEmailStats(r.db, scope, scopeID, groupBy)
+}
+
+func queryEmailStats(db *gorm.DB, scope string, scopeID int, groupBy string) ([]models.EmailStats, error) {
+	condition, ok := emailStatsScopes[scope]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stats scope: %s", scope)
+	}
+
+	groupColumn := "NULL"
+	if groupBy != "" {
+		if groupColumn, ok = emailStatsGroups[groupBy]; !ok {
+			return nil, fmt.Errorf("unsupported stats grouping: %s", groupBy)
+		}
+	}
+
+	events := db.Table("email_events").
+		Select("message_id, SUM(CASE WHEN type = 'open' THEN 1 ELSE 0 END) AS opens, SUM(CASE WHEN type = 'click' THEN 1 ELSE 0 END) AS clicks").
+		Where("is_bot = ?", false).
+		Group("message_id")
+
+	query := db.Table("email_messages AS m").
+		Select(groupColumn+" AS group_id, "+
+			"SUM(CASE WHEN m.sent_at IS NOT NULL THEN 1 ELSE 0 END) AS sent, "+
+			"SUM(CASE WHEN m.sent_at IS NOT NULL AND m.status <> ? THEN 1 ELSE 0 END) AS delivered, "+
+			"SUM(CASE WHEN m.status = ? THEN 1 ELSE 0 END) AS bounced, "+
+			"SUM(CASE WHEN m.status = ? THEN 1 ELSE 0 END) AS complained, "+
+			"COALESCE(SUM(ev.opens), 0) AS opens, "+
+			"COALESCE(SUM(ev.clicks), 0) AS clicks, "+
+			// A click implies the message was opened even when images were blocked
+			"SUM(CASE WHEN ev.opens > 0 OR ev.clicks > 0 THEN 1 ELSE 0 END) AS unique_opens, "+
+			"SUM(CASE WHEN ev.clicks > 0 THEN 1 ELSE 0 END) AS unique_clicks",
+			models.EmailStatusBounced, models.EmailStatusBounced, models.EmailStatusComplained).
+		Joins("LEFT JOIN (?) AS ev ON ev.message_id = m.id", events).
+		Where(condition, scopeID)
+	if groupBy != "" {
+		query = query.Group(groupColumn)
+	}
+
+	var stats []models.EmailStats
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		if s.Delivered > 0 {
+			s.OpenRate = float64(s.UniqueOpens) / float64(s.Delivered) * 100
+			s.ClickRate = float64(s.UniqueClicks) / float64(s.Delivered) * 100
+		}
+		if s.UniqueOpens > 0 {
+			s.ClickToOpenRate = float64(s.UniqueClicks) / float64(s.UniqueOpens) * 100
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClaimDueMessages(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormEmailRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	leaseUntil := now.Add(time.Minute)
+
+	mock.ExpectQuery("SELECT `id` FROM `email_messages` WHERE next_attempt_at <= \\? "+
+		"AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\) .*ORDER BY next_attempt_at LIMIT 20").
+		WithArgs(now, models.EmailStatusQueued, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(5))
+	// another worker sent the first message between the select and the claim
+	expectLeaseClaim(mock, "email_messages", 4, models.EmailStatusQueued, "worker-a", now, leaseUntil, 0)
+	expectLeaseClaim(mock, "email_messages", 5, models.EmailStatusQueued, "worker-a", now, leaseUntil, 1)
+	mock.ExpectQuery("SELECT \\* FROM `email_messages` WHERE `email_messages`.`id` = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(5, models.EmailStatusQueued, "worker-a"))
+
+	claimed, err := repo.ClaimDueMessages("worker-a", now, leaseUntil, 20)
+	if err != nil {
+		t.Fatalf("ClaimDueMessages error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 5 {
+		t.Errorf("claimed = %+v, want message 5", claimed)
+	}
+}
@@ -0,0 +1,252 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetExchangeRates returns a company's exchange rates, newest first, optionally for one currency
+func (r *gormExchangeRateRepository) GetExchangeRates(offset int, limit int, currency string, companyId int) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	query := r.db.Where("company_id = ?", companyId)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+	err := query.Order("effective_date DESC, currency").Offset(offset).Limit(limit).Find(&rates).Error
+	return rates, err
+}
+
+// GetExchangeRateByID returns an exchange rate by ID
+func (r *gormExchangeRateRepository) GetExchangeRateByID(id int) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := r.db.First(&rate, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// CreateExchangeRate creates a new exchange rate
+func (r *gormExchangeRateRepository) CreateExchangeRate(rate *models.ExchangeRate) error {
+	return r.db.Create(rate).Error
+}
+
+// UpdateExchangeRate updates an exchange rate
+func (r *gormExchangeRateRepository) UpdateExchangeRate(rate *models.ExchangeRate) error {
+	return r.db.Omit("CreatedAt").Save(rate).Error
+}
+
+// DeleteExchangeRate deletes an exchange rate
+func (r *gormExchangeRateRepository) DeleteExchangeRate(id int) error {
+	return r.db.Delete(&models.ExchangeRate{}, id).Error
+}
+
+// ImportExchangeRates stores a batch of rates in one transaction, replacing the rate of any
+// currency already recorded for the same base currency and date
+func (r *gormExchangeRateRepository) ImportExchangeRates(rates []models.ExchangeRate) (*models.ExchangeRateImportResult, error) {
+	result := &models.ExchangeRateImportResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range rates {
+			rate := &rates[i]
+			var existing models.ExchangeRate
+			err := tx.Where("company_id = ? AND base_currency = ? AND currency = ? AND effective_date = ?",
+				rate.CompanyId, rate.BaseCurrency, rate.Currency, rate.EffectiveDate.Format("2006-01-02")).
+				First(&existing).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+
+			if err == gorm.ErrRecordNotFound {
+				if err := tx.Create(rate).Error; err != nil {
+					return err
+				}
+				result.Created++
+				continue
+			}
+
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"rate":   rate.Rate,
+				"source": rate.Source,
+			}).Error; err != nil {
+				return err
+			}
+			*rate = existing
+			result.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetRate returns the rate converting a currency into a base currency on a date, or nil if the
+// company has no rate for the currency
+func (r *gormExchangeRateRepository) GetRate(companyId int, baseCurrency string, currency string, date time.Time) (*models.ExchangeRate, error) {
+	return exchangeRateOn(r.db, companyId, baseCurrency, currency, date)
+}
+
+// exchangeRateOn returns the latest rate effective on a date, or the earliest later rate when
+// none is
+func exchangeRateOn(db *gorm.DB, companyId int, baseCurrency string, currency string, date time.Time) (*models.ExchangeRate, error) {
+	day := date.Format("2006-01-02")
+	var rate models.ExchangeRate
+	err := db.Where("company_id = ? AND base_currency = ? AND currency = ?", companyId, baseCurrency, currency).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "effective_date > ?, CASE WHEN effective_date <= ? THEN effective_date END DESC, effective_date",
+			Vars:               []interface{}{day, day},
+			WithoutParentheses: true,
+		}}).
+		Take(&rate).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// currencyConversion builds SQL converting deal amounts into a company's reporting currency.
+// Amounts in the reporting currency are used as they are; others are multiplied by the company's
+// rate for their currency on the given date, and are NULL when there is none.
+type currencyConversion struct {
+	companyId int
+	currency  string
+}
+
+// reportingCurrency returns the conversion into a company's base currency
+func reportingCurrency(db *gorm.DB, companyId int) (*currencyConversion, error) {
+	var currencies []string
+	if err := db.Model(&models.CompanySettings{}).Where("company_id = ?", companyId).
+		Limit(1).Pluck("base_currency", &currencies).Error; err != nil {
+		return nil, err
+	}
+
+	currency := models.DefaultCurrency
+	if len(currencies) > 0 && currencies[0] != "" {
+		currency = strings.ToUpper(currencies[0])
+	}
+	return &currencyConversion{companyId: companyId, currency: currency}, nil
+}
+
+// dealCurrencySQL is a deal's currency, treating a blank currency as the reporting currency
+func (c *currencyConversion) dealCurrencySQL() (string, []interface{}) {
+	return "COALESCE(NULLIF(deals.currency, ''), ?)", []interface{}{c.currency}
+}
+
+// dealAmountSQL converts amountExpr, in the deal's currency, at the rate effective on dateExpr
+func (c *currencyConversion) dealAmountSQL(amountExpr string, dateExpr string) (string, []interface{}) {
+	sql := fmt.Sprintf(`(CASE WHEN COALESCE(NULLIF(deals.currency, ''), ?) = ? THEN %[1]s ELSE %[1]s * (
+		SELECT er.rate FROM exchange_rates er
+		WHERE er.company_id = ? AND er.base_currency = ? AND er.currency = deals.currency
+		ORDER BY er.effective_date > DATE(%[2]s), CASE WHEN er.effective_date <= DATE(%[2]s) THEN er.effective_date END DESC, er.effective_date
+		LIMIT 1) END)`, amountExpr, dateExpr)
+	return sql, []interface{}{c.currency, c.currency, c.companyId, c.currency}
+}
+
+// sumDealAmounts totals amountExpr over the deals a query selects, per groupExpr and deal
+// currency, both as recorded and converted at the rate effective on dateExpr
+func (c *currencyConversion) sumDealAmounts(query *gorm.DB, groupExpr string, amountExpr string, dateExpr string) (*currencyTotals, error) {
+	currencySQL, args := c.dealCurrencySQL()
+	convertedSQL, convertedArgs := c.dealAmountSQL(amountExpr, dateExpr)
+	args = append(args, convertedArgs...)
+
+	var rows []currencyAmountRow
+	if err := query.
+		Select(fmt.Sprintf("%s AS grp, %s AS deal_currency, COALESCE(SUM(%s), 0) AS amount, SUM(%s) AS converted_amount",
+			groupExpr, currencySQL, amountExpr, convertedSQL), args...).
+		Group("grp, deal_currency").
+		Order("grp, deal_currency").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return newCurrencyTotals(c.currency, rows), nil
+}
+
+// currencyAmountRow is a group's deal amount total in one currency, converted into the reporting
+// currency unless a rate is missing
+type currencyAmountRow struct {
+	Grp             string
+	DealCurrency    string
+	Amount          float64
+	ConvertedAmount *float64
+}
+
+// newCurrencyTotals collects rows, ordered by group, into totals in a reporting currency
+func newCurrencyTotals(currency string, rows []currencyAmountRow) *currencyTotals {
+	totals := &currencyTotals{
+		currency:  currency,
+		converted: make(map[string]float64),
+		amounts:   make(map[string][]models.CurrencyAmount),
+	}
+	for _, row := range rows {
+		if _, ok := totals.amounts[row.Grp]; !ok {
+			totals.groups = append(totals.groups, row.Grp)
+		}
+		if row.ConvertedAmount != nil {
+			totals.converted[row.Grp] += *row.ConvertedAmount
+		}
+		totals.amounts[row.Grp] = append(totals.amounts[row.Grp], models.CurrencyAmount{
+			Currency:        strings.ToUpper(row.DealCurrency),
+			Amount:          row.Amount,
+			ConvertedAmount: row.ConvertedAmount,
+		})
+	}
+	return totals
+}
+
+// currencyTotals are deal amount totals per group, converted into the reporting currency and
+// split by original currency. groups lists the groups in order.
+type currencyTotals struct {
+	currency  string
+	groups    []string
+	converted map[string]float64
+	amounts   map[string][]models.CurrencyAmount
+}
+
+// total returns a group's total in the reporting currency; amounts without a rate are left out
+func (t *currencyTotals) total(group string) float64 {
+	return t.converted[group]
+}
+
+// byCurrency returns a group's totals per original currency
+func (t *currencyTotals) byCurrency(group string) []models.CurrencyAmount {
+	if amounts, ok := t.amounts[group]; ok {
+		return amounts
+	}
+	return []models.CurrencyAmount{}
+}
+
+// convertTotal converts a total in the reporting currency into another currency at the rate
+// effective on a date, reporting false and returning the total unchanged when there is no rate
+func (c *currencyConversion) convertTotal(db *gorm.DB, total float64, currency string, date time.Time) (float64, bool, error) {
+	if currency == "" || strings.EqualFold(currency, c.currency) {
+		return total, true, nil
+	}
+
+	rate, err := exchangeRateOn(db, c.companyId, c.currency, strings.ToUpper(currency), date)
+	if err != nil {
+		return 0, false, err
+	}
+	converted, ok := convertAtRate(total, rate)
+	return converted, ok, nil
+}
+
+// convertAtRate converts a total in a rate's base currency into its currency, reporting false
+// and returning the total unchanged when there is no usable rate
+func convertAtRate(total float64, rate *models.ExchangeRate) (float64, bool) {
+	if rate == nil || rate.Rate == 0 {
+		return total, false
+	}
+	return total / rate.Rate, true
+}
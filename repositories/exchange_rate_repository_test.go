@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConvertTotal(t *testing.T) {
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		currency string
+		lookedUp bool
+		rate     float64 // 0 when the company has no rate for the currency
+		want     float64
+		wantOK   bool
+	}{
+		{"reporting currency is not looked up", "eur", false, 0, 250, true},
+		{"no currency is not looked up", "", false, 0, 250, true},
+		{"converted at the rate on the date", "usd", true, 0.5, 500, true},
+		{"no rate leaves the total unchanged", "JPY", true, 0, 250, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			conversion := &currencyConversion{companyId: 1, currency: "EUR"}
+			if tt.lookedUp {
+				// The latest rate effective on the date, or the earliest later one
+				rows := sqlmock.NewRows([]string{"id", "base_currency", "currency", "rate"})
+				if tt.rate != 0 {
+					rows.AddRow(3, "EUR", "USD", tt.rate)
+				}
+				mock.ExpectQuery("SELECT \\* FROM `exchange_rates` WHERE company_id = \\? AND base_currency = \\? AND currency = \\? "+
+					"ORDER BY effective_date > \\?, CASE WHEN effective_date <= \\? THEN effective_date END DESC, effective_date LIMIT 1").
+					WithArgs(1, "EUR", strings.ToUpper(tt.currency), "2024-05-01", "2024-05-01").
+					WillReturnRows(rows)
+			}
+
+			got, ok, err := conversion.convertTotal(db, 250, tt.currency, date)
+			if err != nil || got != tt.want || ok != tt.wantOK {
+				t.Errorf("convertTotal(250, %q) = (%v, %v, %v), want (%v, %v, nil)", tt.currency, got, ok, err, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestConvertAtRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  float64
+		rate   *models.ExchangeRate
+		want   float64
+		wantOK bool
+	}{
+		{"divided by the rate", 250, &models.ExchangeRate{Currency: "USD", Rate: 0.5}, 500, true},
+		{"fractional rate", 100, &models.ExchangeRate{Currency: "GBP", Rate: 1.25}, 80, true},
+		{"missing rate", 250, nil, 250, false},
+		{"zero rate", 250, &models.ExchangeRate{Currency: "USD"}, 250, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convertAtRate(tt.total, tt.rate)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("convertAtRate(%v) = (%v, %v), want (%v, %v)", tt.total, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewCurrencyTotals(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name           string
+		rows           []currencyAmountRow
+		wantGroups     []string
+		wantTotals     map[string]float64
+		wantByCurrency map[string][]models.CurrencyAmount
+	}{
+		{
+			name:           "no rows",
+			wantTotals:     map[string]float64{"won": 0},
+			wantByCurrency: map[string][]models.CurrencyAmount{"won": {}},
+		},
+		{
+			name: "converted amounts summed per group",
+			rows: []currencyAmountRow{
+				{Grp: "lost", DealCurrency: "EUR", Amount: 100, ConvertedAmount: amount(100)},
+				{Grp: "won", DealCurrency: "eur", Amount: 300, ConvertedAmount: amount(300)},
+				{Grp: "won", DealCurrency: "USD", Amount: 200, ConvertedAmount: amount(180)},
+			},
+			wantGroups: []string{"lost", "won"},
+			wantTotals: map[string]float64{"lost": 100, "won": 480},
+			wantByCurrency: map[string][]models.CurrencyAmount{
+				"lost": {{Currency: "EUR", Amount: 100, ConvertedAmount: amount(100)}},
+				"won":  {{Currency: "EUR", Amount: 300, ConvertedAmount: amount(300)}, {Currency: "USD", Amount: 200, ConvertedAmount: amount(180)}},
+			},
+		},
+		{
+			name: "amounts without a rate left out of the total",
+			rows: []currencyAmountRow{
+				{Grp: "", DealCurrency: "EUR", Amount: 50, ConvertedAmount: amount(50)},
+				{Grp: "", DealCurrency: "JPY", Amount: 9000},
+			},
+			wantGroups: []string{""},
+			wantTotals: map[string]float64{"": 50},
+			wantByCurrency: map[string][]models.CurrencyAmount{
+				"": {{Currency: "EUR", Amount: 50, ConvertedAmount: amount(50)}, {Currency: "JPY", Amount: 9000}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := newCurrencyTotals("EUR", tt.rows)
+			if !reflect.DeepEqual(totals.groups, tt.wantGroups) {
+				t.Errorf("groups = %v, want %v", totals.groups, tt.wantGroups)
+			}
+			for group, want := range tt.wantTotals {
+				if got := totals.total(group); got != want {
+					t.Errorf("total(%q) = %v, want %v", group, got, want)
+				}
+			}
+			for group, want := range tt.wantByCurrency {
+				if got := totals.byCurrency(group); !reflect.DeepEqual(got, want) {
+					t.Errorf("byCurrency(%q) = %+v, want %+v", group, got, want)
+				}
+			}
+		})
+	}
+}
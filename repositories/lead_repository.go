@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -165,6 +166,7 @@ func (r *gormLeadRepository) List(companyId int) ([]models.GroupedLead, error) {
 		Select("crm_field_data.submit_id, leads.id as lead_id, crm_field_data.crm_field_id, lead_field_configs.field_name, crm_field_data.field_value").
 		Joins("INNER JOIN crm_field_data ON crm_field_data.submit_id = leads.id").
 		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("leads.deleted_at IS NULL").
 		Scan(&results).Error
 	if err != nil {
 		return nil, err
@@ -238,7 +240,7 @@ func (r *gormLeadRepository) ListByStatus(status string) ([]models.GroupedLead,
 		Select("crm_field_data.submit_id, leads.id as lead_id, crm_field_data.crm_field_id, lead_field_configs.field_name, crm_field_data.field_value").
 		Joins("INNER JOIN crm_field_data ON crm_field_data.submit_id = leads.id").
 		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
-		Where("leads.status = ?", status).
+		Where("leads.status = ? AND leads.deleted_at IS NULL", status).
 		Scan(&results).Error
 
 	if err != nil {
@@ -276,7 +278,7 @@ func (r *gormLeadRepository) ListByAssignee(assigneeID int) ([]models.GroupedLea
 		Select("crm_field_data.submit_id, leads.id as lead_id, crm_field_data.crm_field_id, lead_field_configs.field_name, crm_field_data.field_value").
 		Joins("INNER JOIN crm_field_data ON crm_field_data.submit_id = leads.id").
 		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
-		Where("leads.assigned_to_id = ?", assigneeID).
+		Where("leads.assigned_to_id = ? AND leads.deleted_at IS NULL", assigneeID).
 		Scan(&results).Error
 
 	if err != nil {
@@ -305,9 +307,47 @@ func (r *gormLeadRepository) ListByAssignee(assigneeID int) ([]models.GroupedLea
 	return finalResult, nil
 }
 
-// Create creates a new lead
+// leadEvent returns the payload of a lead's domain events
+func leadEvent(lead *models.Lead) models.LeadEvent {
+	return models.LeadEvent{
+		LeadID:       int(lead.ID),
+		Status:       lead.Status,
+		Source:       lead.Source,
+		AssignedToID: lead.AssignedToID,
+	}
+}
+
+// recordLeadChangeEvents records the domain events of a change to a lead: LeadUpdated, then
+// LeadQualified when it becomes qualified, and LeadAssigned when it is assigned to someone other
+// than before
+func recordLeadChangeEvents(tx *gorm.DB, previous *models.Lead, lead *models.Lead) error {
+	if err := recordDomainEvent(tx, models.EventLeadUpdated, int(lead.ID), lead.CompanyId, leadEvent(lead)); err != nil {
+		return err
+	}
+	if lead.Status == "qualified" && previous.Status != "qualified" {
+		event := leadEvent(lead)
+		event.PreviousStatus = previous.Status
+		if err := recordDomainEvent(tx, models.EventLeadQualified, int(lead.ID), lead.CompanyId, event); err != nil {
+			return err
+		}
+	}
+	if lead.AssignedToID != nil && (previous.AssignedToID == nil || *previous.AssignedToID != *lead.AssignedToID) {
+		event := leadEvent(lead)
+		event.PreviousAssignedToID = previous.AssignedToID
+		if err := recordDomainEvent(tx, models.EventLeadAssigned, int(lead.ID), lead.CompanyId, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create creates the field values of new leads; LeadCreated is recorded with the lead itself
 func (r *gormLeadRepository) Create(lead []models.CrmFieldData) error {
-	return r.db.Create(&lead).Error
+	if err := r.db.Create(&lead).Error; err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeLead, fieldDataLeadIDs(lead)...)
+	return nil
 	// Start a transaction
 	// tx := r.db.Begin()
 	// if tx.Error != nil {
@@ -359,32 +399,31 @@ func (r *gormLeadRepository) Update(lead *models.Lead) error {
 		return tx.Error
 	}
 
-	// Update the lead
-	if err := tx.Save(lead).Error; err != nil {
+	// Read what the lead was, for the events of the change
+	previous, err := findLeadForEvent(tx, int(lead.ID))
+	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Delete existing tags
-	if err := tx.Where("lead_id = ?", lead.ID).Delete(&models.LeadTag{}).Error; err != nil {
+	// Update the lead
+	if err := tx.Save(lead).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
-
-	// Save new tags if provided
-	if len(lead.Tags) > 0 {
-		for _, tag := range lead.Tags {
-			leadTag := models.LeadTag{
-				LeadID: lead.ID,
-				Tag:    tag,
-			}
-			if err := tx.Create(&leadTag).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
+	if previous.ID != 0 {
+		if err := recordLeadChangeEvents(tx, previous, lead); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
+	// Replace the tags
+	if err := setRecordTags(tx, models.TagEntityLead, int(lead.ID), lead.Tags, lead.CompanyId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Delete existing custom fields
 	if err := tx.Where("lead_id = ?", lead.ID).Delete(&models.LeadCustomField{}).Error; err != nil {
 		tx.Rollback()
@@ -403,12 +442,82 @@ func (r *gormLeadRepository) Update(lead *models.Lead) error {
 	}
 
 	// Commit the transaction
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeLead, int(lead.ID))
+	return nil
+}
+
+// leadEventColumns are the lead columns its events are made from
+const leadEventColumns = "id, status, source, assigned_to_id, created_at, company_id"
+
+// findLeadForEvent reads the lead columns its events carry, returning a lead with no ID if there
+// is no such lead
+func findLeadForEvent(tx *gorm.DB, id int) (*models.Lead, error) {
+	var lead models.Lead
+	err := tx.Select(leadEventColumns).Where("id = ?", id).Limit(1).Find(&lead).Error
+	return &lead, err
 }
 
 // Delete deletes a lead
 func (r *gormLeadRepository) Delete(id int) error {
-	return r.db.Delete(&models.Lead{}, id).Error
+	// The lead's field data and nurture enrollments are deleted with it, at the same time, so
+	// that restoring it from the recycle bin restores them too
+	at := deletionTime()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		lead, err := findLeadForEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := softDeleteAt(tx, &models.CrmFieldData{}, at, "submit_id = ?", id); err != nil {
+			return err
+		}
+		if err := softDeleteAt(tx, &models.NurtureEnrollment{}, at, "lead_id = ?", id); err != nil {
+			return err
+		}
+		if err := softDeleteAt(tx, &models.Lead{}, at, "id = ?", id); err != nil {
+			return err
+		}
+		if lead.ID == 0 {
+			return nil
+		}
+		if err := recordDomainEvent(tx, models.EventLeadDeleted, id, lead.CompanyId, leadEvent(lead)); err != nil {
+			return err
+		}
+		return refreshTargets(tx, lead.CompanyId, lead.CreatedAt, "leads")
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeLead, id)
+	return nil
+}
+
+// AddTags tags a lead, returning how many of the tags it did not have already
+func (r *gormLeadRepository) AddTags(leadID int, tags []string, companyId int) (int, error) {
+	var added int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if added, err = addRecordTags(tx, models.TagEntityLead, leadID, tags, companyId); err != nil || added == 0 {
+			return err
+		}
+		return recordUpdatedEvents(tx, models.AggregateLead, []int{leadID})
+	})
+	return added, err
+}
+
+// RemoveTags removes tags from a lead, returning how many of them it had
+func (r *gormLeadRepository) RemoveTags(leadID int, tags []string) (int64, error) {
+	var removed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if removed, err = removeRecordTags(tx, models.TagEntityLead, leadID, tags); err != nil || removed == 0 {
+			return err
+		}
+		return recordUpdatedEvents(tx, models.AggregateLead, []int{leadID})
+	})
+	return removed, err
 }
 
 // ValidateLeadFields validates that all required fields are present in the lead
@@ -474,5 +583,225 @@ func (r *gormLeadRepository) GetLastSubmitId() (int, error) {
 
 func (r *gormLeadRepository) CreateMainLead(lead *models.Lead) error {
 	fmt.Println("inline")
-	return r.db.Create(lead).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(lead).Error; err != nil {
+			return err
+		}
+		if err := recordDomainEvent(tx, models.EventLeadCreated, int(lead.ID), lead.CompanyId, leadEvent(lead)); err != nil {
+			return err
+		}
+		return refreshTargets(tx, lead.CompanyId, lead.CreatedAt, "leads")
+	})
+	if err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeLead, int(lead.ID))
+	return nil
+}
+
+// leadCoreColumns are lead attributes stored on the leads table rather than in crm_field_data
+var leadCoreColumns = map[string]string{
+	"status": "status",
+	"source": "source",
+	"notes":  "notes",
+	"type":   "type",
+}
+
+// GetFieldValues returns a lead's values keyed by field name, merging core columns with EAV fields
+func (r *gormLeadRepository) GetFieldValues(leadID int) (map[string]string, error) {
+	var lead models.Lead
+	if err := r.db.First(&lead, leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return leadFieldValues(r.db, &lead)
+}
+
+// leadFieldValues returns a lead's values keyed by field name, merging its core columns with its
+// EAV fields
+func leadFieldValues(db *gorm.DB, lead *models.Lead) (map[string]string, error) {
+	values := leadValues(lead)
+
+	var results []models.LeadFieldResult
+	if err := db.Table("crm_field_data").
+		Select("crm_field_data.submit_id, crm_field_data.crm_field_id, lead_field_configs.field_name, crm_field_data.field_value").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("crm_field_data.submit_id = ?", lead.ID).
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	mergeLeadFieldValues(values, results)
+
+	return values, nil
+}
+
+// leadValues returns a lead's core columns keyed by field name
+func leadValues(lead *models.Lead) map[string]string {
+	values := map[string]string{
+		"id":      strconv.Itoa(int(lead.ID)),
+		"name":    lead.Name,
+		"email":   lead.Email,
+		"phone":   lead.Phone,
+		"company": lead.Company,
+		"source":  lead.Source,
+		"status":  lead.Status,
+		"type":    lead.Type,
+	}
+	if lead.Score != nil {
+		values["score"] = strconv.Itoa(*lead.Score)
+	}
+	if lead.AssignedToID != nil {
+		values["assigned_to_id"] = strconv.Itoa(int(*lead.AssignedToID))
+	}
+	return values
+}
+
+// mergeLeadFieldValues adds a lead's EAV field values to its core columns, keeping a core value
+// when the field of the same name is blank
+func mergeLeadFieldValues(values map[string]string, results []models.LeadFieldResult) {
+	for _, result := range results {
+		if result.FieldValue != "" || values[result.FieldName] == "" {
+			values[result.FieldName] = result.FieldValue
+		}
+	}
+}
+
+// SetFieldValue sets a single lead field, writing core columns to leads and others to crm_field_data
+func (r *gormLeadRepository) SetFieldValue(leadID int, fieldName string, value string) error {
+	if err := r.setFieldValue(leadID, fieldName, value); err != nil {
+		return err
+	}
+	indexForSearch(r.db, models.SearchTypeLead, leadID)
+	return nil
+}
+
+// setFieldValue writes a lead field and records the change's events, without updating the search
+// index
+func (r *gormLeadRepository) setFieldValue(leadID int, fieldName string, value string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := findLeadForEvent(tx, leadID)
+		if err != nil {
+			return err
+		}
+		if previous.ID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if column, ok := leadCoreColumns[fieldName]; ok {
+			if err := tx.Model(&models.Lead{}).Where("id = ?", leadID).Update(column, value).Error; err != nil {
+				return err
+			}
+			updated := *previous
+			if column == "status" {
+				updated.Status = value
+			} else if column == "source" {
+				updated.Source = value
+			}
+			return recordLeadChangeEvents(tx, previous, &updated)
+		}
+
+		if err := setLeadFieldData(tx, previous, fieldName, value); err != nil {
+			return err
+		}
+		return recordDomainEvent(tx, models.EventLeadUpdated, leadID, previous.CompanyId, leadEvent(previous))
+	})
+}
+
+// setLeadFieldData writes a lead's value of a field stored in crm_field_data
+func setLeadFieldData(tx *gorm.DB, lead *models.Lead, fieldName string, value string) error {
+	var config models.LeadFieldConfig
+	if err := tx.Where("company_id = ? AND field_name = ?", lead.CompanyId, fieldName).First(&config).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("unknown lead field: %s", fieldName)
+		}
+		return err
+	}
+
+	// A new phone number has no display forms until it is normalized
+	now := time.Now()
+	result := tx.Model(&models.CrmFieldData{}).
+		Where("submit_id = ? AND crm_field_id = ?", lead.ID, config.ID).
+		Updates(map[string]interface{}{"field_value": value, "phone_national": "", "phone_international": "", "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	return tx.Create(&models.CrmFieldData{
+		CompanyId:  lead.CompanyId,
+		CrmStageId: config.SectionId,
+		CrmFieldId: int(config.ID),
+		FieldValue: value,
+		SubmitId:   lead.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}).Error
+}
+
+// fieldDataLeadIDs returns the distinct leads a set of lead field values belong to
+func fieldDataLeadIDs(fieldData []models.CrmFieldData) []int {
+	seen := make(map[uint]bool)
+	var ids []int
+	for _, data := range fieldData {
+		if !seen[data.SubmitId] {
+			seen[data.SubmitId] = true
+			ids = append(ids, int(data.SubmitId))
+		}
+	}
+	return ids
+}
+
+// leadFilterColumns are the leads table columns that can be used in lead filters
+var leadFilterColumns = map[string]bool{
+	"status":         true,
+	"source":         true,
+	"type":           true,
+	"assigned_to_id": true,
+	"email":          true,
+	"company":        true,
+}
+
+// FindIDsByFilter returns the IDs of a company's leads matching every filter.
+// Keys that are not lead columns are matched against EAV fields by field name.
+func (r *gormLeadRepository) FindIDsByFilter(companyId int, filters map[string]interface{}) ([]int, error) {
+	query := r.db.Model(&models.Lead{}).Where("company_id = ?", companyId)
+
+	for key, value := range filters {
+		if leadFilterColumns[key] {
+			query = query.Where(key+" = ?", value)
+			continue
+		}
+
+		fieldMatch := r.db.Table("crm_field_data").
+			Select("crm_field_data.submit_id").
+			Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+			Where("lead_field_configs.company_id = ? AND lead_field_configs.field_name = ? AND crm_field_data.field_value = ?", companyId, key, value)
+		query = query.Where("id IN (?)", fieldMatch)
+	}
+
+	var ids []int
+	err := query.Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindIDsByEmail returns the IDs of a company's leads with an email address, stored either on
+// the lead or in its email field, compared case-insensitively
+func (r *gormLeadRepository) FindIDsByEmail(companyId int, email string) ([]int, error) {
+	fieldMatch := r.db.Table("crm_field_data").
+		Select("crm_field_data.submit_id").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("lead_field_configs.company_id = ? AND lead_field_configs.field_name = ? AND LOWER(crm_field_data.field_value) = LOWER(?)", companyId, "email", email)
+
+	var ids []int
+	err := r.db.Model(&models.Lead{}).
+		Where("company_id = ?", companyId).
+		Where("LOWER(email) = LOWER(?) OR id IN (?)", email, fieldMatch).
+		Pluck("id", &ids).Error
+	return ids, err
 }
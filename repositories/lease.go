@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimRows claims, one at a time, up to limit rows of the IDs a candidate query selects in its
+// order, and returns the claimed rows as they are after the claim. claim is a conditional update
+// that affects no row once another instance has claimed it, so concurrent instances never claim
+// the same row.
+func claimRows[T any](db *gorm.DB, candidates *gorm.DB, limit int, claim func(id int) *gorm.DB) ([]T, error) {
+	var ids []int
+	if err := candidates.Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result := claim(id)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another instance claimed it first
+			continue
+		}
+
+		var row T
+		if err := db.First(&row, id).Error; err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, row)
+	}
+
+	return claimed, nil
+}
+
+// claimLeased leases up to limit rows in a status that a candidate query selects to a worker
+// until leaseUntil, skipping rows leased by another worker until their lease expires
+func claimLeased[T any](db *gorm.DB, candidates *gorm.DB, status string, workerID string, now time.Time, leaseUntil time.Time, limit int) ([]T, error) {
+	const leasable = "status = ? AND (locked_until IS NULL OR locked_until < ?)"
+	return claimRows[T](db, candidates.Where(leasable, status, now), limit, func(id int) *gorm.DB {
+		return db.Model(new(T)).
+			Where("id = ?", id).
+			Where(leasable, status, now).
+			Updates(map[string]interface{}{"locked_by": workerID, "locked_until": leaseUntil})
+	})
+}
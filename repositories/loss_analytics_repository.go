@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dealClosedAtSQL is when a deal was closed; deals closed before close dates were recorded fall
+// back to their last update
+const dealClosedAtSQL = "COALESCE(deals.closed_at, deals.updated_at)"
+
+// lossPeriodSQL groups close dates by month, ISO week or quarter
+var lossPeriodSQL = map[string]string{
+	"week":    "DATE_FORMAT(" + dealClosedAtSQL + ", '%x-W%v')",
+	"month":   "DATE_FORMAT(" + dealClosedAtSQL + ", '%Y-%m')",
+	"quarter": "CONCAT(YEAR(" + dealClosedAtSQL + "), '-Q', QUARTER(" + dealClosedAtSQL + "))",
+}
+
+// GetLossAnalytics breaks down the deals lost in a date range by reason, competitor, the stage
+// they were lost at, owner and period (week, month or quarter), with lead disqualifications by
+// reason. Lost value is converted into the company's reporting currency.
+func (r *gormAnalyticsRepository) GetLossAnalytics(startDate time.Time, endDate time.Time, period string, companyId int) (map[string]interface{}, error) {
+	periodSQL, ok := lossPeriodSQL[period]
+	if !ok {
+		periodSQL = lossPeriodSQL["month"]
+	}
+
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	closedDeals := func(stages []string) *gorm.DB {
+		return r.db.Model(&models.Deal{}).
+			Joins("LEFT JOIN close_reasons ON close_reasons.id = deals.close_reason_id").
+			Where("deals.stage IN ? AND deals.company_id = ? AND "+dealClosedAtSQL+" BETWEEN ? AND ?", stages, companyId, startDate, endDate)
+	}
+	lostDeals := func() *gorm.DB { return closedDeals(models.DealStagesLost) }
+
+	var lostCount, wonCount int64
+	if err := lostDeals().Count(&lostCount).Error; err != nil {
+		return nil, err
+	}
+	if err := closedDeals(models.DealStagesWon).Count(&wonCount).Error; err != nil {
+		return nil, err
+	}
+	lostValue, err := conversion.sumDealAmounts(lostDeals(), "''", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	// breakdown counts lost deals and their value per groupExpr, labelling each group with key
+	breakdown := func(key string, groupExpr string) ([]map[string]interface{}, error) {
+		var counts []struct {
+			Grp   string
+			Count int64
+		}
+		if err := lostDeals().
+			Select(groupExpr + " AS grp, COUNT(*) AS count").
+			Group("grp").
+			Order("count DESC, grp").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		values, err := conversion.sumDealAmounts(lostDeals(), groupExpr, "deals.amount", "deals.created_at")
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([]map[string]interface{}, 0, len(counts))
+		for _, row := range counts {
+			rows = append(rows, map[string]interface{}{
+				key:                 row.Grp,
+				"count":             row.Count,
+				"percentage":        float64(row.Count) * 100 / float64(lostCount),
+				"value":             values.total(row.Grp),
+				"value_by_currency": values.byCurrency(row.Grp),
+			})
+		}
+		return rows, nil
+	}
+
+	byReason, err := breakdown("reason", "COALESCE(close_reasons.name, 'Unspecified')")
+	if err != nil {
+		return nil, err
+	}
+	byCompetitor, err := breakdown("competitor", "COALESCE(NULLIF(deals.competitor, ''), 'None')")
+	if err != nil {
+		return nil, err
+	}
+	byStage, err := breakdown("stage", "COALESCE(NULLIF(deals.lost_at_stage, ''), 'unknown')")
+	if err != nil {
+		return nil, err
+	}
+	byOwner, err := breakdown("user_id", "COALESCE(CAST(deals.assigned_to AS CHAR), '')")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.nameLossOwners(byOwner); err != nil {
+		return nil, err
+	}
+	byPeriod, err := breakdown("period", periodSQL)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(byPeriod, func(i, j int) bool {
+		return byPeriod[i]["period"].(string) < byPeriod[j]["period"].(string)
+	})
+
+	// Lead disqualifications in the range by reason
+	var disqualifications []struct {
+		Reason string `json:"reason"`
+		Count  int64  `json:"count"`
+	}
+	if err := r.db.Model(&models.Lead{}).
+		Select("COALESCE(close_reasons.name, 'Unspecified') AS reason, COUNT(*) AS count").
+		Joins("LEFT JOIN close_reasons ON close_reasons.id = leads.disqualification_reason_id").
+		Where("leads.status = ? AND leads.company_id = ? AND leads.disqualified_at BETWEEN ? AND ?", "disqualified", companyId, startDate, endDate).
+		Group("reason").
+		Order("count DESC, reason").
+		Scan(&disqualifications).Error; err != nil {
+		return nil, err
+	}
+
+	lossRate := float64(0)
+	if wonCount+lostCount > 0 {
+		lossRate = float64(lostCount) * 100 / float64(wonCount+lostCount)
+	}
+
+	return map[string]interface{}{
+		"deals_lost":                  lostCount,
+		"deals_won":                   wonCount,
+		"loss_rate":                   lossRate,
+		"lost_value":                  lostValue.total(""),
+		"lost_value_by_currency":      lostValue.byCurrency(""),
+		"currency":                    conversion.currency,
+		"period":                      period,
+		"by_reason":                   byReason,
+		"by_competitor":               byCompetitor,
+		"by_stage":                    byStage,
+		"by_owner":                    byOwner,
+		"by_period":                   byPeriod,
+		"disqualifications_by_reason": disqualifications,
+	}, nil
+}
+
+// nameLossOwners adds each owner's name to a loss breakdown by user_id; deals without an owner
+// get a nil user_id
+func (r *gormAnalyticsRepository) nameLossOwners(rows []map[string]interface{}) error {
+	var ids []int
+	for _, row := range rows {
+		if id, err := strconv.Atoi(row["user_id"].(string)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	names := make(map[int]string, len(ids))
+	if len(ids) > 0 {
+		var users []models.User
+		if err := r.db.Select("id, name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			names[user.ID] = user.Name
+		}
+	}
+
+	for _, row := range rows {
+		id, err := strconv.Atoi(row["user_id"].(string))
+		if err != nil {
+			row["user_id"] = nil
+			row["user_name"] = "Unassigned"
+			continue
+		}
+		row["user_id"] = id
+		row["user_name"] = names[id]
+	}
+	return nil
+}
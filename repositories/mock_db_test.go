@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockDB returns a MySQL gorm connection backed by sqlmock. Every expectation set on the mock
+// must be met by the end of the test.
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		sqlDB.Close()
+	})
+	return db, mock
+}
+
+// expectBaseCurrency expects the company base currency lookup made by reportingCurrency
+func expectBaseCurrency(mock sqlmock.Sqlmock, companyId int, currency string) {
+	mock.ExpectQuery("SELECT `base_currency` FROM `company_settings` WHERE company_id = \\?").
+		WithArgs(companyId).
+		WillReturnRows(sqlmock.NewRows([]string{"base_currency"}).AddRow(currency))
+}
+
+// expectLeaseClaim expects the conditional update claimLeased makes to lease a row, affecting
+// rowsAffected rows
+func expectLeaseClaim(mock sqlmock.Sqlmock, table string, id int, status string, workerID string, now time.Time, leaseUntil time.Time, rowsAffected int64) {
+	mock.ExpectExec("UPDATE `"+table+"` SET `locked_by`=\\?,`locked_until`=\\?,`updated_at`=\\? "+
+		"WHERE id = \\? AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\)").
+		WithArgs(workerID, leaseUntil, sqlmock.AnyArg(), id, status, now).
+		WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"crm-app/backend/models"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,16 +18,18 @@ import (
 // }
 
 // GetSequences returns nurture sequences
-func (r *gormNurtureRepository) GetSequences(offset int, limit int) ([]models.NurtureSequence, error) {
+func (r *gormNurtureRepository) GetSequences(offset int, limit int, companyId int) ([]models.NurtureSequence, error) {
 	var sequences []models.NurtureSequence
-	err := r.db.Offset(offset).Limit(limit).Find(&sequences).Error
+	err := r.db.Where("company_id = ?", companyId).Offset(offset).Limit(limit).Find(&sequences).Error
 	return sequences, err
 }
 
 // GetSequenceByID returns a nurture sequence by ID
 func (r *gormNurtureRepository) GetSequenceByID(id int) (*models.NurtureSequence, error) {
 	var sequence models.NurtureSequence
-	err := r.db.First(&sequence, id).Error
+	err := r.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_index")
+	}).First(&sequence, id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -42,12 +46,23 @@ func (r *gormNurtureRepository) CreateSequence(sequence *models.NurtureSequence)
 
 // UpdateSequence updates a nurture sequence
 func (r *gormNurtureRepository) UpdateSequence(sequence *models.NurtureSequence) error {
-	return r.db.Save(sequence).Error
+	return r.db.Omit("CreatedAt", "Steps").Save(sequence).Error
 }
 
 // DeleteSequence deletes a nurture sequence
 func (r *gormNurtureRepository) DeleteSequence(id int) error {
-	return r.db.Delete(&models.NurtureSequence{}, id).Error
+	// Steps and enrollments are deleted with the sequence, at the same time, so that restoring it
+	// from the recycle bin restores them too
+	at := deletionTime()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := softDeleteAt(tx, &models.NurtureStep{}, at, "sequence_id = ?", id); err != nil {
+			return err
+		}
+		if err := softDeleteAt(tx, &models.NurtureEnrollment{}, at, "sequence_id = ?", id); err != nil {
+			return err
+		}
+		return softDeleteAt(tx, &models.NurtureSequence{}, at, "id = ?", id)
+	})
 }
 
 // GetStepsBySequence returns steps by sequence ID
@@ -57,14 +72,23 @@ func (r *gormNurtureRepository) GetStepsBySequence(sequenceID int) ([]models.Nur
 	return steps, err
 }
 
+// GetStepsIncludingDeleted returns all steps of a sequence in order, deleted ones included
+func (r *gormNurtureRepository) GetStepsIncludingDeleted(sequenceID int) ([]models.NurtureStep, error) {
+	var steps []models.NurtureStep
+	err := r.db.Unscoped().Where("sequence_id = ?", sequenceID).Order("order_index, id").Find(&steps).Error
+	return steps, err
+}
+
 // CreateStep creates a new nurture step
 func (r *gormNurtureRepository) CreateStep(step *models.NurtureStep) error {
 	return r.db.Create(step).Error
 }
 
-// UpdateStep updates a nurture step
+// UpdateStep updates a nurture step's editable fields
 func (r *gormNurtureRepository) UpdateStep(step *models.NurtureStep) error {
-	return r.db.Save(step).Error
+	return r.db.Model(step).
+		Select("name", "type", "content", "delay", "order_index", "is_active", "conditions").
+		Updates(step).Error
 }
 
 // DeleteStep deletes a nurture step
@@ -72,6 +96,37 @@ func (r *gormNurtureRepository) DeleteStep(id int) error {
 	return r.db.Delete(&models.NurtureStep{}, id).Error
 }
 
+// GetStepByID returns a nurture step by ID
+func (r *gormNurtureRepository) GetStepByID(id int) (*models.NurtureStep, error) {
+	var step models.NurtureStep
+	err := r.db.First(&step, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &step, nil
+}
+
+// ReorderSteps sets the order of a sequence's steps to the order of the given IDs
+func (r *gormNurtureRepository) ReorderSteps(sequenceID int, stepIDs []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, stepID := range stepIDs {
+			result := tx.Model(&models.NurtureStep{}).
+				Where("id = ? AND sequence_id = ?", stepID, sequenceID).
+				Update("order_index", i+1)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("step %d does not belong to sequence %d", stepID, sequenceID)
+			}
+		}
+		return nil
+	})
+}
+
 // GetEnrollments returns enrollments by sequence ID
 func (r *gormNurtureRepository) GetEnrollments(sequenceID int, offset int, limit int) ([]models.NurtureEnrollment, error) {
 	var enrollments []models.NurtureEnrollment
@@ -79,16 +134,115 @@ func (r *gormNurtureRepository) GetEnrollments(sequenceID int, offset int, limit
 	return enrollments, err
 }
 
+// GetEnrollmentByID returns a nurture enrollment by ID
+func (r *gormNurtureRepository) GetEnrollmentByID(id int) (*models.NurtureEnrollment, error) {
+	var enrollment models.NurtureEnrollment
+	err := r.db.First(&enrollment, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
 // EnrollLead enrolls a lead in a nurture sequence
 func (r *gormNurtureRepository) EnrollLead(enrollment *models.NurtureEnrollment) error {
 	return r.db.Create(enrollment).Error
 }
 
+// EnrollLeads enrolls leads in a sequence, skipping leads with an active or paused enrollment.
+// It returns the number of new enrollments.
+func (r *gormNurtureRepository) EnrollLeads(sequenceID int, leadIDs []int) (int, error) {
+	if len(leadIDs) == 0 {
+		return 0, nil
+	}
+
+	var enrolled []int
+	if err := r.db.Model(&models.NurtureEnrollment{}).
+		Where("sequence_id = ? AND lead_id IN ? AND status IN ?", sequenceID, leadIDs, []string{"active", "paused"}).
+		Pluck("lead_id", &enrolled).Error; err != nil {
+		return 0, err
+	}
+
+	skip := make(map[int]bool, len(enrolled))
+	for _, leadID := range enrolled {
+		skip[leadID] = true
+	}
+
+	now := time.Now()
+	enrollments := make([]models.NurtureEnrollment, 0, len(leadIDs))
+	for _, leadID := range leadIDs {
+		if skip[leadID] {
+			continue
+		}
+		skip[leadID] = true
+		enrollments = append(enrollments, models.NurtureEnrollment{
+			SequenceID: sequenceID,
+			LeadID:     leadID,
+			Status:     "active",
+			StartedAt:  now,
+			NextRunAt:  &now,
+		})
+	}
+	if len(enrollments) == 0 {
+		return 0, nil
+	}
+
+	if err := r.db.CreateInBatches(enrollments, 100).Error; err != nil {
+		return 0, err
+	}
+	return len(enrollments), nil
+}
+
+// UnenrollLeads cancels the active or paused enrollments of leads in a sequence
+func (r *gormNurtureRepository) UnenrollLeads(sequenceID int, leadIDs []int) (int64, error) {
+	if len(leadIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.NurtureEnrollment{}).
+		Where("sequence_id = ? AND lead_id IN ? AND status IN ?", sequenceID, leadIDs, []string{"active", "paused"}).
+		Updates(map[string]interface{}{"status": "cancelled", "completed_at": time.Now(), "next_run_at": nil})
+	return result.RowsAffected, result.Error
+}
+
 // UpdateEnrollment updates a nurture enrollment
 func (r *gormNurtureRepository) UpdateEnrollment(enrollment *models.NurtureEnrollment) error {
 	return r.db.Save(enrollment).Error
 }
 
+// SetEnrollmentStatus changes an enrollment's status if it is currently in one of fromStatuses.
+// It reports whether the enrollment was updated.
+func (r *gormNurtureRepository) SetEnrollmentStatus(id int, fromStatuses []string, status string) (bool, error) {
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case "active":
+		updates["next_run_at"] = time.Now()
+		updates["completed_at"] = nil
+	case "cancelled", "exited", "completed":
+		updates["completed_at"] = time.Now()
+		updates["next_run_at"] = nil
+	}
+
+	result := r.db.Model(&models.NurtureEnrollment{}).
+		Where("id = ? AND status IN ?", id, fromStatuses).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetEnrollmentCounts returns enrollment counts for a sequence grouped by status and current step
+func (r *gormNurtureRepository) GetEnrollmentCounts(sequenceID int) ([]models.NurtureEnrollmentCount, error) {
+	var counts []models.NurtureEnrollmentCount
+	err := r.db.Model(&models.NurtureEnrollment{}).
+		Select("status, current_step_id, COUNT(*) as count").
+		Where("sequence_id = ?", sequenceID).
+		Group("status, current_step_id").
+		Scan(&counts).Error
+	return counts, err
+}
+
 // GetEnrollmentActivity returns enrollment activity
 func (r *gormNurtureRepository) GetEnrollmentActivity(enrollmentID int) ([]models.NurtureActivity, error) {
 	var activities []models.NurtureActivity
@@ -101,6 +255,37 @@ func (r *gormNurtureRepository) RecordActivity(activity *models.NurtureActivity)
 	return r.db.Create(activity).Error
 }
 
+// ClaimDueEnrollments leases active enrollments that are due to run to a single worker.
+// Each row is claimed with a conditional update so concurrent instances never process the same enrollment.
+func (r *gormNurtureRepository) ClaimDueEnrollments(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]models.NurtureEnrollment, error) {
+	candidates := r.db.Model(&models.NurtureEnrollment{}).
+		Where("next_run_at IS NULL OR next_run_at <= ?", now).
+		Order("next_run_at")
+	return claimLeased[models.NurtureEnrollment](r.db, candidates, "active", workerID, now, leaseUntil, limit)
+}
+
+// ReleaseEnrollment saves the enrollment's progress and gives up the worker's lease
+func (r *gormNurtureRepository) ReleaseEnrollment(enrollment *models.NurtureEnrollment, workerID string) error {
+	enrollment.LockedBy = ""
+	enrollment.LockedUntil = nil
+
+	// A pause or cancel made while the enrollment was leased takes precedence over the worker's status
+	status := gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", "active", enrollment.Status)
+	completedAt := gorm.Expr("CASE WHEN status = ? THEN ? ELSE completed_at END", "active", enrollment.CompletedAt)
+	return r.db.Model(&models.NurtureEnrollment{}).
+		Where("id = ? AND locked_by = ?", enrollment.ID, workerID).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"current_step_id": enrollment.CurrentStepID,
+			"step_attempts":   enrollment.StepAttempts,
+			"completed_at":    completedAt,
+			"last_step_at":    enrollment.LastStepAt,
+			"next_run_at":     enrollment.NextRunAt,
+			"locked_by":       "",
+			"locked_until":    nil,
+		}).Error
+}
+
 // Campaign related methods
 
 // GetCampaigns returns campaigns
@@ -140,17 +325,61 @@ func (r *gormNurtureRepository) DeleteCampaign(id int) error {
 
 // GetCampaignStats returns campaign statistics
 func (r *gormNurtureRepository) GetCampaignStats(id int) (map[string]interface{}, error) {
-	// This is a stub implementation
+	var leads int64
+	if err := r.db.Table("campaign_leads").Where("campaign_id = ?", id).Count(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	totals, err := queryEmailStats(r.db, "campaign", id, "")
+	if err != nil {
+		return nil, err
+	}
+	byTemplate, err := queryEmailStats(r.db, "campaign", id, "template_id")
+	if err != nil {
+		return nil, err
+	}
+
+	memberCounts, err := r.GetCampaignLeadCounts(id)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]int64, len(memberCounts))
+	for _, count := range memberCounts {
+		members[count.Status] = count.Count
+	}
+
+	email := models.EmailStats{}
+	if len(totals) > 0 {
+		email = totals[0]
+	}
+
 	stats := map[string]interface{}{
-		"sent":      0,
-		"opened":    0,
-		"clicked":   0,
-		"bounced":   0,
-		"leads":     0,
-		"revenue":   0,
-		"roi":       0,
-		"ctr":       0,
-		"open_rate": 0,
+		"leads":              leads,
+		"members":            members,
+		"sent":               email.Sent,
+		"delivered":          email.Delivered,
+		"bounced":            email.Bounced,
+		"complained":         email.Complained,
+		"opened":             email.UniqueOpens,
+		"clicked":            email.UniqueClicks,
+		"opens":              email.Opens,
+		"clicks":             email.Clicks,
+		"open_rate":          email.OpenRate,
+		"ctr":                email.ClickRate,
+		"click_to_open_rate": email.ClickToOpenRate,
+		"by_template":        byTemplate,
+	}
+
+	test, err := (&gormABTestRepository{db: r.db}).GetTestByCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+	if test != nil {
+		abTest, err := abTestResults(r.db, test)
+		if err != nil {
+			return nil, err
+		}
+		stats["ab_test"] = abTest
 	}
 
 	return stats, nil
@@ -175,7 +404,7 @@ func (r *gormNurtureRepository) AssignLeadsToCampaign(campaignID int, leadIDs []
 	}
 
 	for _, leadID := range leadIDs {
-		if err := tx.Exec("INSERT INTO campaign_leads (campaign_id, lead_id) VALUES (?, ?)", campaignID, leadID).Error; err != nil {
+		if err := tx.Exec("INSERT INTO campaign_leads (campaign_id, lead_id, status, created_at, updated_at) VALUES (?, ?, ?, NOW(), NOW())", campaignID, leadID, models.CampaignLeadPending).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -189,6 +418,129 @@ func (r *gormNurtureRepository) RemoveLeadsFromCampaign(campaignID int, leadIDs
 	return r.db.Where("campaign_id = ? AND lead_id IN ?", campaignID, leadIDs).Delete("campaign_leads").Error
 }
 
+// SetCampaignStatus changes a campaign's status if it is currently in one of fromStatuses
+func (r *gormNurtureRepository) SetCampaignStatus(id int, fromStatuses []string, status string) (bool, error) {
+	updates := map[string]interface{}{"status": status}
+	if status == models.CampaignStatusCompleted || status == models.CampaignStatusCancelled {
+		updates["completed_at"] = time.Now()
+	}
+	result := r.db.Model(&models.Campaign{}).
+		Where("id = ? AND status IN ?", id, fromStatuses).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// LaunchCampaign schedules a draft campaign to start sending at scheduledAt
+func (r *gormNurtureRepository) LaunchCampaign(id int, scheduledAt time.Time, endsAt *time.Time) (bool, error) {
+	result := r.db.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", id, models.CampaignStatusDraft).
+		Updates(map[string]interface{}{
+			"status":       models.CampaignStatusScheduled,
+			"scheduled_at": scheduledAt,
+			"ends_at":      endsAt,
+			"launched_at":  time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// StartDueCampaigns moves scheduled campaigns whose start time has passed to running
+func (r *gormNurtureRepository) StartDueCampaigns(now time.Time) (int64, error) {
+	result := r.db.Model(&models.Campaign{}).
+		Where("status = ? AND scheduled_at <= ?", models.CampaignStatusScheduled, now).
+		Update("status", models.CampaignStatusRunning)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimRunningCampaigns leases running campaigns to a single worker
+func (r *gormNurtureRepository) ClaimRunningCampaigns(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]models.Campaign, error) {
+	candidates := r.db.Model(&models.Campaign{}).Order("scheduled_at")
+	return claimLeased[models.Campaign](r.db, candidates, models.CampaignStatusRunning, workerID, now, leaseUntil, limit)
+}
+
+// ReleaseCampaign saves the campaign's status and gives up the worker's lease
+func (r *gormNurtureRepository) ReleaseCampaign(campaign *models.Campaign, workerID string) error {
+	campaign.LockedBy = ""
+	campaign.LockedUntil = nil
+
+	// A pause or cancel made while the campaign was leased takes precedence over the worker's status
+	status := gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", models.CampaignStatusRunning, campaign.Status)
+	completedAt := gorm.Expr("CASE WHEN status = ? THEN ? ELSE completed_at END", models.CampaignStatusRunning, campaign.CompletedAt)
+	return r.db.Model(&models.Campaign{}).
+		Where("id = ? AND locked_by = ?", campaign.ID, workerID).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"completed_at": completedAt,
+			"locked_by":    "",
+			"locked_until": nil,
+		}).Error
+}
+
+// GetPendingCampaignLeads returns campaign members that have not been sent to yet
+func (r *gormNurtureRepository) GetPendingCampaignLeads(campaignID int, limit int) ([]models.CampaignLead, error) {
+	var members []models.CampaignLead
+	// Members added before campaign execution existed have the status active
+	err := r.db.Where("campaign_id = ? AND status IN ?", campaignID, []string{models.CampaignLeadPending, "active"}).
+		Order("created_at, lead_id").
+		Limit(limit).
+		Find(&members).Error
+	return members, err
+}
+
+// UpdateCampaignLead saves the send state of a campaign member
+func (r *gormNurtureRepository) UpdateCampaignLead(member *models.CampaignLead) error {
+	return r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND lead_id = ?", member.CampaignID, member.LeadID).
+		Updates(map[string]interface{}{
+			"status":       member.Status,
+			"variant_id":   member.VariantID,
+			"message_id":   member.MessageID,
+			"last_error":   member.LastError,
+			"processed_at": member.ProcessedAt,
+		}).Error
+}
+
+// SetCampaignLeadStatus records the delivery outcome of a campaign member's email
+func (r *gormNurtureRepository) SetCampaignLeadStatus(campaignID int, leadID int, status string, lastError string) error {
+	return r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND lead_id = ?", campaignID, leadID).
+		Updates(map[string]interface{}{"status": status, "last_error": lastError}).Error
+}
+
+// CancelPendingCampaignLeads marks the members a campaign has not sent to as cancelled
+func (r *gormNurtureRepository) CancelPendingCampaignLeads(campaignID int) error {
+	return r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND status IN ?", campaignID, []string{models.CampaignLeadPending, "active", models.CampaignLeadQueued, models.CampaignLeadAwaiting}).
+		Update("status", models.CampaignLeadCancelled).Error
+}
+
+// ReleaseAwaitingCampaignLeads assigns the winning variant to the members waiting for an A/B test
+// result and makes them pending again so the campaign sends to them
+func (r *gormNurtureRepository) ReleaseAwaitingCampaignLeads(campaignID int, variantID int) error {
+	return r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND status = ?", campaignID, models.CampaignLeadAwaiting).
+		Updates(map[string]interface{}{"status": models.CampaignLeadPending, "variant_id": variantID}).Error
+}
+
+// CountCampaignLeadsProcessedSince counts the members a campaign has queued email for since a time
+func (r *gormNurtureRepository) CountCampaignLeadsProcessedSince(campaignID int, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND message_id IS NOT NULL AND processed_at >= ?", campaignID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// GetCampaignLeadCounts returns the number of campaign members per status
+func (r *gormNurtureRepository) GetCampaignLeadCounts(campaignID int) ([]models.CampaignLeadStatusCount, error) {
+	var counts []models.CampaignLeadStatusCount
+	err := r.db.Model(&models.CampaignLead{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).
+		Group("status").
+		Scan(&counts).Error
+	return counts, err
+}
+
 // GetTemplates returns campaign templates
 func (r *gormNurtureRepository) GetTemplates(offset int, limit int, companyId int) ([]models.CampaignTemplate, error) {
 	var templates []models.CampaignTemplate
@@ -223,3 +575,42 @@ func (r *gormNurtureRepository) UpdateTemplate(template *models.CampaignTemplate
 func (r *gormNurtureRepository) DeleteTemplate(id int) error {
 	return r.db.Delete(&models.CampaignTemplate{}, id).Error
 }
+
+// GetCampaignsBySegment returns the unfinished campaigns targeting a segment
+func (r *gormNurtureRepository) GetCampaignsBySegment(segmentID int) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := r.db.Where("segment_id = ? AND status NOT IN ?", segmentID,
+		[]string{models.CampaignStatusCompleted, models.CampaignStatusCancelled}).Find(&campaigns).Error
+	return campaigns, err
+}
+
+// GetSequencesBySegment returns the active sequences targeting a segment
+func (r *gormNurtureRepository) GetSequencesBySegment(segmentID int) ([]models.NurtureSequence, error) {
+	var sequences []models.NurtureSequence
+	err := r.db.Where("segment_id = ? AND is_active = ?", segmentID, true).Find(&sequences).Error
+	return sequences, err
+}
+
+// AddSegmentMembersToCampaign adds the members of a segment that are not yet campaign members
+func (r *gormNurtureRepository) AddSegmentMembersToCampaign(campaignID int, segmentID int) (int, error) {
+	result := r.db.Exec(`INSERT INTO campaign_leads (campaign_id, lead_id, status, created_at, updated_at)
+		SELECT ?, segment_members.lead_id, ?, NOW(), NOW() FROM segment_members
+		WHERE segment_members.segment_id = ?
+		AND NOT EXISTS (SELECT 1 FROM campaign_leads existing WHERE existing.campaign_id = ? AND existing.lead_id = segment_members.lead_id)`,
+		campaignID, models.CampaignLeadPending, segmentID, campaignID)
+	return int(result.RowsAffected), result.Error
+}
+
+// EnrollSegmentMembers enrolls the members of a segment that have never been enrolled in the
+// sequence, so leads that finished or left it are not enrolled again
+func (r *gormNurtureRepository) EnrollSegmentMembers(sequenceID int, segmentID int) (int, error) {
+	enrolled := r.db.Unscoped().Model(&models.NurtureEnrollment{}).Select("lead_id").Where("sequence_id = ?", sequenceID)
+
+	var leadIDs []int
+	if err := r.db.Model(&models.SegmentMember{}).
+		Where("segment_id = ? AND lead_id NOT IN (?)", segmentID, enrolled).
+		Pluck("lead_id", &leadIDs).Error; err != nil {
+		return 0, err
+	}
+	return r.EnrollLeads(sequenceID, leadIDs)
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClaimDueEnrollments(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormNurtureRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	leaseUntil := now.Add(5 * time.Minute)
+
+	mock.ExpectQuery("SELECT `id` FROM `nurture_enrollments` WHERE \\(next_run_at IS NULL OR next_run_at <= \\?\\) "+
+		"AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\) .*ORDER BY next_run_at LIMIT 10").
+		WithArgs(now, "active", now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	expectLeaseClaim(mock, "nurture_enrollments", 1, "active", "worker-a", now, leaseUntil, 1)
+	mock.ExpectQuery("SELECT \\* FROM `nurture_enrollments` WHERE `nurture_enrollments`.`id` = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(1, "active", "worker-a"))
+	// another worker leased the second enrollment between the select and the claim
+	expectLeaseClaim(mock, "nurture_enrollments", 2, "active", "worker-a", now, leaseUntil, 0)
+
+	claimed, err := repo.ClaimDueEnrollments("worker-a", now, leaseUntil, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueEnrollments error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 1 || claimed[0].LockedBy != "worker-a" {
+		t.Errorf("claimed = %+v, want enrollment 1 leased to worker-a", claimed)
+	}
+}
+
+func TestClaimRunningCampaigns(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormNurtureRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	leaseUntil := now.Add(2 * time.Minute)
+
+	mock.ExpectQuery("SELECT `id` FROM `campaigns` WHERE status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\) .*ORDER BY scheduled_at LIMIT 5").
+		WithArgs(models.CampaignStatusRunning, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectLeaseClaim(mock, "campaigns", 3, models.CampaignStatusRunning, "worker-a", now, leaseUntil, 1)
+	mock.ExpectQuery("SELECT \\* FROM `campaigns` WHERE `campaigns`.`id` = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(3, models.CampaignStatusRunning, "worker-a"))
+
+	claimed, err := repo.ClaimRunningCampaigns("worker-a", now, leaseUntil, 5)
+	if err != nil {
+		t.Fatalf("ClaimRunningCampaigns error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 3 || claimed[0].LockedBy != "worker-a" {
+		t.Errorf("claimed = %+v, want campaign 3 leased to worker-a", claimed)
+	}
+}
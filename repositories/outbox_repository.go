@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordDomainEvent writes a domain event to the outbox. It is called with the transaction making
+// the change the event records, so the event is stored if and only if the change is.
+func recordDomainEvent(tx *gorm.DB, eventType string, aggregateID int, companyId int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return tx.Create(&models.OutboxEvent{
+		EventID:       newEventID(),
+		Type:          eventType,
+		AggregateType: models.DomainEventAggregates[eventType],
+		AggregateID:   aggregateID,
+		Payload:       payload,
+		Status:        models.OutboxEventPending,
+		NextAttemptAt: &now,
+		OccurredAt:    now,
+		CompanyId:     companyId,
+	}).Error
+}
+
+// recordUpdatedEvents records the updated event of each live lead, deal or contact among ids, for
+// a change made to many records at once such as renaming a tag they have
+func recordUpdatedEvents(tx *gorm.DB, aggregateType string, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	switch aggregateType {
+	case models.AggregateLead:
+		var leads []models.Lead
+		if err := tx.Select(leadEventColumns).Where("id IN ?", ids).Find(&leads).Error; err != nil {
+			return err
+		}
+		for i := range leads {
+			if err := recordDomainEvent(tx, models.EventLeadUpdated, int(leads[i].ID), leads[i].CompanyId, leadEvent(&leads[i])); err != nil {
+				return err
+			}
+		}
+	case models.AggregateDeal:
+		var deals []models.Deal
+		if err := tx.Select(dealEventColumns).Where("id IN ?", ids).Find(&deals).Error; err != nil {
+			return err
+		}
+		for i := range deals {
+			if err := recordDomainEvent(tx, models.EventDealUpdated, deals[i].ID, deals[i].CompanyId, dealEvent(&deals[i])); err != nil {
+				return err
+			}
+		}
+	case models.AggregateContact:
+		var contacts []models.Contact
+		if err := tx.Select(contactEventColumns).Where("id IN ?", ids).Find(&contacts).Error; err != nil {
+			return err
+		}
+		for i := range contacts {
+			if err := recordDomainEvent(tx, models.EventContactUpdated, contacts[i].ID, contacts[i].CompanyId, contactEvent(&contacts[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetEvents returns a company's outbox events newest first, optionally only those in a status
+func (r *gormOutboxRepository) GetEvents(status string, offset int, limit int, companyId int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	query := r.db.Where("company_id = ?", companyId)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// GetEventByID returns an outbox event by ID
+func (r *gormOutboxRepository) GetEventByID(id int) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ReplayEvent makes a failed event pending again, due at once with a fresh set of attempts. The
+// subscribers that handled it already are not given it again.
+func (r *gormOutboxRepository) ReplayEvent(event *models.OutboxEvent, now time.Time) error {
+	event.Status = models.OutboxEventPending
+	event.Attempts = 0
+	event.NextAttemptAt = &now
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND status = ?", event.ID, models.OutboxEventFailed).
+		Updates(map[string]interface{}{"status": event.Status, "attempts": 0, "next_attempt_at": now}).Error
+}
+
+// ClaimDueEvents leases pending events that are due, in the order they occurred, to a worker. An
+// event leased by another worker is skipped until its lease expires.
+func (r *gormOutboxRepository) ClaimDueEvents(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	candidates := r.db.Model(&models.OutboxEvent{}).
+		Where("next_attempt_at <= ?", now).
+		Order("id")
+	return claimLeased[models.OutboxEvent](r.db, candidates, models.OutboxEventPending, workerID, now, leaseUntil, limit)
+}
+
+// ReleaseEvent saves the outcome of a dispatch attempt and gives up the worker's lease
+func (r *gormOutboxRepository) ReleaseEvent(event *models.OutboxEvent, workerID string) error {
+	event.LockedBy = ""
+	event.LockedUntil = nil
+	handledBy, err := json.Marshal(event.HandledBy)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND locked_by = ?", event.ID, workerID).
+		Updates(map[string]interface{}{
+			"status":          event.Status,
+			"attempts":        event.Attempts,
+			"next_attempt_at": event.NextAttemptAt,
+			"handled_by":      string(handledBy),
+			"last_error":      event.LastError,
+			"dispatched_at":   event.DispatchedAt,
+			"locked_by":       "",
+			"locked_until":    nil,
+		}).Error
+}
+
+// PurgeDispatchedEvents deletes up to limit events dispatched before a time, returning how many
+func (r *gormOutboxRepository) PurgeDispatchedEvents(dispatchedBefore time.Time, limit int) (int64, error) {
+	result := r.db.Where("status = ? AND dispatched_at < ?", models.OutboxEventDispatched, dispatchedBefore).
+		Limit(limit).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
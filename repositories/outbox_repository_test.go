@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClaimDueEvents(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormOutboxRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	leaseUntil := now.Add(30 * time.Second)
+
+	// events are claimed in the order they occurred
+	mock.ExpectQuery("SELECT `id` FROM `outbox_events` WHERE next_attempt_at <= \\? "+
+		"AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\) .*ORDER BY id LIMIT 50").
+		WithArgs(now, models.OutboxEventPending, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100).AddRow(101))
+	expectEventClaim(mock, 100, now, leaseUntil)
+	mock.ExpectQuery("SELECT \\* FROM `outbox_events` WHERE `outbox_events`.`id` = \\?").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(100, models.OutboxEventPending, "worker-a"))
+	expectEventClaim(mock, 101, now, leaseUntil)
+	mock.ExpectQuery("SELECT \\* FROM `outbox_events` WHERE `outbox_events`.`id` = \\?").
+		WithArgs(101).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(101, models.OutboxEventPending, "worker-a"))
+
+	claimed, err := repo.ClaimDueEvents("worker-a", now, leaseUntil, 50)
+	if err != nil {
+		t.Fatalf("ClaimDueEvents error: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != 100 || claimed[1].ID != 101 {
+		t.Errorf("claimed = %+v, want events 100 and 101 in order", claimed)
+	}
+}
+
+// expectEventClaim expects an event to be leased to worker-a. Events have no updated_at, so the
+// claim sets only the lease.
+func expectEventClaim(mock sqlmock.Sqlmock, id int, now time.Time, leaseUntil time.Time) {
+	mock.ExpectExec("UPDATE `outbox_events` SET `locked_by`=\\?,`locked_until`=\\? "+
+		"WHERE id = \\? AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\)").
+		WithArgs("worker-a", leaseUntil, id, models.OutboxEventPending, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// unnormalizedPhoneSQL matches rows with a phone number that has no stored display forms, either
+// because it predates normalization or because it was written without being normalized
+const unnormalizedPhoneSQL = "%s <> '' AND COALESCE(%s, '') = ''"
+
+// GetUnnormalizedPhones returns up to limit phone numbers of one record type that have not been
+// normalized, in record order after the given record. A companyId of 0 covers every company.
+func (r *gormPhoneRepository) GetUnnormalizedPhones(recordType string, after models.PhoneRecord, limit int, companyId int) ([]models.PhoneRecord, error) {
+	var query *gorm.DB
+	companyColumn := "company_id"
+	switch recordType {
+	case models.PhoneRecordContact:
+		query = r.db.Model(&models.Contact{}).
+			Select("id AS record_id, company_id, phone").
+			Where(fmt.Sprintf(unnormalizedPhoneSQL, "phone", "phone_international")).
+			Where("id > ?", after.RecordID).
+			Order("id")
+	case models.PhoneRecordLead:
+		query = r.db.Model(&models.Lead{}).
+			Select("id AS record_id, company_id, phone").
+			Where(fmt.Sprintf(unnormalizedPhoneSQL, "phone", "phone_international")).
+			Where("id > ?", after.RecordID).
+			Order("id")
+	case models.PhoneRecordLeadField:
+		query = r.db.Table("crm_field_data").
+			Select("crm_field_data.submit_id AS record_id, crm_field_data.crm_field_id AS field_id, crm_field_data.company_id, crm_field_data.field_value AS phone").
+			Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+			Where("lead_field_configs.field_type = ?", "phone").
+			Where(fmt.Sprintf(unnormalizedPhoneSQL, "crm_field_data.field_value", "crm_field_data.phone_international")).
+			Where("(crm_field_data.submit_id, crm_field_data.crm_field_id) > (?, ?)", after.RecordID, after.FieldID).
+			Order("crm_field_data.submit_id, crm_field_data.crm_field_id")
+		companyColumn = "crm_field_data.company_id"
+	default:
+		return nil, fmt.Errorf("unknown phone record type: %s", recordType)
+	}
+	if companyId > 0 {
+		query = query.Where(companyColumn+" = ?", companyId)
+	}
+
+	var records []models.PhoneRecord
+	if err := query.Limit(limit).Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	for i := range records {
+		records[i].Type = recordType
+	}
+	return records, nil
+}
+
+// SaveNormalizedPhone stores a normalized phone number and its display forms without touching
+// the record's updated_at, then brings the record's search entry in step
+func (r *gormPhoneRepository) SaveNormalizedPhone(record *models.PhoneRecord) error {
+	switch record.Type {
+	case models.PhoneRecordContact, models.PhoneRecordLead:
+		var model interface{} = &models.Contact{}
+		docType := models.SearchTypeContact
+		if record.Type == models.PhoneRecordLead {
+			model = &models.Lead{}
+			docType = models.SearchTypeLead
+		}
+		if err := r.db.Model(model).Where("id = ?", record.RecordID).UpdateColumns(map[string]interface{}{
+			"phone":               record.Phone,
+			"phone_national":      record.PhoneNational,
+			"phone_international": record.PhoneInternational,
+		}).Error; err != nil {
+			return err
+		}
+		indexForSearch(r.db, docType, record.RecordID)
+	case models.PhoneRecordLeadField:
+		if err := r.db.Model(&models.CrmFieldData{}).
+			Where("submit_id = ? AND crm_field_id = ?", record.RecordID, record.FieldID).
+			UpdateColumns(map[string]interface{}{
+				"field_value":         record.Phone,
+				"phone_national":      record.PhoneNational,
+				"phone_international": record.PhoneInternational,
+			}).Error; err != nil {
+			return err
+		}
+		indexForSearch(r.db, models.SearchTypeLead, record.RecordID)
+	default:
+		return fmt.Errorf("unknown phone record type: %s", record.Type)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetUnnormalizedPhones(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordType string
+		after      models.PhoneRecord
+		companyId  int
+		wantSQL    string
+		wantArgs   []driver.Value
+		rows       *sqlmock.Rows
+		want       []models.PhoneRecord
+	}{
+		{
+			name:       "contacts of every company",
+			recordType: models.PhoneRecordContact,
+			after:      models.PhoneRecord{RecordID: 20},
+			wantSQL: "SELECT id AS record_id, company_id, phone FROM `contacts` " +
+				"WHERE \\(phone <> '' AND COALESCE\\(phone_international, ''\\) = ''\\) AND id > \\? " +
+				"AND `contacts`.`deleted_at` IS NULL ORDER BY id LIMIT 2",
+			wantArgs: []driver.Value{20},
+			rows:     sqlmock.NewRows([]string{"record_id", "company_id", "phone"}).AddRow(21, 1, "030 1234567").AddRow(25, 2, "+1 (555) 010-2030"),
+			want: []models.PhoneRecord{
+				{Type: models.PhoneRecordContact, RecordID: 21, CompanyId: 1, Phone: "030 1234567"},
+				{Type: models.PhoneRecordContact, RecordID: 25, CompanyId: 2, Phone: "+1 (555) 010-2030"},
+			},
+		},
+		{
+			// Lead field values are paged by lead and field together
+			name:       "lead fields of one company",
+			recordType: models.PhoneRecordLeadField,
+			after:      models.PhoneRecord{RecordID: 7, FieldID: 3},
+			companyId:  1,
+			wantSQL: "SELECT crm_field_data.submit_id AS record_id, crm_field_data.crm_field_id AS field_id, crm_field_data.company_id, crm_field_data.field_value AS phone " +
+				"FROM `crm_field_data` INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id " +
+				"WHERE lead_field_configs.field_type = \\? " +
+				"AND \\(crm_field_data.field_value <> '' AND COALESCE\\(crm_field_data.phone_international, ''\\) = ''\\) " +
+				"AND \\(crm_field_data.submit_id, crm_field_data.crm_field_id\\) > \\(\\?, \\?\\) " +
+				"AND crm_field_data.company_id = \\? ORDER BY crm_field_data.submit_id, crm_field_data.crm_field_id LIMIT 2",
+			wantArgs: []driver.Value{"phone", 7, 3, 1},
+			rows:     sqlmock.NewRows([]string{"record_id", "field_id", "company_id", "phone"}).AddRow(7, 5, 1, "0151 2345678"),
+			want: []models.PhoneRecord{
+				{Type: models.PhoneRecordLeadField, RecordID: 7, FieldID: 5, CompanyId: 1, Phone: "0151 2345678"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := &gormPhoneRepository{db: db}
+			mock.ExpectQuery(tt.wantSQL).WithArgs(tt.wantArgs...).WillReturnRows(tt.rows)
+
+			records, err := repo.GetUnnormalizedPhones(tt.recordType, tt.after, 2, tt.companyId)
+			if err != nil {
+				t.Fatalf("GetUnnormalizedPhones error: %v", err)
+			}
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.want))
+			}
+			for i := range records {
+				if records[i] != tt.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, records[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
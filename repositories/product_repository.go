@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetProducts returns a company's products with pagination, optionally matching a name or SKU
+func (r *gormProductRepository) GetProducts(offset int, limit int, search string, activeOnly bool, companyId int) ([]models.Product, error) {
+	var products []models.Product
+	query := r.db.Where("company_id = ?", companyId)
+	if search != "" {
+		query = query.Where("name LIKE ? OR sku LIKE ?", "%"+search+"%", search+"%")
+	}
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("name").Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
+}
+
+// GetProductByID returns a product by ID
+func (r *gormProductRepository) GetProductByID(id int) (*models.Product, error) {
+	var product models.Product
+	err := r.db.First(&product, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetProductBySKU returns a company's product with the given SKU
+func (r *gormProductRepository) GetProductBySKU(sku string, companyId int) (*models.Product, error) {
+	var product models.Product
+	err := r.db.Where("sku = ? AND company_id = ?", sku, companyId).First(&product).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// CreateProduct creates a new product
+func (r *gormProductRepository) CreateProduct(product *models.Product) error {
+	return r.db.Create(product).Error
+}
+
+// UpdateProduct updates a product
+func (r *gormProductRepository) UpdateProduct(product *models.Product) error {
+	return r.db.Omit("CreatedAt").Save(product).Error
+}
+
+// DeleteProduct deletes a product and its prices; line items keep the product's name and SKU
+func (r *gormProductRepository) DeleteProduct(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", id).Delete(&models.PriceBookEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Product{}, id).Error
+	})
+}
+
+// GetProductPrices returns a product's prices in every price book and currency
+func (r *gormProductRepository) GetProductPrices(productID int) ([]models.PriceBookEntry, error) {
+	var entries []models.PriceBookEntry
+	err := r.db.Where("product_id = ?", productID).Order("price_book_id, currency").Find(&entries).Error
+	return entries, err
+}
+
+// GetPriceBooks returns a company's price books
+func (r *gormProductRepository) GetPriceBooks(companyId int) ([]models.PriceBook, error) {
+	var priceBooks []models.PriceBook
+	err := r.db.Where("company_id = ?", companyId).Order("is_default DESC, name").Find(&priceBooks).Error
+	return priceBooks, err
+}
+
+// GetPriceBookByID returns a price book with its entries
+func (r *gormProductRepository) GetPriceBookByID(id int) (*models.PriceBook, error) {
+	var priceBook models.PriceBook
+	err := r.db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("product_id, currency")
+	}).First(&priceBook, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &priceBook, nil
+}
+
+// GetDefaultPriceBook returns a company's active default price book, or nil if it has none
+func (r *gormProductRepository) GetDefaultPriceBook(companyId int) (*models.PriceBook, error) {
+	var priceBook models.PriceBook
+	err := r.db.Where("company_id = ? AND is_default = ? AND is_active = ?", companyId, true, true).First(&priceBook).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &priceBook, nil
+}
+
+// CreatePriceBook creates a new price book, making it the only default if it is one
+func (r *gormProductRepository) CreatePriceBook(priceBook *models.PriceBook) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Entries").Create(priceBook).Error; err != nil {
+			return err
+		}
+		return clearOtherDefaultPriceBooks(tx, priceBook)
+	})
+}
+
+// UpdatePriceBook updates a price book, making it the only default if it is one
+func (r *gormProductRepository) UpdatePriceBook(priceBook *models.PriceBook) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("CreatedAt", "Entries").Save(priceBook).Error; err != nil {
+			return err
+		}
+		return clearOtherDefaultPriceBooks(tx, priceBook)
+	})
+}
+
+// clearOtherDefaultPriceBooks unsets the default flag of a company's other price books when
+// priceBook is the default
+func clearOtherDefaultPriceBooks(tx *gorm.DB, priceBook *models.PriceBook) error {
+	if !priceBook.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.PriceBook{}).
+		Where("company_id = ? AND id <> ? AND is_default = ?", priceBook.CompanyId, priceBook.ID, true).
+		Update("is_default", false).Error
+}
+
+// DeletePriceBook deletes a price book and its entries; deals priced from it fall back to the
+// default price book
+func (r *gormProductRepository) DeletePriceBook(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Deal{}).Where("price_book_id = ?", id).Update("price_book_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("price_book_id = ?", id).Delete(&models.PriceBookEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.PriceBook{}, id).Error
+	})
+}
+
+// SavePriceBookEntries sets product prices in a price book, replacing the price of any product
+// and currency already in it
+func (r *gormProductRepository) SavePriceBookEntries(priceBookID int, entries []models.PriceBookEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range entries {
+			entry := &entries[i]
+			entry.PriceBookID = priceBookID
+
+			var existing models.PriceBookEntry
+			err := tx.Where("price_book_id = ? AND product_id = ? AND currency = ?", priceBookID, entry.ProductID, entry.Currency).
+				First(&existing).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+			if err == gorm.ErrRecordNotFound {
+				if err := tx.Create(entry).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			entry.ID = existing.ID
+			entry.CreatedAt = existing.CreatedAt
+			if err := tx.Save(entry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePriceBookEntry removes a price from a price book
+func (r *gormProductRepository) DeletePriceBookEntry(priceBookID int, entryID int) error {
+	result := r.db.Where("price_book_id = ?", priceBookID).Delete(&models.PriceBookEntry{}, entryID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetPrice returns a product's price in a currency from a price book, or nil if it has none
+func (r *gormProductRepository) GetPrice(priceBookID int, productID int, currency string) (*models.PriceBookEntry, error) {
+	var entry models.PriceBookEntry
+	err := r.db.Where("price_book_id = ? AND product_id = ? AND currency = ?", priceBookID, productID, currency).First(&entry).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
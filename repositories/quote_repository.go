@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDealQuotes returns every quote version of a deal, newest first, without line items
+func (r *gormQuoteRepository) GetDealQuotes(dealID int) ([]models.Quote, error) {
+	var quotes []models.Quote
+	err := r.db.Where("deal_id = ?", dealID).Order("version DESC").Find(&quotes).Error
+	return quotes, err
+}
+
+// GetQuoteByID returns a quote with its line items
+func (r *gormQuoteRepository) GetQuoteByID(id int) (*models.Quote, error) {
+	var quote models.Quote
+	err := r.db.Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).First(&quote, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// CreateQuoteVersion stores a quote and its line items as the next version of the deal's quote
+func (r *gormQuoteRepository) CreateQuoteVersion(quote *models.Quote) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the deal so concurrent requests cannot take the same version
+		var deal models.Deal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&deal, quote.DealID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.Quote{}).Where("deal_id = ?", quote.DealID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		quote.Version = latest + 1
+		quote.Number = fmt.Sprintf("Q-%d-%d", quote.DealID, quote.Version)
+		return tx.Create(quote).Error
+	})
+}
+
+// UpdateQuote updates a quote's details and status; its line items are never changed
+func (r *gormQuoteRepository) UpdateQuote(quote *models.Quote) error {
+	return r.db.Omit("CreatedAt", "LineItems").Save(quote).Error
+}
+
+// AcceptQuote stores an accepted quote and the deal it wins in one transaction
+func (r *gormQuoteRepository) AcceptQuote(quote *models.Quote, deal *models.Deal) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("CreatedAt", "LineItems").Save(quote).Error; err != nil {
+			return err
+		}
+		return tx.Omit("CreatedAt").Save(deal).Error
+	})
+}
+
+// ExpireQuotes marks a deal's draft and sent quotes whose validity ended before today as expired
+func (r *gormQuoteRepository) ExpireQuotes(dealID int, today time.Time) (int64, error) {
+	result := r.db.Model(&models.Quote{}).
+		Where("deal_id = ? AND status IN ? AND valid_until < ?", dealID,
+			[]string{models.QuoteStatusDraft, models.QuoteStatusSent}, today.Format("2006-01-02")).
+		Update("status", models.QuoteStatusExpired)
+	return result.RowsAffected, result.Error
+}
+
+// GetQuoteTemplate returns a company's quote template, or nil if none has been saved
+func (r *gormQuoteRepository) GetQuoteTemplate(companyId int) (*models.QuoteTemplate, error) {
+	var template models.QuoteTemplate
+	err := r.db.Where("company_id = ?", companyId).First(&template).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	template.HasLogo = len(template.Logo) > 0
+	return &template, nil
+}
+
+// SaveQuoteTemplate creates or replaces a company's quote template
+func (r *gormQuoteRepository) SaveQuoteTemplate(template *models.QuoteTemplate) error {
+	existing, err := r.GetQuoteTemplate(template.CompanyId)
+	if err != nil {
+		return err
+	}
+	template.HasLogo = len(template.Logo) > 0
+	if existing == nil {
+		template.ID = 0
+		return r.db.Create(template).Error
+	}
+
+	template.ID = existing.ID
+	template.CreatedAt = existing.CreatedAt
+	return r.db.Save(template).Error
+}
@@ -20,6 +20,26 @@ func NewRepositoriesInit(db *gorm.DB) *models.Repositories {
 	repos.CampaignRepo = NewCampaignRepository(db)
 	repos.AnalyticsRepo = NewAnalyticsRepository(db)
 	repos.ScoreRepo = NewLeadScoreRepository(db)
+	repos.AttributionRepo = NewAttributionRepository(db)
+	repos.EmailRepo = NewEmailRepository(db)
+	repos.CompanyRepo = NewCompanyRepository(db)
+	repos.ConsentRepo = NewConsentRepository(db)
+	repos.ABTestRepo = NewABTestRepository(db)
+	repos.SegmentRepo = NewSegmentRepository(db)
+	repos.ExchangeRateRepo = NewExchangeRateRepository(db)
+	repos.ProductRepo = NewProductRepository(db)
+	repos.QuoteRepo = NewQuoteRepository(db)
+	repos.CloseReasonRepo = NewCloseReasonRepository(db)
+	repos.AccountRepo = NewAccountRepository(db)
+	repos.SearchRepo = NewSearchRepository(db)
+	repos.PhoneRepo = NewPhoneRepository(db)
+	repos.SavedViewRepo = NewSavedViewRepository(db)
+	repos.AuditRepo = NewAuditRepository(db)
+	repos.BulkOperationRepo = NewBulkOperationRepository(db)
+	repos.TrashRepo = NewTrashRepository(db)
+	repos.TagRepo = NewTagRepository(db)
+	repos.WebhookRepo = NewWebhookRepository(db)
+	repos.OutboxRepo = NewOutboxRepository(db)
 
 	return repos
 }
@@ -38,6 +58,26 @@ func NewCRMRepositories(db *gorm.DB) *models.CRMRepositories {
 		NurtureRepo:         NewNurtureRepository(db),
 		UserRepo:            NewUserRepository(db),
 		LeadScoreType:       NewLeadScoreRepository(db),
+		AttributionRepo:     NewAttributionRepository(db),
+		EmailRepo:           NewEmailRepository(db),
+		CompanyRepo:         NewCompanyRepository(db),
+		ConsentRepo:         NewConsentRepository(db),
+		ABTestRepo:          NewABTestRepository(db),
+		SegmentRepo:         NewSegmentRepository(db),
+		ExchangeRateRepo:    NewExchangeRateRepository(db),
+		ProductRepo:         NewProductRepository(db),
+		QuoteRepo:           NewQuoteRepository(db),
+		CloseReasonRepo:     NewCloseReasonRepository(db),
+		AccountRepo:         NewAccountRepository(db),
+		SearchRepo:          NewSearchRepository(db),
+		PhoneRepo:           NewPhoneRepository(db),
+		SavedViewRepo:       NewSavedViewRepository(db),
+		AuditRepo:           NewAuditRepository(db),
+		BulkOperationRepo:   NewBulkOperationRepository(db),
+		TrashRepo:           NewTrashRepository(db),
+		TagRepo:             NewTagRepository(db),
+		WebhookRepo:         NewWebhookRepository(db),
+		OutboxRepo:          NewOutboxRepository(db),
 	}
 }
 
@@ -79,6 +119,86 @@ type GormScoreRepository struct {
 	DB *gorm.DB
 }
 
+type gormAttributionRepository struct {
+	db *gorm.DB
+}
+
+type gormEmailRepository struct {
+	db *gorm.DB
+}
+
+type gormCompanyRepository struct {
+	db *gorm.DB
+}
+
+type gormConsentRepository struct {
+	db *gorm.DB
+}
+
+type gormABTestRepository struct {
+	db *gorm.DB
+}
+
+type gormSegmentRepository struct {
+	db *gorm.DB
+}
+
+type gormExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+type gormProductRepository struct {
+	db *gorm.DB
+}
+
+type gormQuoteRepository struct {
+	db *gorm.DB
+}
+
+type gormCloseReasonRepository struct {
+	db *gorm.DB
+}
+
+type gormAccountRepository struct {
+	db *gorm.DB
+}
+
+type gormSearchRepository struct {
+	db *gorm.DB
+}
+
+type gormPhoneRepository struct {
+	db *gorm.DB
+}
+
+type gormSavedViewRepository struct {
+	db *gorm.DB
+}
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+type gormBulkOperationRepository struct {
+	db *gorm.DB
+}
+
+type gormTrashRepository struct {
+	db *gorm.DB
+}
+
+type gormTagRepository struct {
+	db *gorm.DB
+}
+
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
 // NewLeadRepository creates a new lead repository
 func NewLeadRepository(db *gorm.DB) models.LeadRepository {
 	return &gormLeadRepository{db: db}
@@ -132,3 +252,103 @@ func NewNurtureRepository(db *gorm.DB) models.NurtureRepository {
 func NewLeadScoreRepository(db *gorm.DB) models.ScoreRepository {
 	return &GormScoreRepository{DB: db}
 }
+
+// NewAttributionRepository creates a new attribution repository
+func NewAttributionRepository(db *gorm.DB) models.AttributionRepository {
+	return &gormAttributionRepository{db: db}
+}
+
+// NewEmailRepository creates a new email repository
+func NewEmailRepository(db *gorm.DB) models.EmailRepository {
+	return &gormEmailRepository{db: db}
+}
+
+// NewCompanyRepository creates a new company repository
+func NewCompanyRepository(db *gorm.DB) models.CompanyRepository {
+	return &gormCompanyRepository{db: db}
+}
+
+// NewConsentRepository creates a new consent repository
+func NewConsentRepository(db *gorm.DB) models.ConsentRepository {
+	return &gormConsentRepository{db: db}
+}
+
+// NewABTestRepository creates a new A/B test repository
+func NewABTestRepository(db *gorm.DB) models.ABTestRepository {
+	return &gormABTestRepository{db: db}
+}
+
+// NewSegmentRepository creates a new segment repository
+func NewSegmentRepository(db *gorm.DB) models.SegmentRepository {
+	return &gormSegmentRepository{db: db}
+}
+
+// NewExchangeRateRepository creates a new exchange rate repository
+func NewExchangeRateRepository(db *gorm.DB) models.ExchangeRateRepository {
+	return &gormExchangeRateRepository{db: db}
+}
+
+// NewProductRepository creates a new product repository
+func NewProductRepository(db *gorm.DB) models.ProductRepository {
+	return &gormProductRepository{db: db}
+}
+
+// NewQuoteRepository creates a new quote repository
+func NewQuoteRepository(db *gorm.DB) models.QuoteRepository {
+	return &gormQuoteRepository{db: db}
+}
+
+// NewCloseReasonRepository creates a new close reason repository
+func NewCloseReasonRepository(db *gorm.DB) models.CloseReasonRepository {
+	return &gormCloseReasonRepository{db: db}
+}
+
+// NewAccountRepository creates a new account repository
+func NewAccountRepository(db *gorm.DB) models.AccountRepository {
+	return &gormAccountRepository{db: db}
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(db *gorm.DB) models.SearchRepository {
+	return &gormSearchRepository{db: db}
+}
+
+// NewPhoneRepository creates a new phone number repository
+func NewPhoneRepository(db *gorm.DB) models.PhoneRepository {
+	return &gormPhoneRepository{db: db}
+}
+
+// NewSavedViewRepository creates a new saved view repository
+func NewSavedViewRepository(db *gorm.DB) models.SavedViewRepository {
+	return &gormSavedViewRepository{db: db}
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) models.AuditRepository {
+	return &gormAuditRepository{db: db}
+}
+
+// NewBulkOperationRepository creates a new bulk operation repository
+func NewBulkOperationRepository(db *gorm.DB) models.BulkOperationRepository {
+	return &gormBulkOperationRepository{db: db}
+}
+
+// NewTrashRepository creates a new recycle bin repository
+func NewTrashRepository(db *gorm.DB) models.TrashRepository {
+	return &gormTrashRepository{db: db}
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(db *gorm.DB) models.TagRepository {
+	return &gormTagRepository{db: db}
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) models.WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) models.OutboxRepository {
+	return &gormOutboxRepository{db: db}
+}
@@ -0,0 +1,440 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// viewTables are the tables each entity's views list
+var viewTables = map[string]string{
+	models.ViewEntityLead:    "leads",
+	models.ViewEntityDeal:    "deals",
+	models.ViewEntityContact: "contacts",
+}
+
+// GetSavedViews returns the views of an entity a user can see: their own and those shared with
+// the company. An empty entity returns the views of every entity.
+func (r *gormSavedViewRepository) GetSavedViews(entity string, userID int, companyId int) ([]models.SavedView, error) {
+	var views []models.SavedView
+	query := r.db.Where("company_id = ? AND (owner_id = ? OR is_shared = ?)", companyId, userID, true)
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	err := query.Order("entity, name").Find(&views).Error
+	return views, err
+}
+
+// GetSavedViewByID returns a saved view by ID
+func (r *gormSavedViewRepository) GetSavedViewByID(id int) (*models.SavedView, error) {
+	var view models.SavedView
+	err := r.db.First(&view, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &view, nil
+}
+
+// CreateSavedView creates a new saved view
+func (r *gormSavedViewRepository) CreateSavedView(view *models.SavedView) error {
+	return r.db.Create(view).Error
+}
+
+// UpdateSavedView updates a saved view
+func (r *gormSavedViewRepository) UpdateSavedView(view *models.SavedView) error {
+	return r.db.Omit("CreatedAt").Save(view).Error
+}
+
+// DeleteSavedView deletes a saved view
+func (r *gormSavedViewRepository) DeleteSavedView(id int) error {
+	return r.db.Delete(&models.SavedView{}, id).Error
+}
+
+// ListLeads returns a page of a company's leads matching every filter, keyed by field name with
+// their lead field values, and how many match in all
+func (r *gormSavedViewRepository) ListLeads(filters []models.ViewFilter, order models.ViewSort, offset int, limit int, companyId int) ([]map[string]string, int64, error) {
+	query, err := r.viewQuery(models.ViewEntityLead, filters, companyId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var leads []models.Lead
+	if err := r.viewOrder(query, models.ViewEntityLead, order, companyId).Offset(offset).Limit(limit).Find(&leads).Error; err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.leadRows(leads)
+	return rows, total, err
+}
+
+// ListDeals returns a page of a company's deals matching every filter, and how many match in all
+func (r *gormSavedViewRepository) ListDeals(filters []models.ViewFilter, order models.ViewSort, offset int, limit int, companyId int) ([]models.Deal, int64, error) {
+	query, err := r.viewQuery(models.ViewEntityDeal, filters, companyId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	deals := []models.Deal{}
+	err = r.viewOrder(query, models.ViewEntityDeal, order, companyId).Offset(offset).Limit(limit).Find(&deals).Error
+	return deals, total, err
+}
+
+// ListContacts returns a page of a company's contacts matching every filter, and how many match
+// in all
+func (r *gormSavedViewRepository) ListContacts(filters []models.ViewFilter, order models.ViewSort, offset int, limit int, companyId int) ([]models.Contact, int64, error) {
+	query, err := r.viewQuery(models.ViewEntityContact, filters, companyId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	contacts := []models.Contact{}
+	err = r.viewOrder(query, models.ViewEntityContact, order, companyId).Offset(offset).Limit(limit).Find(&contacts).Error
+	return contacts, total, err
+}
+
+// FindIDs returns the IDs of up to limit of a company's records of an entity matching every
+// filter, in ID order
+func (r *gormSavedViewRepository) FindIDs(entity string, filters []models.ViewFilter, limit int, companyId int) ([]int, error) {
+	query, err := r.viewQuery(entity, filters, companyId)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	column := viewTables[entity] + ".id"
+	err = query.Order(column).Limit(limit).Pluck(column, &ids).Error
+	return ids, err
+}
+
+// GetDealBoard groups a company's deals matching every filter by stage, totalling each stage in
+// the reporting currency and returning a page of its deals. The pipeline stages come first, even
+// when empty, then any other stage deals are in; columns limits the board to the stages given.
+// A limit of 0 returns the column totals without cards.
+func (r *gormSavedViewRepository) GetDealBoard(filters []models.ViewFilter, order models.ViewSort, columns []string, offset int, limit int, companyId int) (*models.KanbanBoard, error) {
+	query, err := r.viewQuery(models.ViewEntityDeal, filters, companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := r.boardCounts(query, "deals.stage")
+	if err != nil {
+		return nil, err
+	}
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := conversion.sumDealAmounts(query.Session(&gorm.Session{}), "deals.stage", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.KanbanBoard{
+		Entity:   models.ViewEntityDeal,
+		GroupBy:  "stage",
+		Currency: conversion.currency,
+		Columns:  []models.KanbanColumn{},
+	}
+	for _, key := range boardColumnKeys(models.DealPipelineStages, counts, columns) {
+		column := models.KanbanColumn{
+			Key:             key,
+			Count:           counts[key],
+			Total:           totals.total(key),
+			TotalByCurrency: totals.byCurrency(key),
+			Cards:           []models.Deal{},
+		}
+		if limit > 0 && column.Count > int64(offset) {
+			var deals []models.Deal
+			if err := r.viewOrder(query.Session(&gorm.Session{}).Where("deals.stage = ?", key), models.ViewEntityDeal, order, companyId).
+				Offset(offset).Limit(limit).Find(&deals).Error; err != nil {
+				return nil, err
+			}
+			column.Cards = deals
+			column.HasMore = int64(offset+len(deals)) < column.Count
+		}
+		board.Columns = append(board.Columns, column)
+	}
+	return board, nil
+}
+
+// GetLeadBoard groups a company's leads matching every filter by status, totalling the open
+// deals of each status's leads in the reporting currency and returning a page of its leads. The
+// lead statuses come first, even when empty, then any other status leads have; columns limits
+// the board to the statuses given. A limit of 0 returns the column totals without cards.
+func (r *gormSavedViewRepository) GetLeadBoard(filters []models.ViewFilter, order models.ViewSort, columns []string, offset int, limit int, companyId int) (*models.KanbanBoard, error) {
+	query, err := r.viewQuery(models.ViewEntityLead, filters, companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := r.boardCounts(query, "leads.status")
+	if err != nil {
+		return nil, err
+	}
+	conversion, err := reportingCurrency(r.db, companyId)
+	if err != nil {
+		return nil, err
+	}
+	closedStages := append(append([]string{}, models.DealStagesWon...), models.DealStagesLost...)
+	openDeals := r.db.Model(&models.Deal{}).
+		Joins("INNER JOIN leads ON leads.id = deals.lead_id").
+		Where("deals.stage NOT IN ? AND leads.id IN (?)", closedStages, query.Session(&gorm.Session{}).Select("leads.id"))
+	totals, err := conversion.sumDealAmounts(openDeals, "leads.status", "deals.amount", "deals.created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.KanbanBoard{
+		Entity:   models.ViewEntityLead,
+		GroupBy:  "status",
+		Currency: conversion.currency,
+		Columns:  []models.KanbanColumn{},
+	}
+	for _, key := range boardColumnKeys(models.LeadStatuses, counts, columns) {
+		column := models.KanbanColumn{
+			Key:             key,
+			Count:           counts[key],
+			Total:           totals.total(key),
+			TotalByCurrency: totals.byCurrency(key),
+			Cards:           []map[string]string{},
+		}
+		if limit > 0 && column.Count > int64(offset) {
+			var leads []models.Lead
+			if err := r.viewOrder(query.Session(&gorm.Session{}).Where("leads.status = ?", key), models.ViewEntityLead, order, companyId).
+				Offset(offset).Limit(limit).Find(&leads).Error; err != nil {
+				return nil, err
+			}
+			rows, err := r.leadRows(leads)
+			if err != nil {
+				return nil, err
+			}
+			column.Cards = rows
+			column.HasMore = int64(offset+len(leads)) < column.Count
+		}
+		board.Columns = append(board.Columns, column)
+	}
+	return board, nil
+}
+
+// boardCounts counts the records a query selects per value of groupExpr
+func (r *gormSavedViewRepository) boardCounts(query *gorm.DB, groupExpr string) (map[string]int64, error) {
+	var rows []struct {
+		Grp   string
+		Count int64
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select(groupExpr + " AS grp, COUNT(*) AS count").
+		Group(groupExpr).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Grp] = row.Count
+	}
+	return counts, nil
+}
+
+// boardColumnKeys returns the columns of a board: only those given, or the standard ones in
+// order followed by any others that have records, sorted by name
+func boardColumnKeys(standard []string, counts map[string]int64, only []string) []string {
+	if len(only) > 0 {
+		return uniqueStrings(only)
+	}
+
+	keys := append([]string{}, standard...)
+	known := make(map[string]bool, len(standard))
+	for _, key := range standard {
+		known[key] = true
+	}
+	var others []string
+	for key := range counts {
+		if !known[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+// leadRows returns leads keyed by field name with their lead field values, in the order given
+func (r *gormSavedViewRepository) leadRows(leads []models.Lead) ([]map[string]string, error) {
+	rows := make([]map[string]string, len(leads))
+	if len(leads) == 0 {
+		return rows, nil
+	}
+
+	ids := make([]uint, len(leads))
+	for i := range leads {
+		rows[i] = leadValues(&leads[i])
+		ids[i] = leads[i].ID
+	}
+
+	var results []models.LeadFieldResult
+	if err := r.db.Table("crm_field_data").
+		Select("crm_field_data.submit_id, crm_field_data.crm_field_id, lead_field_configs.field_name, crm_field_data.field_value").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("crm_field_data.submit_id IN ?", ids).
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[uint][]models.LeadFieldResult)
+	for _, result := range results {
+		grouped[result.SubmitID] = append(grouped[result.SubmitID], result)
+	}
+	for i := range leads {
+		mergeLeadFieldValues(rows[i], grouped[leads[i].ID])
+	}
+	return rows, nil
+}
+
+// viewQuery builds a query over a company's records of an entity matching every filter
+func (r *gormSavedViewRepository) viewQuery(entity string, filters []models.ViewFilter, companyId int) (*gorm.DB, error) {
+	var query *gorm.DB
+	switch entity {
+	case models.ViewEntityLead:
+		query = r.db.Model(&models.Lead{})
+	case models.ViewEntityDeal:
+		query = r.db.Model(&models.Deal{})
+	case models.ViewEntityContact:
+		query = r.db.Model(&models.Contact{})
+	default:
+		return nil, fmt.Errorf("unsupported view entity %q", entity)
+	}
+	query = query.Where(viewTables[entity]+".company_id = ?", companyId)
+
+	for _, filter := range filters {
+		clause, args, err := r.viewFilterClause(entity, filter, companyId)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(clause, args...)
+	}
+	return query, nil
+}
+
+// viewFilterClause returns the SQL condition for a single view filter, on a column or, for
+// leads, an EAV field by field name
+func (r *gormSavedViewRepository) viewFilterClause(entity string, filter models.ViewFilter, companyId int) (string, []interface{}, error) {
+	if !models.ViewFilterOperators[filter.Operator] {
+		return "", nil, fmt.Errorf("unsupported operator %q", filter.Operator)
+	}
+	if filter.Field == models.ViewFilterTags {
+		return r.viewTagClause(entity, filter, companyId)
+	}
+	isColumn := models.ViewFields[entity][filter.Field]
+	if !isColumn && (entity != models.ViewEntityLead || filter.Field == "") {
+		return "", nil, fmt.Errorf("unknown field %q", filter.Field)
+	}
+
+	// condition is applied to the column or field value, substituted for %s
+	var condition string
+	var args []interface{}
+	switch filter.Operator {
+	case "eq", "neq":
+		condition, args = "%s = ?", []interface{}{filter.Value}
+	case "contains":
+		condition, args = "%s LIKE ?", []interface{}{"%" + filter.Value + "%"}
+	case "starts_with":
+		condition, args = "%s LIKE ?", []interface{}{filter.Value + "%"}
+	case "gt", "gte", "lt", "lte":
+		comparison := map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}[filter.Operator]
+		condition, args = "%s "+comparison+" ?", []interface{}{filter.Value}
+		// Field values are text, so compare them as numbers when the value is one
+		if number, err := strconv.ParseFloat(filter.Value, 64); err == nil && !isColumn {
+			condition, args = "CAST(%s AS DECIMAL(20,4)) "+comparison+" ?", []interface{}{number}
+		}
+	case "in", "not_in":
+		if len(filter.Values) == 0 {
+			return "", nil, fmt.Errorf("%s filter on %q needs values", filter.Operator, filter.Field)
+		}
+		condition, args = "%s IN ?", []interface{}{filter.Values}
+	case "empty", "not_empty":
+		condition = "COALESCE(%s, '') <> ''"
+	}
+	negate := filter.Operator == "neq" || filter.Operator == "not_in" || filter.Operator == "empty"
+
+	if isColumn {
+		column := viewTables[entity] + "." + filter.Field
+		clause := fmt.Sprintf(condition, column)
+		if negate {
+			return "NOT (" + column + " IS NOT NULL AND " + clause + ")", args, nil
+		}
+		return clause, args, nil
+	}
+
+	fieldMatch := r.db.Table("crm_field_data").
+		Select("crm_field_data.submit_id").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("lead_field_configs.company_id = ? AND lead_field_configs.field_name = ?", companyId, filter.Field).
+		Where(fmt.Sprintf(condition, "crm_field_data.field_value"), args...)
+	if negate {
+		return "leads.id NOT IN (?)", []interface{}{fieldMatch}, nil
+	}
+	return "leads.id IN (?)", []interface{}{fieldMatch}, nil
+}
+
+// viewTagClause returns the SQL condition for a tags filter. View entities are also tag entities.
+func (r *gormSavedViewRepository) viewTagClause(entity string, filter models.ViewFilter, companyId int) (string, []interface{}, error) {
+	t := tagTables[entity]
+	tagged := r.db.Table(t.table).Select(t.column).Where("company_id = ?", companyId)
+	switch filter.Operator {
+	case "in", "not_in":
+		if len(filter.Values) == 0 {
+			return "", nil, fmt.Errorf("%s filter on tags needs values", filter.Operator)
+		}
+		tagged = tagged.Where("tag IN ?", filter.Values)
+	case "empty", "not_empty":
+	default:
+		return "", nil, fmt.Errorf("unsupported operator %q for tags", filter.Operator)
+	}
+	if filter.Operator == "not_in" || filter.Operator == "empty" {
+		return viewTables[entity] + ".id NOT IN (?)", []interface{}{tagged}, nil
+	}
+	return viewTables[entity] + ".id IN (?)", []interface{}{tagged}, nil
+}
+
+// viewOrder orders a view's records by a column or, for leads, an EAV field by field name, then
+// newest first. Records are newest first when no sort is given.
+func (r *gormSavedViewRepository) viewOrder(query *gorm.DB, entity string, order models.ViewSort, companyId int) *gorm.DB {
+	table := viewTables[entity]
+	direction := " ASC"
+	if order.Desc {
+		direction = " DESC"
+	}
+
+	switch {
+	case order.Field == "":
+		return query.Order(table + ".id DESC")
+	case models.ViewFields[entity][order.Field]:
+		return query.Order(table + "." + order.Field + direction).Order(table + ".id DESC")
+	case entity == models.ViewEntityLead:
+		return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL: `(SELECT crm_field_data.field_value FROM crm_field_data
+				INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id
+				WHERE crm_field_data.submit_id = leads.id AND lead_field_configs.company_id = ? AND lead_field_configs.field_name = ?
+				ORDER BY crm_field_data.field_value <> '' DESC LIMIT 1)` + direction + ", leads.id DESC",
+			Vars: []interface{}{companyId, order.Field},
+		}})
+	}
+	return query.Order(table + ".id DESC")
+}
@@ -0,0 +1,545 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	unicodetokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"gorm.io/gorm"
+)
+
+// searchBatchSize is how many documents are written to the search index at a time when rebuilding
+const searchBatchSize = 500
+
+var (
+	searchIndexMu sync.Mutex
+	searchIndex   bleve.Index
+)
+
+// searchDocument is what the search index stores for a lead, contact, deal or account. Only the
+// title and subtitle are stored; the body is indexed for matching.
+type searchDocument struct {
+	Type      string `json:"type"`
+	CompanyID string `json:"company_id"`
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle"`
+	Body      string `json:"body"`
+}
+
+// searchScope narrows the rows a search source reads
+type searchScope func(db *gorm.DB) *gorm.DB
+
+// searchSources build the search documents, keyed by document ID, of one record type
+var searchSources = map[string]func(db *gorm.DB, scope searchScope) (map[string]searchDocument, error){
+	models.SearchTypeLead:    leadSearchDocuments,
+	models.SearchTypeContact: contactSearchDocuments,
+	models.SearchTypeDeal:    dealSearchDocuments,
+	models.SearchTypeAccount: accountSearchDocuments,
+}
+
+// OpenSearchIndex opens the full-text index behind global search at path, creating it if it does
+// not exist. With an empty path the index is kept in memory and must be rebuilt on every start.
+func OpenSearchIndex(path string) error {
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+
+	// An index on disk is locked while open, so any index already open is closed first
+	if searchIndex != nil {
+		searchIndex.Close()
+		searchIndex = nil
+	}
+
+	var index bleve.Index
+	var err error
+	if path == "" {
+		index, err = bleve.NewMemOnly(newSearchMapping())
+	} else if _, statErr := os.Stat(path); statErr == nil {
+		index, err = bleve.Open(path)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		index, err = bleve.New(path, newSearchMapping())
+	}
+	if err != nil {
+		return err
+	}
+	searchIndex = index
+	return nil
+}
+
+// openSearchIndex returns the search index, keeping one in memory if none has been opened
+func openSearchIndex() (bleve.Index, error) {
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+
+	if searchIndex == nil {
+		index, err := bleve.NewMemOnly(newSearchMapping())
+		if err != nil {
+			return nil, err
+		}
+		searchIndex = index
+	}
+	return searchIndex, nil
+}
+
+// newSearchMapping indexes text without stop words, so every word of a name or title can be
+// searched for, and keeps the type and company as exact terms for filtering and facets
+func newSearchMapping() mapping.IndexMapping {
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.AddCustomAnalyzer("crm_text", map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicodetokenizer.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	indexMapping.DefaultAnalyzer = "crm_text"
+
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.IncludeInAll = false
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = "crm_text"
+	body := bleve.NewTextFieldMapping()
+	body.Analyzer = "crm_text"
+	body.Store = false
+
+	document := bleve.NewDocumentMapping()
+	document.AddFieldMappingsAt("type", keyword)
+	document.AddFieldMappingsAt("company_id", keyword)
+	document.AddFieldMappingsAt("title", text)
+	document.AddFieldMappingsAt("subtitle", text)
+	document.AddFieldMappingsAt("body", body)
+	indexMapping.DefaultMapping = document
+	return indexMapping
+}
+
+// indexForSearch brings the search index in step with the records of a type after they are
+// written, removing any that no longer exist. Failures are logged rather than failing the write;
+// the index catches up on the next write or reindex.
+func indexForSearch(db *gorm.DB, docType string, ids ...int) {
+	if len(ids) == 0 {
+		return
+	}
+	index, err := openSearchIndex()
+	if err != nil {
+		log.Printf("Failed to open search index: %v", err)
+		return
+	}
+
+	documents, err := searchSources[docType](db, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN ?", ids)
+	})
+	if err != nil {
+		log.Printf("Failed to index %s %v for search: %v", docType, ids, err)
+		return
+	}
+
+	batch := index.NewBatch()
+	for _, id := range ids {
+		docID := searchDocumentID(docType, id)
+		if document, ok := documents[docID]; ok {
+			if err := batch.Index(docID, document); err != nil {
+				log.Printf("Failed to index %s for search: %v", docID, err)
+			}
+		} else {
+			batch.Delete(docID)
+		}
+	}
+	if err := index.Batch(batch); err != nil {
+		log.Printf("Failed to index %s %v for search: %v", docType, ids, err)
+	}
+}
+
+// Search finds a company's leads, contacts, deals and accounts matching every word of a query,
+// allowing typos and words still being typed, optionally limited to some record types
+func (r *gormSearchRepository) Search(text string, types []string, offset int, limit int, companyId int) (*models.SearchResults, error) {
+	index, err := openSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := searchTextQuery(text)
+	company := bleve.NewTermQuery(strconv.Itoa(companyId))
+	company.SetField("company_id")
+	companyMatches := bleve.NewConjunctionQuery(company, matches)
+
+	hitsQuery := query.Query(companyMatches)
+	if len(types) > 0 {
+		typeQueries := make([]query.Query, 0, len(types))
+		for _, docType := range types {
+			typeQuery := bleve.NewTermQuery(docType)
+			typeQuery.SetField("type")
+			typeQueries = append(typeQueries, typeQuery)
+		}
+		hitsQuery = bleve.NewConjunctionQuery(company, matches, bleve.NewDisjunctionQuery(typeQueries...))
+	}
+
+	request := bleve.NewSearchRequestOptions(hitsQuery, limit, offset, false)
+	request.Fields = []string{"type", "title", "subtitle"}
+	result, err := index.Search(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Facets count every type's matches, not just those of the types the hits are limited to
+	facetRequest := bleve.NewSearchRequestOptions(companyMatches, 0, 0, false)
+	facetRequest.AddFacet("type", bleve.NewFacetRequest("type", len(models.SearchTypes)))
+	facetResult, err := index.Search(facetRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &models.SearchResults{
+		Query:  text,
+		Total:  result.Total,
+		Hits:   make([]models.SearchHit, 0, len(result.Hits)),
+		Facets: make(map[string]int64, len(models.SearchTypes)),
+	}
+	for _, docType := range models.SearchTypes {
+		results.Facets[docType] = 0
+	}
+	if facet, ok := facetResult.Facets["type"]; ok && facet.Terms != nil {
+		for _, term := range facet.Terms.Terms() {
+			results.Facets[term.Term] = int64(term.Count)
+		}
+	}
+
+	for _, hit := range result.Hits {
+		docType, id := parseSearchDocumentID(hit.ID)
+		title, _ := hit.Fields["title"].(string)
+		subtitle, _ := hit.Fields["subtitle"].(string)
+		results.Hits = append(results.Hits, models.SearchHit{
+			Type:     docType,
+			ID:       id,
+			Title:    title,
+			Subtitle: subtitle,
+			Score:    hit.Score,
+		})
+	}
+	return results, nil
+}
+
+// searchTextQuery requires every word of the text to match exactly, as a prefix, or within a
+// word length dependent edit distance, ranking exact and title matches highest
+func searchTextQuery(text string) query.Query {
+	words := strings.Fields(strings.ToLower(text))
+	wordQueries := make([]query.Query, 0, len(words))
+	for _, word := range words {
+		exact := bleve.NewMatchQuery(word)
+		exact.SetBoost(3)
+		title := bleve.NewMatchQuery(word)
+		title.SetField("title")
+		title.SetBoost(2)
+		alternatives := []query.Query{exact, title}
+
+		length := utf8.RuneCountInString(word)
+		if length >= 2 {
+			prefix := bleve.NewPrefixQuery(word)
+			prefix.SetBoost(2)
+			alternatives = append(alternatives, prefix)
+		}
+		if fuzziness := searchFuzziness(length); fuzziness > 0 {
+			fuzzy := bleve.NewMatchQuery(word)
+			fuzzy.SetFuzziness(fuzziness)
+			alternatives = append(alternatives, fuzzy)
+		}
+		wordQueries = append(wordQueries, bleve.NewDisjunctionQuery(alternatives...))
+	}
+	if len(wordQueries) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	return bleve.NewConjunctionQuery(wordQueries...)
+}
+
+// searchFuzziness is how many typos a search word of a given length may contain
+func searchFuzziness(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Reindex rebuilds a company's search documents from the database, dropping any for records that
+// no longer exist, and returns how many documents it indexed
+func (r *gormSearchRepository) Reindex(companyId int) (int, error) {
+	index, err := openSearchIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect the company's current documents so those no longer backed by a record can be dropped
+	company := bleve.NewTermQuery(strconv.Itoa(companyId))
+	company.SetField("company_id")
+	stale := make(map[string]bool)
+	for from := 0; ; from += searchBatchSize {
+		request := bleve.NewSearchRequestOptions(company, searchBatchSize, from, false)
+		request.SortBy([]string{"_id"})
+		result, err := index.Search(request)
+		if err != nil {
+			return 0, err
+		}
+		for _, hit := range result.Hits {
+			stale[hit.ID] = true
+		}
+		if len(result.Hits) < searchBatchSize {
+			break
+		}
+	}
+
+	indexed, err := r.indexAll(index, func(db *gorm.DB) *gorm.DB {
+		return db.Where("company_id = ?", companyId)
+	}, stale)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := index.NewBatch()
+	for docID := range stale {
+		batch.Delete(docID)
+	}
+	return indexed, index.Batch(batch)
+}
+
+// EnsureIndexed builds the search index from the database if it is empty, as it is when kept in
+// memory or newly created
+func (r *gormSearchRepository) EnsureIndexed() error {
+	index, err := openSearchIndex()
+	if err != nil {
+		return err
+	}
+	count, err := index.DocCount()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = r.indexAll(index, func(db *gorm.DB) *gorm.DB { return db }, nil)
+	return err
+}
+
+// indexAll indexes every record of every type a scope selects, removing each from stale
+func (r *gormSearchRepository) indexAll(index bleve.Index, scope searchScope, stale map[string]bool) (int, error) {
+	indexed := 0
+	for _, docType := range models.SearchTypes {
+		documents, err := searchSources[docType](r.db, scope)
+		if err != nil {
+			return indexed, err
+		}
+
+		batch := index.NewBatch()
+		for docID, document := range documents {
+			if err := batch.Index(docID, document); err != nil {
+				return indexed, err
+			}
+			delete(stale, docID)
+			indexed++
+			if batch.Size() >= searchBatchSize {
+				if err := index.Batch(batch); err != nil {
+					return indexed, err
+				}
+				batch = index.NewBatch()
+			}
+		}
+		if err := index.Batch(batch); err != nil {
+			return indexed, err
+		}
+	}
+	return indexed, nil
+}
+
+// leadSearchDocuments indexes a lead's name, email, phone, company and notes along with every
+// value captured in its lead fields
+func leadSearchDocuments(db *gorm.DB, scope searchScope) (map[string]searchDocument, error) {
+	var leads []models.Lead
+	if err := scope(db.Model(&models.Lead{})).Find(&leads).Error; err != nil {
+		return nil, err
+	}
+	documents := make(map[string]searchDocument, len(leads))
+	if len(leads) == 0 {
+		return documents, nil
+	}
+
+	ids := make([]uint, len(leads))
+	for i, lead := range leads {
+		ids[i] = lead.ID
+	}
+	var values []models.LeadFieldResult
+	if err := db.Table("crm_field_data").
+		Select("crm_field_data.submit_id, lead_field_configs.field_name, crm_field_data.field_value").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("crm_field_data.submit_id IN ?", ids).
+		Scan(&values).Error; err != nil {
+		return nil, err
+	}
+	fields := make(map[uint]map[string]string, len(leads))
+	for _, value := range values {
+		if fields[value.SubmitID] == nil {
+			fields[value.SubmitID] = make(map[string]string)
+		}
+		fields[value.SubmitID][value.FieldName] = value.FieldValue
+	}
+
+	for _, lead := range leads {
+		leadFields := fields[lead.ID]
+		field := func(name string, fallback string) string {
+			if fallback != "" {
+				return fallback
+			}
+			return leadFields[name]
+		}
+		name := field("name", lead.Name)
+		email := field("email", lead.Email)
+		company := field("company", lead.Company)
+		if name == "" {
+			name = email
+		}
+		if name == "" {
+			name = "Lead #" + strconv.Itoa(int(lead.ID))
+		}
+
+		body := []string{lead.Name, searchEmailText(lead.Email), searchPhoneText(lead.Phone), lead.Company, lead.Notes}
+		for fieldName, value := range leadFields {
+			switch fieldName {
+			case "email":
+				value = searchEmailText(value)
+			case "phone":
+				value = searchPhoneText(value)
+			}
+			body = append(body, value)
+		}
+
+		documents[searchDocumentID(models.SearchTypeLead, int(lead.ID))] = searchDocument{
+			Type:      models.SearchTypeLead,
+			CompanyID: strconv.Itoa(lead.CompanyId),
+			Title:     name,
+			Subtitle:  firstNonEmpty(company, email),
+			Body:      strings.Join(body, " "),
+		}
+	}
+	return documents, nil
+}
+
+// contactSearchDocuments indexes a contact's name, email, phone, position and notes
+func contactSearchDocuments(db *gorm.DB, scope searchScope) (map[string]searchDocument, error) {
+	var contacts []models.Contact
+	if err := scope(db.Model(&models.Contact{})).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]searchDocument, len(contacts))
+	for _, contact := range contacts {
+		documents[searchDocumentID(models.SearchTypeContact, contact.ID)] = searchDocument{
+			Type:      models.SearchTypeContact,
+			CompanyID: strconv.Itoa(contact.CompanyId),
+			Title:     contact.Name,
+			Subtitle:  firstNonEmpty(contact.Email, contact.Phone),
+			Body: strings.Join([]string{searchEmailText(contact.Email), searchPhoneText(contact.Phone),
+				contact.Position, contact.Notes}, " "),
+		}
+	}
+	return documents, nil
+}
+
+// dealSearchDocuments indexes a deal's title, notes and close notes
+func dealSearchDocuments(db *gorm.DB, scope searchScope) (map[string]searchDocument, error) {
+	var deals []models.Deal
+	if err := scope(db.Model(&models.Deal{})).Find(&deals).Error; err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]searchDocument, len(deals))
+	for _, deal := range deals {
+		documents[searchDocumentID(models.SearchTypeDeal, deal.ID)] = searchDocument{
+			Type:      models.SearchTypeDeal,
+			CompanyID: strconv.Itoa(deal.CompanyId),
+			Title:     deal.Title,
+			Subtitle:  deal.Stage,
+			Body:      strings.Join([]string{deal.Notes, deal.CloseNotes, deal.Competitor}, " "),
+		}
+	}
+	return documents, nil
+}
+
+// accountSearchDocuments indexes an account's name, domain, industry, phone, address and notes
+func accountSearchDocuments(db *gorm.DB, scope searchScope) (map[string]searchDocument, error) {
+	var accounts []models.Account
+	if err := scope(db.Model(&models.Account{})).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]searchDocument, len(accounts))
+	for _, account := range accounts {
+		documents[searchDocumentID(models.SearchTypeAccount, account.ID)] = searchDocument{
+			Type:      models.SearchTypeAccount,
+			CompanyID: strconv.Itoa(account.CompanyId),
+			Title:     account.Name,
+			Subtitle:  firstNonEmpty(account.Domain, account.Industry),
+			Body: strings.Join([]string{searchEmailText(account.Domain), account.Industry, searchPhoneText(account.Phone),
+				account.City, account.State, account.Country, account.Notes}, " "),
+		}
+	}
+	return documents, nil
+}
+
+func searchDocumentID(docType string, id int) string {
+	return docType + ":" + strconv.Itoa(id)
+}
+
+func parseSearchDocumentID(docID string) (string, int) {
+	docType, idStr, _ := strings.Cut(docID, ":")
+	id, _ := strconv.Atoi(idStr)
+	return docType, id
+}
+
+// searchEmailText indexes an email address or domain both whole and split into its words, so
+// jane.doe@example.com is found by jane, doe or example
+func searchEmailText(email string) string {
+	if email == "" {
+		return ""
+	}
+	words := strings.FieldsFunc(email, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return email + " " + strings.Join(words, " ")
+}
+
+// searchPhoneText indexes a phone number as written, as its digits alone, and as its last ten and
+// seven digits, so it is found however it is formatted and with or without its country or area code
+func searchPhoneText(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	text := []string{phone, digits}
+	for _, length := range []int{10, 7} {
+		if len(digits) > length {
+			text = append(text, digits[len(digits)-length:])
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
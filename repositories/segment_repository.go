@@ -0,0 +1,403 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultScoreBands are the score bands used when a company has not configured its own
+var defaultScoreBands = map[string][2]int{
+	"cold": {0, 30},
+	"warm": {31, 60},
+	"hot":  {61, 100},
+}
+
+// GetSegments returns a company's segments with pagination
+func (r *gormSegmentRepository) GetSegments(offset int, limit int, companyId int) ([]models.Segment, error) {
+	var segments []models.Segment
+	err := r.db.Where("company_id = ?", companyId).Order("name").Offset(offset).Limit(limit).Find(&segments).Error
+	return segments, err
+}
+
+// GetSegmentByID returns a segment by ID
+func (r *gormSegmentRepository) GetSegmentByID(id int) (*models.Segment, error) {
+	var segment models.Segment
+	err := r.db.First(&segment, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// CreateSegment creates a new segment
+func (r *gormSegmentRepository) CreateSegment(segment *models.Segment) error {
+	return r.db.Create(segment).Error
+}
+
+// UpdateSegment updates a segment
+func (r *gormSegmentRepository) UpdateSegment(segment *models.Segment) error {
+	return r.db.Omit("CreatedAt").Save(segment).Error
+}
+
+// DeleteSegment deletes a segment and its members, and stops campaigns and sequences targeting it
+func (r *gormSegmentRepository) DeleteSegment(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Campaign{}).Where("segment_id = ?", id).Update("segment_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.NurtureSequence{}).Where("segment_id = ?", id).Update("segment_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("segment_id = ?", id).Delete(&models.SegmentMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Segment{}, id).Error
+	})
+}
+
+// EvaluateSegment returns the IDs of a company's leads currently matching a filter
+func (r *gormSegmentRepository) EvaluateSegment(companyId int, filter *models.SegmentFilter) ([]int, error) {
+	query, err := r.segmentQuery(companyId, filter)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	err = query.Order("leads.id").Pluck("leads.id", &ids).Error
+	return ids, err
+}
+
+// PreviewSegment counts the leads matching a filter and returns a sample of their IDs
+func (r *gormSegmentRepository) PreviewSegment(companyId int, filter *models.SegmentFilter, sample int) (int64, []int, error) {
+	query, err := r.segmentQuery(companyId, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var count int64
+	if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	var ids []int
+	if err := query.Order("leads.id DESC").Limit(sample).Pluck("leads.id", &ids).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, ids, nil
+}
+
+// GetSegmentMembers returns the members of a segment as of its last refresh
+func (r *gormSegmentRepository) GetSegmentMembers(segmentID int, offset int, limit int) ([]models.SegmentMember, error) {
+	var members []models.SegmentMember
+	err := r.db.Where("segment_id = ?", segmentID).Order("lead_id").Offset(offset).Limit(limit).Find(&members).Error
+	return members, err
+}
+
+// ReplaceSegmentMembers stores a refreshed membership, keeping the time existing members were
+// added, and schedules the next refresh. It returns how many leads joined and left.
+func (r *gormSegmentRepository) ReplaceSegmentMembers(segment *models.Segment, leadIDs []int, refreshedAt time.Time) (int, int, error) {
+	added, removed := 0, 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current []int
+		if err := tx.Model(&models.SegmentMember{}).Where("segment_id = ?", segment.ID).Pluck("lead_id", &current).Error; err != nil {
+			return err
+		}
+
+		matched := make(map[int]bool, len(leadIDs))
+		for _, leadID := range leadIDs {
+			matched[leadID] = true
+		}
+		existing := make(map[int]bool, len(current))
+		var leaving []int
+		for _, leadID := range current {
+			existing[leadID] = true
+			if !matched[leadID] {
+				leaving = append(leaving, leadID)
+			}
+		}
+
+		var joining []models.SegmentMember
+		for leadID := range matched {
+			if !existing[leadID] {
+				joining = append(joining, models.SegmentMember{SegmentID: segment.ID, LeadID: leadID, AddedAt: refreshedAt})
+			}
+		}
+
+		if len(leaving) > 0 {
+			if err := tx.Where("segment_id = ? AND lead_id IN ?", segment.ID, leaving).Delete(&models.SegmentMember{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(joining) > 0 {
+			if err := tx.CreateInBatches(joining, 500).Error; err != nil {
+				return err
+			}
+		}
+		added, removed = len(joining), len(leaving)
+
+		updates := map[string]interface{}{
+			"member_count":      len(matched),
+			"last_refreshed_at": refreshedAt,
+			"next_refresh_at":   nil,
+		}
+		if segment.RefreshMinutes > 0 {
+			updates["next_refresh_at"] = refreshedAt.Add(time.Duration(segment.RefreshMinutes) * time.Minute)
+		}
+		return tx.Model(&models.Segment{}).Where("id = ?", segment.ID).Updates(updates).Error
+	})
+	return added, removed, err
+}
+
+// ClaimDueSegments returns the segments whose scheduled refresh is due, pushing each one's next
+// refresh forward so that other instances do not refresh it at the same time
+func (r *gormSegmentRepository) ClaimDueSegments(now time.Time, limit int) ([]models.Segment, error) {
+	const due = "refresh_minutes > 0 AND next_refresh_at IS NOT NULL AND next_refresh_at <= ?"
+	candidates := r.db.Model(&models.Segment{}).Where(due, now).Order("next_refresh_at")
+	return claimRows[models.Segment](r.db, candidates, limit, func(id int) *gorm.DB {
+		return r.db.Model(&models.Segment{}).
+			Where("id = ?", id).
+			Where(due, now).
+			Update("next_refresh_at", gorm.Expr("DATE_ADD(?, INTERVAL refresh_minutes MINUTE)", now))
+	})
+}
+
+// segmentQuery builds a query over a company's leads matching every rule of a filter, or any of
+// them when the filter's match is "any"
+func (r *gormSegmentRepository) segmentQuery(companyId int, filter *models.SegmentFilter) (*gorm.DB, error) {
+	query := r.db.Model(&models.Lead{}).Where("leads.company_id = ?", companyId)
+	if filter == nil || len(filter.Rules) == 0 {
+		return query, nil
+	}
+
+	clauses := make([]string, 0, len(filter.Rules))
+	var args []interface{}
+	for _, rule := range filter.Rules {
+		clause, ruleArgs, err := r.segmentRuleClause(companyId, rule)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, "("+clause+")")
+		args = append(args, ruleArgs...)
+	}
+
+	joiner := " AND "
+	if filter.Match == "any" {
+		joiner = " OR "
+	}
+	return query.Where(strings.Join(clauses, joiner), args...), nil
+}
+
+// segmentRuleClause returns the SQL condition on leads for a single segment rule
+func (r *gormSegmentRepository) segmentRuleClause(companyId int, rule models.SegmentRule) (string, []interface{}, error) {
+	if !models.SegmentRuleOperators[rule.Type][rule.Operator] {
+		return "", nil, fmt.Errorf("unsupported operator %q for %s rule", rule.Operator, rule.Type)
+	}
+
+	switch rule.Type {
+	case models.SegmentRuleField:
+		return r.fieldRuleClause(companyId, rule)
+
+	case models.SegmentRuleStatus:
+		if len(rule.Values) == 0 {
+			return "", nil, fmt.Errorf("status rule needs values")
+		}
+		if rule.Operator == "not_in" {
+			return "leads.status NOT IN ?", []interface{}{rule.Values}, nil
+		}
+		return "leads.status IN ?", []interface{}{rule.Values}, nil
+
+	case models.SegmentRuleScore:
+		return r.scoreRuleClause(companyId, rule)
+
+	case models.SegmentRuleTag:
+		if len(rule.Values) == 0 {
+			return "", nil, fmt.Errorf("tag rule needs values")
+		}
+		tagged := r.db.Model(&models.LeadTag{}).Select("lead_id").Where("company_id = ? AND tag IN ?", companyId, rule.Values)
+		switch rule.Operator {
+		case "has_none":
+			return "leads.id NOT IN (?)", []interface{}{tagged}, nil
+		case "has_all":
+			tagged = tagged.Group("lead_id").Having("COUNT(DISTINCT tag) = ?", len(uniqueStrings(rule.Values)))
+		}
+		return "leads.id IN (?)", []interface{}{tagged}, nil
+
+	case models.SegmentRuleActivity:
+		if !models.SegmentActivities[rule.Activity] {
+			return "", nil, fmt.Errorf("unsupported activity %q", rule.Activity)
+		}
+		if rule.Days <= 0 {
+			return "", nil, fmt.Errorf("activity rule needs a positive number of days")
+		}
+		active := r.activityLeads(companyId, rule.Activity, time.Now().AddDate(0, 0, -rule.Days))
+		if rule.Operator == "not_within" {
+			return "leads.id NOT IN (?)", []interface{}{active}, nil
+		}
+		return "leads.id IN (?)", []interface{}{active}, nil
+
+	case models.SegmentRuleCampaign:
+		if rule.CampaignID == nil {
+			return "", nil, fmt.Errorf("campaign rule needs a campaign_id")
+		}
+		operator := strings.TrimPrefix(rule.Operator, "not_")
+		var matched *gorm.DB
+		switch operator {
+		case "member":
+			matched = r.db.Table("campaign_leads").Select("lead_id").Where("campaign_id = ?", *rule.CampaignID)
+		case "sent":
+			matched = r.db.Model(&models.EmailMessage{}).Select("lead_id").
+				Where("campaign_id = ? AND sent_at IS NOT NULL AND lead_id IS NOT NULL", *rule.CampaignID)
+		default:
+			eventType := map[string]string{"opened": "open", "clicked": "click"}[operator]
+			matched = r.db.Table("email_events").Select("email_messages.lead_id").
+				Joins("INNER JOIN email_messages ON email_messages.id = email_events.message_id").
+				Where("email_messages.campaign_id = ? AND email_events.type = ? AND email_events.is_bot = ? AND email_messages.lead_id IS NOT NULL", *rule.CampaignID, eventType, false)
+		}
+		if strings.HasPrefix(rule.Operator, "not_") {
+			return "leads.id NOT IN (?)", []interface{}{matched}, nil
+		}
+		return "leads.id IN (?)", []interface{}{matched}, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported rule type %q", rule.Type)
+}
+
+// fieldRuleClause matches a lead column, or an EAV field by field name
+func (r *gormSegmentRepository) fieldRuleClause(companyId int, rule models.SegmentRule) (string, []interface{}, error) {
+	if rule.Field == "" {
+		return "", nil, fmt.Errorf("field rule needs a field")
+	}
+
+	// condition is applied to the column or field value, substituted for %s
+	var condition string
+	var value interface{}
+	switch rule.Operator {
+	case "eq", "neq":
+		condition, value = "%s = ?", rule.Value
+	case "contains":
+		condition, value = "%s LIKE ?", "%"+rule.Value+"%"
+	case "starts_with":
+		condition, value = "%s LIKE ?", rule.Value+"%"
+	case "gt", "lt":
+		comparison := map[string]string{"gt": ">", "lt": "<"}[rule.Operator]
+		condition, value = "%s "+comparison+" ?", rule.Value
+		if number, err := strconv.ParseFloat(rule.Value, 64); err == nil {
+			condition, value = "CAST(%s AS DECIMAL(20,4)) "+comparison+" ?", number
+		}
+	case "exists", "not_exists":
+		condition = "%s <> ''"
+	}
+	negate := rule.Operator == "neq" || rule.Operator == "not_exists"
+	var args []interface{}
+	if value != nil {
+		args = append(args, value)
+	}
+
+	if leadFilterColumns[rule.Field] {
+		column := "leads." + rule.Field
+		clause := column + " IS NOT NULL AND " + fmt.Sprintf(condition, column)
+		if negate {
+			return "NOT (" + clause + ")", args, nil
+		}
+		return clause, args, nil
+	}
+
+	fieldMatch := r.db.Table("crm_field_data").
+		Select("crm_field_data.submit_id").
+		Joins("INNER JOIN lead_field_configs ON lead_field_configs.id = crm_field_data.crm_field_id").
+		Where("lead_field_configs.company_id = ? AND lead_field_configs.field_name = ?", companyId, rule.Field).
+		Where(fmt.Sprintf(condition, "crm_field_data.field_value"), args...)
+	if negate {
+		return "leads.id NOT IN (?)", []interface{}{fieldMatch}, nil
+	}
+	return "leads.id IN (?)", []interface{}{fieldMatch}, nil
+}
+
+// scoreRuleClause matches leads by score range or by the company's named score bands
+func (r *gormSegmentRepository) scoreRuleClause(companyId int, rule models.SegmentRule) (string, []interface{}, error) {
+	if rule.Operator == "between" {
+		if rule.Min == nil && rule.Max == nil {
+			return "", nil, fmt.Errorf("score rule needs min or max")
+		}
+		clause := "leads.score IS NOT NULL"
+		var args []interface{}
+		if rule.Min != nil {
+			clause += " AND leads.score >= ?"
+			args = append(args, *rule.Min)
+		}
+		if rule.Max != nil {
+			clause += " AND leads.score <= ?"
+			args = append(args, *rule.Max)
+		}
+		return clause, args, nil
+	}
+
+	if len(rule.Values) == 0 {
+		return "", nil, fmt.Errorf("score rule needs score bands")
+	}
+	var scoreTypes []models.ScoreType
+	if err := r.db.Where("company_id = ?", companyId).Find(&scoreTypes).Error; err != nil {
+		return "", nil, err
+	}
+	bands := make(map[string][2]int, len(scoreTypes))
+	for _, scoreType := range scoreTypes {
+		bands[scoreType.Type] = [2]int{scoreType.MinScore, scoreType.MaxScore}
+	}
+	if len(bands) == 0 {
+		bands = defaultScoreBands
+	}
+
+	ranges := make([]string, 0, len(rule.Values))
+	var args []interface{}
+	for _, name := range rule.Values {
+		band, ok := bands[name]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown score band %q", name)
+		}
+		ranges = append(ranges, "leads.score BETWEEN ? AND ?")
+		args = append(args, band[0], band[1])
+	}
+	clause := "leads.score IS NOT NULL AND (" + strings.Join(ranges, " OR ") + ")"
+	if rule.Operator == "not_in" {
+		return "NOT (" + clause + ")", args, nil
+	}
+	return clause, args, nil
+}
+
+// activityLeads selects the company's leads with an activity since a time
+func (r *gormSegmentRepository) activityLeads(companyId int, activity string, since time.Time) *gorm.DB {
+	switch activity {
+	case "email_sent":
+		return r.db.Model(&models.EmailMessage{}).Select("lead_id").
+			Where("company_id = ? AND lead_id IS NOT NULL AND sent_at >= ?", companyId, since)
+	case "touchpoint":
+		return r.db.Model(&models.LeadTouchpoint{}).Select("lead_id").
+			Where("company_id = ? AND touched_at >= ?", companyId, since)
+	}
+
+	eventType := "open"
+	if activity == "email_clicked" {
+		eventType = "click"
+	}
+	return r.db.Table("email_events").Select("email_messages.lead_id").
+		Joins("INNER JOIN email_messages ON email_messages.id = email_events.message_id").
+		Where("email_events.company_id = ? AND email_events.type = ? AND email_events.is_bot = ? AND email_events.created_at >= ? AND email_messages.lead_id IS NOT NULL", companyId, eventType, false, since)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClaimDueSegments(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormSegmentRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	const due = "refresh_minutes > 0 AND next_refresh_at IS NOT NULL AND next_refresh_at <= \\?"
+
+	mock.ExpectQuery("SELECT `id` FROM `segments` WHERE " + due + " ORDER BY next_refresh_at LIMIT 5").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	// each claim pushes the next refresh forward only while the segment is still due
+	mock.ExpectExec("UPDATE `segments` SET `next_refresh_at`=DATE_ADD\\(\\?, INTERVAL refresh_minutes MINUTE\\),`updated_at`=\\? "+
+		"WHERE id = \\? AND \\("+due+"\\)").
+		WithArgs(now, sqlmock.AnyArg(), 2, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM `segments` WHERE `segments`.`id` = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "refresh_minutes", "next_refresh_at"}).AddRow(2, 60, now.Add(time.Hour)))
+	// another instance refreshed segment 3 first, so it is no longer due
+	mock.ExpectExec("UPDATE `segments` SET `next_refresh_at`=DATE_ADD").
+		WithArgs(now, sqlmock.AnyArg(), 3, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	claimed, err := repo.ClaimDueSegments(now, 5)
+	if err != nil {
+		t.Fatalf("ClaimDueSegments error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 2 || claimed[0].NextRefreshAt == nil || !claimed[0].NextRefreshAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("claimed = %+v, want segment 2 due again in an hour", claimed)
+	}
+}
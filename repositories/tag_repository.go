@@ -0,0 +1,446 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tagTable describes the table tagging one record type
+type tagTable struct {
+	table   string // the tags of each record
+	column  string // the tagged record's ID
+	records string // the tagged records
+}
+
+var tagTables = map[string]tagTable{
+	models.TagEntityLead:    {table: "lead_tags", column: "lead_id", records: "leads"},
+	models.TagEntityDeal:    {table: "deal_tags", column: "deal_id", records: "deals"},
+	models.TagEntityContact: {table: "contact_tags", column: "contact_id", records: "contacts"},
+}
+
+// canonicalTags returns tag names as the company defines them, without duplicates, defining any
+// it does not have yet. Names match their definitions whatever their case.
+func canonicalTags(db *gorm.DB, names []string, companyId int) ([]string, error) {
+	var wanted []string
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		wanted = append(wanted, name)
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	var existing []models.Tag
+	if err := db.Where("company_id = ? AND name IN ?", companyId, wanted).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	defined := make(map[string]string, len(existing))
+	for _, tag := range existing {
+		defined[strings.ToLower(tag.Name)] = tag.Name
+	}
+
+	var missing []models.Tag
+	canonical := make([]string, len(wanted))
+	for i, name := range wanted {
+		if definedName, ok := defined[strings.ToLower(name)]; ok {
+			canonical[i] = definedName
+			continue
+		}
+		canonical[i] = name
+		missing = append(missing, models.Tag{Name: name, Color: models.DefaultTagColor, CompanyId: companyId})
+	}
+	if len(missing) > 0 {
+		// Another request may define the same tag meanwhile; its definition is as good as ours
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return nil, err
+		}
+	}
+	return canonical, nil
+}
+
+// setRecordTags replaces the tags of a record
+func setRecordTags(tx *gorm.DB, entity string, recordID int, names []string, companyId int) error {
+	t := tagTables[entity]
+	if err := tx.Exec("DELETE FROM "+t.table+" WHERE "+t.column+" = ?", recordID).Error; err != nil {
+		return err
+	}
+	_, err := addRecordTags(tx, entity, recordID, names, companyId)
+	return err
+}
+
+// addRecordTags tags a record, returning how many of the tags it did not have already
+func addRecordTags(db *gorm.DB, entity string, recordID int, names []string, companyId int) (int, error) {
+	t := tagTables[entity]
+	tags, err := canonicalTags(db, names, companyId)
+	if err != nil || len(tags) == 0 {
+		return 0, err
+	}
+
+	var existing []string
+	if err := db.Table(t.table).Where(t.column+" = ? AND tag IN ?", recordID, tags).Pluck("tag", &existing).Error; err != nil {
+		return 0, err
+	}
+	has := make(map[string]bool, len(existing))
+	for _, tag := range existing {
+		has[strings.ToLower(tag)] = true
+	}
+
+	now := time.Now()
+	var rows []map[string]interface{}
+	for _, tag := range tags {
+		if !has[strings.ToLower(tag)] {
+			rows = append(rows, map[string]interface{}{
+				t.column: recordID, "tag": tag, "created_at": now, "company_id": companyId,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if err := db.Table(t.table).Create(rows).Error; err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// removeRecordTags removes tags from a record, returning how many of them it had
+func removeRecordTags(db *gorm.DB, entity string, recordID int, names []string) (int64, error) {
+	t := tagTables[entity]
+	result := db.Exec("DELETE FROM "+t.table+" WHERE "+t.column+" = ? AND tag IN ?", recordID, names)
+	return result.RowsAffected, result.Error
+}
+
+// loadRecordTags returns the tags of records by record ID
+func loadRecordTags(db *gorm.DB, entity string, ids []int) (map[int][]string, error) {
+	tags := make(map[int][]string, len(ids))
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	t := tagTables[entity]
+	var rows []struct {
+		RecordID int
+		Tag      string
+	}
+	if err := db.Table(t.table).Select(t.column+" AS record_id, tag").Where(t.column+" IN ?", ids).
+		Order("id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		tags[row.RecordID] = append(tags[row.RecordID], row.Tag)
+	}
+	return tags, nil
+}
+
+// taggedRecords returns a subquery selecting the IDs of a company's records matching a tag filter
+func taggedRecords(db *gorm.DB, entity string, filter models.TagFilter, companyId int) *gorm.DB {
+	t := tagTables[entity]
+	query := db.Table(t.table).Select(t.column).Where("company_id = ? AND tag IN ?", companyId, filter.Tags)
+	if filter.MatchAll {
+		query = query.Group(t.column).Having("COUNT(DISTINCT tag) = ?", len(filter.Tags))
+	}
+	return query
+}
+
+// GetTags returns a company's tags by name
+func (r *gormTagRepository) GetTags(companyId int) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.Where("company_id = ?", companyId).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+// GetTagByID returns a tag by ID
+func (r *gormTagRepository) GetTagByID(id int) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.First(&tag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagByName returns a company's tag by name, whatever its case
+func (r *gormTagRepository) GetTagByName(name string, companyId int) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.Where("company_id = ? AND name = ?", companyId, name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag creates a new tag
+func (r *gormTagRepository) CreateTag(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// UpdateTag updates a tag; renaming it renames it on every record tagged with it
+func (r *gormTagRepository) UpdateTag(tag *models.Tag, previousName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tag).Error; err != nil {
+			return err
+		}
+		if tag.Name == previousName {
+			return nil
+		}
+		tagged, err := taggedRecordIDs(tx, tag.CompanyId, previousName)
+		if err != nil {
+			return err
+		}
+		for _, entity := range models.TagEntities {
+			if err := tx.Exec("UPDATE "+tagTables[entity].table+" SET tag = ? WHERE company_id = ? AND tag = ?",
+				tag.Name, tag.CompanyId, previousName).Error; err != nil {
+				return err
+			}
+		}
+		if err := rewriteTagFilters(tx, tag.CompanyId, previousName, tag.Name); err != nil {
+			return err
+		}
+		return recordTaggedRecordsUpdated(tx, tagged)
+	})
+}
+
+// taggedRecordIDs returns the IDs of a company's records with a tag, by tag entity
+func taggedRecordIDs(tx *gorm.DB, companyId int, name string) (map[string][]int, error) {
+	tagged := make(map[string][]int, len(models.TagEntities))
+	for _, entity := range models.TagEntities {
+		t := tagTables[entity]
+		var ids []int
+		if err := tx.Table(t.table).Where("company_id = ? AND tag = ?", companyId, name).
+			Distinct().Pluck(t.column, &ids).Error; err != nil {
+			return nil, err
+		}
+		tagged[entity] = ids
+	}
+	return tagged, nil
+}
+
+// recordTaggedRecordsUpdated records the updated events of the records a change to a tag changed
+func recordTaggedRecordsUpdated(tx *gorm.DB, tagged map[string][]int) error {
+	for _, entity := range models.TagEntities {
+		if err := recordUpdatedEvents(tx, entity, tagged[entity]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeTag moves every record tagged with the source tag to the target tag and deletes the source
+func (r *gormTagRepository) MergeTag(source *models.Tag, target *models.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tagged, err := taggedRecordIDs(tx, source.CompanyId, source.Name)
+		if err != nil {
+			return err
+		}
+		for _, entity := range models.TagEntities {
+			t := tagTables[entity]
+			// Records with both tags keep only the target. The inner select is wrapped in a
+			// derived table because MySQL cannot delete from a table it selects from directly.
+			if err := tx.Exec("DELETE FROM "+t.table+" WHERE company_id = ? AND tag = ? AND "+t.column+" IN "+
+				"(SELECT "+t.column+" FROM (SELECT "+t.column+" FROM "+t.table+" WHERE company_id = ? AND tag = ?) AS tagged)",
+				source.CompanyId, source.Name, target.CompanyId, target.Name).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE "+t.table+" SET tag = ? WHERE company_id = ? AND tag = ?",
+				target.Name, source.CompanyId, source.Name).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(source).Error; err != nil {
+			return err
+		}
+		if err := rewriteTagFilters(tx, source.CompanyId, source.Name, target.Name); err != nil {
+			return err
+		}
+		return recordTaggedRecordsUpdated(tx, tagged)
+	})
+}
+
+// DeleteTag deletes a tag and removes it from every record tagged with it
+func (r *gormTagRepository) DeleteTag(tag *models.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tagged, err := taggedRecordIDs(tx, tag.CompanyId, tag.Name)
+		if err != nil {
+			return err
+		}
+		for _, entity := range models.TagEntities {
+			if err := tx.Exec("DELETE FROM "+tagTables[entity].table+" WHERE company_id = ? AND tag = ?",
+				tag.CompanyId, tag.Name).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(tag).Error; err != nil {
+			return err
+		}
+		if err := rewriteTagFilters(tx, tag.CompanyId, tag.Name, ""); err != nil {
+			return err
+		}
+		return recordTaggedRecordsUpdated(tx, tagged)
+	})
+}
+
+// rewriteTagFilters applies a renamed, merged or deleted tag to the segment rules and saved view
+// filters of its company. The tag is replaced by newName, or with an empty newName removed where
+// that leaves what the filter matches unchanged: from lists of tags of which any or none must
+// match that name other tags too.
+func rewriteTagFilters(tx *gorm.DB, companyId int, name string, newName string) error {
+	var segments []models.Segment
+	if err := tx.Select("id, filters").Where("company_id = ?", companyId).Find(&segments).Error; err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		var filter models.SegmentFilter
+		if err := json.Unmarshal([]byte(segment.Filters), &filter); err != nil {
+			continue
+		}
+		changed := false
+		for i, rule := range filter.Rules {
+			if rule.Type != models.SegmentRuleTag {
+				continue
+			}
+			if values, ok := rewriteTagValues(rule.Values, name, newName, rule.Operator != "has_all"); ok {
+				filter.Rules[i].Values = values
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		encoded, err := json.Marshal(filter)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Segment{}).Where("id = ?", segment.ID).Update("filters", string(encoded)).Error; err != nil {
+			return err
+		}
+	}
+
+	var views []models.SavedView
+	if err := tx.Select("id, filters").Where("company_id = ?", companyId).Find(&views).Error; err != nil {
+		return err
+	}
+	for _, view := range views {
+		var filters []models.ViewFilter
+		if err := json.Unmarshal([]byte(view.Filters), &filters); err != nil {
+			continue
+		}
+		changed := false
+		for i, filter := range filters {
+			if filter.Field != models.ViewFilterTags {
+				continue
+			}
+			if values, ok := rewriteTagValues(filter.Values, name, newName, true); ok {
+				filters[i].Values = values
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SavedView{}).Where("id = ?", view.ID).Update("filters", string(encoded)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteTagValues replaces a tag in a filter's tags with newName, or with an empty newName removes
+// it if removable and other tags remain. It reports whether the tags changed.
+func rewriteTagValues(values []string, name string, newName string, removable bool) ([]string, bool) {
+	rewritten := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	changed := false
+	for _, value := range values {
+		if strings.EqualFold(value, name) {
+			changed = true
+			if newName == "" {
+				continue
+			}
+			value = newName
+		}
+		if seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		rewritten = append(rewritten, value)
+	}
+	if !changed || (newName == "" && (!removable || len(rewritten) == 0)) {
+		return values, false
+	}
+	return rewritten, true
+}
+
+// GetTaggedIDs returns the IDs of a company's records of a type that match a tag filter
+func (r *gormTagRepository) GetTaggedIDs(entity string, filter models.TagFilter, companyId int) ([]int, error) {
+	var ids []int
+	err := taggedRecords(r.db, entity, filter, companyId).Pluck(tagTables[entity].column, &ids).Error
+	return ids, err
+}
+
+// GetTagUsage returns how many live leads, deals and contacts each of a company's tags is on,
+// most used first
+func (r *gormTagRepository) GetTagUsage(companyId int) ([]models.TagUsage, error) {
+	tags, err := r.GetTags(companyId)
+	if err != nil {
+		return nil, err
+	}
+	usage := make([]models.TagUsage, len(tags))
+	byName := make(map[string]*models.TagUsage, len(tags))
+	for i, tag := range tags {
+		usage[i] = models.TagUsage{TagID: tag.ID, Name: tag.Name, Color: tag.Color}
+		byName[strings.ToLower(tag.Name)] = &usage[i]
+	}
+
+	for _, entity := range models.TagEntities {
+		t := tagTables[entity]
+		var counts []struct {
+			Tag   string
+			Count int64
+		}
+		if err := r.db.Table(t.table).
+			Select(t.table+".tag, COUNT(DISTINCT "+t.table+"."+t.column+") AS count").
+			Joins("INNER JOIN "+t.records+" ON "+t.records+".id = "+t.table+"."+t.column+" AND "+t.records+".deleted_at IS NULL").
+			Where(t.table+".company_id = ?", companyId).
+			Group(t.table + ".tag").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		for _, count := range counts {
+			tagUsage, ok := byName[strings.ToLower(count.Tag)]
+			if !ok {
+				continue
+			}
+			switch entity {
+			case models.TagEntityLead:
+				tagUsage.Leads += count.Count
+			case models.TagEntityDeal:
+				tagUsage.Deals += count.Count
+			case models.TagEntityContact:
+				tagUsage.Contacts += count.Count
+			}
+			tagUsage.Total += count.Count
+		}
+	}
+
+	sort.SliceStable(usage, func(i, j int) bool { return usage[i].Total > usage[j].Total })
+	return usage, nil
+}
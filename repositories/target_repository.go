@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // gormTargetRepository implements TargetRepository with GORM
@@ -56,7 +57,45 @@ func (r *gormTargetRepository) CreateTarget(target *models.Target) error {
 
 // UpdateTarget updates an existing target
 func (r *gormTargetRepository) UpdateTarget(target *models.Target) error {
-	return r.db.Omit("CreatedAt").Save(target).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := lockTargetValues(tx, target.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("CreatedAt").Save(target).Error; err != nil {
+			return err
+		}
+		return recordTargetAchieved(tx, previous, target)
+	})
+}
+
+// lockTargetValues reads a target's actual and target values, locking it until the transaction
+// ends so that only one change can record reaching the target
+func lockTargetValues(tx *gorm.DB, id int) (*models.Target, error) {
+	var target models.Target
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, target_value, actual_value").
+		Where("id = ?", id).Limit(1).Find(&target).Error
+	return &target, err
+}
+
+// recordTargetAchieved records TargetAchieved when a change brings a target's actual value up to
+// its target value
+func recordTargetAchieved(tx *gorm.DB, previous *models.Target, target *models.Target) error {
+	achieved := func(t *models.Target) bool {
+		return t.TargetValue > 0 && t.ActualValue >= t.TargetValue
+	}
+	if previous.ID == 0 || !achieved(target) || achieved(previous) {
+		return nil
+	}
+	return recordDomainEvent(tx, models.EventTargetAchieved, target.ID, target.CompanyId, models.TargetEvent{
+		TargetID:    target.ID,
+		Name:        target.Name,
+		TargetType:  target.TargetType,
+		TargetValue: target.TargetValue,
+		ActualValue: target.ActualValue,
+		UserId:      target.UserId,
+		TeamId:      target.TeamId,
+	})
 }
 
 // DeleteTarget deletes a target
@@ -64,7 +103,8 @@ func (r *gormTargetRepository) DeleteTarget(id int) error {
 	return r.db.Delete(&models.Target{}, id).Error
 }
 
-// GetTargetProgress gets the progress toward a target
+// GetTargetProgress gets the progress toward a target. It only reads: the stored actual value is
+// kept up to date by the changes that move it.
 func (r *gormTargetRepository) GetTargetProgress(id int, companyId int) (map[string]interface{}, error) {
 	var target models.Target
 	if err := r.db.Where("company_id=?", companyId).First(&target, id).Error; err != nil {
@@ -74,46 +114,8 @@ func (r *gormTargetRepository) GetTargetProgress(id int, companyId int) (map[str
 		return nil, err
 	}
 
-	// Calculate progress based on target type
-	var actualValue float64
-
-	switch target.TargetType {
-	case "revenue":
-		// Sum the revenue from closed-won deals in the target period
-		var result struct {
-			Value float64
-		}
-		if err := r.db.Model(&models.Deal{}).
-			Select("COALESCE(SUM(amount), 0) as value").
-			Where("company_id = ? AND stage = ? AND created_at BETWEEN ? AND ?", companyId, "closed_won", target.StartDate, target.EndDate).
-			Scan(&result).Error; err != nil {
-			return nil, err
-		}
-		actualValue = result.Value
-
-	case "leads":
-		// Count leads created in the target period
-		var count int64
-		if err := r.db.Model(&models.Lead{}).
-			Where("company_id = ? AND created_at BETWEEN ? AND ?", companyId, target.StartDate, target.EndDate).
-			Count(&count).Error; err != nil {
-			return nil, err
-		}
-		actualValue = float64(count)
-
-	case "deals":
-		// Count deals created in the target period
-		var count int64
-		if err := r.db.Model(&models.Deal{}).
-			Where("company_id = ? AND created_at BETWEEN ? AND ?", companyId, target.StartDate, target.EndDate).
-			Count(&count).Error; err != nil {
-			return nil, err
-		}
-		actualValue = float64(count)
-	}
-
-	// Update the actual value in the database
-	if err := r.db.Model(&target).Where("company_id = ?", companyId).Update("actual_value", actualValue).Error; err != nil {
+	actualValue, revenue, err := targetActualValue(r.db, &target)
+	if err != nil {
 		return nil, err
 	}
 
@@ -142,7 +144,7 @@ func (r *gormTargetRepository) GetTargetProgress(id int, companyId int) (map[str
 		onTrack = percentComplete >= timeProgress
 	}
 
-	return map[string]interface{}{
+	progress := map[string]interface{}{
 		"target_id":        target.ID,
 		"target_type":      target.TargetType,
 		"target_value":     target.TargetValue,
@@ -153,7 +155,103 @@ func (r *gormTargetRepository) GetTargetProgress(id int, companyId int) (map[str
 		"end_date":         target.EndDate,
 		"on_track":         onTrack,
 		"period":           target.Period,
-	}, nil
+		"currency":         target.Currency,
+	}
+	for key, value := range revenue {
+		progress[key] = value
+	}
+	return progress, nil
+}
+
+// targetActualValue computes a target's actual value from the records in its period. A revenue
+// target also returns the revenue in the reporting currency, and whether an exchange rate into
+// the target's currency was missing.
+func targetActualValue(db *gorm.DB, target *models.Target) (float64, map[string]interface{}, error) {
+	switch target.TargetType {
+	case "revenue":
+		// Sum the revenue from won deals in the target period, in the reporting currency, then
+		// convert it into the target's currency
+		conversion, err := reportingCurrency(db, target.CompanyId)
+		if err != nil {
+			return 0, nil, err
+		}
+		totals, err := conversion.sumDealAmounts(db.Model(&models.Deal{}).
+			Where("company_id = ? AND stage IN ? AND created_at BETWEEN ? AND ?", target.CompanyId, models.DealStagesWon, target.StartDate, target.EndDate),
+			"''", "deals.amount", "deals.created_at")
+		if err != nil {
+			return 0, nil, err
+		}
+		converted, ok, err := conversion.convertTotal(db, totals.total(""), target.Currency, targetRateDate(target.EndDate))
+		if err != nil {
+			return 0, nil, err
+		}
+		return converted, map[string]interface{}{
+			"reporting_currency":     conversion.currency,
+			"actual_value_reporting": totals.total(""),
+			"revenue_by_currency":    totals.byCurrency(""),
+			"exchange_rate_missing":  !ok,
+		}, nil
+
+	case "leads":
+		// Count leads created in the target period
+		var count int64
+		err := db.Model(&models.Lead{}).
+			Where("company_id = ? AND created_at BETWEEN ? AND ?", target.CompanyId, target.StartDate, target.EndDate).
+			Count(&count).Error
+		return float64(count), nil, err
+
+	case "deals":
+		// Count deals created in the target period
+		var count int64
+		err := db.Model(&models.Deal{}).
+			Where("company_id = ? AND created_at BETWEEN ? AND ?", target.CompanyId, target.StartDate, target.EndDate).
+			Count(&count).Error
+		return float64(count), nil, err
+	}
+	return 0, nil, nil
+}
+
+// refreshTargets recomputes the actual values of a company's active targets of the given types
+// whose period includes a time, recording TargetAchieved for any it brings up to their target
+// value. It is called in the transaction of a change that can move them, such as a lead being
+// created or a deal being won, with the time the record was created.
+func refreshTargets(tx *gorm.DB, companyId int, at time.Time, targetTypes ...string) error {
+	var targets []models.Target
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? AND status = ? AND target_type IN ? AND start_date <= ? AND end_date >= ?", companyId, "active", targetTypes, at, at).
+		Order("id").Find(&targets).Error; err != nil {
+		return err
+	}
+
+	for i := range targets {
+		target := &targets[i]
+		actualValue, revenue, err := targetActualValue(tx, target)
+		if err != nil {
+			return err
+		}
+		// Revenue that could not be converted into the target's currency is not its actual value
+		if missing, _ := revenue["exchange_rate_missing"].(bool); missing || actualValue == target.ActualValue {
+			continue
+		}
+		previous := *target
+		if err := tx.Model(&models.Target{}).Where("id = ?", target.ID).Update("actual_value", actualValue).Error; err != nil {
+			return err
+		}
+		target.ActualValue = actualValue
+		if err := recordTargetAchieved(tx, &previous, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetRateDate is the date whose exchange rates convert revenue for a target ending on endDate:
+// the end of the target, or today while it is running
+func targetRateDate(endDate time.Time) time.Time {
+	if now := time.Now(); now.Before(endDate) {
+		return now
+	}
+	return endDate
 }
 
 // GetAllTargetProgress gets progress for all active targets
@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// trashTable describes how the recycle bin lists a record type
+type trashTable struct {
+	table      string
+	name       string // SQL for the record's display name
+	searchType string // search document type, if the records are searchable
+}
+
+var trashTables = map[string]trashTable{
+	models.TrashTypeLead:     {table: "leads", name: "COALESCE(NULLIF(name, ''), email, '')", searchType: models.SearchTypeLead},
+	models.TrashTypeContact:  {table: "contacts", name: "name", searchType: models.SearchTypeContact},
+	models.TrashTypeDeal:     {table: "deals", name: "title", searchType: models.SearchTypeDeal},
+	models.TrashTypeTarget:   {table: "targets", name: "name"},
+	models.TrashTypeSequence: {table: "nurture_sequences", name: "name"},
+}
+
+// trashSelect returns SQL selecting the deleted records of a type as trash items, to which a
+// condition can be appended with AND
+func trashSelect(recordType string) (string, error) {
+	table, ok := trashTables[recordType]
+	if !ok {
+		return "", fmt.Errorf("unknown record type %q", recordType)
+	}
+	return fmt.Sprintf("SELECT '%s' AS type, id, %s AS name, deleted_at, company_id FROM %s WHERE deleted_at IS NOT NULL",
+		recordType, table.name, table.table), nil
+}
+
+// deletionTime is the time a record and its children are marked deleted. It is whole seconds so
+// that the parent and children compare equal whatever precision their columns store.
+func deletionTime() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// softDeleteAt marks the rows of a model matching a condition deleted at a time; rows already
+// deleted keep their time. A record's children are deleted at the record's time so that
+// restoring the record restores only them.
+func softDeleteAt(tx *gorm.DB, model interface{}, at time.Time, query string, args ...interface{}) error {
+	return tx.Model(model).Where(query, args...).Update("deleted_at", at).Error
+}
+
+// restoreAt clears the deletion of the rows of a model matching a condition that were deleted at
+// a time
+func restoreAt(tx *gorm.DB, model interface{}, at time.Time, query string, args ...interface{}) error {
+	return tx.Unscoped().Model(model).Where(query, args...).Where("deleted_at = ?", at).
+		Update("deleted_at", nil).Error
+}
+
+// GetTrash returns a page of a company's deleted records newest first, optionally of one type,
+// with the total number there are
+func (r *gormTrashRepository) GetTrash(recordType string, offset int, limit int, companyId int) ([]models.TrashItem, int64, error) {
+	types := models.TrashTypes
+	if recordType != "" {
+		types = []string{recordType}
+	}
+
+	var selects []string
+	var args []interface{}
+	for _, t := range types {
+		query, err := trashSelect(t)
+		if err != nil {
+			return nil, 0, err
+		}
+		selects = append(selects, query+" AND company_id = ?")
+		args = append(args, companyId)
+	}
+	union := strings.Join(selects, " UNION ALL ")
+
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(*) FROM ("+union+") AS trash", args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := []models.TrashItem{}
+	if err := r.db.Raw(union+" ORDER BY deleted_at DESC, type, id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...).Scan(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.setDeletedBy(items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// GetTrashItem returns a deleted record, or nil if there is no such record or it is not deleted
+func (r *gormTrashRepository) GetTrashItem(recordType string, id int) (*models.TrashItem, error) {
+	query, err := trashSelect(recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.TrashItem
+	if err := r.db.Raw(query+" AND id = ?", id).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if err := r.setDeletedBy(items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// setDeletedBy fills in who deleted each record from the latest deletion in the audit trail
+func (r *gormTrashRepository) setDeletedBy(items []models.TrashItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	keys := make([][]interface{}, len(items))
+	for i, item := range items {
+		keys[i] = []interface{}{item.Type, item.ID}
+	}
+
+	var entries []models.AuditEntry
+	if err := r.db.Where("action IN ? AND (entity_type, entity_id) IN ?", models.DeleteAuditActions, keys).
+		Order("id").Find(&entries).Error; err != nil {
+		return err
+	}
+	deletedBy := make(map[string]*int, len(entries))
+	for _, entry := range entries {
+		deletedBy[fmt.Sprintf("%s:%d", entry.EntityType, entry.EntityID)] = entry.UserID
+	}
+	for i := range items {
+		items[i].DeletedBy = deletedBy[fmt.Sprintf("%s:%d", items[i].Type, items[i].ID)]
+	}
+	return nil
+}
+
+// RestoreRecord restores a deleted record along with the children deleted with it. A restored
+// lead, deal or contact records its updated event, and counts toward targets again.
+func (r *gormTrashRepository) RestoreRecord(recordType string, id int) error {
+	item, err := r.GetTrashItem(recordType, id)
+	if err != nil || item == nil {
+		return err
+	}
+	at := item.DeletedAt
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		switch recordType {
+		case models.TrashTypeLead:
+			if err := restoreAt(tx, &models.CrmFieldData{}, at, "submit_id = ?", id); err != nil {
+				return err
+			}
+			if err := restoreAt(tx, &models.NurtureEnrollment{}, at, "lead_id = ?", id); err != nil {
+				return err
+			}
+			if err := restoreAt(tx, &models.Lead{}, at, "id = ?", id); err != nil {
+				return err
+			}
+			lead, err := findLeadForEvent(tx, id)
+			if err != nil || lead.ID == 0 {
+				return err
+			}
+			if err := recordDomainEvent(tx, models.EventLeadUpdated, id, lead.CompanyId, leadEvent(lead)); err != nil {
+				return err
+			}
+			return refreshTargets(tx, lead.CompanyId, lead.CreatedAt, "leads")
+		case models.TrashTypeContact:
+			if err := restoreAt(tx, &models.Contact{}, at, "id = ?", id); err != nil {
+				return err
+			}
+			return recordUpdatedEvents(tx, models.AggregateContact, []int{id})
+		case models.TrashTypeDeal:
+			if err := restoreAt(tx, &models.Deal{}, at, "id = ?", id); err != nil {
+				return err
+			}
+			deal, err := findDealForEvent(tx, id)
+			if err != nil || deal.ID == 0 {
+				return err
+			}
+			if err := recordDomainEvent(tx, models.EventDealUpdated, id, deal.CompanyId, dealEvent(deal)); err != nil {
+				return err
+			}
+			return refreshDealTargets(tx, deal.CompanyId, deal.CreatedAt, true, deal.Stage)
+		case models.TrashTypeTarget:
+			return restoreAt(tx, &models.Target{}, at, "id = ?", id)
+		case models.TrashTypeSequence:
+			if err := restoreAt(tx, &models.NurtureStep{}, at, "sequence_id = ?", id); err != nil {
+				return err
+			}
+			if err := restoreAt(tx, &models.NurtureEnrollment{}, at, "sequence_id = ?", id); err != nil {
+				return err
+			}
+			return restoreAt(tx, &models.NurtureSequence{}, at, "id = ?", id)
+		}
+		return fmt.Errorf("unknown record type %q", recordType)
+	})
+	if err != nil {
+		return err
+	}
+	if searchType := trashTables[recordType].searchType; searchType != "" {
+		indexForSearch(r.db, searchType, id)
+	}
+	return nil
+}
+
+// PurgeRecord permanently deletes a deleted record and everything belonging to it. Records that
+// are not deleted are left alone. A lead that deals, deleted or not, still belong to is not
+// purged; a PurgeBlockedError lists them.
+func (r *gormTrashRepository) PurgeRecord(recordType string, id int) error {
+	item, err := r.GetTrashItem(recordType, id)
+	if err != nil || item == nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		switch recordType {
+		case models.TrashTypeLead:
+			if err := leadPurgeBlockers(tx, id); err != nil {
+				return err
+			}
+			enrollments := tx.Unscoped().Model(&models.NurtureEnrollment{}).Select("id").Where("lead_id = ?", id)
+			if err := tx.Where("enrollment_id IN (?)", enrollments).Delete(&models.NurtureActivity{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("lead_id = ?", id).Delete(&models.NurtureEnrollment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("submit_id = ?", id).Delete(&models.CrmFieldData{}).Error; err != nil {
+				return err
+			}
+			for _, child := range []interface{}{
+				&models.LeadTag{}, &models.LeadCustomField{}, &models.LeadTouchpoint{},
+				&models.LeadConsent{}, &models.CampaignLead{}, &models.SegmentMember{},
+			} {
+				if err := tx.Where("lead_id = ?", id).Delete(child).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Model(&models.Contact{}).Where("lead_id = ?", id).Update("lead_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.Lead{}, id).Error
+		case models.TrashTypeContact:
+			if err := tx.Where("contact_id = ?", id).Delete(&models.DealContact{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("contact_id = ?", id).Delete(&models.AccountContact{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.Contact{}, id).Error
+		case models.TrashTypeDeal:
+			quotes := tx.Model(&models.Quote{}).Select("id").Where("deal_id = ?", id)
+			if err := tx.Where("quote_id IN (?)", quotes).Delete(&models.QuoteLineItem{}).Error; err != nil {
+				return err
+			}
+			for _, child := range []interface{}{&models.Quote{}, &models.DealLineItem{}, &models.DealContact{}} {
+				if err := tx.Where("deal_id = ?", id).Delete(child).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Unscoped().Delete(&models.Deal{}, id).Error
+		case models.TrashTypeTarget:
+			return tx.Unscoped().Delete(&models.Target{}, id).Error
+		case models.TrashTypeSequence:
+			enrollments := tx.Unscoped().Model(&models.NurtureEnrollment{}).Select("id").Where("sequence_id = ?", id)
+			if err := tx.Where("enrollment_id IN (?)", enrollments).Delete(&models.NurtureActivity{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("sequence_id = ?", id).Delete(&models.NurtureEnrollment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("sequence_id = ?", id).Delete(&models.NurtureStep{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.NurtureSequence{}, id).Error
+		}
+		return fmt.Errorf("unknown record type %q", recordType)
+	})
+}
+
+// leadPurgeBlockers returns a PurgeBlockedError listing a lead's deals, including deleted ones,
+// which would be left pointing at nothing if the lead were purged
+func leadPurgeBlockers(tx *gorm.DB, leadID int) error {
+	var deals []struct {
+		ID        int
+		Title     string
+		DeletedAt *time.Time
+	}
+	if err := tx.Unscoped().Model(&models.Deal{}).
+		Select("id, title, deleted_at").
+		Where("lead_id = ?", leadID).
+		Order("id").
+		Scan(&deals).Error; err != nil {
+		return err
+	}
+	if len(deals) == 0 {
+		return nil
+	}
+
+	blocked := &models.PurgeBlockedError{Type: models.TrashTypeLead, ID: leadID}
+	for _, deal := range deals {
+		blocked.Blockers = append(blocked.Blockers, models.TrashBlocker{
+			Type:    models.TrashTypeDeal,
+			ID:      deal.ID,
+			Name:    deal.Title,
+			Deleted: deal.DeletedAt != nil,
+		})
+	}
+	return blocked
+}
+
+// GetExpiredTrash returns the IDs of records of a type deleted before a time, oldest first
+func (r *gormTrashRepository) GetExpiredTrash(recordType string, deletedBefore time.Time, limit int) ([]int, error) {
+	table, ok := trashTables[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unknown record type %q", recordType)
+	}
+	var ids []int
+	err := r.db.Table(table.table).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Order("deleted_at, id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPurgeRecordRefusesLeadWithDeals(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormTrashRepository{db: db}
+	deletedAt := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT 'lead' AS type, id, .* FROM leads WHERE deleted_at IS NOT NULL AND id = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "name", "deleted_at", "company_id"}).
+			AddRow(models.TrashTypeLead, 5, "Ada", deletedAt, 1))
+	mock.ExpectQuery("SELECT \\* FROM `audit_entries`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	// deals are looked up whether or not they are deleted themselves
+	mock.ExpectQuery("SELECT id, title, deleted_at FROM `deals` WHERE lead_id = \\? ORDER BY id$").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "deleted_at"}).
+			AddRow(20, "Renewal", nil).
+			AddRow(21, "Upsell", deletedAt))
+	// nothing is deleted
+	mock.ExpectRollback()
+
+	err := repo.PurgeRecord(models.TrashTypeLead, 5)
+	var blocked *models.PurgeBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("PurgeRecord error = %v, want a PurgeBlockedError", err)
+	}
+	want := []models.TrashBlocker{
+		{Type: models.TrashTypeDeal, ID: 20, Name: "Renewal"},
+		{Type: models.TrashTypeDeal, ID: 21, Name: "Upsell", Deleted: true},
+	}
+	if blocked.Type != models.TrashTypeLead || blocked.ID != 5 || !reflect.DeepEqual(blocked.Blockers, want) {
+		t.Errorf("blocked = %+v, want lead 5 blocked by %+v", blocked, want)
+	}
+}
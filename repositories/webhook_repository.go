@@ -0,0 +1,292 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newEventID returns a random UUID identifying a webhook or domain event
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// queueWebhookEvent queues delivery of an event to each of the company's active webhooks
+// subscribed to its type that it has not been queued for already, returning how many it queued
+func queueWebhookEvent(db *gorm.DB, event *models.WebhookEvent) (int, error) {
+	subscribed, err := subscribedWebhooks(db, event.CompanyId, event.Type)
+	if err != nil || len(subscribed) == 0 {
+		return 0, err
+	}
+
+	var queued []int
+	if err := db.Model(&models.WebhookDelivery{}).Where("event_id = ? AND subscription_id IN ?", event.ID, subscribed).
+		Distinct().Pluck("subscription_id", &queued).Error; err != nil {
+		return 0, err
+	}
+	alreadyQueued := make(map[int]bool, len(queued))
+	for _, subscriptionID := range queued {
+		alreadyQueued[subscriptionID] = true
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, subscriptionID := range subscribed {
+		if alreadyQueued[subscriptionID] {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscriptionID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  &now,
+			CompanyId:      event.CompanyId,
+		})
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	if err := db.Create(&deliveries).Error; err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// subscribedWebhooks returns the IDs of a company's active webhooks subscribed to an event type
+func subscribedWebhooks(db *gorm.DB, companyId int, eventType string) ([]int, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := db.Select("id, events").Where("company_id = ? AND active = ?", companyId, true).
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	var subscribed []int
+	for _, subscription := range subscriptions {
+		if subscription.Subscribes(eventType) {
+			subscribed = append(subscribed, subscription.ID)
+		}
+	}
+	return subscribed, nil
+}
+
+// HasSubscribers reports whether a company has an active webhook subscribed to an event type
+func (r *gormWebhookRepository) HasSubscribers(companyId int, eventType string) (bool, error) {
+	subscribed, err := subscribedWebhooks(r.db, companyId, eventType)
+	return len(subscribed) > 0, err
+}
+
+// GetSubscriptions returns a company's webhook subscriptions
+func (r *gormWebhookRepository) GetSubscriptions(companyId int) ([]models.WebhookSubscription, error) {
+	subscriptions := []models.WebhookSubscription{}
+	err := r.db.Where("company_id = ?", companyId).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetSubscriptionByID returns a webhook subscription by ID
+func (r *gormWebhookRepository) GetSubscriptionByID(id int) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := r.db.First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CreateSubscription creates a webhook subscription
+func (r *gormWebhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+// UpdateSubscription saves a webhook subscription
+func (r *gormWebhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Omit("CreatedAt").Save(subscription).Error
+}
+
+// webhookLead is a lead as webhook events carry it, with its field values by field name
+type webhookLead struct {
+	*models.Lead
+	Fields map[string]string `json:"fields"`
+}
+
+// GetEventRecord returns the record of a type a domain event is about, as webhook events carry it,
+// or nil if it does not exist. Deleted records are returned too, so that the events of their
+// deletion carry them.
+func (r *gormWebhookRepository) GetEventRecord(aggregateType string, id int) (interface{}, error) {
+	db := r.db.Unscoped()
+	switch aggregateType {
+	case models.AggregateLead:
+		var lead models.Lead
+		if err := db.Where("id = ?", id).Limit(1).Find(&lead).Error; err != nil || lead.ID == 0 {
+			return nil, err
+		}
+		if err := db.Where("lead_id = ?", id).Find(&lead.CustomFields).Error; err != nil {
+			return nil, err
+		}
+		tags, err := loadRecordTags(db, models.TagEntityLead, []int{id})
+		if err != nil {
+			return nil, err
+		}
+		lead.Tags = tags[id]
+		fields, err := leadFieldValues(db, &lead)
+		if err != nil {
+			return nil, err
+		}
+		return &webhookLead{Lead: &lead, Fields: fields}, nil
+
+	case models.AggregateDeal:
+		var deal models.Deal
+		if err := db.Where("id = ?", id).Limit(1).Find(&deal).Error; err != nil || deal.ID == 0 {
+			return nil, err
+		}
+		tags, err := loadRecordTags(db, models.TagEntityDeal, []int{id})
+		if err != nil {
+			return nil, err
+		}
+		deal.Tags = tags[id]
+		return &deal, nil
+
+	case models.AggregateContact:
+		var contact models.Contact
+		if err := db.Where("id = ?", id).Limit(1).Find(&contact).Error; err != nil || contact.ID == 0 {
+			return nil, err
+		}
+		tags, err := loadRecordTags(db, models.TagEntityContact, []int{id})
+		if err != nil {
+			return nil, err
+		}
+		contact.Tags = tags[id]
+		return &contact, nil
+
+	case models.AggregateTarget:
+		var target models.Target
+		if err := db.Where("id = ?", id).Limit(1).Find(&target).Error; err != nil || target.ID == 0 {
+			return nil, err
+		}
+		return &target, nil
+	}
+	return nil, fmt.Errorf("unknown record type %q", aggregateType)
+}
+
+// QueueEvent queues delivery of an event to each of the company's active webhooks subscribed to
+// its type, returning how many it queued. Webhooks the event has been queued for already, by its
+// ID, are skipped, so queueing an event again does not deliver it twice.
+func (r *gormWebhookRepository) QueueEvent(event *models.WebhookEvent) (int, error) {
+	return queueWebhookEvent(r.db, event)
+}
+
+// DeleteSubscription deletes a webhook subscription along with its deliveries
+func (r *gormWebhookRepository) DeleteSubscription(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+// RecordDeliveryResult counts a subscription's consecutive failed delivery attempts, resetting the
+// count on success. A failure that brings the count to disableAfter disables the subscription,
+// and the return value reports whether it did.
+func (r *gormWebhookRepository) RecordDeliveryResult(subscriptionID int, succeeded bool, disableAfter int, reason string, now time.Time) (bool, error) {
+	if succeeded {
+		return false, r.db.Model(&models.WebhookSubscription{}).Where("id = ?", subscriptionID).
+			Update("consecutive_failures", 0).Error
+	}
+
+	if err := r.db.Model(&models.WebhookSubscription{}).Where("id = ?", subscriptionID).
+		Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+		return false, err
+	}
+	result := r.db.Model(&models.WebhookSubscription{}).
+		Where("id = ? AND active = ? AND consecutive_failures >= ?", subscriptionID, true, disableAfter).
+		Updates(map[string]interface{}{"active": false, "disabled_at": now, "disabled_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDeliveries returns a webhook's deliveries newest first, optionally only those in a status
+func (r *gormWebhookRepository) GetDeliveries(subscriptionID int, status string, offset int, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	query := r.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDeliveryByID returns a webhook delivery by ID
+func (r *gormWebhookRepository) GetDeliveryByID(id int) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ReplayDelivery queues a new delivery of a delivery's event, with the same payload, to be
+// attempted at once
+func (r *gormWebhookRepository) ReplayDelivery(delivery *models.WebhookDelivery, now time.Time) (*models.WebhookDelivery, error) {
+	replayOf := delivery.ID
+	replay := &models.WebhookDelivery{
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  &now,
+		ReplayOf:       &replayOf,
+		CompanyId:      delivery.CompanyId,
+	}
+	if err := r.db.Create(replay).Error; err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// ClaimDueDeliveries leases pending deliveries to active webhooks that are due, oldest first, to
+// a worker. A delivery leased by another worker is skipped until its lease expires.
+func (r *gormWebhookRepository) ClaimDueDeliveries(workerID string, now time.Time, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	candidates := r.db.Model(&models.WebhookDelivery{}).
+		Where("next_attempt_at <= ?", now).
+		Where("subscription_id IN (?)", r.db.Model(&models.WebhookSubscription{}).Select("id").Where("active = ?", true)).
+		Order("next_attempt_at")
+	return claimLeased[models.WebhookDelivery](r.db, candidates, models.WebhookDeliveryPending, workerID, now, leaseUntil, limit)
+}
+
+// ReleaseDelivery saves the outcome of a delivery attempt and gives up the worker's lease
+func (r *gormWebhookRepository) ReleaseDelivery(delivery *models.WebhookDelivery, workerID string) error {
+	delivery.LockedBy = ""
+	delivery.LockedUntil = nil
+	return r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND locked_by = ?", delivery.ID, workerID).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"response_body":   delivery.ResponseBody,
+			"last_error":      delivery.LastError,
+			"delivered_at":    delivery.DeliveredAt,
+			"locked_by":       "",
+			"locked_until":    nil,
+		}).Error
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"crm-app/backend/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClaimDueDeliveries(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := &gormWebhookRepository{db: db}
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	leaseUntil := now.Add(time.Minute)
+
+	// only deliveries to active subscriptions are claimed
+	mock.ExpectQuery("SELECT `id` FROM `webhook_deliveries` WHERE next_attempt_at <= \\? "+
+		"AND subscription_id IN \\(SELECT `id` FROM `webhook_subscriptions` WHERE active = \\?.*\\) "+
+		"AND \\(status = \\? AND \\(locked_until IS NULL OR locked_until < \\?\\)\\) .*ORDER BY next_attempt_at LIMIT 10").
+		WithArgs(now, true, models.WebhookDeliveryPending, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	expectLeaseClaim(mock, "webhook_deliveries", 11, models.WebhookDeliveryPending, "worker-a", now, leaseUntil, 1)
+	mock.ExpectQuery("SELECT \\* FROM `webhook_deliveries` WHERE `webhook_deliveries`.`id` = \\?").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "locked_by"}).AddRow(11, models.WebhookDeliveryPending, "worker-a"))
+
+	claimed, err := repo.ClaimDueDeliveries("worker-a", now, leaseUntil, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != 11 || claimed[0].LockedBy != "worker-a" {
+		t.Errorf("claimed = %+v, want delivery 11 leased to worker-a", claimed)
+	}
+}
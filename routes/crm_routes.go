@@ -23,10 +23,95 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 	targetHandler := handlers.NewCRMTargetHandler(repos)
 	leadFieldsHandler := handlers.NewCRMLeadFieldsHandler(repos)
 	LeadScoreHandler := handlers.NewScoreLeadHandler(repos)
+	emailHandler := handlers.NewCRMEmailHandler(repos)
+	companyHandler := handlers.NewCRMCompanyHandler(repos)
+	consentHandler := handlers.NewCRMConsentHandler(repos)
+	segmentHandler := handlers.NewCRMSegmentHandler(repos)
+	exchangeRateHandler := handlers.NewCRMExchangeRateHandler(repos)
+	productHandler := handlers.NewCRMProductHandler(repos)
+	quoteHandler := handlers.NewCRMQuoteHandler(repos)
+	closeReasonHandler := handlers.NewCRMCloseReasonHandler(repos)
+	accountHandler := handlers.NewCRMAccountHandler(repos)
+	searchHandler := handlers.NewCRMSearchHandler(repos)
+	savedViewHandler := handlers.NewCRMSavedViewHandler(repos)
+	kanbanHandler := handlers.NewCRMKanbanHandler(repos)
+	bulkHandler := handlers.NewCRMBulkHandler(repos)
+	auditHandler := handlers.NewCRMAuditHandler(repos)
+	trashHandler := handlers.NewCRMTrashHandler(repos)
+	tagHandler := handlers.NewCRMTagHandler(repos)
+	webhookHandler := handlers.NewCRMWebhookHandler(repos)
+	eventHandler := handlers.NewCRMEventHandler(repos)
 
 	// CRM API group
 	crm := r.Group("/api/crm")
 
+	// Global search routes
+	search := crm.Group("/search")
+	{
+		search.GET("", middleware.JwtAuthMiddleware(), searchHandler.Search)
+		search.POST("/reindex", middleware.JwtAuthMiddleware(), searchHandler.Reindex)
+	}
+
+	// Saved list view routes
+	views := crm.Group("/views")
+	{
+		views.GET("", middleware.JwtAuthMiddleware(), savedViewHandler.GetSavedViews)
+		views.POST("", middleware.JwtAuthMiddleware(), savedViewHandler.CreateSavedView)
+		views.GET("/:id", middleware.JwtAuthMiddleware(), savedViewHandler.GetSavedView)
+		views.PUT("/:id", middleware.JwtAuthMiddleware(), savedViewHandler.UpdateSavedView)
+		views.DELETE("/:id", middleware.JwtAuthMiddleware(), savedViewHandler.DeleteSavedView)
+		views.GET("/:id/records", middleware.JwtAuthMiddleware(), savedViewHandler.GetSavedViewRecords)
+	}
+
+	// Bulk operation routes
+	bulkOperations := crm.Group("/bulk-operations")
+	{
+		bulkOperations.GET("", middleware.JwtAuthMiddleware(), bulkHandler.GetBulkOperations)
+		bulkOperations.GET("/:id", middleware.JwtAuthMiddleware(), bulkHandler.GetBulkOperation)
+	}
+
+	// Audit trail routes
+	crm.GET("/audit", middleware.JwtAuthMiddleware(), auditHandler.GetAuditEntries)
+
+	// Recycle bin routes
+	trash := crm.Group("/trash")
+	{
+		trash.GET("", middleware.JwtAuthMiddleware(), trashHandler.GetTrash)
+		trash.POST("/:type/:id/restore", middleware.JwtAuthMiddleware(), trashHandler.RestoreTrashItem)
+		trash.DELETE("/:type/:id", middleware.JwtAuthMiddleware(), trashHandler.PurgeTrashItem)
+	}
+
+	// Tag routes
+	tags := crm.Group("/tags")
+	{
+		tags.GET("", middleware.JwtAuthMiddleware(), tagHandler.GetTags)
+		tags.POST("", middleware.JwtAuthMiddleware(), tagHandler.CreateTag)
+		tags.PUT("/:id", middleware.JwtAuthMiddleware(), tagHandler.UpdateTag)
+		tags.DELETE("/:id", middleware.JwtAuthMiddleware(), tagHandler.DeleteTag)
+		tags.POST("/:id/merge", middleware.JwtAuthMiddleware(), tagHandler.MergeTag)
+	}
+
+	// Webhook routes
+	webhooks := crm.Group("/webhooks")
+	{
+		webhooks.GET("", middleware.JwtAuthMiddleware(), webhookHandler.GetWebhooks)
+		webhooks.POST("", middleware.JwtAuthMiddleware(), webhookHandler.CreateWebhook)
+		webhooks.GET("/:id", middleware.JwtAuthMiddleware(), webhookHandler.GetWebhook)
+		webhooks.PUT("/:id", middleware.JwtAuthMiddleware(), webhookHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", middleware.JwtAuthMiddleware(), webhookHandler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", middleware.JwtAuthMiddleware(), webhookHandler.GetWebhookDeliveries)
+		webhooks.GET("/:id/deliveries/:deliveryId", middleware.JwtAuthMiddleware(), webhookHandler.GetWebhookDelivery)
+		webhooks.POST("/:id/deliveries/:deliveryId/replay", middleware.JwtAuthMiddleware(), webhookHandler.ReplayWebhookDelivery)
+	}
+
+	// Domain event routes
+	events := crm.Group("/events")
+	{
+		events.GET("", middleware.JwtAuthMiddleware(), eventHandler.GetEvents)
+		events.GET("/:id", middleware.JwtAuthMiddleware(), eventHandler.GetEvent)
+		events.POST("/:id/replay", middleware.JwtAuthMiddleware(), eventHandler.ReplayEvent)
+	}
+
 	// Dashboard routes
 	dashboard := crm.Group("/dashboard")
 	{
@@ -38,6 +123,7 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		dashboard.GET("/top-deals", middleware.JwtAuthMiddleware(), dashboardHandler.GetTopDeals)
 		dashboard.GET("/recent-leads", middleware.JwtAuthMiddleware(), dashboardHandler.GetRecentLeads)
 		dashboard.GET("/target-progress", middleware.JwtAuthMiddleware(), dashboardHandler.GetTargetProgress)
+		dashboard.GET("/tag-usage", middleware.JwtAuthMiddleware(), tagHandler.GetTagUsage)
 	}
 	// Lead routes
 	leads := crm.Group("/leads")
@@ -56,6 +142,22 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		leads.PUT("/:id/assign", middleware.JwtAuthMiddleware(), leadHandler.AssignLead)
 		leads.PUT("/updateScore", middleware.JwtAuthMiddleware(), LeadScoreHandler.UpdateScore)
 
+		// Kanban board by status
+		leads.GET("/kanban", middleware.JwtAuthMiddleware(), kanbanHandler.GetLeadBoard)
+		leads.PUT("/:id/move", middleware.JwtAuthMiddleware(), kanbanHandler.MoveLead)
+
+		// Bulk operations
+		leads.POST("/bulk", middleware.JwtAuthMiddleware(), bulkHandler.BulkUpdateLeads)
+
+		// Lead consent routes
+		leads.GET("/:id/consent", middleware.JwtAuthMiddleware(), consentHandler.GetLeadConsent)
+		leads.PUT("/:id/consent", middleware.JwtAuthMiddleware(), consentHandler.UpdateLeadConsent)
+		leads.GET("/:id/consent/history", middleware.JwtAuthMiddleware(), consentHandler.GetLeadConsentHistory)
+
+		// Attribution touchpoints
+		leads.GET("/:id/touchpoints", middleware.JwtAuthMiddleware(), leadHandler.GetLeadTouchpoints)
+		leads.POST("/:id/touchpoints", middleware.JwtAuthMiddleware(), leadHandler.RecordTouchpoint)
+
 		// Bulk operations
 		leads.POST("/import", middleware.JwtAuthMiddleware(), leadHandler.BulkImportLeads)
 		leads.GET("/export", middleware.JwtAuthMiddleware(), leadHandler.ExportLeads)
@@ -100,6 +202,73 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		deals.PUT("/:id/stage", middleware.JwtAuthMiddleware(), dealHandler.UpdateDealStage)
 		deals.GET("/lead/:lead_id", middleware.JwtAuthMiddleware(), dealHandler.GetDealsByLead)
 		deals.GET("/pipeline", middleware.JwtAuthMiddleware(), dealHandler.GetDealPipeline)
+		deals.GET("/kanban", middleware.JwtAuthMiddleware(), kanbanHandler.GetDealBoard)
+		deals.PUT("/:id/move", middleware.JwtAuthMiddleware(), kanbanHandler.MoveDeal)
+		deals.POST("/bulk", middleware.JwtAuthMiddleware(), bulkHandler.BulkUpdateDeals)
+
+		deals.GET("/:id/line-items", middleware.JwtAuthMiddleware(), dealHandler.GetDealLineItems)
+		deals.POST("/:id/line-items", middleware.JwtAuthMiddleware(), dealHandler.CreateDealLineItem)
+		deals.PUT("/:id/line-items/:itemId", middleware.JwtAuthMiddleware(), dealHandler.UpdateDealLineItem)
+		deals.DELETE("/:id/line-items/:itemId", middleware.JwtAuthMiddleware(), dealHandler.DeleteDealLineItem)
+
+		deals.GET("/:id/contacts", middleware.JwtAuthMiddleware(), dealHandler.GetDealContacts)
+		deals.POST("/:id/contacts", middleware.JwtAuthMiddleware(), dealHandler.AddDealContact)
+		deals.PUT("/:id/contacts/:contactId", middleware.JwtAuthMiddleware(), dealHandler.UpdateDealContact)
+		deals.DELETE("/:id/contacts/:contactId", middleware.JwtAuthMiddleware(), dealHandler.RemoveDealContact)
+
+		deals.GET("/:id/quotes", middleware.JwtAuthMiddleware(), quoteHandler.GetDealQuotes)
+		deals.POST("/:id/quotes", middleware.JwtAuthMiddleware(), quoteHandler.CreateDealQuote)
+	}
+
+	// Product catalog routes
+	products := crm.Group("/products")
+	{
+		products.GET("", middleware.JwtAuthMiddleware(), productHandler.GetProducts)
+		products.POST("", middleware.JwtAuthMiddleware(), productHandler.CreateProduct)
+		products.GET("/:id", middleware.JwtAuthMiddleware(), productHandler.GetProduct)
+		products.PUT("/:id", middleware.JwtAuthMiddleware(), productHandler.UpdateProduct)
+		products.DELETE("/:id", middleware.JwtAuthMiddleware(), productHandler.DeleteProduct)
+	}
+
+	priceBooks := crm.Group("/price-books")
+	{
+		priceBooks.GET("", middleware.JwtAuthMiddleware(), productHandler.GetPriceBooks)
+		priceBooks.POST("", middleware.JwtAuthMiddleware(), productHandler.CreatePriceBook)
+		priceBooks.GET("/:id", middleware.JwtAuthMiddleware(), productHandler.GetPriceBook)
+		priceBooks.PUT("/:id", middleware.JwtAuthMiddleware(), productHandler.UpdatePriceBook)
+		priceBooks.DELETE("/:id", middleware.JwtAuthMiddleware(), productHandler.DeletePriceBook)
+		priceBooks.PUT("/:id/entries", middleware.JwtAuthMiddleware(), productHandler.SavePriceBookEntries)
+		priceBooks.DELETE("/:id/entries/:entryId", middleware.JwtAuthMiddleware(), productHandler.DeletePriceBookEntry)
+	}
+
+	// Quote routes
+	quotes := crm.Group("/quotes")
+	{
+		quotes.GET("/:id", middleware.JwtAuthMiddleware(), quoteHandler.GetQuote)
+		quotes.PUT("/:id", middleware.JwtAuthMiddleware(), quoteHandler.UpdateQuote)
+		quotes.GET("/:id/pdf", middleware.JwtAuthMiddleware(), quoteHandler.GetQuotePDF)
+		quotes.POST("/:id/send", middleware.JwtAuthMiddleware(), quoteHandler.SendQuote)
+		quotes.POST("/:id/accept", middleware.JwtAuthMiddleware(), quoteHandler.AcceptQuote)
+		quotes.POST("/:id/reject", middleware.JwtAuthMiddleware(), quoteHandler.RejectQuote)
+	}
+
+	// Account routes
+	accounts := crm.Group("/accounts")
+	{
+		accounts.GET("", middleware.JwtAuthMiddleware(), accountHandler.GetAccounts)
+		accounts.POST("", middleware.JwtAuthMiddleware(), accountHandler.CreateAccount)
+		accounts.GET("/search", middleware.JwtAuthMiddleware(), accountHandler.SearchAccounts)
+		accounts.GET("/:id", middleware.JwtAuthMiddleware(), accountHandler.GetAccount)
+		accounts.PUT("/:id", middleware.JwtAuthMiddleware(), accountHandler.UpdateAccount)
+		accounts.DELETE("/:id", middleware.JwtAuthMiddleware(), accountHandler.DeleteAccount)
+		accounts.GET("/:id/children", middleware.JwtAuthMiddleware(), accountHandler.GetChildAccounts)
+		accounts.GET("/:id/contacts", middleware.JwtAuthMiddleware(), accountHandler.GetAccountContacts)
+		accounts.POST("/:id/contacts", middleware.JwtAuthMiddleware(), accountHandler.LinkAccountContact)
+		accounts.GET("/:id/contact-links", middleware.JwtAuthMiddleware(), accountHandler.GetAccountContactLinks)
+		accounts.PUT("/:id/contacts/:contactId", middleware.JwtAuthMiddleware(), accountHandler.UpdateAccountContact)
+		accounts.DELETE("/:id/contacts/:contactId", middleware.JwtAuthMiddleware(), accountHandler.UnlinkAccountContact)
+		accounts.GET("/:id/deals", middleware.JwtAuthMiddleware(), accountHandler.GetAccountDeals)
+		accounts.GET("/:id/rollup", middleware.JwtAuthMiddleware(), accountHandler.GetAccountRollup)
 	}
 
 	// Contact routes
@@ -114,6 +283,8 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		// Contact-specific routes
 		contacts.GET("/search", middleware.JwtAuthMiddleware(), contactHandler.SearchContacts)
 		contacts.GET("/lead/:lead_id", middleware.JwtAuthMiddleware(), contactHandler.GetContactsByLead)
+		contacts.GET("/:id/deals", middleware.JwtAuthMiddleware(), contactHandler.GetContactDeals)
+		contacts.GET("/:id/accounts", middleware.JwtAuthMiddleware(), contactHandler.GetContactAccounts)
 	}
 
 	// Nurture routes
@@ -130,6 +301,18 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 
 			// Campaign-specific routes
 			campaigns.GET("/:id/stats", middleware.JwtAuthMiddleware(), nurtureHandler.GetCampaignStats)
+			campaigns.POST("/:id/launch", middleware.JwtAuthMiddleware(), nurtureHandler.LaunchCampaign)
+			campaigns.POST("/:id/pause", middleware.JwtAuthMiddleware(), nurtureHandler.PauseCampaign)
+			campaigns.POST("/:id/resume", middleware.JwtAuthMiddleware(), nurtureHandler.ResumeCampaign)
+			campaigns.POST("/:id/cancel", middleware.JwtAuthMiddleware(), nurtureHandler.CancelCampaign)
+			campaigns.GET("/:id/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.GetCampaignABTest)
+			campaigns.PUT("/:id/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.SaveCampaignABTest)
+			campaigns.DELETE("/:id/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteCampaignABTest)
+			campaigns.GET("/:id/roi", middleware.JwtAuthMiddleware(), nurtureHandler.GetCampaignROI)
+			campaigns.GET("/:id/costs", middleware.JwtAuthMiddleware(), nurtureHandler.GetCampaignCosts)
+			campaigns.POST("/:id/costs", middleware.JwtAuthMiddleware(), nurtureHandler.CreateCampaignCost)
+			campaigns.PUT("/:id/costs/:costId", middleware.JwtAuthMiddleware(), nurtureHandler.UpdateCampaignCost)
+			campaigns.DELETE("/:id/costs/:costId", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteCampaignCost)
 			campaigns.GET("/:id/leads", middleware.JwtAuthMiddleware(), nurtureHandler.GetCampaignLeads)
 			campaigns.POST("/:id/leads", middleware.JwtAuthMiddleware(), nurtureHandler.AddLeadsToCampaign)
 			campaigns.DELETE("/:id/leads", middleware.JwtAuthMiddleware(), nurtureHandler.RemoveLeadsFromCampaign)
@@ -140,12 +323,128 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		{
 			templates.GET("", middleware.JwtAuthMiddleware(), nurtureHandler.GetTemplates)
 			templates.POST("", middleware.JwtAuthMiddleware(), nurtureHandler.CreateTemplate)
+			templates.GET("/merge-fields", middleware.JwtAuthMiddleware(), nurtureHandler.GetTemplateMergeFields)
 			templates.GET("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.GetTemplate)
 			templates.PUT("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.UpdateTemplate)
 			templates.DELETE("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteTemplate)
+			templates.POST("/:id/preview", middleware.JwtAuthMiddleware(), nurtureHandler.PreviewTemplate)
+			templates.GET("/:id/stats", middleware.JwtAuthMiddleware(), nurtureHandler.GetTemplateStats)
+		}
+
+		// Sequence routes
+		sequences := nurture.Group("/sequences")
+		{
+			sequences.GET("", middleware.JwtAuthMiddleware(), nurtureHandler.GetSequences)
+			sequences.POST("", middleware.JwtAuthMiddleware(), nurtureHandler.CreateSequence)
+			sequences.GET("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.GetSequence)
+			sequences.PUT("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.UpdateSequence)
+			sequences.DELETE("/:id", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteSequence)
+			sequences.PUT("/:id/activate", middleware.JwtAuthMiddleware(), nurtureHandler.ActivateSequence)
+			sequences.PUT("/:id/deactivate", middleware.JwtAuthMiddleware(), nurtureHandler.DeactivateSequence)
+			sequences.GET("/:id/stats", middleware.JwtAuthMiddleware(), nurtureHandler.GetSequenceStats)
+
+			// Step routes
+			sequences.GET("/:id/steps", middleware.JwtAuthMiddleware(), nurtureHandler.GetSequenceSteps)
+			sequences.POST("/:id/steps", middleware.JwtAuthMiddleware(), nurtureHandler.CreateSequenceStep)
+			sequences.POST("/:id/steps/reorder", middleware.JwtAuthMiddleware(), nurtureHandler.ReorderSequenceSteps)
+			sequences.PUT("/:id/steps/:stepId", middleware.JwtAuthMiddleware(), nurtureHandler.UpdateSequenceStep)
+			sequences.DELETE("/:id/steps/:stepId", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteSequenceStep)
+			sequences.PUT("/:id/steps/:stepId/activate", middleware.JwtAuthMiddleware(), nurtureHandler.ActivateSequenceStep)
+			sequences.PUT("/:id/steps/:stepId/deactivate", middleware.JwtAuthMiddleware(), nurtureHandler.DeactivateSequenceStep)
+			sequences.GET("/:id/steps/:stepId/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.GetStepABTest)
+			sequences.PUT("/:id/steps/:stepId/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.SaveStepABTest)
+			sequences.DELETE("/:id/steps/:stepId/ab-test", middleware.JwtAuthMiddleware(), nurtureHandler.DeleteStepABTest)
+
+			// Enrollment routes
+			sequences.GET("/:id/enrollments", middleware.JwtAuthMiddleware(), nurtureHandler.GetSequenceEnrollments)
+			sequences.POST("/:id/enroll", middleware.JwtAuthMiddleware(), nurtureHandler.EnrollLeads)
+			sequences.POST("/:id/unenroll", middleware.JwtAuthMiddleware(), nurtureHandler.UnenrollLeads)
+		}
+
+		// Enrollment routes
+		enrollments := nurture.Group("/enrollments")
+		{
+			enrollments.GET("/:id/activity", middleware.JwtAuthMiddleware(), nurtureHandler.GetEnrollmentActivity)
+			enrollments.PUT("/:id/pause", middleware.JwtAuthMiddleware(), nurtureHandler.PauseEnrollment)
+			enrollments.PUT("/:id/resume", middleware.JwtAuthMiddleware(), nurtureHandler.ResumeEnrollment)
+			enrollments.PUT("/:id/cancel", middleware.JwtAuthMiddleware(), nurtureHandler.CancelEnrollment)
 		}
 	}
 
+	// Segment routes
+	segments := crm.Group("/segments")
+	{
+		segments.GET("", middleware.JwtAuthMiddleware(), segmentHandler.GetSegments)
+		segments.POST("", middleware.JwtAuthMiddleware(), segmentHandler.CreateSegment)
+		segments.POST("/preview", middleware.JwtAuthMiddleware(), segmentHandler.PreviewSegment)
+		segments.GET("/:id", middleware.JwtAuthMiddleware(), segmentHandler.GetSegment)
+		segments.PUT("/:id", middleware.JwtAuthMiddleware(), segmentHandler.UpdateSegment)
+		segments.DELETE("/:id", middleware.JwtAuthMiddleware(), segmentHandler.DeleteSegment)
+		segments.GET("/:id/leads", middleware.JwtAuthMiddleware(), segmentHandler.GetSegmentLeads)
+		segments.POST("/:id/refresh", middleware.JwtAuthMiddleware(), segmentHandler.RefreshSegment)
+	}
+
+	// Company routes
+	company := crm.Group("/company")
+	{
+		company.GET("/settings", middleware.JwtAuthMiddleware(), companyHandler.GetSettings)
+		company.PUT("/settings", middleware.JwtAuthMiddleware(), companyHandler.UpdateSettings)
+		company.POST("/phone-backfill", middleware.JwtAuthMiddleware(), companyHandler.BackfillPhones)
+
+		company.GET("/exchange-rates", middleware.JwtAuthMiddleware(), exchangeRateHandler.GetExchangeRates)
+		company.POST("/exchange-rates", middleware.JwtAuthMiddleware(), exchangeRateHandler.CreateExchangeRate)
+		company.POST("/exchange-rates/import", middleware.JwtAuthMiddleware(), exchangeRateHandler.ImportExchangeRates)
+		company.PUT("/exchange-rates/:id", middleware.JwtAuthMiddleware(), exchangeRateHandler.UpdateExchangeRate)
+		company.DELETE("/exchange-rates/:id", middleware.JwtAuthMiddleware(), exchangeRateHandler.DeleteExchangeRate)
+
+		company.GET("/quote-template", middleware.JwtAuthMiddleware(), quoteHandler.GetQuoteTemplate)
+		company.PUT("/quote-template", middleware.JwtAuthMiddleware(), quoteHandler.UpdateQuoteTemplate)
+		company.PUT("/quote-template/logo", middleware.JwtAuthMiddleware(), quoteHandler.UploadQuoteLogo)
+
+		company.GET("/close-reasons", middleware.JwtAuthMiddleware(), closeReasonHandler.GetCloseReasons)
+		company.POST("/close-reasons", middleware.JwtAuthMiddleware(), closeReasonHandler.CreateCloseReason)
+		company.PUT("/close-reasons/:id", middleware.JwtAuthMiddleware(), closeReasonHandler.UpdateCloseReason)
+		company.DELETE("/close-reasons/:id", middleware.JwtAuthMiddleware(), closeReasonHandler.DeleteCloseReason)
+	}
+
+	// Email routes
+	email := crm.Group("/email")
+	{
+		email.GET("/senders", middleware.JwtAuthMiddleware(), emailHandler.GetSenderIdentities)
+		email.POST("/senders", middleware.JwtAuthMiddleware(), emailHandler.CreateSenderIdentity)
+		email.PUT("/senders/:id", middleware.JwtAuthMiddleware(), emailHandler.UpdateSenderIdentity)
+		email.DELETE("/senders/:id", middleware.JwtAuthMiddleware(), emailHandler.DeleteSenderIdentity)
+
+		email.GET("/messages", middleware.JwtAuthMiddleware(), emailHandler.GetMessages)
+		email.POST("/messages", middleware.JwtAuthMiddleware(), emailHandler.SendMessage)
+		email.GET("/messages/:id", middleware.JwtAuthMiddleware(), emailHandler.GetMessage)
+
+		email.GET("/suppressions", middleware.JwtAuthMiddleware(), consentHandler.GetSuppressions)
+		email.POST("/suppressions", middleware.JwtAuthMiddleware(), consentHandler.AddSuppression)
+		email.DELETE("/suppressions/:id", middleware.JwtAuthMiddleware(), consentHandler.DeleteSuppression)
+
+		// Provider callbacks authenticate with a shared secret instead of a user token
+		email.POST("/webhooks/events", emailHandler.HandleDeliveryWebhook)
+	}
+
+	// Email tracking routes are opened from mail clients and authenticated by signed tokens
+	track := crm.Group("/track")
+	{
+		track.GET("/open/:token", emailHandler.TrackOpen)
+		track.HEAD("/open/:token", emailHandler.TrackOpen)
+		track.GET("/click/:token", emailHandler.TrackClick)
+		track.HEAD("/click/:token", emailHandler.TrackClick)
+	}
+
+	// Preference center routes are opened by email recipients and authenticated by signed tokens
+	preferences := crm.Group("/preferences")
+	{
+		preferences.GET("/:token", consentHandler.GetPreferences)
+		preferences.PUT("/:token", consentHandler.UpdatePreferences)
+		preferences.GET("/:token/unsubscribe", consentHandler.ShowUnsubscribe)
+		preferences.POST("/:token/unsubscribe", consentHandler.Unsubscribe)
+	}
+
 	// Analytics routes
 	analytics := crm.Group("/analytics")
 	{
@@ -157,6 +456,9 @@ func SetupCRMRoutes(r *gin.Engine, repos *models.CRMRepositories) {
 		analytics.GET("/targets", middleware.JwtAuthMiddleware(), analyticsHandler.GetTargetAnalytics)
 		analytics.GET("/dashboard", middleware.JwtAuthMiddleware(), analyticsHandler.GetDashboardAnalytics)
 		analytics.GET("/conversion", middleware.JwtAuthMiddleware(), analyticsHandler.GetConversionAnalytics)
+		analytics.GET("/attribution", middleware.JwtAuthMiddleware(), analyticsHandler.GetAttributionAnalytics)
+		analytics.GET("/campaign-roi", middleware.JwtAuthMiddleware(), analyticsHandler.GetCampaignROIAnalytics)
+		analytics.GET("/losses", middleware.JwtAuthMiddleware(), analyticsHandler.GetLossAnalytics)
 	}
 
 	// Target routes
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+)
+
+// A/B test groups a recipient can be placed in
+const (
+	ABGroupHoldout   = "holdout"
+	ABGroupTest      = "test"
+	ABGroupRemainder = "remainder"
+	ABGroupWinner    = "winner"
+)
+
+// ABTestService selects the winners of A/B tests once their test period is over
+type ABTestService struct {
+	abTestRepo  models.ABTestRepository
+	nurtureRepo models.NurtureRepository
+}
+
+// NewABTestService creates a new A/B test service
+func NewABTestService(repos *models.CRMRepositories) *ABTestService {
+	return &ABTestService{
+		abTestRepo:  repos.ABTestRepo,
+		nurtureRepo: repos.NurtureRepo,
+	}
+}
+
+// AssignABTestVariant places a lead in a test group and returns the variant to send, which is nil
+// for the holdout and for campaign recipients waiting for the winner. Assignment is a stable hash
+// of the test and lead, so a lead always lands in the same group. Nurture steps have no
+// remainder: every lead outside the holdout is tested until a winner is selected.
+func AssignABTestVariant(test *models.EmailABTest, leadID int) (string, *models.EmailVariant) {
+	if abBucket(test.ID, leadID, "group") < test.HoldoutPercent {
+		return ABGroupHoldout, nil
+	}
+
+	if test.Status == models.ABTestStatusWinnerSelected && test.WinnerVariantID != nil {
+		for i := range test.Variants {
+			if test.Variants[i].ID == *test.WinnerVariantID {
+				return ABGroupWinner, &test.Variants[i]
+			}
+		}
+	}
+
+	if test.CampaignID != nil && abBucket(test.ID, leadID, "group") >= test.HoldoutPercent+test.TestPercent {
+		return ABGroupRemainder, nil
+	}
+
+	if len(test.Variants) == 0 {
+		return ABGroupHoldout, nil
+	}
+	bucket := abBucket(test.ID, leadID, "variant")
+	cumulative := 0
+	for i := range test.Variants {
+		cumulative += test.Variants[i].SplitPercent
+		if bucket < cumulative {
+			return ABGroupTest, &test.Variants[i]
+		}
+	}
+	return ABGroupTest, &test.Variants[len(test.Variants)-1]
+}
+
+// abBucket hashes a lead into one of 100 buckets, independently for each test and purpose
+func abBucket(testID int, leadID int, purpose string) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d:%s", testID, leadID, purpose)
+	return int(h.Sum32() % 100)
+}
+
+// SelectDueWinners picks the winner of every test whose test period has ended. For campaign tests
+// the recipients waiting for the result are released to receive the winning variant.
+func (s *ABTestService) SelectDueWinners(now time.Time) (int, error) {
+	tests, err := s.abTestRepo.GetStartedTests()
+	if err != nil {
+		return 0, err
+	}
+
+	selected := 0
+	for i := range tests {
+		test := &tests[i]
+		if now.Before(test.TestStartedAt.Add(time.Duration(test.WinnerAfterHours) * time.Hour)) {
+			continue
+		}
+		if err := s.selectWinner(test, now); err != nil {
+			log.Printf("A/B test %d winner selection failed: %v", test.ID, err)
+			continue
+		}
+		selected++
+	}
+	return selected, nil
+}
+
+func (s *ABTestService) selectWinner(test *models.EmailABTest, now time.Time) error {
+	stats, err := s.abTestRepo.GetVariantStats(test.ID)
+	if err != nil {
+		return err
+	}
+	winner := chooseABTestWinner(test, stats)
+	if winner == nil {
+		return fmt.Errorf("test has no variants")
+	}
+
+	selected, err := s.abTestRepo.SelectWinner(test.ID, winner.ID, now)
+	if err != nil || !selected {
+		return err
+	}
+
+	if test.CampaignID != nil {
+		return s.nurtureRepo.ReleaseAwaitingCampaignLeads(*test.CampaignID, winner.ID)
+	}
+	return nil
+}
+
+// chooseABTestWinner returns the variant with the highest winner metric; ties go to the first variant
+func chooseABTestWinner(test *models.EmailABTest, stats []models.EmailStats) *models.EmailVariant {
+	rates := make(map[int]float64, len(stats))
+	for _, variantStats := range stats {
+		if variantStats.GroupID == nil {
+			continue
+		}
+		rate := variantStats.OpenRate
+		if test.WinnerMetric == "click_rate" {
+			rate = variantStats.ClickRate
+		}
+		rates[*variantStats.GroupID] = rate
+	}
+
+	var winner *models.EmailVariant
+	for i := range test.Variants {
+		variant := &test.Variants[i]
+		if winner == nil || rates[variant.ID] > rates[winner.ID] {
+			winner = variant
+		}
+	}
+	return winner
+}
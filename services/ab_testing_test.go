@@ -0,0 +1,142 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"math"
+	"testing"
+)
+
+func TestAssignABTestVariantSplit(t *testing.T) {
+	const leads = 20000
+	campaignID := 3
+	stepID := 4
+	winnerID := 8
+
+	tests := []struct {
+		name string
+		test models.EmailABTest
+		want map[string]float64 // expected share of leads by group, or by variant name for the test group
+	}{
+		{
+			name: "campaign with holdout and remainder",
+			test: models.EmailABTest{ID: 1, CampaignID: &campaignID, HoldoutPercent: 10, TestPercent: 20, Status: models.ABTestStatusTesting,
+				Variants: []models.EmailVariant{{ID: 1, Name: "A", SplitPercent: 50}, {ID: 2, Name: "B", SplitPercent: 50}}},
+			want: map[string]float64{ABGroupHoldout: 0.10, "A": 0.10, "B": 0.10, ABGroupRemainder: 0.70},
+		},
+		{
+			name: "nurture step tests everyone outside the holdout",
+			test: models.EmailABTest{ID: 2, StepID: &stepID, HoldoutPercent: 20, TestPercent: 10, Status: models.ABTestStatusTesting,
+				Variants: []models.EmailVariant{{ID: 3, Name: "A", SplitPercent: 25}, {ID: 4, Name: "B", SplitPercent: 75}}},
+			want: map[string]float64{ABGroupHoldout: 0.20, "A": 0.20, "B": 0.60},
+		},
+		{
+			name: "uneven splits fall back to the last variant",
+			test: models.EmailABTest{ID: 3, StepID: &stepID, Status: models.ABTestStatusTesting,
+				Variants: []models.EmailVariant{{ID: 5, Name: "A", SplitPercent: 30}, {ID: 6, Name: "B", SplitPercent: 30}}},
+			want: map[string]float64{"A": 0.30, "B": 0.70},
+		},
+		{
+			name: "winner replaces the test and remainder groups",
+			test: models.EmailABTest{ID: 4, CampaignID: &campaignID, HoldoutPercent: 10, TestPercent: 20, Status: models.ABTestStatusWinnerSelected, WinnerVariantID: &winnerID,
+				Variants: []models.EmailVariant{{ID: 7, Name: "A", SplitPercent: 50}, {ID: 8, Name: "B", SplitPercent: 50}}},
+			want: map[string]float64{ABGroupHoldout: 0.10, ABGroupWinner: 0.90},
+		},
+		{
+			name: "no variants",
+			test: models.EmailABTest{ID: 5, StepID: &stepID, Status: models.ABTestStatusTesting},
+			want: map[string]float64{ABGroupHoldout: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := make(map[string]int)
+			for leadID := 1; leadID <= leads; leadID++ {
+				group, variant := AssignABTestVariant(&tt.test, leadID)
+				switch {
+				case group == ABGroupTest && variant != nil:
+					counts[variant.Name]++
+				case group == ABGroupWinner && (variant == nil || variant.ID != *tt.test.WinnerVariantID):
+					t.Fatalf("lead %d got winner group with variant %v", leadID, variant)
+				case group != ABGroupTest && group != ABGroupWinner && variant != nil:
+					t.Fatalf("lead %d in group %s got variant %d", leadID, group, variant.ID)
+				default:
+					counts[group]++
+				}
+			}
+
+			for key := range counts {
+				if _, ok := tt.want[key]; !ok {
+					t.Errorf("unexpected group %s with %d leads", key, counts[key])
+				}
+			}
+			for key, share := range tt.want {
+				got := float64(counts[key]) / leads
+				if math.Abs(got-share) > 0.02 {
+					t.Errorf("%s share = %.3f, want %.2f", key, got, share)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignABTestVariantIsStable(t *testing.T) {
+	stepID := 4
+	test := &models.EmailABTest{ID: 9, StepID: &stepID, HoldoutPercent: 10, Status: models.ABTestStatusTesting,
+		Variants: []models.EmailVariant{{ID: 1, SplitPercent: 50}, {ID: 2, SplitPercent: 50}}}
+
+	for leadID := 1; leadID <= 1000; leadID++ {
+		group, variant := AssignABTestVariant(test, leadID)
+		againGroup, againVariant := AssignABTestVariant(test, leadID)
+		if group != againGroup || variant != againVariant {
+			t.Fatalf("lead %d assigned to %s then %s", leadID, group, againGroup)
+		}
+	}
+}
+
+func TestChooseABTestWinner(t *testing.T) {
+	variants := []models.EmailVariant{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}, {ID: 3, Name: "C"}}
+	id := func(v int) *int { return &v }
+
+	tests := []struct {
+		name     string
+		metric   string
+		variants []models.EmailVariant
+		stats    []models.EmailStats
+		want     int
+	}{
+		{"highest open rate", "open_rate", variants, []models.EmailStats{
+			{GroupID: id(1), OpenRate: 0.2, ClickRate: 0.9},
+			{GroupID: id(2), OpenRate: 0.4, ClickRate: 0.1},
+			{GroupID: id(3), OpenRate: 0.3},
+		}, 2},
+		{"highest click rate", "click_rate", variants, []models.EmailStats{
+			{GroupID: id(1), OpenRate: 0.2, ClickRate: 0.9},
+			{GroupID: id(2), OpenRate: 0.4, ClickRate: 0.1},
+		}, 1},
+		{"tie goes to the first variant", "open_rate", variants, []models.EmailStats{
+			{GroupID: id(2), OpenRate: 0.5},
+			{GroupID: id(3), OpenRate: 0.5},
+		}, 2},
+		{"no stats", "open_rate", variants, nil, 1},
+		{"ungrouped stats ignored", "open_rate", variants, []models.EmailStats{
+			{OpenRate: 1},
+			{GroupID: id(3), OpenRate: 0.1},
+		}, 3},
+		{"no variants", "open_rate", nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := &models.EmailABTest{WinnerMetric: tt.metric, Variants: tt.variants}
+			winner := chooseABTestWinner(test, tt.stats)
+			got := 0
+			if winner != nil {
+				got = winner.ID
+			}
+			if got != tt.want {
+				t.Errorf("winner = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,17 +8,21 @@ import (
 
 // AnalyticsService handles business logic for analytics
 type AnalyticsService struct {
-	analyticsRepo models.AnalyticsRepository
-	leadRepo      models.LeadRepository
-	dealRepo      models.DealRepository
+	analyticsRepo   models.AnalyticsRepository
+	leadRepo        models.LeadRepository
+	dealRepo        models.DealRepository
+	attributionRepo models.AttributionRepository
+	nurtureRepo     models.NurtureRepository
 }
 
 // NewAnalyticsService creates a new analytics service
 func NewAnalyticsService(repos *models.CRMRepositories) *AnalyticsService {
 	return &AnalyticsService{
-		analyticsRepo: repos.AnalyticsRepo,
-		leadRepo:      repos.LeadRepo,
-		dealRepo:      repos.DealRepo,
+		analyticsRepo:   repos.AnalyticsRepo,
+		leadRepo:        repos.LeadRepo,
+		dealRepo:        repos.DealRepo,
+		attributionRepo: repos.AttributionRepo,
+		nurtureRepo:     repos.NurtureRepo,
 	}
 }
 
@@ -118,6 +122,16 @@ func (s *AnalyticsService) GetFunnelAnalytics(companyId int) (map[string]interfa
 	return analytics, nil
 }
 
+// GetLossAnalytics breaks down lost deals by reason, competitor, stage, owner and period
+func (s *AnalyticsService) GetLossAnalytics(filters AnalyticsFilters, period string, companyId int) (map[string]interface{}, error) {
+	analytics, err := s.analyticsRepo.GetLossAnalytics(filters.StartDate, filters.EndDate, period, companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch loss analytics: %w", err)
+	}
+
+	return analytics, nil
+}
+
 // GetDashboardAnalytics provides comprehensive dashboard data
 func (s *AnalyticsService) GetDashboardAnalytics(filters AnalyticsFilters, companyId int) (map[string]interface{}, error) {
 	leadAnalytics, err := s.GetLeadAnalytics(filters, companyId)
@@ -142,14 +156,16 @@ func (s *AnalyticsService) GetDashboardAnalytics(filters AnalyticsFilters, compa
 			"total_deals":        dealAnalytics["total_deals"],
 			"deals_won":          dealAnalytics["deals_won"],
 			"total_revenue":      dealAnalytics["total_revenue"],
+			"currency":           dealAnalytics["currency"],
 			"average_deal_value": dealAnalytics["average_deal_value"],
 			"win_rate":           dealAnalytics["win_rate"],
 		},
 		"charts": map[string]interface{}{
-			"leads_by_source": leadAnalytics["leads_by_source"],
-			"deals_by_stage":  dealAnalytics["deals_by_stage"],
-			"revenue_trend":   dealAnalytics["revenue_trend"],
-			"daily_trend":     leadAnalytics["daily_trend"],
+			"leads_by_source":    leadAnalytics["leads_by_source"],
+			"deals_by_stage":     dealAnalytics["deals_by_stage"],
+			"revenue_trend":      dealAnalytics["revenue_trend"],
+			"revenue_by_product": dealAnalytics["revenue_by_product"],
+			"daily_trend":        leadAnalytics["daily_trend"],
 		},
 		"insights": s.generateInsights(leadAnalytics, dealAnalytics),
 	}
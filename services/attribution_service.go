@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// timeDecayHalfLifeDays is the half-life used by the time-decay attribution model
+const timeDecayHalfLifeDays = 7.0
+
+// GetAttributionAnalytics credits won deal revenue back to campaigns and sources
+func (s *AnalyticsService) GetAttributionAnalytics(filters AnalyticsFilters, model string, companyId int) (map[string]interface{}, error) {
+	if filters.EndDate.Before(filters.StartDate) {
+		return nil, fmt.Errorf("end date cannot be before start date")
+	}
+
+	if model == "" {
+		model = models.AttributionLastTouch
+	}
+	switch model {
+	case models.AttributionFirstTouch, models.AttributionLastTouch, models.AttributionLinear, models.AttributionTimeDecay:
+	default:
+		return nil, fmt.Errorf("unsupported attribution model: %s", model)
+	}
+
+	conversions, currency, err := s.attributionRepo.GetConversions(filters.StartDate, filters.EndDate, companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch conversions: %w", err)
+	}
+
+	campaignCounts, err := s.attributionRepo.GetCampaignLeadCounts(filters.StartDate, filters.EndDate, companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign lead counts: %w", err)
+	}
+
+	sourceCounts, err := s.attributionRepo.GetSourceLeadCounts(filters.StartDate, filters.EndDate, companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source lead counts: %w", err)
+	}
+
+	campaignRevenue := make(map[int]float64)
+	campaignConversions := make(map[int]float64)
+	sourceRevenue := make(map[string]float64)
+	sourceConversions := make(map[string]float64)
+	totalRevenue := 0.0
+	unattributedRevenue := 0.0
+	exchangeRateMissing := false
+
+	for _, conversion := range conversions {
+		if conversion.ExchangeRateMissing {
+			// Revenue without a rate cannot be added to the base currency totals
+			exchangeRateMissing = true
+			continue
+		}
+		totalRevenue += conversion.Amount
+
+		weights := attributionWeights(model, conversion)
+		if len(weights) == 0 {
+			unattributedRevenue += conversion.Amount
+			continue
+		}
+
+		for i, weight := range weights {
+			tp := conversion.Touchpoints[i]
+			if tp.CampaignID != nil {
+				campaignRevenue[*tp.CampaignID] += conversion.Amount * weight
+				campaignConversions[*tp.CampaignID] += weight
+			}
+			source := tp.UtmSource
+			if source == "" {
+				source = "direct"
+			}
+			sourceRevenue[source] += conversion.Amount * weight
+			sourceConversions[source] += weight
+		}
+	}
+
+	campaigns := make([]map[string]interface{}, 0, len(campaignCounts))
+	for _, cc := range campaignCounts {
+		revenue := campaignRevenue[cc.CampaignID]
+		campaigns = append(campaigns, map[string]interface{}{
+			"campaign_id":   cc.CampaignID,
+			"name":          cc.Name,
+			"currency":      cc.Currency,
+			"budget":        cc.Budget,
+			"leads":         cc.Leads,
+			"conversions":   campaignConversions[cc.CampaignID],
+			"revenue":       revenue,
+			"cost_per_lead": costPerLead(cc.Budget, cc.Leads),
+			"roi":           returnOnInvestment(revenue, cc.Budget),
+		})
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i]["revenue"].(float64) > campaigns[j]["revenue"].(float64)
+	})
+
+	sourceLeads := make(map[string]int64)
+	for _, sc := range sourceCounts {
+		source := sc.Source
+		if source == "" {
+			source = "direct"
+		}
+		sourceLeads[source] += sc.Leads
+	}
+	for source := range sourceRevenue {
+		if _, ok := sourceLeads[source]; !ok {
+			sourceLeads[source] = 0
+		}
+	}
+
+	sources := make([]map[string]interface{}, 0, len(sourceLeads))
+	for source, leads := range sourceLeads {
+		sources = append(sources, map[string]interface{}{
+			"source":      source,
+			"leads":       leads,
+			"conversions": sourceConversions[source],
+			"revenue":     sourceRevenue[source],
+		})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i]["revenue"].(float64) > sources[j]["revenue"].(float64)
+	})
+
+	return map[string]interface{}{
+		"model":                 model,
+		"currency":              currency,
+		"exchange_rate_missing": exchangeRateMissing,
+		"total_revenue":         totalRevenue,
+		"attributed_revenue":    totalRevenue - unattributedRevenue,
+		"unattributed_revenue":  unattributedRevenue,
+		"conversions":           len(conversions),
+		"campaigns":             campaigns,
+		"sources":               sources,
+	}, nil
+}
+
+// attributionWeights returns the share of credit for each touchpoint of a conversion
+func attributionWeights(model string, conversion models.AttributionConversion) []float64 {
+	n := len(conversion.Touchpoints)
+	if n == 0 {
+		return nil
+	}
+
+	weights := make([]float64, n)
+	switch model {
+	case models.AttributionFirstTouch:
+		weights[0] = 1
+	case models.AttributionLastTouch:
+		weights[n-1] = 1
+	case models.AttributionLinear:
+		for i := range weights {
+			weights[i] = 1 / float64(n)
+		}
+	case models.AttributionTimeDecay:
+		total := 0.0
+		for i, tp := range conversion.Touchpoints {
+			days := conversion.ConvertedAt.Sub(tp.TouchedAt).Hours() / 24
+			if days < 0 {
+				days = 0
+			}
+			weights[i] = math.Pow(2, -days/timeDecayHalfLifeDays)
+			total += weights[i]
+		}
+		for i := range weights {
+			weights[i] /= total
+		}
+	}
+
+	return weights
+}
+
+func costPerLead(spend float64, leads int64) float64 {
+	if leads == 0 {
+		return 0
+	}
+	return spend / float64(leads)
+}
+
+func returnOnInvestment(revenue float64, spend float64) float64 {
+	if spend == 0 {
+		return 0
+	}
+	return ((revenue - spend) / spend) * 100
+}
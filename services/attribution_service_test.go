@@ -0,0 +1,79 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAttributionWeights(t *testing.T) {
+	convertedAt := time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)
+	touchpoints := func(daysBefore ...int) []models.LeadTouchpoint {
+		var tps []models.LeadTouchpoint
+		for _, days := range daysBefore {
+			tps = append(tps, models.LeadTouchpoint{TouchedAt: convertedAt.AddDate(0, 0, -days)})
+		}
+		return tps
+	}
+
+	tests := []struct {
+		name        string
+		model       string
+		touchpoints []models.LeadTouchpoint
+		want        []float64
+	}{
+		{"no touchpoints", models.AttributionLinear, nil, nil},
+		{"first touch", models.AttributionFirstTouch, touchpoints(21, 14, 0), []float64{1, 0, 0}},
+		{"last touch", models.AttributionLastTouch, touchpoints(21, 14, 0), []float64{0, 0, 1}},
+		{"single touch", models.AttributionLastTouch, touchpoints(3), []float64{1}},
+		{"linear", models.AttributionLinear, touchpoints(21, 14, 7, 0), []float64{0.25, 0.25, 0.25, 0.25}},
+		// weights 1/4, 1/2 and 1 halve every seven days before conversion
+		{"time decay", models.AttributionTimeDecay, touchpoints(14, 7, 0), []float64{1.0 / 7, 2.0 / 7, 4.0 / 7}},
+		{"time decay same day", models.AttributionTimeDecay, touchpoints(0, 0), []float64{0.5, 0.5}},
+		{"time decay after conversion counts as same day", models.AttributionTimeDecay, touchpoints(7, -3), []float64{1.0 / 3, 2.0 / 3}},
+		{"unknown model gives no credit", "u_shaped", touchpoints(7, 0), []float64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion := models.AttributionConversion{ConvertedAt: convertedAt, Touchpoints: tt.touchpoints}
+			got := attributionWeights(tt.model, conversion)
+			if len(got) != len(tt.want) {
+				t.Fatalf("attributionWeights = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("attributionWeights = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCampaignSpendRatios(t *testing.T) {
+	tests := []struct {
+		name    string
+		spend   float64
+		revenue float64
+		leads   int64
+		wantCPL float64
+		wantROI float64
+	}{
+		{"profit", 1000, 2500, 40, 25, 150},
+		{"loss", 1000, 500, 8, 125, -50},
+		{"no leads", 1000, 0, 0, 0, -100},
+		{"no spend", 0, 800, 10, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := costPerLead(tt.spend, tt.leads); got != tt.wantCPL {
+				t.Errorf("costPerLead(%v, %d) = %v, want %v", tt.spend, tt.leads, got, tt.wantCPL)
+			}
+			if got := returnOnInvestment(tt.revenue, tt.spend); got != tt.wantROI {
+				t.Errorf("returnOnInvestment(%v, %v) = %v, want %v", tt.revenue, tt.spend, got, tt.wantROI)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxBulkErrorLength caps the error recorded for a record a bulk operation failed on
+const maxBulkErrorLength = 500
+
+// ErrBulkRecordSkipped is returned by a BulkRecordFunc when a record needs no change
+var ErrBulkRecordSkipped = errors.New("record needs no change")
+
+// BulkRecordFunc applies a bulk operation's action to one record, returning ErrBulkRecordSkipped
+// when the record needs no change
+type BulkRecordFunc func(id int) error
+
+// BulkService runs bulk operations on leads and deals, tracking what each did to every record
+// and recording the changes in the audit trail
+type BulkService struct {
+	bulkRepo  models.BulkOperationRepository
+	auditRepo models.AuditRepository
+}
+
+// NewBulkService creates a new bulk operation service
+func NewBulkService(repos *models.CRMRepositories) *BulkService {
+	return &BulkService{
+		bulkRepo:  repos.BulkOperationRepo,
+		auditRepo: repos.AuditRepo,
+	}
+}
+
+// Run applies an operation's action to each record in turn, storing the operation with a result
+// per record and an audit entry for each record it changed. A record that fails does not stop the
+// operation; failing to store the operation's results marks it failed.
+func (s *BulkService) Run(operation *models.BulkOperation, ids []int, apply BulkRecordFunc) error {
+	operation.Status = models.BulkStatusRunning
+	operation.Total = len(ids)
+	operation.Succeeded, operation.Skipped, operation.Failed = 0, 0, 0
+	operation.StartedAt = time.Now()
+	if err := s.bulkRepo.CreateBulkOperation(operation); err != nil {
+		return fmt.Errorf("failed to create bulk operation: %w", err)
+	}
+
+	results := make([]models.BulkOperationResult, 0, len(ids))
+	var entries []models.AuditEntry
+	for _, id := range ids {
+		result := models.BulkOperationResult{OperationID: operation.ID, RecordID: id, Status: models.BulkRecordSucceeded}
+		err := apply(id)
+		switch {
+		case errors.Is(err, ErrBulkRecordSkipped):
+			result.Status = models.BulkRecordSkipped
+			operation.Skipped++
+		case err != nil:
+			result.Status = models.BulkRecordFailed
+			result.Error = err.Error()
+			if len(result.Error) > maxBulkErrorLength {
+				result.Error = result.Error[:maxBulkErrorLength]
+			}
+			operation.Failed++
+		default:
+			operation.Succeeded++
+			entries = append(entries, models.AuditEntry{
+				EntityType:      operation.Entity,
+				EntityID:        id,
+				Action:          "bulk_" + operation.Action,
+				Details:         operation.Params,
+				UserID:          operation.CreatedBy,
+				BulkOperationID: &operation.ID,
+				CreatedAt:       time.Now(),
+				CompanyId:       operation.CompanyId,
+			})
+		}
+		results = append(results, result)
+	}
+
+	err := s.bulkRepo.AddBulkOperationResults(results)
+	if err == nil {
+		err = s.auditRepo.RecordAudit(entries)
+	}
+
+	now := time.Now()
+	operation.CompletedAt = &now
+	operation.Status = models.BulkStatusCompleted
+	if err != nil {
+		operation.Status = models.BulkStatusFailed
+		operation.Error = err.Error()
+	}
+	if updateErr := s.bulkRepo.UpdateBulkOperation(operation); updateErr != nil && err == nil {
+		err = updateErr
+	}
+	operation.Results = results
+	return err
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"sort"
+)
+
+// GetCampaignROI returns a campaign's lifetime spend, cost per lead and opportunity, revenue and ROI
+func (s *AnalyticsService) GetCampaignROI(campaignID int, companyId int) (*models.CampaignROI, error) {
+	results, err := s.nurtureRepo.GetCampaignROI(companyId, &campaignID, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign ROI: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	roi := &results[0]
+	calculateCampaignROI(roi)
+	return roi, nil
+}
+
+// GetCampaignROIComparison compares the ROI of a company's campaigns over a period, best first.
+// Totals are per currency because campaigns may be budgeted in different currencies.
+func (s *AnalyticsService) GetCampaignROIComparison(filters AnalyticsFilters, companyId int) (map[string]interface{}, error) {
+	if filters.EndDate.Before(filters.StartDate) {
+		return nil, fmt.Errorf("end date cannot be before start date")
+	}
+
+	campaigns, err := s.nurtureRepo.GetCampaignROI(companyId, nil, &filters.StartDate, &filters.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign ROI: %w", err)
+	}
+
+	totals := make(map[string]*models.CampaignROI)
+	for i := range campaigns {
+		roi := &campaigns[i]
+		calculateCampaignROI(roi)
+
+		total, ok := totals[roi.Currency]
+		if !ok {
+			total = &models.CampaignROI{
+				Currency:           roi.Currency,
+				CostByChannel:      make(map[string]float64),
+				Revenue:            new(float64),
+				PipelineInfluenced: new(float64),
+			}
+			totals[roi.Currency] = total
+		}
+		total.Budget += roi.Budget
+		total.Cost += roi.Cost
+		for channel, amount := range roi.CostByChannel {
+			total.CostByChannel[channel] += amount
+		}
+		total.Leads += roi.Leads
+		total.Opportunities += roi.Opportunities
+		total.WonDeals += roi.WonDeals
+		// A total is only known when every campaign's deal values could be converted
+		if roi.ExchangeRateMissing || total.ExchangeRateMissing {
+			total.ExchangeRateMissing = true
+			total.Revenue, total.PipelineInfluenced = nil, nil
+		} else {
+			*total.Revenue += *roi.Revenue
+			*total.PipelineInfluenced += *roi.PipelineInfluenced
+		}
+	}
+
+	// Campaigns without an ROI come last
+	sort.SliceStable(campaigns, func(i, j int) bool {
+		if campaigns[i].ROI == nil || campaigns[j].ROI == nil {
+			return campaigns[j].ROI == nil && campaigns[i].ROI != nil
+		}
+		return *campaigns[i].ROI > *campaigns[j].ROI
+	})
+
+	totalsByCurrency := make([]models.CampaignROI, 0, len(totals))
+	for _, total := range totals {
+		calculateCampaignROI(total)
+		totalsByCurrency = append(totalsByCurrency, *total)
+	}
+	sort.Slice(totalsByCurrency, func(i, j int) bool {
+		return totalsByCurrency[i].Currency < totalsByCurrency[j].Currency
+	})
+
+	return map[string]interface{}{
+		"campaigns": campaigns,
+		"totals":    totalsByCurrency,
+	}, nil
+}
+
+// calculateCampaignROI fills in the metrics derived from a campaign's spend and outcomes
+func calculateCampaignROI(roi *models.CampaignROI) {
+	roi.BudgetRemaining = roi.Budget - roi.Cost
+	roi.CostPerLead = costPerLead(roi.Cost, roi.Leads)
+	roi.CostPerOpportunity = costPerLead(roi.Cost, roi.Opportunities)
+	roi.ROI = nil
+	if roi.Revenue != nil {
+		value := returnOnInvestment(*roi.Revenue, roi.Cost)
+		roi.ROI = &value
+	}
+}
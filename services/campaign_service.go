@@ -0,0 +1,478 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	campaignLeaseDuration = 5 * time.Minute
+	campaignBatchSize     = 10
+	campaignSendBatchSize = 200
+)
+
+// CampaignService launches campaigns and sends their template to campaign members
+type CampaignService struct {
+	nurtureRepo     models.NurtureRepository
+	companyRepo     models.CompanyRepository
+	emailRepo       models.EmailRepository
+	abTestRepo      models.ABTestRepository
+	segmentRepo     models.SegmentRepository
+	templateService *TemplateService
+	emailService    *EmailService
+	abTestService   *ABTestService
+	segmentService  *SegmentService
+	workerID        string
+}
+
+// NewCampaignService creates a campaign service that queues email through emailService
+func NewCampaignService(repos *models.CRMRepositories, emailService *EmailService) *CampaignService {
+	hostname, _ := os.Hostname()
+	return &CampaignService{
+		nurtureRepo:     repos.NurtureRepo,
+		companyRepo:     repos.CompanyRepo,
+		emailRepo:       repos.EmailRepo,
+		abTestRepo:      repos.ABTestRepo,
+		segmentRepo:     repos.SegmentRepo,
+		templateService: NewTemplateService(repos),
+		emailService:    emailService,
+		abTestService:   NewABTestService(repos),
+		segmentService:  NewSegmentService(repos),
+		workerID:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// ErrInvalidCampaignTransition is returned when a campaign is not in a status the action applies to
+var ErrInvalidCampaignTransition = errors.New("campaign status does not allow this action")
+
+// CompanyLocation returns a company's configured timezone, UTC if none is set
+func CompanyLocation(companyRepo models.CompanyRepository, companyId int) (*time.Location, error) {
+	settings, err := companyRepo.GetSettings(companyId)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.Timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid company timezone %q: %w", settings.Timezone, err)
+	}
+	return location, nil
+}
+
+// Launch validates a draft campaign and schedules it. The StartDate wall clock time is read in
+// the company timezone; a campaign without one, or with one in the past, starts on the next run.
+func (s *CampaignService) Launch(campaign *models.Campaign) error {
+	if campaign.Status != models.CampaignStatusDraft {
+		return ErrInvalidCampaignTransition
+	}
+
+	// An A/B test's variants supply the templates in place of the campaign template
+	test, err := s.abTestRepo.GetTestByCampaign(campaign.ID)
+	if err != nil {
+		return err
+	}
+	var templateIDs []int
+	if test != nil {
+		for _, variant := range test.Variants {
+			templateIDs = append(templateIDs, variant.TemplateID)
+		}
+	} else if campaign.TemplateID != nil {
+		templateIDs = append(templateIDs, *campaign.TemplateID)
+	}
+	if len(templateIDs) == 0 {
+		return fmt.Errorf("campaign has no template")
+	}
+
+	for _, templateID := range templateIDs {
+		template, err := s.nurtureRepo.GetTemplateByID(templateID)
+		if err != nil {
+			return err
+		}
+		if template == nil || template.CompanyId != campaign.CompanyId {
+			return fmt.Errorf("email template %d not found", templateID)
+		}
+		unknown, err := s.templateService.ValidateTemplate(template.Subject, template.Content, campaign.CompanyId)
+		if err != nil {
+			return fmt.Errorf("invalid template %d: %w", templateID, err)
+		}
+		if len(unknown) > 0 {
+			return fmt.Errorf("template %d uses unknown merge fields: %v", templateID, unknown)
+		}
+	}
+
+	location, err := CompanyLocation(s.companyRepo, campaign.CompanyId)
+	if err != nil {
+		return err
+	}
+
+	scheduledAt := time.Now()
+	if campaign.StartDate != nil {
+		scheduledAt = wallClockIn(*campaign.StartDate, location)
+	}
+	var endsAt *time.Time
+	if campaign.EndDate != nil {
+		end := wallClockIn(*campaign.EndDate, location)
+		if !scheduledAt.Before(end) {
+			return fmt.Errorf("campaign ends before it starts")
+		}
+		endsAt = &end
+	}
+
+	// A campaign targeting a segment starts with the segment's current members
+	if campaign.SegmentID != nil {
+		segment, err := s.segmentRepo.GetSegmentByID(*campaign.SegmentID)
+		if err != nil {
+			return err
+		}
+		if segment == nil || segment.CompanyId != campaign.CompanyId {
+			return fmt.Errorf("segment %d not found", *campaign.SegmentID)
+		}
+		if _, err := s.segmentService.Refresh(segment, time.Now()); err != nil {
+			return fmt.Errorf("failed to refresh segment: %w", err)
+		}
+	}
+
+	launched, err := s.nurtureRepo.LaunchCampaign(campaign.ID, scheduledAt, endsAt)
+	if err != nil {
+		return err
+	}
+	if !launched {
+		return ErrInvalidCampaignTransition
+	}
+	return nil
+}
+
+// wallClockIn returns the time with the same date and clock time as t in a location
+func wallClockIn(t time.Time, location *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, location)
+}
+
+// Pause stops a scheduled or running campaign from sending
+func (s *CampaignService) Pause(campaign *models.Campaign) error {
+	return s.setStatus(campaign, []string{models.CampaignStatusScheduled, models.CampaignStatusRunning}, models.CampaignStatusPaused)
+}
+
+// Resume continues a paused campaign. It goes back to scheduled and starts running on the next
+// run if its start time has passed.
+func (s *CampaignService) Resume(campaign *models.Campaign) error {
+	return s.setStatus(campaign, []string{models.CampaignStatusPaused}, models.CampaignStatusScheduled)
+}
+
+// Cancel stops a campaign for good, cancelling its unsent email
+func (s *CampaignService) Cancel(campaign *models.Campaign) error {
+	fromStatuses := []string{models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusRunning, models.CampaignStatusPaused}
+	if err := s.setStatus(campaign, fromStatuses, models.CampaignStatusCancelled); err != nil {
+		return err
+	}
+	if _, err := s.emailRepo.CancelQueuedMessages(campaign.ID); err != nil {
+		return err
+	}
+	return s.nurtureRepo.CancelPendingCampaignLeads(campaign.ID)
+}
+
+func (s *CampaignService) setStatus(campaign *models.Campaign, fromStatuses []string, status string) error {
+	changed, err := s.nurtureRepo.SetCampaignStatus(campaign.ID, fromStatuses, status)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrInvalidCampaignTransition
+	}
+	campaign.Status = status
+	return nil
+}
+
+// Start runs the campaign scheduler loop until the context is cancelled
+func (s *CampaignService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Campaign scheduler started (worker %s, interval %s)", s.workerID, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if queued, err := s.RunOnce(time.Now()); err != nil {
+			log.Printf("Campaign scheduler run failed: %v", err)
+		} else if queued > 0 {
+			log.Printf("Campaign scheduler queued %d emails", queued)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce selects due A/B test winners, starts campaigns that are due and queues the next batch of
+// email for running campaigns
+func (s *CampaignService) RunOnce(now time.Time) (int, error) {
+	if _, err := s.abTestService.SelectDueWinners(now); err != nil {
+		log.Printf("A/B test winner selection failed: %v", err)
+	}
+
+	if _, err := s.nurtureRepo.StartDueCampaigns(now); err != nil {
+		return 0, fmt.Errorf("failed to start due campaigns: %w", err)
+	}
+
+	campaigns, err := s.nurtureRepo.ClaimRunningCampaigns(s.workerID, now, now.Add(campaignLeaseDuration), campaignBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim campaigns: %w", err)
+	}
+
+	queued := 0
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		count, err := s.runCampaign(campaign, now)
+		if err != nil {
+			log.Printf("Campaign %d run failed: %v", campaign.ID, err)
+		}
+		queued += count
+		if err := s.nurtureRepo.ReleaseCampaign(campaign, s.workerID); err != nil {
+			log.Printf("Campaign %d could not be released: %v", campaign.ID, err)
+		}
+	}
+
+	return queued, nil
+}
+
+// runCampaign queues email for as many pending members as the hourly limit and quiet hours allow,
+// completing the campaign once every member has been processed or its end date has passed
+func (s *CampaignService) runCampaign(campaign *models.Campaign, now time.Time) (int, error) {
+	if campaign.EndsAt != nil && now.After(*campaign.EndsAt) {
+		if err := s.nurtureRepo.CancelPendingCampaignLeads(campaign.ID); err != nil {
+			return 0, err
+		}
+		s.complete(campaign, now)
+		return 0, nil
+	}
+
+	location, err := CompanyLocation(s.companyRepo, campaign.CompanyId)
+	if err != nil {
+		return 0, err
+	}
+	if inQuietHours(now.In(location), campaign.QuietHoursStart, campaign.QuietHoursEnd) {
+		return 0, nil
+	}
+
+	limit := campaignSendBatchSize
+	if campaign.HourlyLimit > 0 {
+		sent, err := s.nurtureRepo.CountCampaignLeadsProcessedSince(campaign.ID, now.Add(-time.Hour))
+		if err != nil {
+			return 0, err
+		}
+		remaining := campaign.HourlyLimit - int(sent)
+		if remaining <= 0 {
+			return 0, nil
+		}
+		if remaining < limit {
+			limit = remaining
+		}
+	}
+
+	test, err := s.abTestRepo.GetTestByCampaign(campaign.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	members, err := s.nurtureRepo.GetPendingCampaignLeads(campaign.ID, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(members) == 0 {
+		// Recipients held back for an A/B test are sent to once the winner is selected, and a
+		// segment campaign with an end date keeps sending to new segment members until it ends
+		waitingForWinner := test != nil && test.Status == models.ABTestStatusTesting
+		waitingForMembers := campaign.SegmentID != nil && campaign.EndsAt != nil
+		if !waitingForWinner && !waitingForMembers {
+			s.complete(campaign, now)
+		}
+		return 0, nil
+	}
+
+	if test != nil && test.TestStartedAt == nil {
+		if err := s.abTestRepo.MarkTestStarted(test.ID, now); err != nil {
+			return 0, err
+		}
+	}
+
+	templates := make(map[int]*models.CampaignTemplate)
+	queued := 0
+	for i := range members {
+		member := &members[i]
+
+		templateID, ok := s.memberTemplate(campaign, test, member, now)
+		if !ok {
+			if err := s.nurtureRepo.UpdateCampaignLead(member); err != nil {
+				return queued, err
+			}
+			continue
+		}
+
+		template, ok := templates[templateID]
+		if !ok {
+			template, err = s.nurtureRepo.GetTemplateByID(templateID)
+			if err != nil {
+				return queued, err
+			}
+			if template == nil {
+				return queued, fmt.Errorf("email template %d not found", templateID)
+			}
+			templates[templateID] = template
+		}
+
+		s.sendToMember(campaign, template, member, now)
+		if member.Status == models.CampaignLeadQueued {
+			queued++
+		}
+		if err := s.nurtureRepo.UpdateCampaignLead(member); err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// memberTemplate returns the template to send a member. With an A/B test the member is assigned
+// a variant; members in the holdout or waiting for the winner get no template and are marked
+// accordingly.
+func (s *CampaignService) memberTemplate(campaign *models.Campaign, test *models.EmailABTest, member *models.CampaignLead, now time.Time) (int, bool) {
+	if test == nil {
+		if campaign.TemplateID == nil {
+			member.Status = models.CampaignLeadFailed
+			member.LastError = "campaign has no template"
+			member.ProcessedAt = &now
+			return 0, false
+		}
+		return *campaign.TemplateID, true
+	}
+
+	if member.VariantID == nil {
+		group, variant := AssignABTestVariant(test, member.LeadID)
+		switch group {
+		case ABGroupHoldout:
+			member.Status = models.CampaignLeadHoldout
+			member.ProcessedAt = &now
+			return 0, false
+		case ABGroupRemainder:
+			member.Status = models.CampaignLeadAwaiting
+			return 0, false
+		}
+		member.VariantID = &variant.ID
+	}
+
+	for _, variant := range test.Variants {
+		if variant.ID == *member.VariantID {
+			return variant.TemplateID, true
+		}
+	}
+	member.Status = models.CampaignLeadFailed
+	member.LastError = fmt.Sprintf("A/B test variant %d not found", *member.VariantID)
+	member.ProcessedAt = &now
+	return 0, false
+}
+
+// sendToMember renders the campaign template for a member and queues it, recording the outcome on the member
+func (s *CampaignService) sendToMember(campaign *models.Campaign, template *models.CampaignTemplate, member *models.CampaignLead, now time.Time) {
+	member.ProcessedAt = &now
+
+	data, err := s.templateService.BuildMergeData(member.LeadID, campaign.CompanyId)
+	if err != nil {
+		member.Status = models.CampaignLeadFailed
+		member.LastError = err.Error()
+		return
+	}
+	lead, _ := data["lead"].(map[string]interface{})
+	to, _ := lead["email"].(string)
+	if to == "" {
+		member.Status = models.CampaignLeadSkipped
+		member.LastError = "lead has no email address"
+		return
+	}
+
+	rendered, err := s.templateService.Render(template.Subject, template.Content, data)
+	if err != nil {
+		member.Status = models.CampaignLeadFailed
+		member.LastError = err.Error()
+		return
+	}
+
+	campaignID := campaign.ID
+	leadID := member.LeadID
+	templateID := template.ID
+	message := &models.EmailMessage{
+		CompanyId:        campaign.CompanyId,
+		LeadID:           &leadID,
+		CampaignID:       &campaignID,
+		TemplateID:       &templateID,
+		VariantID:        member.VariantID,
+		SenderIdentityID: campaign.SenderIdentityID,
+		ToEmail:          to,
+		Subject:          rendered.Subject,
+		HTMLBody:         rendered.HTML,
+		TextBody:         rendered.Text,
+	}
+	if err := s.emailService.Queue(message); err != nil {
+		if errors.Is(err, ErrEmailNotPermitted) {
+			member.Status = models.CampaignLeadSkipped
+		} else {
+			member.Status = models.CampaignLeadFailed
+		}
+		member.LastError = err.Error()
+		return
+	}
+
+	member.Status = models.CampaignLeadQueued
+	member.MessageID = &message.ID
+	member.LastError = ""
+}
+
+func (s *CampaignService) complete(campaign *models.Campaign, now time.Time) {
+	campaign.Status = models.CampaignStatusCompleted
+	campaign.CompletedAt = &now
+}
+
+// inQuietHours reports whether a local time falls within quiet hours given as HH:MM. Quiet hours
+// may span midnight, e.g. 20:00 to 08:00. Empty bounds mean no quiet hours.
+func inQuietHours(local time.Time, start string, end string) bool {
+	startMinute, ok := parseClockMinute(start)
+	if !ok {
+		return false
+	}
+	endMinute, ok := parseClockMinute(end)
+	if !ok || startMinute == endMinute {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	if startMinute < endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
+
+// parseClockMinute parses HH:MM into minutes after midnight
+func parseClockMinute(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return parsed.Hour()*60 + parsed.Minute(), true
+}
+
+// ValidClockTime reports whether a value is empty or a valid HH:MM time
+func ValidClockTime(value string) bool {
+	if value == "" {
+		return true
+	}
+	_, ok := parseClockMinute(value)
+	return ok
+}
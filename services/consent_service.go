@@ -0,0 +1,266 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEmailNotPermitted is returned when marketing email may not be sent to a recipient
+var ErrEmailNotPermitted = errors.New("recipient does not accept marketing email")
+
+// ConsentChangeSource describes who or what changed a consent, for the audit trail
+type ConsentChangeSource struct {
+	Source      string
+	ChangedByID *int
+	IPAddress   string
+}
+
+// EmailPreferences is what the preference center shows a recipient
+type EmailPreferences struct {
+	Email    string          `json:"email"`
+	Company  string          `json:"company"`
+	Channels map[string]bool `json:"channels"`
+}
+
+// ConsentService checks and changes communication consent and the email suppression list
+type ConsentService struct {
+	consentRepo models.ConsentRepository
+	leadRepo    models.LeadRepository
+	companyRepo models.CompanyRepository
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(repos *models.CRMRepositories) *ConsentService {
+	return &ConsentService{
+		consentRepo: repos.ConsentRepo,
+		leadRepo:    repos.LeadRepo,
+		companyRepo: repos.CompanyRepo,
+	}
+}
+
+// NormalizeEmail returns the form email addresses are stored in on the suppression list
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailBlockReason returns why marketing email may not be sent to an address, or "" if it may.
+// leadID is optional; without it only the suppression list is checked.
+func (s *ConsentService) EmailBlockReason(companyId int, leadID *int, email string) (string, error) {
+	suppression, err := s.consentRepo.FindSuppression(companyId, NormalizeEmail(email))
+	if err != nil {
+		return "", err
+	}
+	if suppression != nil {
+		return fmt.Sprintf("address is on the suppression list (%s)", suppression.Reason), nil
+	}
+
+	if leadID != nil {
+		allowed, err := s.CanContact(*leadID, models.ConsentChannelEmail)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return "lead has opted out of email", nil
+		}
+	}
+	return "", nil
+}
+
+// CanContact reports whether a lead may be contacted on a channel. Leads that have not
+// opted out of a channel may be contacted on it.
+func (s *ConsentService) CanContact(leadID int, channel string) (bool, error) {
+	consents, err := s.consentRepo.GetLeadConsents(leadID)
+	if err != nil {
+		return false, err
+	}
+	for _, consent := range consents {
+		if consent.Channel == channel {
+			return consent.Status != models.ConsentStatusOptedOut, nil
+		}
+	}
+	return true, nil
+}
+
+// SetLeadConsent changes a lead's consent for a channel, recording the change in the audit trail
+func (s *ConsentService) SetLeadConsent(leadID int, companyId int, channel string, status string, source ConsentChangeSource) (*models.LeadConsent, error) {
+	if !models.ConsentChannels[channel] {
+		return nil, fmt.Errorf("unsupported consent channel: %s", channel)
+	}
+	if status != models.ConsentStatusOptedIn && status != models.ConsentStatusOptedOut {
+		return nil, fmt.Errorf("unsupported consent status: %s", status)
+	}
+
+	consent := &models.LeadConsent{
+		LeadID:    leadID,
+		Channel:   channel,
+		Status:    status,
+		Source:    source.Source,
+		CompanyId: companyId,
+	}
+	change := &models.ConsentChange{
+		LeadID:      &leadID,
+		Channel:     channel,
+		Status:      status,
+		Source:      source.Source,
+		ChangedByID: source.ChangedByID,
+		IPAddress:   source.IPAddress,
+		CompanyId:   companyId,
+	}
+	if _, err := s.consentRepo.SetLeadConsent(consent, change); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// Suppress adds an address to the company's suppression list
+func (s *ConsentService) Suppress(companyId int, email string, reason string, notes string, source ConsentChangeSource) (*models.EmailSuppression, error) {
+	email = NormalizeEmail(email)
+	suppression := &models.EmailSuppression{
+		Email:     email,
+		Reason:    reason,
+		Notes:     notes,
+		CompanyId: companyId,
+	}
+	change := &models.ConsentChange{
+		Email:       email,
+		Channel:     models.ConsentChannelEmail,
+		Status:      models.ConsentStatusOptedOut,
+		Source:      source.Source,
+		ChangedByID: source.ChangedByID,
+		IPAddress:   source.IPAddress,
+		CompanyId:   companyId,
+	}
+	added, err := s.consentRepo.AddSuppression(suppression, change)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return s.consentRepo.FindSuppression(companyId, email)
+	}
+	return suppression, nil
+}
+
+// Unsuppress removes an address from the company's suppression list
+func (s *ConsentService) Unsuppress(suppression *models.EmailSuppression, source ConsentChangeSource) error {
+	change := &models.ConsentChange{
+		Email:          suppression.Email,
+		Channel:        models.ConsentChannelEmail,
+		PreviousStatus: models.ConsentStatusOptedOut,
+		Status:         models.ConsentStatusOptedIn,
+		Source:         source.Source,
+		ChangedByID:    source.ChangedByID,
+		IPAddress:      source.IPAddress,
+		CompanyId:      suppression.CompanyId,
+	}
+	return s.consentRepo.RemoveSuppression(suppression, change)
+}
+
+// Unsubscribe opts an address out of marketing email: it is suppressed and every lead of the
+// company with that address is opted out of email
+func (s *ConsentService) Unsubscribe(companyId int, email string, source ConsentChangeSource) error {
+	if _, err := s.Suppress(companyId, email, models.SuppressionReasonUnsubscribe, "", source); err != nil {
+		return err
+	}
+	return s.setConsentForAddress(companyId, email, models.ConsentChannelEmail, models.ConsentStatusOptedOut, source)
+}
+
+// GetPreferences returns the channels a recipient currently accepts
+func (s *ConsentService) GetPreferences(companyId int, email string) (*EmailPreferences, error) {
+	email = NormalizeEmail(email)
+	preferences := &EmailPreferences{
+		Email:    email,
+		Channels: make(map[string]bool, len(models.ConsentChannels)),
+	}
+	for channel := range models.ConsentChannels {
+		preferences.Channels[channel] = true
+	}
+
+	settings, err := s.companyRepo.GetSettings(companyId)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil {
+		preferences.Company = settings.Name
+	}
+
+	suppression, err := s.consentRepo.FindSuppression(companyId, email)
+	if err != nil {
+		return nil, err
+	}
+	if suppression != nil {
+		preferences.Channels[models.ConsentChannelEmail] = false
+	}
+
+	leadIDs, err := s.leadRepo.FindIDsByEmail(companyId, email)
+	if err != nil {
+		return nil, err
+	}
+	for _, leadID := range leadIDs {
+		consents, err := s.consentRepo.GetLeadConsents(leadID)
+		if err != nil {
+			return nil, err
+		}
+		for _, consent := range consents {
+			if consent.Status == models.ConsentStatusOptedOut {
+				preferences.Channels[consent.Channel] = false
+			}
+		}
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences applies a recipient's choices from the preference center. Addresses
+// suppressed because of bounces or complaints stay suppressed when email is opted back into.
+func (s *ConsentService) UpdatePreferences(companyId int, email string, channels map[string]bool, source ConsentChangeSource) error {
+	for channel := range channels {
+		if !models.ConsentChannels[channel] {
+			return fmt.Errorf("unsupported consent channel: %s", channel)
+		}
+	}
+
+	for channel, accepted := range channels {
+		if channel == models.ConsentChannelEmail {
+			if !accepted {
+				if err := s.Unsubscribe(companyId, email, source); err != nil {
+					return err
+				}
+				continue
+			}
+
+			suppression, err := s.consentRepo.FindSuppression(companyId, NormalizeEmail(email))
+			if err != nil {
+				return err
+			}
+			if suppression != nil && suppression.Reason == models.SuppressionReasonUnsubscribe {
+				if err := s.Unsuppress(suppression, source); err != nil {
+					return err
+				}
+			}
+		}
+
+		status := models.ConsentStatusOptedIn
+		if !accepted {
+			status = models.ConsentStatusOptedOut
+		}
+		if err := s.setConsentForAddress(companyId, email, channel, status, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setConsentForAddress sets a channel's consent on every lead of the company with the address
+func (s *ConsentService) setConsentForAddress(companyId int, email string, channel string, status string, source ConsentChangeSource) error {
+	leadIDs, err := s.leadRepo.FindIDsByEmail(companyId, email)
+	if err != nil {
+		return err
+	}
+	for _, leadID := range leadIDs {
+		if _, err := s.SetLeadConsent(leadID, companyId, channel, status, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
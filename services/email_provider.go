@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"crm-app/backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EmailProvider delivers a single email message and returns the provider's message ID
+type EmailProvider interface {
+	Send(message *models.EmailMessage) (string, error)
+}
+
+// NewEmailProviderFromEnv builds the provider selected by EMAIL_PROVIDER (smtp or log)
+func NewEmailProviderFromEnv() EmailProvider {
+	switch os.Getenv("EMAIL_PROVIDER") {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPEmailProvider{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	default:
+		return &LogEmailProvider{Dir: os.Getenv("EMAIL_LOG_DIR")}
+	}
+}
+
+// SMTPEmailProvider sends email through an SMTP server, using STARTTLS when the server offers it
+type SMTPEmailProvider struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Send delivers the message over SMTP
+func (p *SMTPEmailProvider) Send(message *models.EmailMessage) (string, error) {
+	if p.Host == "" {
+		return "", fmt.Errorf("SMTP host is not configured")
+	}
+
+	messageID := newEmailMessageID(message.FromEmail)
+	body, err := buildMIMEMessage(message, messageID)
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if p.Username != "" {
+		auth = smtp.PlainAuth("", p.Username, p.Password, p.Host)
+	}
+
+	addr := net.JoinHostPort(p.Host, p.Port)
+	if err := smtp.SendMail(addr, auth, message.FromEmail, []string{message.ToEmail}, body); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// LogEmailProvider writes messages to .eml files in Dir, or to the log when Dir is empty.
+// It is intended for development.
+type LogEmailProvider struct {
+	Dir string
+}
+
+// Send records the message instead of delivering it
+func (p *LogEmailProvider) Send(message *models.EmailMessage) (string, error) {
+	messageID := newEmailMessageID(message.FromEmail)
+
+	if p.Dir == "" {
+		log.Printf("Email %d from %s to %s: %s", message.ID, message.FromEmail, message.ToEmail, message.Subject)
+		return messageID, nil
+	}
+
+	body, err := buildMIMEMessage(message, messageID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102T150405"), message.ID)
+	if err := os.WriteFile(filepath.Join(p.Dir, name), body, 0o644); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// isPermanentEmailError reports whether a send error should not be retried (SMTP 5xx replies)
+func isPermanentEmailError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+	return false
+}
+
+// newEmailMessageID returns a unique RFC 5322 Message-ID in the sender's domain
+func newEmailMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(buf), time.Now().UnixNano(), domain)
+}
+
+// buildMIMEMessage renders the message as RFC 5322 text with text and/or HTML parts
+func buildMIMEMessage(message *models.EmailMessage, messageID string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := map[string]string{
+		"From":         (&mail.Address{Name: message.FromName, Address: message.FromEmail}).String(),
+		"To":           (&mail.Address{Address: message.ToEmail}).String(),
+		"Subject":      mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID,
+		"MIME-Version": "1.0",
+	}
+	if message.ReplyTo != "" {
+		headers["Reply-To"] = (&mail.Address{Address: message.ReplyTo}).String()
+	}
+	if message.Headers != "" {
+		var extra map[string]string
+		if err := json.Unmarshal([]byte(message.Headers), &extra); err != nil {
+			return nil, fmt.Errorf("invalid message headers: %w", err)
+		}
+		for key, value := range extra {
+			headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+		}
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, stripHeaderNewlines(headers[key]))
+	}
+
+	// A single part when only one body is present
+	if message.HTMLBody == "" || message.TextBody == "" {
+		contentType, content := "text/plain", message.TextBody
+		if message.HTMLBody != "" {
+			contentType, content = "text/html", message.HTMLBody
+		}
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", contentType)
+		if err := writeQuotedPrintable(&buf, content); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", message.TextBody},
+		{"text/html", message.HTMLBody},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// stripHeaderNewlines prevents header injection through user-supplied values
+func stripHeaderNewlines(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,507 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"time"
+)
+
+// ErrUnsubscribeNotConfigured is returned for marketing email when unsubscribe links cannot be
+// signed, since it may not go out without them
+var ErrUnsubscribeNotConfigured = errors.New("unsubscribe links are not configured")
+
+const (
+	emailLeaseDuration = 5 * time.Minute
+	emailBatchSize     = 50
+	emailBaseBackoff   = time.Minute
+	emailMaxBackoff    = time.Hour
+)
+
+// EmailService queues outbound email and delivers it through an EmailProvider
+type EmailService struct {
+	emailRepo       models.EmailRepository
+	nurtureRepo     models.NurtureRepository
+	abTestRepo      models.ABTestRepository
+	templateService *TemplateService
+	consentService  *ConsentService
+	tracker         *EmailTracker
+	unsubscribe     *UnsubscribeLinks
+	provider        EmailProvider
+	workerID        string
+}
+
+// NewEmailService creates an email service; provider may be nil when the service only queues
+func NewEmailService(repos *models.CRMRepositories, provider EmailProvider) *EmailService {
+	hostname, _ := os.Hostname()
+	return &EmailService{
+		emailRepo:       repos.EmailRepo,
+		nurtureRepo:     repos.NurtureRepo,
+		abTestRepo:      repos.ABTestRepo,
+		templateService: NewTemplateService(repos),
+		consentService:  NewConsentService(repos),
+		tracker:         NewEmailTrackerFromEnv(),
+		unsubscribe:     NewUnsubscribeLinksFromEnv(),
+		provider:        provider,
+		workerID:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Queue fills in the sender identity and stores the message for delivery
+func (s *EmailService) Queue(message *models.EmailMessage) error {
+	if _, err := mail.ParseAddress(message.ToEmail); err != nil {
+		return fmt.Errorf("invalid recipient address: %s", message.ToEmail)
+	}
+	if message.HTMLBody == "" && message.TextBody == "" {
+		return fmt.Errorf("email has no content")
+	}
+	if message.TextBody == "" {
+		message.TextBody = HTMLToText(message.HTMLBody)
+	}
+
+	if isMarketingEmail(message) {
+		if s.unsubscribe == nil {
+			return ErrUnsubscribeNotConfigured
+		}
+		reason, err := s.consentService.EmailBlockReason(message.CompanyId, message.LeadID, message.ToEmail)
+		if err != nil {
+			return fmt.Errorf("failed to check consent: %w", err)
+		}
+		if reason != "" {
+			return fmt.Errorf("%w: %s", ErrEmailNotPermitted, reason)
+		}
+	}
+
+	if message.FromEmail == "" {
+		if err := s.applySenderIdentity(message); err != nil {
+			return err
+		}
+	}
+
+	return s.emailRepo.QueueMessage(message)
+}
+
+// isMarketingEmail reports whether a message was sent by a campaign or nurture sequence, which
+// requires consent and carries tracking and unsubscribe links
+func isMarketingEmail(message *models.EmailMessage) bool {
+	return message.CampaignID != nil || message.EnrollmentID != nil
+}
+
+// applySenderIdentity sets the from address from the chosen or default sender identity of the company
+func (s *EmailService) applySenderIdentity(message *models.EmailMessage) error {
+	var identity *models.EmailSenderIdentity
+	var err error
+	if message.SenderIdentityID != nil {
+		identity, err = s.emailRepo.GetSenderIdentityByID(*message.SenderIdentityID)
+		if err == nil && identity != nil && identity.CompanyId != message.CompanyId {
+			identity = nil
+		}
+	} else {
+		identity, err = s.emailRepo.GetDefaultSenderIdentity(message.CompanyId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if identity == nil {
+		// Fall back to a deployment-wide sender when the company has none
+		from := os.Getenv("EMAIL_DEFAULT_FROM")
+		if from == "" {
+			return fmt.Errorf("no sender identity configured for company %d", message.CompanyId)
+		}
+		address, err := mail.ParseAddress(from)
+		if err != nil {
+			return fmt.Errorf("invalid EMAIL_DEFAULT_FROM: %w", err)
+		}
+		message.FromName = address.Name
+		message.FromEmail = address.Address
+		return nil
+	}
+
+	message.SenderIdentityID = &identity.ID
+	message.FromName = identity.Name
+	message.FromEmail = identity.Email
+	if message.ReplyTo == "" {
+		message.ReplyTo = identity.ReplyTo
+	}
+	return nil
+}
+
+// Start runs the send queue loop until the context is cancelled
+func (s *EmailService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Email queue started (worker %s, interval %s)", s.workerID, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := s.RunOnce(time.Now()); err != nil {
+			log.Printf("Email queue run failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Email queue processed %d messages", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and attempts delivery of all queued messages that are due at the given time
+func (s *EmailService) RunOnce(now time.Time) (int, error) {
+	if s.provider == nil {
+		return 0, fmt.Errorf("no email provider configured")
+	}
+
+	messages, err := s.emailRepo.ClaimDueMessages(s.workerID, now, now.Add(emailLeaseDuration), emailBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim messages: %w", err)
+	}
+
+	for i := range messages {
+		message := &messages[i]
+		s.deliver(message, now)
+		if err := s.emailRepo.ReleaseMessage(message, s.workerID); err != nil {
+			log.Printf("Email %d could not be released: %v", message.ID, err)
+		}
+	}
+
+	return len(messages), nil
+}
+
+// deliver makes one delivery attempt, scheduling a retry with exponential backoff on temporary failures
+func (s *EmailService) deliver(message *models.EmailMessage, now time.Time) {
+	// Consent may have been withdrawn while the message was queued
+	if isMarketingEmail(message) {
+		reason, err := s.consentService.EmailBlockReason(message.CompanyId, message.LeadID, message.ToEmail)
+		if err != nil {
+			next := now.Add(emailBaseBackoff)
+			message.NextAttemptAt = &next
+			message.LastError = fmt.Sprintf("failed to check consent: %v", err)
+			return
+		}
+		if reason != "" {
+			message.Status = models.EmailStatusSuppressed
+			message.NextAttemptAt = nil
+			message.LastError = reason
+			s.recordCampaignOutcome(message, models.CampaignLeadSuppressed, reason)
+			s.recordNurtureActivity(message, "suppressed", map[string]interface{}{
+				"message_id": message.ID,
+				"reason":     reason,
+			})
+			return
+		}
+	}
+
+	message.Attempts++
+
+	outgoing, err := s.prepareOutgoing(message)
+	if err != nil {
+		message.Status = models.EmailStatusFailed
+		message.NextAttemptAt = nil
+		message.LastError = err.Error()
+		s.recordCampaignOutcome(message, models.CampaignLeadFailed, err.Error())
+		return
+	}
+
+	providerMessageID, err := s.provider.Send(outgoing)
+	if err == nil {
+		message.Status = models.EmailStatusSent
+		message.ProviderMessageID = providerMessageID
+		message.SentAt = &now
+		message.NextAttemptAt = nil
+		message.LastError = ""
+		s.recordCampaignOutcome(message, models.CampaignLeadSent, "")
+		s.recordNurtureActivity(message, "sent", map[string]interface{}{"message_id": message.ID})
+		return
+	}
+
+	message.LastError = err.Error()
+	if isPermanentEmailError(err) || message.Attempts >= message.MaxAttempts {
+		message.Status = models.EmailStatusFailed
+		message.NextAttemptAt = nil
+		s.recordCampaignOutcome(message, models.CampaignLeadFailed, err.Error())
+		s.recordNurtureActivity(message, "failed", map[string]interface{}{
+			"message_id": message.ID,
+			"error":      err.Error(),
+			"attempts":   message.Attempts,
+		})
+		return
+	}
+
+	next := now.Add(emailBackoff(message.Attempts))
+	message.NextAttemptAt = &next
+}
+
+// prepareOutgoing returns the message as it goes out. Campaign and nurture emails get
+// List-Unsubscribe headers, and open and click tracking when it is configured; the stored
+// message is left unchanged.
+func (s *EmailService) prepareOutgoing(message *models.EmailMessage) (*models.EmailMessage, error) {
+	if !isMarketingEmail(message) {
+		return message, nil
+	}
+	if s.unsubscribe == nil {
+		return nil, ErrUnsubscribeNotConfigured
+	}
+
+	outgoing := *message
+	if s.tracker != nil && outgoing.HTMLBody != "" {
+		outgoing.HTMLBody = s.tracker.Instrument(message.ID, message.HTMLBody)
+	}
+
+	headers := map[string]string{}
+	if message.Headers != "" {
+		if err := json.Unmarshal([]byte(message.Headers), &headers); err != nil {
+			return nil, fmt.Errorf("invalid message headers: %w", err)
+		}
+	}
+	// RFC 8058 one-click unsubscribe
+	headers["List-Unsubscribe"] = "<" + s.unsubscribe.UnsubscribeURL(message.CompanyId, NormalizeEmail(message.ToEmail)) + ">"
+	headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+	outgoing.Headers = string(encoded)
+	return &outgoing, nil
+}
+
+// emailBackoff returns the delay before the next attempt, doubling per attempt up to emailMaxBackoff
+func emailBackoff(attempts int) time.Duration {
+	delay := emailBaseBackoff
+	for i := 1; i < attempts && delay < emailMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > emailMaxBackoff {
+		delay = emailMaxBackoff
+	}
+	return delay
+}
+
+// HandleDeliveryEvent applies a bounce or complaint reported by the provider to the message it
+// concerns. Events without a provider message ID are matched to the latest message to their
+// address from their company.
+func (s *EmailService) HandleDeliveryEvent(event models.EmailDeliveryEvent) (*models.EmailMessage, error) {
+	var message *models.EmailMessage
+	var err error
+	if event.ProviderMessageID != "" {
+		message, err = s.emailRepo.FindMessageByProviderID(event.ProviderMessageID)
+	} else if event.Email != "" && event.CompanyId != 0 {
+		message, err = s.emailRepo.FindLatestMessageTo(event.Email, event.CompanyId)
+	}
+	if err != nil || message == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	details := map[string]interface{}{
+		"message_id": message.ID,
+		"reason":     event.Reason,
+	}
+
+	switch event.Type {
+	case "bounce":
+		details["bounce_type"] = event.BounceType
+		// Soft bounces are transient; only hard bounces mark the message as undeliverable
+		if event.BounceType != "soft" {
+			message.Status = models.EmailStatusBounced
+			message.BouncedAt = &now
+			s.suppressAddress(message, models.SuppressionReasonBounce, event.Reason)
+			s.recordCampaignOutcome(message, models.CampaignLeadBounced, event.Reason)
+		}
+		s.recordNurtureActivity(message, "bounced", details)
+	case "complaint":
+		message.Status = models.EmailStatusComplained
+		s.suppressAddress(message, models.SuppressionReasonComplaint, event.Reason)
+		s.recordCampaignOutcome(message, models.CampaignLeadComplained, event.Reason)
+		s.recordNurtureActivity(message, "complained", details)
+	default:
+		return nil, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	if err := s.emailRepo.UpdateMessage(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// suppressAddress stops further marketing email to the recipient of a bounced or complained message
+func (s *EmailService) suppressAddress(message *models.EmailMessage, reason string, notes string) {
+	source := ConsentChangeSource{Source: "delivery_webhook"}
+	if _, err := s.consentService.Suppress(message.CompanyId, message.ToEmail, reason, notes, source); err != nil {
+		log.Printf("Failed to suppress %s after %s: %v", message.ToEmail, reason, err)
+	}
+}
+
+// Tracker returns the open and click tracker, or nil when tracking is not configured
+func (s *EmailService) Tracker() *EmailTracker {
+	return s.tracker
+}
+
+// RecordTrackingEvent stores an open or click of a message. Requests that look automated are
+// stored as bot events, which are excluded from stats and do not create nurture activity.
+func (s *EmailService) RecordTrackingEvent(messageID int, eventType string, target string, clientIP string, r *http.Request) error {
+	message, err := s.emailRepo.GetMessageByID(messageID)
+	if err != nil || message == nil {
+		return err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	isBot := IsLikelyBot(r, message.SentAt, time.Now(), eventType == "click")
+	event := &models.EmailEvent{
+		MessageID: message.ID,
+		Type:      eventType,
+		URL:       target,
+		UserAgent: userAgent,
+		IPAddress: clientIP,
+		IsBot:     isBot,
+		CompanyId: message.CompanyId,
+	}
+	if err := s.emailRepo.RecordEvent(event); err != nil {
+		return err
+	}
+
+	if !isBot {
+		details := map[string]interface{}{"message_id": message.ID}
+		activityType := "opened"
+		if eventType == "click" {
+			activityType = "clicked"
+			details["url"] = target
+		}
+		s.recordNurtureActivity(message, activityType, details)
+	}
+	return nil
+}
+
+// recordCampaignOutcome records a delivery outcome on the campaign member the message was sent to, if any
+func (s *EmailService) recordCampaignOutcome(message *models.EmailMessage, status string, lastError string) {
+	if message.CampaignID == nil || message.LeadID == nil || s.nurtureRepo == nil {
+		return
+	}
+	if err := s.nurtureRepo.SetCampaignLeadStatus(*message.CampaignID, *message.LeadID, status, lastError); err != nil {
+		log.Printf("Failed to record email outcome for campaign %d: %v", *message.CampaignID, err)
+	}
+}
+
+// recordNurtureActivity records an email event against the nurture enrollment that sent it, if any
+func (s *EmailService) recordNurtureActivity(message *models.EmailMessage, activityType string, details map[string]interface{}) {
+	if message.EnrollmentID == nil || s.nurtureRepo == nil {
+		return
+	}
+
+	activity := &models.NurtureActivity{
+		EnrollmentID: *message.EnrollmentID,
+		Type:         activityType,
+	}
+	if message.StepID != nil {
+		activity.StepID = *message.StepID
+	}
+	if encoded, err := json.Marshal(details); err == nil {
+		activity.Details = string(encoded)
+	}
+	if err := s.nurtureRepo.RecordActivity(activity); err != nil {
+		log.Printf("Failed to record email activity for enrollment %d: %v", *message.EnrollmentID, err)
+	}
+}
+
+// NurtureEmailExecutor returns a nurture step executor that queues the step's template for the lead.
+// Step content is JSON: {"template_id": 1, "sender_identity_id": 2}. When the step has an A/B test
+// the lead's variant template is sent instead, or nothing if the lead is in the holdout.
+func (s *EmailService) NurtureEmailExecutor() NurtureStepExecutor {
+	return func(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+		var settings struct {
+			TemplateID       int  `json:"template_id"`
+			SenderIdentityID *int `json:"sender_identity_id"`
+		}
+		if err := decodeStepContent(ctx.Step.Content, &settings); err != nil {
+			return "", nil, err
+		}
+
+		to := ctx.Lead["email"]
+		if to == "" {
+			return "skipped", map[string]interface{}{"reason": "lead has no email address"}, nil
+		}
+
+		leadID := ctx.Enrollment.LeadID
+		reason, err := s.consentService.EmailBlockReason(ctx.Sequence.CompanyId, &leadID, to)
+		if err != nil {
+			return "", nil, err
+		}
+		if reason != "" {
+			return "skipped", map[string]interface{}{"reason": reason}, nil
+		}
+
+		templateID := settings.TemplateID
+		var variantID *int
+		test, err := s.abTestRepo.GetTestByStep(ctx.Step.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		if test != nil {
+			group, variant := AssignABTestVariant(test, leadID)
+			if variant == nil {
+				return "skipped", map[string]interface{}{"reason": "A/B test " + group, "ab_test_id": test.ID}, nil
+			}
+			if test.TestStartedAt == nil {
+				if err := s.abTestRepo.MarkTestStarted(test.ID, ctx.Now); err != nil {
+					return "", nil, err
+				}
+			}
+			templateID = variant.TemplateID
+			variantID = &variant.ID
+		}
+
+		template, err := s.nurtureRepo.GetTemplateByID(templateID)
+		if err != nil {
+			return "", nil, err
+		}
+		if template == nil || template.CompanyId != ctx.Sequence.CompanyId {
+			return "", nil, fmt.Errorf("email template %d not found", templateID)
+		}
+
+		rendered, err := s.templateService.RenderForLead(template, ctx.Enrollment.LeadID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		enrollmentID := ctx.Enrollment.ID
+		stepID := ctx.Step.ID
+		message := &models.EmailMessage{
+			CompanyId:        ctx.Sequence.CompanyId,
+			LeadID:           &leadID,
+			EnrollmentID:     &enrollmentID,
+			StepID:           &stepID,
+			TemplateID:       &template.ID,
+			VariantID:        variantID,
+			SenderIdentityID: settings.SenderIdentityID,
+			ToEmail:          to,
+			Subject:          rendered.Subject,
+			HTMLBody:         rendered.HTML,
+			TextBody:         rendered.Text,
+		}
+		if err := s.Queue(message); err != nil {
+			return "", nil, err
+		}
+
+		details := map[string]interface{}{
+			"message_id":  message.ID,
+			"template_id": template.ID,
+			"to":          to,
+		}
+		if variantID != nil {
+			details["variant_id"] = *variantID
+		}
+		return "email_queued", details, nil
+	}
+}
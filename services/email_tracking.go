@@ -0,0 +1,210 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scannerClickWindow is how soon after sending a click is assumed to come from a link scanner
+const scannerClickWindow = 5 * time.Second
+
+var (
+	anchorHrefPattern = regexp.MustCompile(`(?i)<a\s[^>]*?href\s*=\s*("[^"]*"|'[^']*')[^>]*>`)
+	bodyClosePattern  = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// botUserAgentMarkers identify crawlers, link scanners and mail prefetchers by user agent
+var botUserAgentMarkers = []string{
+	"bot", "spider", "crawl", "slurp", "preview", "prefetch", "facebookexternalhit",
+	"linkexpander", "barracuda", "mimecast", "proofpoint", "urldefense", "safelinks",
+	"python-requests", "curl/", "wget/", "go-http-client", "headlesschrome",
+}
+
+// EmailTracker signs the open pixel and click redirect links in outgoing email
+type EmailTracker struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewEmailTrackerFromEnv returns a tracker for TRACKING_BASE_URL signed with TRACKING_SECRET,
+// or nil when tracking is not configured. Without it email goes out without open and click
+// tracking.
+func NewEmailTrackerFromEnv() *EmailTracker {
+	baseURL := strings.TrimRight(os.Getenv("TRACKING_BASE_URL"), "/")
+	secret := os.Getenv("TRACKING_SECRET")
+	if baseURL == "" || secret == "" {
+		return nil
+	}
+	return &EmailTracker{baseURL: baseURL, secret: []byte(secret)}
+}
+
+// UnsubscribeLinks signs the unsubscribe and preference center links in marketing email
+type UnsubscribeLinks struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewUnsubscribeLinksFromEnv returns unsubscribe links for UNSUBSCRIBE_BASE_URL signed with
+// UNSUBSCRIBE_SECRET, each falling back to its TRACKING_ counterpart, or nil when neither is
+// configured. Marketing email is not sent without them.
+func NewUnsubscribeLinksFromEnv() *UnsubscribeLinks {
+	baseURL := strings.TrimRight(envOrFallback("UNSUBSCRIBE_BASE_URL", "TRACKING_BASE_URL"), "/")
+	secret := envOrFallback("UNSUBSCRIBE_SECRET", "TRACKING_SECRET")
+	if baseURL == "" || secret == "" {
+		return nil
+	}
+	return &UnsubscribeLinks{baseURL: baseURL, secret: []byte(secret)}
+}
+
+// envOrFallback returns an environment variable, or another one when it is not set
+func envOrFallback(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return os.Getenv(fallback)
+}
+
+// Instrument returns the HTML with tracked links and an open pixel for the message.
+// Links with a data-notrack attribute, mailto:, tel: and in-page anchors are left unchanged.
+func (t *EmailTracker) Instrument(messageID int, body string) string {
+	body = anchorHrefPattern.ReplaceAllStringFunc(body, func(tag string) string {
+		if strings.Contains(strings.ToLower(tag), "data-notrack") {
+			return tag
+		}
+		match := anchorHrefPattern.FindStringSubmatch(tag)
+		quoted := match[1]
+		target := html.UnescapeString(quoted[1 : len(quoted)-1])
+
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return tag
+		}
+
+		tracked := html.EscapeString(t.ClickURL(messageID, target))
+		return strings.Replace(tag, quoted, `"`+tracked+`"`, 1)
+	})
+
+	pixel := fmt.Sprintf(`<img src="%s" width="1" height="1" alt="" style="display:none;border:0" />`, html.EscapeString(t.OpenURL(messageID)))
+	if loc := bodyClosePattern.FindStringIndex(body); loc != nil {
+		return body[:loc[0]] + pixel + body[loc[0]:]
+	}
+	return body + pixel
+}
+
+// OpenURL returns the tracking pixel URL for a message
+func (t *EmailTracker) OpenURL(messageID int) string {
+	return fmt.Sprintf("%s/api/crm/track/open/%d.%s.gif", t.baseURL, messageID, signLink(t.secret, "open", messageID, ""))
+}
+
+// ClickURL returns the signed redirect URL for a link in a message
+func (t *EmailTracker) ClickURL(messageID int, target string) string {
+	return fmt.Sprintf("%s/api/crm/track/click/%d.%s?u=%s", t.baseURL, messageID, signLink(t.secret, "click", messageID, target), url.QueryEscape(target))
+}
+
+// VerifyOpen returns the message ID of a valid open token
+func (t *EmailTracker) VerifyOpen(token string) (int, bool) {
+	return t.verify("open", strings.TrimSuffix(token, ".gif"), "")
+}
+
+// VerifyClick returns the message ID of a valid click token for the target URL
+func (t *EmailTracker) VerifyClick(token string, target string) (int, bool) {
+	return t.verify("click", token, target)
+}
+
+// PreferencesToken returns the token identifying a recipient to the preference center
+func (u *UnsubscribeLinks) PreferencesToken(companyId int, email string) string {
+	encodedEmail := base64.RawURLEncoding.EncodeToString([]byte(email))
+	return fmt.Sprintf("%d.%s.%s", companyId, encodedEmail, signLink(u.secret, "preferences", companyId, email))
+}
+
+// PreferencesURL returns the preference center URL for a recipient
+func (u *UnsubscribeLinks) PreferencesURL(companyId int, email string) string {
+	return fmt.Sprintf("%s/api/crm/preferences/%s", u.baseURL, u.PreferencesToken(companyId, email))
+}
+
+// UnsubscribeURL returns the one-click unsubscribe URL for a recipient
+func (u *UnsubscribeLinks) UnsubscribeURL(companyId int, email string) string {
+	return u.PreferencesURL(companyId, email) + "/unsubscribe"
+}
+
+// VerifyPreferences returns the company and email address of a valid preference center token
+func (u *UnsubscribeLinks) VerifyPreferences(token string) (int, string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	companyId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false
+	}
+	email, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, "", false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signLink(u.secret, "preferences", companyId, string(email)))) {
+		return 0, "", false
+	}
+	return companyId, string(email), true
+}
+
+func (t *EmailTracker) verify(kind string, token string, target string) (int, bool) {
+	idPart, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, false
+	}
+	messageID, err := strconv.Atoi(idPart)
+	if err != nil {
+		return 0, false
+	}
+	if !hmac.Equal([]byte(signature), []byte(signLink(t.secret, kind, messageID, target))) {
+		return 0, false
+	}
+	return messageID, true
+}
+
+// signLink returns the signature of a link of a kind for an ID and target
+func signLink(secret []byte, kind string, id int, target string) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s:%d:%s", kind, id, target)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IsLikelyBot reports whether a tracking request looks automated: a known crawler or scanner
+// user agent, a prefetch request, or a click arriving implausibly soon after the email was sent
+func IsLikelyBot(r *http.Request, sentAt *time.Time, now time.Time, isClick bool) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	if userAgent == "" {
+		return true
+	}
+	for _, marker := range botUserAgentMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+
+	for _, header := range []string{"Purpose", "X-Purpose", "Sec-Purpose", "X-Moz"} {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+
+	if isClick && sentAt != nil && now.Sub(*sentAt) < scannerClickWindow {
+		return true
+	}
+	return false
+}
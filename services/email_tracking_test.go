@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeLinksVerifyPreferences(t *testing.T) {
+	links := &UnsubscribeLinks{baseURL: "https://crm.example.com", secret: []byte("unsubscribe-secret")}
+	other := &UnsubscribeLinks{baseURL: "https://crm.example.com", secret: []byte("another-secret")}
+	valid := links.PreferencesToken(7, "ada@example.com")
+	parts := strings.Split(valid, ".")
+	tampered := []byte(parts[2])
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name        string
+		token       string
+		wantCompany int
+		wantEmail   string
+		wantOK      bool
+	}{
+		{"valid", valid, 7, "ada@example.com", true},
+		{"other company", strings.Join([]string{"8", parts[1], parts[2]}, "."), 0, "", false},
+		{"other email", strings.Join([]string{parts[0], base64.RawURLEncoding.EncodeToString([]byte("bob@example.com")), parts[2]}, "."), 0, "", false},
+		{"signed with another secret", other.PreferencesToken(7, "ada@example.com"), 0, "", false},
+		{"tampered signature", strings.Join([]string{parts[0], parts[1], string(tampered)}, "."), 0, "", false},
+		{"missing part", parts[0] + "." + parts[1], 0, "", false},
+		{"non-numeric company", "x." + parts[1] + "." + parts[2], 0, "", false},
+		{"invalid email encoding", parts[0] + ".!!." + parts[2], 0, "", false},
+		{"empty", "", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			companyId, email, ok := links.VerifyPreferences(tt.token)
+			if companyId != tt.wantCompany || email != tt.wantEmail || ok != tt.wantOK {
+				t.Errorf("VerifyPreferences(%q) = (%d, %q, %v), want (%d, %q, %v)",
+					tt.token, companyId, email, ok, tt.wantCompany, tt.wantEmail, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEmailTrackerVerify(t *testing.T) {
+	tracker := &EmailTracker{baseURL: "https://crm.example.com", secret: []byte("tracking-secret")}
+	openToken := "12." + signLink(tracker.secret, "open", 12, "") + ".gif"
+	clickToken := "12." + signLink(tracker.secret, "click", 12, "https://example.com/a")
+
+	tests := []struct {
+		name   string
+		verify func() (int, bool)
+		wantID int
+		wantOK bool
+	}{
+		{"open", func() (int, bool) { return tracker.VerifyOpen(openToken) }, 12, true},
+		{"open without gif suffix", func() (int, bool) { return tracker.VerifyOpen(strings.TrimSuffix(openToken, ".gif")) }, 12, true},
+		{"open token used as click", func() (int, bool) { return tracker.VerifyClick(strings.TrimSuffix(openToken, ".gif"), "") }, 0, false},
+		{"click", func() (int, bool) { return tracker.VerifyClick(clickToken, "https://example.com/a") }, 12, true},
+		{"click for another target", func() (int, bool) { return tracker.VerifyClick(clickToken, "https://example.com/b") }, 0, false},
+		{"click for another message", func() (int, bool) { return tracker.VerifyClick("13"+clickToken[2:], "https://example.com/a") }, 0, false},
+		{"click without signature", func() (int, bool) { return tracker.VerifyClick("12", "https://example.com/a") }, 0, false},
+		{"non-numeric message", func() (int, bool) { return tracker.VerifyClick("x"+clickToken[2:], "https://example.com/a") }, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := tt.verify()
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("got (%d, %v), want (%d, %v)", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSignLinkSeparatesSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"kind", signLink([]byte("s"), "open", 1, ""), signLink([]byte("s"), "click", 1, "")},
+		{"id", signLink([]byte("s"), "open", 1, ""), signLink([]byte("s"), "open", 2, "")},
+		{"target", signLink([]byte("s"), "click", 1, "a"), signLink([]byte("s"), "click", 1, "b")},
+		{"secret", signLink([]byte("tracking"), "preferences", 1, "a@b.c"), signLink([]byte("unsubscribe"), "preferences", 1, "a@b.c")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a == tt.b {
+				t.Errorf("signatures differing only by %s are equal: %q", tt.name, tt.a)
+			}
+		})
+	}
+}
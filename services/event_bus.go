@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	eventLeaseDuration = time.Minute
+	eventBatchSize     = 100
+	eventBaseBackoff   = 5 * time.Second
+	eventMaxBackoff    = time.Hour
+	eventPurgeInterval = time.Hour
+	eventPurgeBatch    = 1000
+)
+
+// DefaultEventRetention is how long dispatched events stay in the outbox before they are deleted
+const DefaultEventRetention = 7 * 24 * time.Hour
+
+// EventHandler handles a domain event. When it returns an error the event is dispatched to it
+// again later, and a crash can redeliver an event it has handled, so handlers must tolerate
+// seeing an event more than once; the event's EventID identifies it across deliveries.
+type EventHandler func(event *models.OutboxEvent) error
+
+// eventSubscriber is a handler registered with the event bus under a name
+type eventSubscriber struct {
+	name    string
+	types   map[string]bool // nil for every type
+	handler EventHandler
+}
+
+// EventBus dispatches the domain events written to the outbox to the subscribers registered in
+// this process, retrying each subscriber with exponential backoff until it has handled the event.
+// An event still not handled after its maximum attempts is marked failed until it is replayed.
+type EventBus struct {
+	outboxRepo  models.OutboxRepository
+	mu          sync.RWMutex
+	subscribers []eventSubscriber
+	workerID    string
+	lastPurge   time.Time
+}
+
+// NewEventBus creates a new event bus with no subscribers
+func NewEventBus(repos *models.CRMRepositories) *EventBus {
+	hostname, _ := os.Hostname()
+	return &EventBus{
+		outboxRepo: repos.OutboxRepo,
+		workerID:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Subscribe registers a handler for events of the given types, or of every type if none are
+// given. The name identifies the subscriber in the outbox, recording which subscribers have
+// handled each event, so it must be unique and stay the same across restarts.
+func (b *EventBus) Subscribe(name string, handler EventHandler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscriber := range b.subscribers {
+		if subscriber.name == name {
+			panic(fmt.Sprintf("event subscriber %q registered twice", name))
+		}
+	}
+	subscriber := eventSubscriber{name: name, handler: handler}
+	if len(eventTypes) > 0 {
+		subscriber.types = make(map[string]bool, len(eventTypes))
+		for _, eventType := range eventTypes {
+			subscriber.types[eventType] = true
+		}
+	}
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Start runs the dispatch loop until the context is cancelled
+func (b *EventBus) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Event bus started (worker %s, interval %s)", b.workerID, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if dispatched, err := b.RunOnce(time.Now()); err != nil {
+			log.Printf("Event dispatch run failed: %v", err)
+		} else if dispatched == eventBatchSize && ctx.Err() == nil {
+			// There may be more waiting; carry on without waiting for the next tick
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and dispatches the outbox events that are due at the given time, and deletes
+// old dispatched events once an hour
+func (b *EventBus) RunOnce(now time.Time) (int, error) {
+	if now.Sub(b.lastPurge) >= eventPurgeInterval {
+		b.lastPurge = now
+		if purged, err := b.outboxRepo.PurgeDispatchedEvents(now.Add(-DefaultEventRetention), eventPurgeBatch); err != nil {
+			log.Printf("Failed to purge dispatched events: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d dispatched events", purged)
+		}
+	}
+
+	events, err := b.outboxRepo.ClaimDueEvents(b.workerID, now, now.Add(eventLeaseDuration), eventBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+
+	for i := range events {
+		event := &events[i]
+		b.dispatch(event, now)
+		if err := b.outboxRepo.ReleaseEvent(event, b.workerID); err != nil {
+			log.Printf("Event %s could not be released: %v", event.EventID, err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// dispatch delivers an event to each subscriber to its type that has not handled it yet. The
+// event is dispatched once all have; otherwise it is retried with exponential backoff, or fails
+// once it has run out of attempts.
+func (b *EventBus) dispatch(event *models.OutboxEvent, now time.Time) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	handled := make(map[string]bool, len(event.HandledBy))
+	for _, name := range event.HandledBy {
+		handled[name] = true
+	}
+
+	event.Attempts++
+	var failures []string
+	for _, subscriber := range subscribers {
+		if handled[subscriber.name] || (subscriber.types != nil && !subscriber.types[event.Type]) {
+			continue
+		}
+		if err := callEventHandler(subscriber.handler, event); err != nil {
+			log.Printf("Event subscriber %s failed on %s %s: %v", subscriber.name, event.Type, event.EventID, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", subscriber.name, err))
+			continue
+		}
+		event.HandledBy = append(event.HandledBy, subscriber.name)
+	}
+
+	if len(failures) == 0 {
+		event.Status = models.OutboxEventDispatched
+		event.DispatchedAt = &now
+		event.NextAttemptAt = nil
+		event.LastError = ""
+		return
+	}
+	event.LastError = fmt.Sprint(failures)
+	if event.Attempts >= event.MaxAttempts {
+		event.Status = models.OutboxEventFailed
+		event.NextAttemptAt = nil
+		log.Printf("Event %s %s failed after %d attempts", event.Type, event.EventID, event.Attempts)
+		return
+	}
+	next := now.Add(eventBackoff(event.Attempts))
+	event.NextAttemptAt = &next
+}
+
+// callEventHandler calls a handler, turning a panic into an error so one bad subscriber cannot
+// stop the dispatch loop
+func callEventHandler(handler EventHandler, event *models.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(event)
+}
+
+// eventBackoff returns the delay before the next attempt, doubling per attempt up to eventMaxBackoff
+func eventBackoff(attempts int) time.Duration {
+	delay := eventBaseBackoff
+	for i := 1; i < attempts && delay < eventMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > eventMaxBackoff {
+		delay = eventMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"errors"
+	"testing"
+)
+
+func TestEventBackoff(t *testing.T) {
+	if got := eventBackoff(0); got != eventBaseBackoff {
+		t.Errorf("eventBackoff(0) = %v, want %v", got, eventBaseBackoff)
+	}
+
+	// Each attempt doubles the delay of the previous one until it reaches the cap
+	previous := eventBackoff(1)
+	if previous != eventBaseBackoff {
+		t.Errorf("eventBackoff(1) = %v, want %v", previous, eventBaseBackoff)
+	}
+	for attempts := 2; attempts <= 50; attempts++ {
+		want := previous * 2
+		if want > eventMaxBackoff {
+			want = eventMaxBackoff
+		}
+		got := eventBackoff(attempts)
+		if got != want {
+			t.Errorf("eventBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+		previous = got
+	}
+	if previous != eventMaxBackoff {
+		t.Errorf("eventBackoff(50) = %v, want the %v cap", previous, eventMaxBackoff)
+	}
+}
+
+func TestCallEventHandler(t *testing.T) {
+	failure := errors.New("handler failed")
+
+	tests := []struct {
+		name    string
+		handler EventHandler
+		wantErr bool
+	}{
+		{"success", func(event *models.OutboxEvent) error { return nil }, false},
+		{"error", func(event *models.OutboxEvent) error { return failure }, true},
+		{"panic", func(event *models.OutboxEvent) error { panic("boom") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := callEventHandler(tt.handler, &models.OutboxEvent{ID: 1})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("callEventHandler error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
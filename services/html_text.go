@@ -0,0 +1,111 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	textSpaceRun   = regexp.MustCompile(`[ \t]+`)
+	textNewlineRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// blockTextElements start on a new line in the plain text version of an email
+var blockTextElements = map[string]bool{
+	"p": true, "div": true, "table": true, "tr": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "section": true, "header": true, "footer": true, "hr": true,
+}
+
+// HTMLToText converts an HTML email body into a readable plain text alternative.
+// Links keep their target in parentheses and list items are prefixed with a dash.
+func HTMLToText(body string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+
+	var sb strings.Builder
+	skipDepth := 0
+	var linkHref, linkText string
+	inLink := false
+
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			return tidyText(sb.String())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "script", "style", "head", "title":
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+			case "br":
+				sb.WriteString("\n")
+			case "li":
+				sb.WriteString("\n- ")
+			case "td", "th":
+				sb.WriteString(" ")
+			case "a":
+				inLink = true
+				linkText = ""
+				linkHref = ""
+				for _, attr := range token.Attr {
+					if attr.Key == "href" {
+						linkHref = attr.Val
+					}
+				}
+			default:
+				if blockTextElements[token.Data] {
+					sb.WriteString("\n\n")
+				}
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "script", "style", "head", "title":
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			case "a":
+				if inLink {
+					text := strings.TrimSpace(linkText)
+					sb.WriteString(linkText)
+					if linkHref != "" && linkHref != text && !strings.HasPrefix(linkHref, "#") && !strings.HasPrefix(linkHref, "mailto:") {
+						sb.WriteString(" (" + linkHref + ")")
+					}
+					inLink = false
+				}
+			default:
+				if blockTextElements[token.Data] {
+					sb.WriteString("\n\n")
+				}
+			}
+
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			text := strings.ReplaceAll(string(tokenizer.Text()), "\n", " ")
+			if inLink {
+				linkText += text
+			} else {
+				sb.WriteString(text)
+			}
+		}
+	}
+}
+
+// tidyText collapses runs of whitespace and blank lines left by the conversion
+func tidyText(text string) string {
+	lines := strings.Split(textSpaceRun.ReplaceAllString(text, " "), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+	text = textNewlineRun.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
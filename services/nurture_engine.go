@@ -0,0 +1,460 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	nurtureLeaseDuration = 5 * time.Minute
+	nurtureBatchSize     = 100
+	nurtureRetryDelay    = 15 * time.Minute
+	nurtureMaxAttempts   = 5
+	nurtureRecheckDelay  = time.Hour
+)
+
+// NurtureStepContext carries everything an executor needs to run a step
+type NurtureStepContext struct {
+	Enrollment *models.NurtureEnrollment
+	Sequence   *models.NurtureSequence
+	Step       *models.NurtureStep
+	Lead       map[string]string
+	Now        time.Time
+}
+
+// NurtureStepExecutor runs a step and returns the activity type and details to record
+type NurtureStepExecutor func(ctx *NurtureStepContext) (string, map[string]interface{}, error)
+
+// NurtureEngine advances nurture enrollments through their sequence steps
+type NurtureEngine struct {
+	nurtureRepo models.NurtureRepository
+	leadRepo    models.LeadRepository
+	executors   map[string]NurtureStepExecutor
+	workerID    string
+}
+
+// NewNurtureEngine creates a nurture engine with the built-in step executors
+func NewNurtureEngine(repos *models.CRMRepositories) *NurtureEngine {
+	hostname, _ := os.Hostname()
+	e := &NurtureEngine{
+		nurtureRepo: repos.NurtureRepo,
+		leadRepo:    repos.LeadRepo,
+		executors:   make(map[string]NurtureStepExecutor),
+		workerID:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+
+	e.RegisterExecutor("email", e.executeEmail)
+	e.RegisterExecutor("task", e.executeTask)
+	e.RegisterExecutor("notification", e.executeNotification)
+	e.RegisterExecutor("field_update", e.executeFieldUpdate)
+	e.RegisterExecutor("wait", e.executeWait)
+
+	return e
+}
+
+// RegisterExecutor sets the executor used for a step type, replacing any existing one
+func (e *NurtureEngine) RegisterExecutor(stepType string, executor NurtureStepExecutor) {
+	e.executors[stepType] = executor
+}
+
+// Start runs the scheduler loop until the context is cancelled
+func (e *NurtureEngine) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Nurture scheduler started (worker %s, interval %s)", e.workerID, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if processed, err := e.RunOnce(time.Now()); err != nil {
+			log.Printf("Nurture scheduler run failed: %v", err)
+		} else if processed > 0 {
+			log.Printf("Nurture scheduler processed %d enrollments", processed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and processes all enrollments that are due at the given time
+func (e *NurtureEngine) RunOnce(now time.Time) (int, error) {
+	enrollments, err := e.nurtureRepo.ClaimDueEnrollments(e.workerID, now, now.Add(nurtureLeaseDuration), nurtureBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim enrollments: %w", err)
+	}
+
+	for i := range enrollments {
+		enrollment := &enrollments[i]
+		if err := e.processEnrollment(enrollment, now); err != nil {
+			log.Printf("Nurture enrollment %d failed: %v", enrollment.ID, err)
+		}
+		if err := e.nurtureRepo.ReleaseEnrollment(enrollment, e.workerID); err != nil {
+			log.Printf("Nurture enrollment %d could not be released: %v", enrollment.ID, err)
+		}
+	}
+
+	return len(enrollments), nil
+}
+
+// processEnrollment runs the enrollment's current step if its delay has elapsed
+func (e *NurtureEngine) processEnrollment(enrollment *models.NurtureEnrollment, now time.Time) error {
+	sequence, err := e.nurtureRepo.GetSequenceByID(enrollment.SequenceID)
+	if err != nil {
+		return err
+	}
+	if sequence == nil {
+		e.finish(enrollment, "exited", 0, map[string]interface{}{"reason": "sequence deleted"}, now)
+		return nil
+	}
+	if !sequence.IsActive {
+		next := now.Add(nurtureRecheckDelay)
+		enrollment.NextRunAt = &next
+		return nil
+	}
+
+	steps, err := e.nurtureRepo.GetStepsIncludingDeleted(sequence.ID)
+	if err != nil {
+		return err
+	}
+	index := nextNurtureStep(steps, currentNurtureStep(steps, enrollment.CurrentStepID))
+	if index < 0 {
+		e.finish(enrollment, "completed", 0, nil, now)
+		return nil
+	}
+	// The current step moves on when it has been deactivated or deleted
+	step := &steps[index]
+	enrollment.CurrentStepID = &step.ID
+
+	// Wait until the step's delay has elapsed since the previous step
+	due := e.stepDueAt(enrollment, step)
+	if now.Before(due) {
+		enrollment.NextRunAt = &due
+		return nil
+	}
+
+	lead, err := e.leadRepo.GetFieldValues(enrollment.LeadID)
+	if err != nil {
+		return err
+	}
+	if lead == nil {
+		e.finish(enrollment, "exited", step.ID, map[string]interface{}{"reason": "lead deleted"}, now)
+		return nil
+	}
+
+	conditions, err := parseNurtureConditions(step.Conditions)
+	if err != nil {
+		e.record(enrollment.ID, step.ID, "failed", map[string]interface{}{"error": err.Error()})
+		e.advance(enrollment, steps, index, now)
+		return nil
+	}
+
+	if conditions != nil {
+		activities, err := e.nurtureRepo.GetEnrollmentActivity(enrollment.ID)
+		if err != nil {
+			return err
+		}
+		if !evaluateNurtureConditions(conditions, lead, activities) {
+			switch conditions.OnFail {
+			case "exit":
+				e.finish(enrollment, "exited", step.ID, map[string]interface{}{"reason": "conditions not met"}, now)
+			case "wait":
+				next := now.Add(nurtureRecheckDelay)
+				enrollment.NextRunAt = &next
+			default:
+				e.record(enrollment.ID, step.ID, "skipped", map[string]interface{}{"reason": "conditions not met"})
+				e.advance(enrollment, steps, index, now)
+			}
+			return nil
+		}
+	}
+
+	executor, ok := e.executors[step.Type]
+	if !ok {
+		e.record(enrollment.ID, step.ID, "failed", map[string]interface{}{"error": "unsupported step type: " + step.Type})
+		e.advance(enrollment, steps, index, now)
+		return nil
+	}
+
+	activityType, details, err := executor(&NurtureStepContext{
+		Enrollment: enrollment,
+		Sequence:   sequence,
+		Step:       step,
+		Lead:       lead,
+		Now:        now,
+	})
+	if err != nil {
+		// Leave the step in place and retry after a delay, skipping it once it keeps failing
+		enrollment.StepAttempts++
+		e.record(enrollment.ID, step.ID, "failed", map[string]interface{}{
+			"error":    err.Error(),
+			"attempts": enrollment.StepAttempts,
+		})
+		if enrollment.StepAttempts >= nurtureMaxAttempts {
+			e.record(enrollment.ID, step.ID, "skipped", map[string]interface{}{"reason": "too many failed attempts"})
+			e.advance(enrollment, steps, index, now)
+			return err
+		}
+		next := now.Add(nurtureRetryDelay)
+		enrollment.NextRunAt = &next
+		return err
+	}
+
+	e.record(enrollment.ID, step.ID, activityType, details)
+	e.advance(enrollment, steps, index, now)
+	return nil
+}
+
+// currentNurtureStep returns the position of an enrollment's current step among a sequence's
+// steps: the first one when it has not started, and -1 when the step no longer exists
+func currentNurtureStep(steps []models.NurtureStep, stepID *int) int {
+	if stepID == nil {
+		return 0
+	}
+	for i := range steps {
+		if steps[i].ID == *stepID {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextNurtureStep returns the position of the first active step at or after a position, or -1
+// when there is none
+func nextNurtureStep(steps []models.NurtureStep, from int) int {
+	if from < 0 {
+		return -1
+	}
+	for i := from; i < len(steps); i++ {
+		if steps[i].IsActive && !steps[i].DeletedAt.Valid {
+			return i
+		}
+	}
+	return -1
+}
+
+// stepDueAt returns when a step becomes due, counting its delay from the previous step
+func (e *NurtureEngine) stepDueAt(enrollment *models.NurtureEnrollment, step *models.NurtureStep) time.Time {
+	base := enrollment.StartedAt
+	if enrollment.LastStepAt != nil {
+		base = *enrollment.LastStepAt
+	}
+	return base.Add(time.Duration(step.Delay) * time.Hour)
+}
+
+// advance moves the enrollment past the step at a position to the next active step, completing
+// it after the last one
+func (e *NurtureEngine) advance(enrollment *models.NurtureEnrollment, steps []models.NurtureStep, index int, now time.Time) {
+	enrollment.LastStepAt = &now
+	enrollment.StepAttempts = 0
+
+	next := nextNurtureStep(steps, index+1)
+	if next < 0 {
+		e.finish(enrollment, "completed", 0, nil, now)
+		return
+	}
+
+	enrollment.CurrentStepID = &steps[next].ID
+	due := e.stepDueAt(enrollment, &steps[next])
+	enrollment.NextRunAt = &due
+}
+
+// finish ends the enrollment with the given status
+func (e *NurtureEngine) finish(enrollment *models.NurtureEnrollment, status string, stepID int, details map[string]interface{}, now time.Time) {
+	enrollment.Status = status
+	enrollment.CompletedAt = &now
+	enrollment.NextRunAt = nil
+	e.record(enrollment.ID, stepID, status, details)
+}
+
+// record stores a nurture activity, logging rather than failing on errors
+func (e *NurtureEngine) record(enrollmentID int, stepID int, activityType string, details map[string]interface{}) {
+	activity := &models.NurtureActivity{
+		EnrollmentID: enrollmentID,
+		StepID:       stepID,
+		Type:         activityType,
+	}
+	if details != nil {
+		if encoded, err := json.Marshal(details); err == nil {
+			activity.Details = string(encoded)
+		}
+	}
+	if err := e.nurtureRepo.RecordActivity(activity); err != nil {
+		log.Printf("Failed to record nurture activity for enrollment %d: %v", enrollmentID, err)
+	}
+}
+
+// Built-in step executors
+
+func (e *NurtureEngine) executeEmail(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+	// Delivery requires an email provider; until one is registered the email is only recorded
+	return "email_pending", map[string]interface{}{
+		"to":     ctx.Lead["email"],
+		"reason": "no email provider configured",
+	}, nil
+}
+
+func (e *NurtureEngine) executeTask(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+	var task struct {
+		Title      string `json:"title"`
+		DueInHours int    `json:"due_in_hours"`
+		AssignedTo *int   `json:"assigned_to"`
+	}
+	if err := decodeStepContent(ctx.Step.Content, &task); err != nil {
+		return "", nil, err
+	}
+	if task.Title == "" {
+		task.Title = ctx.Step.Name
+	}
+	if task.AssignedTo == nil {
+		if assignee, err := strconv.Atoi(ctx.Lead["assigned_to_id"]); err == nil {
+			task.AssignedTo = &assignee
+		}
+	}
+
+	return "task_created", map[string]interface{}{
+		"title":       task.Title,
+		"assigned_to": task.AssignedTo,
+		"due_at":      ctx.Now.Add(time.Duration(task.DueInHours) * time.Hour),
+	}, nil
+}
+
+func (e *NurtureEngine) executeNotification(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+	log.Printf("Nurture notification for lead %d: %s", ctx.Enrollment.LeadID, ctx.Step.Content)
+	return "notification_sent", map[string]interface{}{
+		"message":     ctx.Step.Content,
+		"assigned_to": ctx.Lead["assigned_to_id"],
+	}, nil
+}
+
+func (e *NurtureEngine) executeFieldUpdate(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+	var update struct {
+		Field string `json:"field"`
+		Value string `json:"value"`
+	}
+	if err := decodeStepContent(ctx.Step.Content, &update); err != nil {
+		return "", nil, err
+	}
+	if update.Field == "" {
+		return "", nil, fmt.Errorf("field update step has no field")
+	}
+
+	if err := e.leadRepo.SetFieldValue(ctx.Enrollment.LeadID, update.Field, update.Value); err != nil {
+		return "", nil, err
+	}
+
+	return "field_updated", map[string]interface{}{
+		"field":     update.Field,
+		"old_value": ctx.Lead[update.Field],
+		"new_value": update.Value,
+	}, nil
+}
+
+func (e *NurtureEngine) executeWait(ctx *NurtureStepContext) (string, map[string]interface{}, error) {
+	return "waited", map[string]interface{}{"hours": ctx.Step.Delay}, nil
+}
+
+// decodeStepContent unmarshals a step's JSON content, treating empty content as no settings
+func decodeStepContent(content string, v interface{}) error {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("invalid step content: %w", err)
+	}
+	return nil
+}
+
+// parseNurtureConditions parses a step's Conditions JSON, returning nil when there are none
+func parseNurtureConditions(raw string) (*models.NurtureConditions, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var conditions models.NurtureConditions
+	if err := json.Unmarshal([]byte(raw), &conditions); err != nil {
+		return nil, fmt.Errorf("invalid step conditions: %w", err)
+	}
+	if len(conditions.Rules) == 0 {
+		return nil, nil
+	}
+	return &conditions, nil
+}
+
+// evaluateNurtureConditions checks the rules against lead fields and prior enrollment activity
+func evaluateNurtureConditions(conditions *models.NurtureConditions, lead map[string]string, activities []models.NurtureActivity) bool {
+	matchAny := conditions.Match == "any"
+
+	for _, rule := range conditions.Rules {
+		var ok bool
+		if rule.Activity != "" {
+			ok = evaluateActivityRule(rule, activities)
+		} else {
+			ok = evaluateFieldRule(rule, lead)
+		}
+
+		if matchAny && ok {
+			return true
+		}
+		if !matchAny && !ok {
+			return false
+		}
+	}
+
+	return !matchAny
+}
+
+func evaluateActivityRule(rule models.NurtureConditionRule, activities []models.NurtureActivity) bool {
+	count := 0
+	for _, activity := range activities {
+		if activity.Type != rule.Activity {
+			continue
+		}
+		if rule.StepID != nil && activity.StepID != *rule.StepID {
+			continue
+		}
+		count++
+	}
+
+	if rule.Operator == "not_exists" {
+		return count == 0
+	}
+	return count > 0
+}
+
+func evaluateFieldRule(rule models.NurtureConditionRule, lead map[string]string) bool {
+	value := lead[strings.TrimPrefix(rule.Field, "lead.")]
+
+	switch rule.Operator {
+	case "eq", "":
+		return strings.EqualFold(value, rule.Value)
+	case "neq":
+		return !strings.EqualFold(value, rule.Value)
+	case "contains":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(rule.Value))
+	case "exists":
+		return value != ""
+	case "not_exists":
+		return value == ""
+	case "gt", "lt":
+		left, err1 := strconv.ParseFloat(value, 64)
+		right, err2 := strconv.ParseFloat(rule.Value, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if rule.Operator == "gt" {
+			return left > right
+		}
+		return left < right
+	}
+
+	return false
+}
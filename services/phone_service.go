@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// phoneBackfillBatchSize is how many unnormalized phone numbers the backfill reads at a time
+const phoneBackfillBatchSize = 200
+
+// maxReportedInvalidPhones caps how many invalid numbers a backfill result lists
+const maxReportedInvalidPhones = 100
+
+// PhoneFieldType is the lead field type whose values are normalized as phone numbers
+const PhoneFieldType = "phone"
+
+// InvalidPhoneError reports a phone number that cannot be a real number
+type InvalidPhoneError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *InvalidPhoneError) Error() string {
+	return fmt.Sprintf("%s %q is not a valid phone number: %s", e.Field, e.Value, e.Reason)
+}
+
+// PhoneNumber is a phone number in E.164, e.g. +4930123456, with its forms for display, e.g.
+// 030 123456 and +49 30 123456
+type PhoneNumber struct {
+	E164          string
+	National      string
+	International string
+}
+
+// NormalizePhone parses a phone number as written, reading one without a country code as a
+// number of the default country, and checks it can be a real number
+func NormalizePhone(raw string, defaultCountry string) (*PhoneNumber, error) {
+	raw = strings.TrimSpace(raw)
+	number, err := phonenumbers.Parse(raw, strings.ToUpper(defaultCountry))
+	if err != nil {
+		reason := err.Error()
+		switch {
+		case errors.Is(err, phonenumbers.ErrInvalidCountryCode):
+			reason = "unknown country code"
+			if defaultCountry == "" && !strings.HasPrefix(raw, "+") {
+				reason = "no country code, and no default country is set"
+			}
+		case errors.Is(err, phonenumbers.ErrNotANumber):
+			reason = "not a number"
+		case errors.Is(err, phonenumbers.ErrTooShortNSN), errors.Is(err, phonenumbers.ErrTooShortAfterIDD):
+			reason = "too short"
+		case errors.Is(err, phonenumbers.ErrNumTooLong):
+			reason = "too long"
+		}
+		return nil, &InvalidPhoneError{Field: "phone", Value: raw, Reason: reason}
+	}
+
+	if !phonenumbers.IsValidNumber(number) {
+		reason := "no such number exists"
+		switch phonenumbers.IsPossibleNumberWithReason(number) {
+		case phonenumbers.TOO_SHORT:
+			reason = "too short"
+		case phonenumbers.TOO_LONG:
+			reason = "too long"
+		case phonenumbers.INVALID_COUNTRY_CODE:
+			reason = "unknown country code"
+		}
+		return nil, &InvalidPhoneError{Field: "phone", Value: raw, Reason: reason}
+	}
+
+	return &PhoneNumber{
+		E164:          phonenumbers.Format(number, phonenumbers.E164),
+		National:      phonenumbers.Format(number, phonenumbers.NATIONAL),
+		International: phonenumbers.Format(number, phonenumbers.INTERNATIONAL),
+	}, nil
+}
+
+// ValidPhoneCountry reports whether a region code, e.g. DE, can be used as a default country
+func ValidPhoneCountry(country string) bool {
+	return phonenumbers.GetSupportedRegions()[strings.ToUpper(country)]
+}
+
+// PhoneService normalizes contact and lead phone numbers to E.164 in each company's default
+// country, and backfills numbers stored before they were normalized
+type PhoneService struct {
+	phoneRepo           models.PhoneRepository
+	companyRepo         models.CompanyRepository
+	leadFieldConfigRepo models.LeadFieldConfigRepository
+}
+
+// NewPhoneService creates a new phone service
+func NewPhoneService(repos *models.CRMRepositories) *PhoneService {
+	return &PhoneService{
+		phoneRepo:           repos.PhoneRepo,
+		companyRepo:         repos.CompanyRepo,
+		leadFieldConfigRepo: repos.LeadFieldConfigRepo,
+	}
+}
+
+// NormalizeContact normalizes a contact's phone number, returning an *InvalidPhoneError if it
+// cannot be a real number
+func (s *PhoneService) NormalizeContact(contact *models.Contact) error {
+	number, err := s.newNormalizer().normalize(contact.Phone, "phone", contact.CompanyId)
+	if err != nil {
+		return err
+	}
+	contact.Phone, contact.PhoneNational, contact.PhoneInternational = number.E164, number.National, number.International
+	return nil
+}
+
+// NormalizeLead normalizes a lead's phone number, returning an *InvalidPhoneError if it cannot
+// be a real number
+func (s *PhoneService) NormalizeLead(lead *models.Lead) error {
+	number, err := s.newNormalizer().normalize(lead.Phone, "phone", lead.CompanyId)
+	if err != nil {
+		return err
+	}
+	lead.Phone, lead.PhoneNational, lead.PhoneInternational = number.E164, number.National, number.International
+	return nil
+}
+
+// NormalizeLeadFields normalizes the values of phone-type lead fields, returning an
+// *InvalidPhoneError naming the field if one cannot be a real number
+func (s *PhoneService) NormalizeLeadFields(records []models.CrmFieldData) error {
+	normalizer := s.newNormalizer()
+	for i := range records {
+		record := &records[i]
+		fieldName, err := normalizer.phoneField(record.CompanyId, record.CrmFieldId)
+		if err != nil {
+			return err
+		}
+		if fieldName == "" {
+			continue
+		}
+
+		number, err := normalizer.normalize(record.FieldValue, fieldName, record.CompanyId)
+		if err != nil {
+			return err
+		}
+		record.FieldValue, record.PhoneNational, record.PhoneInternational = number.E164, number.National, number.International
+	}
+	return nil
+}
+
+// Start runs the phone number backfill across every company now and then at each interval, so
+// numbers stored before normalization, or written by paths that do not normalize, catch up
+func (s *PhoneService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Phone number backfill started (interval %s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := s.Backfill(ctx, 0); err != nil {
+			log.Printf("Phone number backfill failed: %v", err)
+		} else if result.Normalized > 0 || result.InvalidCount > 0 {
+			log.Printf("Phone number backfill normalized %d numbers; %d are invalid", result.Normalized, result.InvalidCount)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Backfill normalizes a company's stored phone numbers that have not been normalized, or every
+// company's with a companyId of 0. Numbers that cannot be normalized are left as they are and
+// reported.
+func (s *PhoneService) Backfill(ctx context.Context, companyId int) (*models.PhoneBackfillResult, error) {
+	result := &models.PhoneBackfillResult{Invalid: []models.PhoneRecord{}}
+	normalizer := s.newNormalizer()
+
+	for _, recordType := range models.PhoneRecordTypes {
+		var after models.PhoneRecord
+		for {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			records, err := s.phoneRepo.GetUnnormalizedPhones(recordType, after, phoneBackfillBatchSize, companyId)
+			if err != nil {
+				return result, fmt.Errorf("failed to fetch %s phone numbers: %w", recordType, err)
+			}
+
+			for i := range records {
+				record := &records[i]
+				number, err := normalizer.normalize(record.Phone, "phone", record.CompanyId)
+				var invalid *InvalidPhoneError
+				if errors.As(err, &invalid) {
+					result.InvalidCount++
+					if len(result.Invalid) < maxReportedInvalidPhones {
+						record.Reason = invalid.Reason
+						result.Invalid = append(result.Invalid, *record)
+					}
+					continue
+				}
+				if err != nil {
+					return result, err
+				}
+
+				record.Phone, record.PhoneNational, record.PhoneInternational = number.E164, number.National, number.International
+				if err := s.phoneRepo.SaveNormalizedPhone(record); err != nil {
+					return result, fmt.Errorf("failed to save %s %d phone number: %w", recordType, record.RecordID, err)
+				}
+				result.Normalized++
+			}
+
+			if len(records) < phoneBackfillBatchSize {
+				break
+			}
+			after = records[len(records)-1]
+		}
+	}
+	return result, nil
+}
+
+// phoneNormalizer normalizes the phone numbers of one request or backfill run, looking up each
+// company's default country and phone fields once
+type phoneNormalizer struct {
+	service     *PhoneService
+	countries   map[int]string
+	phoneFields map[int]map[int]string
+}
+
+func (s *PhoneService) newNormalizer() *phoneNormalizer {
+	return &phoneNormalizer{
+		service:     s,
+		countries:   make(map[int]string),
+		phoneFields: make(map[int]map[int]string),
+	}
+}
+
+// normalize normalizes a company's phone number, leaving an empty one empty
+func (n *phoneNormalizer) normalize(raw string, field string, companyId int) (*PhoneNumber, error) {
+	if strings.TrimSpace(raw) == "" {
+		return &PhoneNumber{}, nil
+	}
+
+	country, ok := n.countries[companyId]
+	if !ok {
+		settings, err := n.service.companyRepo.GetSettings(companyId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch company settings: %w", err)
+		}
+		if settings != nil {
+			country = settings.DefaultCountry
+		}
+		n.countries[companyId] = country
+	}
+
+	number, err := NormalizePhone(raw, country)
+	var invalid *InvalidPhoneError
+	if errors.As(err, &invalid) {
+		invalid.Field = field
+	}
+	return number, err
+}
+
+// phoneField returns the name of a company's lead field if it is a phone field, or ""
+func (n *phoneNormalizer) phoneField(companyId int, fieldID int) (string, error) {
+	fields, ok := n.phoneFields[companyId]
+	if !ok {
+		configs, err := n.service.leadFieldConfigRepo.GetAllFieldConfigs(companyId)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch lead fields: %w", err)
+		}
+		fields = make(map[int]string)
+		for _, config := range configs {
+			if config.FieldType == PhoneFieldType {
+				fields[int(config.ID)] = config.FieldName
+			}
+		}
+		n.phoneFields[companyId] = fields
+	}
+	return fields[fieldID], nil
+}
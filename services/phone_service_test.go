@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		defaultCountry string
+		wantE164       string
+		wantNational   string
+		wantReason     string
+	}{
+		{"international format", "+49 30 1234567", "", "+49301234567", "030 1234567", ""},
+		{"national number in the default country", "030 1234567", "DE", "+49301234567", "030 1234567", ""},
+		{"lowercase default country", "(202) 555-0143", "us", "+12025550143", "(202) 555-0143", ""},
+		{"country code overrides the default", "+44 20 7946 0958", "US", "+442079460958", "020 7946 0958", ""},
+		{"surrounding whitespace", "  +1 202-555-0143 ", "", "+12025550143", "(202) 555-0143", ""},
+		{"no country code and no default", "030 1234567", "", "", "", "no country code, and no default country is set"},
+		{"unknown country code", "+999 1234567", "", "", "", "unknown country code"},
+		{"not a number", "call me", "DE", "", "", "not a number"},
+		{"too short", "+1 202 555", "", "", "", "too short"},
+		{"too long", "+1 202 555 0143 0143 01", "", "", "", "too long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := NormalizePhone(tt.raw, tt.defaultCountry)
+			if tt.wantReason != "" {
+				var invalid *InvalidPhoneError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("NormalizePhone(%q, %q) error = %v, want an InvalidPhoneError", tt.raw, tt.defaultCountry, err)
+				}
+				if invalid.Reason != tt.wantReason {
+					t.Errorf("NormalizePhone(%q, %q) reason = %q, want %q", tt.raw, tt.defaultCountry, invalid.Reason, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizePhone(%q, %q) error: %v", tt.raw, tt.defaultCountry, err)
+			}
+			if phone.E164 != tt.wantE164 || phone.National != tt.wantNational {
+				t.Errorf("NormalizePhone(%q, %q) = %s / %s, want %s / %s",
+					tt.raw, tt.defaultCountry, phone.E164, phone.National, tt.wantE164, tt.wantNational)
+			}
+		})
+	}
+}
+
+func TestValidPhoneCountry(t *testing.T) {
+	tests := []struct {
+		country string
+		want    bool
+	}{
+		{"us", true},
+		{"XX", false},
+		{"DEU", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidPhoneCountry(tt.country); got != tt.want {
+			t.Errorf("ValidPhoneCountry(%q) = %v, want %v", tt.country, got, tt.want)
+		}
+	}
+}
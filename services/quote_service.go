@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"crm-app/backend/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const defaultQuoteValidityDays = 30
+
+// Quote PDF layout, in millimetres on A4
+const (
+	quotePageMargin = 15.0
+	quoteLogoWidth  = 40.0
+	quoteLineHeight = 5.0
+)
+
+// QuoteService builds quotes from deals and renders them as PDFs
+type QuoteService struct {
+	quoteRepo       models.QuoteRepository
+	dealRepo        models.DealRepository
+	templateService *TemplateService
+}
+
+// NewQuoteService creates a new quote service
+func NewQuoteService(repos *models.CRMRepositories) *QuoteService {
+	return &QuoteService{
+		quoteRepo:       repos.QuoteRepo,
+		dealRepo:        repos.DealRepo,
+		templateService: NewTemplateService(repos),
+	}
+}
+
+// CreateQuote stores a new draft quote version for a deal, copying the deal's line items. A deal
+// without line items is quoted as a single line for its amount. Terms and validity default to the
+// company's quote template.
+func (s *QuoteService) CreateQuote(deal *models.Deal, quote *models.Quote, now time.Time) error {
+	template, err := s.quoteRepo.GetQuoteTemplate(deal.CompanyId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch quote template: %w", err)
+	}
+	items, err := s.dealRepo.GetLineItems(deal.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch line items: %w", err)
+	}
+
+	quote.DealID = deal.ID
+	quote.CompanyId = deal.CompanyId
+	quote.Status = models.QuoteStatusDraft
+	quote.Currency = strings.ToUpper(deal.Currency)
+	if quote.Currency == "" {
+		quote.Currency = models.DefaultCurrency
+	}
+	if quote.Title == "" {
+		quote.Title = deal.Title
+	}
+	if quote.ValidFrom.IsZero() {
+		quote.ValidFrom = now
+	}
+	if quote.ValidUntil.IsZero() {
+		days := defaultQuoteValidityDays
+		if template != nil && template.ValidityDays > 0 {
+			days = template.ValidityDays
+		}
+		quote.ValidUntil = quote.ValidFrom.AddDate(0, 0, days)
+	}
+	if quote.Terms == "" && template != nil {
+		quote.Terms = template.DefaultTerms
+	}
+
+	quote.LineItems = make([]models.QuoteLineItem, 0, len(items))
+	for _, item := range items {
+		quote.LineItems = append(quote.LineItems, models.QuoteLineItem{
+			ProductID:       item.ProductID,
+			Name:            item.Name,
+			SKU:             item.SKU,
+			Quantity:        item.Quantity,
+			UnitPrice:       item.UnitPrice,
+			DiscountPercent: item.DiscountPercent,
+			TaxPercent:      item.TaxPercent,
+			Subtotal:        item.Subtotal,
+			TaxAmount:       item.TaxAmount,
+			Total:           item.Total,
+			Position:        item.Position,
+		})
+	}
+	if len(quote.LineItems) == 0 {
+		quote.LineItems = append(quote.LineItems, models.QuoteLineItem{
+			Name:      deal.Title,
+			Quantity:  1,
+			UnitPrice: deal.Amount,
+			Subtotal:  deal.Amount,
+			Total:     deal.Amount,
+		})
+	}
+
+	quote.Subtotal, quote.TaxTotal, quote.Total = 0, 0, 0
+	for _, item := range quote.LineItems {
+		quote.Subtotal += item.Subtotal
+		quote.TaxTotal += item.TaxAmount
+		quote.Total += item.Total
+	}
+
+	return s.quoteRepo.CreateQuoteVersion(quote)
+}
+
+// RenderPDF renders a quote with the company's quote template
+func (s *QuoteService) RenderPDF(quote *models.Quote) ([]byte, error) {
+	deal, err := s.dealRepo.FindByID(quote.DealID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deal: %w", err)
+	}
+	if deal == nil {
+		return nil, fmt.Errorf("deal %d not found", quote.DealID)
+	}
+	template, err := s.quoteRepo.GetQuoteTemplate(quote.CompanyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quote template: %w", err)
+	}
+	if template == nil {
+		template = &models.QuoteTemplate{CompanyId: quote.CompanyId}
+	}
+
+	data, err := s.templateService.BuildMergeData(deal.LeadID, quote.CompanyId)
+	if err != nil {
+		return nil, err
+	}
+	data["deal"] = dealMergeData(*deal)
+	data["quote"] = quoteMergeData(quote)
+
+	header, err := renderQuoteText(template.Header, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote template header: %w", err)
+	}
+	footer, err := renderQuoteText(template.Footer, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote template footer: %w", err)
+	}
+	terms, err := renderQuoteText(quote.Terms, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote terms: %w", err)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(quotePageMargin, quotePageMargin, quotePageMargin)
+	pdf.SetAutoPageBreak(true, 25)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	red, green, blue := parseHexColor(template.AccentColor)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-18)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		if footer != "" {
+			pdf.MultiCell(0, 4, tr(footer), "", "C", false)
+		}
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s - page %d of {nb}", quote.Number, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	// Logo and header
+	headerX := quotePageMargin
+	if len(template.Logo) > 0 {
+		imageType := "PNG"
+		if strings.Contains(template.LogoContentType, "jpeg") || strings.Contains(template.LogoContentType, "jpg") {
+			imageType = "JPG"
+		}
+		options := fpdf.ImageOptions{ImageType: imageType}
+		pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(template.Logo))
+		if pdf.Ok() {
+			pdf.ImageOptions("logo", quotePageMargin, quotePageMargin, quoteLogoWidth, 0, false, options, 0, "")
+			headerX += quoteLogoWidth + 5
+		} else {
+			// A logo that cannot be decoded is left out rather than failing the quote
+			pdf.ClearError()
+		}
+	}
+	pdf.SetXY(headerX, quotePageMargin)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(60, 60, 60)
+	if header != "" {
+		pdf.MultiCell(0, 4, tr(header), "", "R", false)
+	}
+	pdf.SetY(max(pdf.GetY(), quotePageMargin+25) + 5)
+
+	// Title and quote details
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(red, green, blue)
+	pdf.CellFormat(0, 10, tr("Quote "+quote.Number), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(0, 0, 0)
+	customer := mergeValueString(lookupMergePath(data, "lead.name"))
+	if company := mergeValueString(lookupMergePath(data, "lead.company")); company != "" {
+		customer = strings.TrimSpace(customer + ", " + company)
+	}
+	details := [][2]string{
+		{"Title", quote.Title},
+		{"Prepared for", customer},
+		{"Version", strconv.Itoa(quote.Version)},
+		{"Date", quote.ValidFrom.Format("2006-01-02")},
+		{"Valid until", quote.ValidUntil.Format("2006-01-02")},
+	}
+	for _, detail := range details {
+		if detail[1] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(35, 6, tr(detail[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 6, tr(detail[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	// Line items
+	columns := []struct {
+		title string
+		width float64
+		align string
+	}{
+		{"Item", 62, "L"}, {"SKU", 25, "L"}, {"Qty", 15, "R"}, {"Unit price", 25, "R"},
+		{"Disc. %", 15, "R"}, {"Tax %", 13, "R"}, {"Amount", 25, "R"},
+	}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(red, green, blue)
+	pdf.SetTextColor(255, 255, 255)
+	for _, column := range columns {
+		pdf.CellFormat(column.width, 7, column.title, "", 0, column.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(0, 0, 0)
+	for _, item := range quote.LineItems {
+		values := []string{
+			item.Name,
+			item.SKU,
+			strconv.FormatFloat(item.Quantity, 'f', -1, 64),
+			formatQuoteAmount(item.UnitPrice),
+			formatQuoteAmount(item.DiscountPercent),
+			formatQuoteAmount(item.TaxPercent),
+			formatQuoteAmount(item.Subtotal),
+		}
+		for i, column := range columns {
+			value := tr(values[i])
+			for pdf.GetStringWidth(value) > column.width-2 && len(value) > 1 {
+				value = value[:len(value)-1]
+			}
+			pdf.CellFormat(column.width, 6, value, "B", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(3)
+
+	// Totals
+	totals := [][2]string{
+		{"Subtotal", formatQuoteAmount(quote.Subtotal) + " " + quote.Currency},
+		{"Tax", formatQuoteAmount(quote.TaxTotal) + " " + quote.Currency},
+		{"Total", formatQuoteAmount(quote.Total) + " " + quote.Currency},
+	}
+	for i, total := range totals {
+		style := ""
+		if i == len(totals)-1 {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(145, 6, total[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(35, 6, total[1], "", 1, "R", false, 0, "")
+	}
+
+	// Terms and notes
+	for _, section := range [][2]string{{"Terms and conditions", terms}, {"Notes", quote.Notes}} {
+		if strings.TrimSpace(section[1]) == "" {
+			continue
+		}
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.SetTextColor(red, green, blue)
+		pdf.CellFormat(0, 6, section[0], "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.MultiCell(0, quoteLineHeight, tr(section[1]), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render quote PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteMergeData returns the merge values of a quote under the quote root
+func quoteMergeData(quote *models.Quote) map[string]interface{} {
+	return map[string]interface{}{
+		"number":      quote.Number,
+		"version":     strconv.Itoa(quote.Version),
+		"title":       quote.Title,
+		"status":      quote.Status,
+		"currency":    quote.Currency,
+		"subtotal":    formatQuoteAmount(quote.Subtotal),
+		"tax_total":   formatQuoteAmount(quote.TaxTotal),
+		"total":       formatQuoteAmount(quote.Total),
+		"valid_from":  quote.ValidFrom.Format("2006-01-02"),
+		"valid_until": quote.ValidUntil.Format("2006-01-02"),
+	}
+}
+
+// renderQuoteText renders merge tags in plain quote text
+func renderQuoteText(text string, data map[string]interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	parsed, err := ParseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Render(data, false), nil
+}
+
+// formatQuoteAmount formats an amount with two decimals
+func formatQuoteAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// parseHexColor parses a #RRGGBB color, falling back to dark blue
+func parseHexColor(color string) (int, int, int) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(color, "#")) != 6 {
+		return 31, 78, 121
+	}
+	return int(value >> 16 & 0xFF), int(value >> 8 & 0xFF), int(value & 0xFF)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseViewFilters parses a saved view's Filters JSON
+func ParseViewFilters(raw string) ([]models.ViewFilter, error) {
+	filters := []models.ViewFilter{}
+	if strings.TrimSpace(raw) == "" {
+		return filters, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &filters); err != nil {
+		return nil, fmt.Errorf("invalid view filters: %w", err)
+	}
+	return filters, nil
+}
+
+// ParseViewColumns parses a saved view's Columns JSON
+func ParseViewColumns(raw string) ([]string, error) {
+	columns := []string{}
+	if strings.TrimSpace(raw) == "" {
+		return columns, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &columns); err != nil {
+		return nil, fmt.Errorf("invalid view columns: %w", err)
+	}
+	return columns, nil
+}
+
+// ViewSortOf returns the order a saved view lists its records in
+func ViewSortOf(view *models.SavedView) models.ViewSort {
+	return models.ViewSort{Field: view.SortBy, Desc: strings.EqualFold(view.SortDir, "desc")}
+}
+
+// ValidateViewFilters checks that every filter names a field the entity's views can use, has a
+// known operator and has the values the operator needs
+func ValidateViewFilters(entity string, filters []models.ViewFilter) error {
+	for i, filter := range filters {
+		if filter.Field == models.ViewFilterTags {
+			if !tagFilterOperators[filter.Operator] {
+				return fmt.Errorf("filter %d: unsupported operator %q for tags", i, filter.Operator)
+			}
+		} else if !validViewField(entity, filter.Field) {
+			return fmt.Errorf("filter %d: unknown field %q", i, filter.Field)
+		}
+		if !models.ViewFilterOperators[filter.Operator] {
+			return fmt.Errorf("filter %d: unsupported operator %q", i, filter.Operator)
+		}
+		if (filter.Operator == "in" || filter.Operator == "not_in") && len(filter.Values) == 0 {
+			return fmt.Errorf("filter %d: values are required", i)
+		}
+	}
+	return nil
+}
+
+// tagFilterOperators are the operators a tags filter accepts
+var tagFilterOperators = map[string]bool{"in": true, "not_in": true, "empty": true, "not_empty": true}
+
+// ValidateSavedView checks a saved view's entity, sort, page size, filters and columns
+func ValidateSavedView(view *models.SavedView) error {
+	if !models.ViewEntities[view.Entity] {
+		return fmt.Errorf("entity must be lead, deal or contact")
+	}
+	if view.SortBy != "" && !validViewField(view.Entity, view.SortBy) {
+		return fmt.Errorf("cannot sort by %q", view.SortBy)
+	}
+	if view.SortDir != "" && !strings.EqualFold(view.SortDir, "asc") && !strings.EqualFold(view.SortDir, "desc") {
+		return fmt.Errorf("sort_dir must be asc or desc")
+	}
+	if view.PageSize < 0 || view.PageSize > models.MaxViewPageSize {
+		return fmt.Errorf("page_size must be between 1 and %d", models.MaxViewPageSize)
+	}
+
+	filters, err := ParseViewFilters(view.Filters)
+	if err != nil {
+		return err
+	}
+	if err := ValidateViewFilters(view.Entity, filters); err != nil {
+		return err
+	}
+
+	columns, err := ParseViewColumns(view.Columns)
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if !validViewField(view.Entity, column) {
+			return fmt.Errorf("unknown column %q", column)
+		}
+	}
+	return nil
+}
+
+// validViewField reports whether an entity's views can use a field: one of its columns or, for
+// leads, any lead field name
+func validViewField(entity string, field string) bool {
+	if entity == models.ViewEntityLead {
+		return strings.TrimSpace(field) != ""
+	}
+	return models.ViewFields[entity][field]
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+const segmentRefreshBatchSize = 20
+
+// SegmentService evaluates segments and keeps the campaigns and sequences targeting them populated
+type SegmentService struct {
+	segmentRepo models.SegmentRepository
+	nurtureRepo models.NurtureRepository
+}
+
+// NewSegmentService creates a new segment service
+func NewSegmentService(repos *models.CRMRepositories) *SegmentService {
+	return &SegmentService{
+		segmentRepo: repos.SegmentRepo,
+		nurtureRepo: repos.NurtureRepo,
+	}
+}
+
+// ParseSegmentFilter parses a segment's Filters JSON
+func ParseSegmentFilter(raw string) (*models.SegmentFilter, error) {
+	var filter models.SegmentFilter
+	if raw == "" {
+		return &filter, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+		return nil, fmt.Errorf("invalid segment filters: %w", err)
+	}
+	return &filter, nil
+}
+
+// ValidateSegmentFilter checks that every rule of a filter has a known type, an operator the type
+// accepts and the values the operator needs
+func ValidateSegmentFilter(filter *models.SegmentFilter) error {
+	if filter.Match != "" && filter.Match != "all" && filter.Match != "any" {
+		return fmt.Errorf("match must be all or any")
+	}
+
+	for i, rule := range filter.Rules {
+		operators, ok := models.SegmentRuleOperators[rule.Type]
+		if !ok {
+			return fmt.Errorf("rule %d: unsupported rule type %q", i, rule.Type)
+		}
+		if !operators[rule.Operator] {
+			return fmt.Errorf("rule %d: unsupported operator %q for %s rule", i, rule.Operator, rule.Type)
+		}
+
+		switch rule.Type {
+		case models.SegmentRuleField:
+			if rule.Field == "" {
+				return fmt.Errorf("rule %d: field is required", i)
+			}
+		case models.SegmentRuleStatus, models.SegmentRuleTag:
+			if len(rule.Values) == 0 {
+				return fmt.Errorf("rule %d: values are required", i)
+			}
+		case models.SegmentRuleScore:
+			if rule.Operator == "between" && rule.Min == nil && rule.Max == nil {
+				return fmt.Errorf("rule %d: min or max is required", i)
+			}
+			if rule.Operator != "between" && len(rule.Values) == 0 {
+				return fmt.Errorf("rule %d: score bands are required", i)
+			}
+		case models.SegmentRuleActivity:
+			if !models.SegmentActivities[rule.Activity] {
+				return fmt.Errorf("rule %d: unsupported activity %q", i, rule.Activity)
+			}
+			if rule.Days <= 0 {
+				return fmt.Errorf("rule %d: days must be positive", i)
+			}
+		case models.SegmentRuleCampaign:
+			if rule.CampaignID == nil {
+				return fmt.Errorf("rule %d: campaign_id is required", i)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the IDs of the leads matching a segment right now, without storing them
+func (s *SegmentService) Evaluate(segment *models.Segment) ([]int, error) {
+	filter, err := ParseSegmentFilter(segment.Filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.segmentRepo.EvaluateSegment(segment.CompanyId, filter)
+}
+
+// Refresh re-evaluates a segment, stores its membership and adds new members to the campaigns
+// and sequences targeting it
+func (s *SegmentService) Refresh(segment *models.Segment, now time.Time) (*models.SegmentSyncResult, error) {
+	leadIDs, err := s.Evaluate(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed, err := s.segmentRepo.ReplaceSegmentMembers(segment, leadIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SegmentSyncResult{Members: int64(len(leadIDs)), Added: added, Removed: removed}
+
+	campaigns, err := s.nurtureRepo.GetCampaignsBySegment(segment.ID)
+	if err != nil {
+		return result, err
+	}
+	for i := range campaigns {
+		count, err := s.SyncCampaign(&campaigns[i])
+		if err != nil {
+			return result, err
+		}
+		result.CampaignLeadsAdded += count
+	}
+
+	sequences, err := s.nurtureRepo.GetSequencesBySegment(segment.ID)
+	if err != nil {
+		return result, err
+	}
+	for i := range sequences {
+		count, err := s.SyncSequence(&sequences[i])
+		if err != nil {
+			return result, err
+		}
+		result.LeadsEnrolled += count
+	}
+
+	return result, nil
+}
+
+// SyncCampaign adds the targeted segment's members to a campaign that has not finished
+func (s *SegmentService) SyncCampaign(campaign *models.Campaign) (int, error) {
+	if campaign.SegmentID == nil ||
+		campaign.Status == models.CampaignStatusCompleted || campaign.Status == models.CampaignStatusCancelled {
+		return 0, nil
+	}
+	return s.nurtureRepo.AddSegmentMembersToCampaign(campaign.ID, *campaign.SegmentID)
+}
+
+// SyncSequence enrolls the targeted segment's members in an active sequence, once per lead
+func (s *SegmentService) SyncSequence(sequence *models.NurtureSequence) (int, error) {
+	if sequence.SegmentID == nil || !sequence.IsActive {
+		return 0, nil
+	}
+	return s.nurtureRepo.EnrollSegmentMembers(sequence.ID, *sequence.SegmentID)
+}
+
+// Start refreshes scheduled segments every interval until the context is cancelled
+func (s *SegmentService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Segment scheduler started (interval %s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if refreshed, err := s.RunOnce(time.Now()); err != nil {
+			log.Printf("Segment scheduler run failed: %v", err)
+		} else if refreshed > 0 {
+			log.Printf("Segment scheduler refreshed %d segments", refreshed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce refreshes the segments whose scheduled refresh is due
+func (s *SegmentService) RunOnce(now time.Time) (int, error) {
+	segments, err := s.segmentRepo.ClaimDueSegments(now, segmentRefreshBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim segments: %w", err)
+	}
+
+	refreshed := 0
+	for i := range segments {
+		if _, err := s.Refresh(&segments[i], now); err != nil {
+			log.Printf("Segment %d refresh failed: %v", segments[i].ID, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
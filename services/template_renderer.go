@@ -0,0 +1,331 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Merge tag syntax supported in templates:
+//
+//	{{lead.name}}                      value of a merge field
+//	{{lead.name | "there"}}            value with a fallback when empty
+//	{{#if lead.company}}..{{else}}..{{/if}}
+//	{{#if lead.status == "qualified"}}..{{/if}}   also !=
+//	{{#each deals}}{{this.title}}{{/each}}
+
+// ParsedTemplate is a template parsed into merge tag nodes
+type ParsedTemplate struct {
+	nodes []templateNode
+}
+
+type templateNode interface{}
+
+type textNode string
+
+type varNode struct {
+	path        string
+	fallback    string
+	hasFallback bool
+}
+
+type ifNode struct {
+	path     string
+	operator string // empty for a truthiness test, otherwise == or !=
+	value    string
+	then     []templateNode
+	els      []templateNode
+}
+
+type eachNode struct {
+	path string
+	body []templateNode
+}
+
+// ParseTemplate parses template text containing merge tags
+func ParseTemplate(text string) (*ParsedTemplate, error) {
+	p := &templateParser{text: text}
+	nodes, closing, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if closing != "" {
+		return nil, fmt.Errorf("unexpected {{%s}}", closing)
+	}
+	return &ParsedTemplate{nodes: nodes}, nil
+}
+
+type templateParser struct {
+	text string
+	pos  int
+}
+
+// parse reads nodes until the end of input or a closing/else tag, which it returns
+func (p *templateParser) parse() ([]templateNode, string, error) {
+	var nodes []templateNode
+
+	for p.pos < len(p.text) {
+		start := strings.Index(p.text[p.pos:], "{{")
+		if start < 0 {
+			nodes = append(nodes, textNode(p.text[p.pos:]))
+			p.pos = len(p.text)
+			break
+		}
+		if start > 0 {
+			nodes = append(nodes, textNode(p.text[p.pos:p.pos+start]))
+		}
+
+		tagStart := p.pos + start
+		end := strings.Index(p.text[tagStart:], "}}")
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated merge tag at position %d", tagStart)
+		}
+		tag := strings.TrimSpace(p.text[tagStart+2 : tagStart+end])
+		p.pos = tagStart + end + 2
+
+		switch {
+		case tag == "else" || tag == "/if" || tag == "/each":
+			return nodes, tag, nil
+
+		case strings.HasPrefix(tag, "#if "):
+			node, err := parseIfTag(strings.TrimSpace(tag[4:]))
+			if err != nil {
+				return nil, "", err
+			}
+			body, closing, err := p.parse()
+			if err != nil {
+				return nil, "", err
+			}
+			node.then = body
+			if closing == "else" {
+				body, closing, err = p.parse()
+				if err != nil {
+					return nil, "", err
+				}
+				node.els = body
+			}
+			if closing != "/if" {
+				return nil, "", fmt.Errorf("{{#if %s}} is not closed with {{/if}}", node.path)
+			}
+			nodes = append(nodes, node)
+
+		case strings.HasPrefix(tag, "#each "):
+			node := &eachNode{path: strings.TrimSpace(tag[6:])}
+			if !isMergePath(node.path) {
+				return nil, "", fmt.Errorf("invalid merge field in {{%s}}", tag)
+			}
+			body, closing, err := p.parse()
+			if err != nil {
+				return nil, "", err
+			}
+			if closing != "/each" {
+				return nil, "", fmt.Errorf("{{#each %s}} is not closed with {{/each}}", node.path)
+			}
+			node.body = body
+			nodes = append(nodes, node)
+
+		default:
+			node, err := parseVarTag(tag)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes, "", nil
+}
+
+func parseVarTag(tag string) (*varNode, error) {
+	node := &varNode{path: tag}
+	if i := strings.Index(tag, "|"); i >= 0 {
+		node.path = strings.TrimSpace(tag[:i])
+		fallback, err := parseQuoted(strings.TrimSpace(tag[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback in {{%s}}: %w", tag, err)
+		}
+		node.fallback = fallback
+		node.hasFallback = true
+	}
+	if !isMergePath(node.path) {
+		return nil, fmt.Errorf("invalid merge field {{%s}}", tag)
+	}
+	return node, nil
+}
+
+func parseIfTag(expr string) (*ifNode, error) {
+	node := &ifNode{path: expr}
+	for _, operator := range []string{"==", "!="} {
+		if i := strings.Index(expr, operator); i >= 0 {
+			value, err := parseQuoted(strings.TrimSpace(expr[i+len(operator):]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid comparison in {{#if %s}}: %w", expr, err)
+			}
+			node.path = strings.TrimSpace(expr[:i])
+			node.operator = operator
+			node.value = value
+			break
+		}
+	}
+	if !isMergePath(node.path) {
+		return nil, fmt.Errorf("invalid merge field in {{#if %s}}", expr)
+	}
+	return node, nil
+}
+
+func parseQuoted(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string")
+	}
+	return strconv.Unquote(s)
+}
+
+// isMergePath reports whether s is a dotted field path such as lead.name
+func isMergePath(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MergeFields returns every merge field path the template references. Fields used inside
+// an {{#each list}} block are reported as list.field.
+func (t *ParsedTemplate) MergeFields() []string {
+	seen := make(map[string]bool)
+	var fields []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			fields = append(fields, path)
+		}
+	}
+
+	var walk func(nodes []templateNode, loop string)
+	walk = func(nodes []templateNode, loop string) {
+		resolve := func(path string) string {
+			if loop != "" && (path == "this" || strings.HasPrefix(path, "this.")) {
+				return loop + strings.TrimPrefix(path, "this")
+			}
+			return path
+		}
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *varNode:
+				add(resolve(n.path))
+			case *ifNode:
+				add(resolve(n.path))
+				walk(n.then, loop)
+				walk(n.els, loop)
+			case *eachNode:
+				add(resolve(n.path))
+				walk(n.body, resolve(n.path))
+			}
+		}
+	}
+	walk(t.nodes, "")
+
+	return fields
+}
+
+// Render renders the template against data, HTML-escaping values when escapeHTML is set.
+// Data values are strings, nested map[string]interface{} or []map[string]interface{} for loops.
+func (t *ParsedTemplate) Render(data map[string]interface{}, escapeHTML bool) string {
+	var sb strings.Builder
+	renderNodes(&sb, t.nodes, data, escapeHTML)
+	return sb.String()
+}
+
+func renderNodes(sb *strings.Builder, nodes []templateNode, scope map[string]interface{}, escapeHTML bool) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case textNode:
+			sb.WriteString(string(n))
+		case *varNode:
+			value := mergeValueString(lookupMergePath(scope, n.path))
+			if value == "" && n.hasFallback {
+				value = n.fallback
+			}
+			if escapeHTML {
+				value = html.EscapeString(value)
+			}
+			sb.WriteString(value)
+		case *ifNode:
+			value := lookupMergePath(scope, n.path)
+			var ok bool
+			switch n.operator {
+			case "==":
+				ok = strings.EqualFold(mergeValueString(value), n.value)
+			case "!=":
+				ok = !strings.EqualFold(mergeValueString(value), n.value)
+			default:
+				ok = isMergeValueTruthy(value)
+			}
+			if ok {
+				renderNodes(sb, n.then, scope, escapeHTML)
+			} else {
+				renderNodes(sb, n.els, scope, escapeHTML)
+			}
+		case *eachNode:
+			items, _ := lookupMergePath(scope, n.path).([]map[string]interface{})
+			for _, item := range items {
+				inner := make(map[string]interface{}, len(scope)+1)
+				for key, value := range scope {
+					inner[key] = value
+				}
+				inner["this"] = item
+				renderNodes(sb, n.body, inner, escapeHTML)
+			}
+		}
+	}
+}
+
+func lookupMergePath(scope map[string]interface{}, path string) interface{} {
+	var current interface{} = scope
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+func mergeValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []map[string]interface{}:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isMergeValueTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != "" && v != "0" && !strings.EqualFold(v, "false")
+	case []map[string]interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsedTemplateRender(t *testing.T) {
+	data := map[string]interface{}{
+		"lead": map[string]interface{}{
+			"name":    "Ada <Lovelace>",
+			"company": "",
+			"status":  "Qualified",
+			"score":   42,
+		},
+		"deals": []map[string]interface{}{
+			{"title": "First"},
+			{"title": "Second"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		template   string
+		escapeHTML bool
+		want       string
+	}{
+		{"plain text", "Hello there", true, "Hello there"},
+		{"value", "Hi {{lead.name}}", false, "Hi Ada <Lovelace>"},
+		{"escaped value", "Hi {{ lead.name }}", true, "Hi Ada &lt;Lovelace&gt;"},
+		{"non-string value", "Score {{lead.score}}", true, "Score 42"},
+		{"missing value", "Hi {{lead.missing}}!", true, "Hi !"},
+		{"fallback on empty", `At {{lead.company | "your company"}}`, true, "At your company"},
+		{"fallback unused", `Hi {{lead.name | "there"}}`, false, "Hi Ada <Lovelace>"},
+		{"if truthy", "{{#if lead.name}}yes{{else}}no{{/if}}", true, "yes"},
+		{"if empty", "{{#if lead.company}}yes{{else}}no{{/if}}", true, "no"},
+		{"if equals ignores case", `{{#if lead.status == "qualified"}}hot{{/if}}`, true, "hot"},
+		{"if not equals", `{{#if lead.status != "qualified"}}cold{{else}}hot{{/if}}`, true, "hot"},
+		{"each", "{{#each deals}}[{{this.title}}]{{/each}}", true, "[First][Second]"},
+		{"each sees outer scope", "{{#each deals}}{{lead.score}}{{/each}}", true, "4242"},
+		{"each over missing list", "{{#each lead.items}}x{{/each}}", true, ""},
+		{"nested if in each", `{{#each deals}}{{#if this.title == "Second"}}{{this.title}}{{/if}}{{/each}}`, true, "Second"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseTemplate(%q) error: %v", tt.template, err)
+			}
+			if got := parsed.Render(data, tt.escapeHTML); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unterminated tag", "Hi {{lead.name"},
+		{"invalid field", "Hi {{lead name}}"},
+		{"empty path segment", "Hi {{lead..name}}"},
+		{"unquoted fallback", "Hi {{lead.name | there}}"},
+		{"unclosed if", "{{#if lead.name}}yes"},
+		{"unclosed each", "{{#each deals}}x"},
+		{"if closed by each", "{{#if lead.name}}x{{/each}}"},
+		{"stray close", "x{{/if}}"},
+		{"stray else", "x{{else}}y"},
+		{"unquoted comparison", "{{#if lead.status == qualified}}x{{/if}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTemplate(tt.template); err == nil {
+				t.Errorf("ParseTemplate(%q) returned no error", tt.template)
+			}
+		})
+	}
+}
+
+func TestParsedTemplateMergeFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{"none", "Hello", nil},
+		{"values deduplicated", "{{lead.name}} {{lead.name}} {{lead.email}}", []string{"lead.name", "lead.email"}},
+		{"if condition and body", `{{#if lead.status == "new"}}{{lead.name}}{{else}}{{user.name}}{{/if}}`, []string{"lead.status", "lead.name", "user.name"}},
+		{"each fields resolved against the list", "{{#each deals}}{{this.title}}{{/each}}", []string{"deals", "deals.title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseTemplate(%q) error: %v", tt.template, err)
+			}
+			if got := parsed.MergeFields(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeFields(%q) = %v, want %v", tt.template, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Merge fields available outside the lead's own fields
+var (
+	leadMergeFields    = []string{"id", "name", "email", "phone", "company", "source", "status", "type", "score", "assigned_to_id"}
+	userMergeFields    = []string{"id", "name", "email", "role"}
+	companyMergeFields = []string{"name", "website", "phone", "address"}
+	dealMergeFields    = []string{"id", "title", "amount", "currency", "stage", "probability", "expected_close_date"}
+)
+
+// RenderedTemplate is a template rendered for a single recipient
+type RenderedTemplate struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// TemplateService renders campaign templates against lead, user, company and deal data
+type TemplateService struct {
+	leadRepo            models.LeadRepository
+	leadFieldConfigRepo models.LeadFieldConfigRepository
+	userRepo            models.UserRepository
+	dealRepo            models.DealRepository
+	companyRepo         models.CompanyRepository
+	unsubscribe         *UnsubscribeLinks
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(repos *models.CRMRepositories) *TemplateService {
+	return &TemplateService{
+		leadRepo:            repos.LeadRepo,
+		leadFieldConfigRepo: repos.LeadFieldConfigRepo,
+		userRepo:            repos.UserRepo,
+		dealRepo:            repos.DealRepo,
+		companyRepo:         repos.CompanyRepo,
+		unsubscribe:         NewUnsubscribeLinksFromEnv(),
+	}
+}
+
+// MergeFieldCatalog returns the merge fields a company's templates may use, grouped by root
+func (s *TemplateService) MergeFieldCatalog(companyId int) (map[string][]string, error) {
+	configs, err := s.leadFieldConfigRepo.GetAllFieldConfigs(companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lead fields: %w", err)
+	}
+
+	leadFields := append([]string{}, leadMergeFields...)
+	seen := make(map[string]bool, len(leadFields))
+	for _, field := range leadFields {
+		seen[field] = true
+	}
+	for _, config := range configs {
+		if config.FieldName != "" && !seen[config.FieldName] {
+			seen[config.FieldName] = true
+			leadFields = append(leadFields, config.FieldName)
+		}
+	}
+	sort.Strings(leadFields)
+
+	return map[string][]string{
+		"lead":    leadFields,
+		"user":    userMergeFields,
+		"company": companyMergeFields,
+		"deal":    dealMergeFields,
+		"deals":   dealMergeFields,
+		// Recipient links, empty unless tracking is configured
+		"unsubscribe_url": {},
+		"preferences_url": {},
+	}, nil
+}
+
+// ValidateTemplate parses a template's subject and content and returns any merge fields
+// that do not exist for the company. A parse error is returned as err.
+func (s *TemplateService) ValidateTemplate(subject string, content string, companyId int) ([]string, error) {
+	var fields []string
+	for _, text := range []string{subject, content} {
+		parsed, err := ParseTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, parsed.MergeFields()...)
+	}
+
+	catalog, err := s.MergeFieldCatalog(companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	unknown := []string{}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if seen[field] || isKnownMergeField(catalog, field) {
+			continue
+		}
+		seen[field] = true
+		unknown = append(unknown, field)
+	}
+	return unknown, nil
+}
+
+// isKnownMergeField reports whether a root or root.field path exists in the catalog
+func isKnownMergeField(catalog map[string][]string, path string) bool {
+	root, field, hasField := strings.Cut(path, ".")
+	names, ok := catalog[root]
+	if !ok {
+		return false
+	}
+	if !hasField {
+		// Only lists and whole objects may be referenced without a field, e.g. {{#each deals}}
+		return true
+	}
+	for _, name := range names {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildMergeData loads the values merge tags resolve to for a lead
+func (s *TemplateService) BuildMergeData(leadID int, companyId int) (map[string]interface{}, error) {
+	leadValues, err := s.leadRepo.GetFieldValues(leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lead: %w", err)
+	}
+	if leadValues == nil {
+		return nil, fmt.Errorf("lead %d not found", leadID)
+	}
+
+	lead := make(map[string]interface{}, len(leadValues))
+	for key, value := range leadValues {
+		lead[key] = value
+	}
+	data := map[string]interface{}{
+		"lead":    lead,
+		"user":    map[string]interface{}{},
+		"company": map[string]interface{}{},
+		"deal":    map[string]interface{}{},
+		"deals":   []map[string]interface{}{},
+	}
+
+	if s.unsubscribe != nil && leadValues["email"] != "" {
+		email := NormalizeEmail(leadValues["email"])
+		data["unsubscribe_url"] = s.unsubscribe.UnsubscribeURL(companyId, email)
+		data["preferences_url"] = s.unsubscribe.PreferencesURL(companyId, email)
+	}
+
+	if assigneeID, err := strconv.Atoi(leadValues["assigned_to_id"]); err == nil {
+		user, err := s.userRepo.FindByID(assigneeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch assigned user: %w", err)
+		}
+		if user != nil {
+			data["user"] = map[string]interface{}{
+				"id":    strconv.Itoa(user.ID),
+				"name":  user.Name,
+				"email": user.Email,
+				"role":  user.Role,
+			}
+		}
+	}
+
+	settings, err := s.companyRepo.GetSettings(companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch company settings: %w", err)
+	}
+	if settings != nil {
+		data["company"] = map[string]interface{}{
+			"name":    settings.Name,
+			"website": settings.Website,
+			"phone":   settings.Phone,
+			"address": settings.Address,
+		}
+	}
+
+	deals, err := s.dealRepo.FindByLead(leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deals: %w", err)
+	}
+	if len(deals) > 0 {
+		// Most recently updated first; the first open deal is the lead's current deal
+		sort.Slice(deals, func(i, j int) bool {
+			return deals[i].UpdatedAt.After(deals[j].UpdatedAt)
+		})
+
+		items := make([]map[string]interface{}, 0, len(deals))
+		current := -1
+		for i, deal := range deals {
+			items = append(items, dealMergeData(deal))
+			if current < 0 && deal.Stage != "won" && deal.Stage != "lost" {
+				current = i
+			}
+		}
+		if current < 0 {
+			current = 0
+		}
+		data["deals"] = items
+		data["deal"] = items[current]
+	}
+
+	return data, nil
+}
+
+func dealMergeData(deal models.Deal) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":          strconv.Itoa(deal.ID),
+		"title":       deal.Title,
+		"amount":      strconv.FormatFloat(deal.Amount, 'f', 2, 64),
+		"currency":    deal.Currency,
+		"stage":       deal.Stage,
+		"probability": strconv.Itoa(deal.Probability),
+	}
+	if deal.ExpectedCloseDate != nil {
+		item["expected_close_date"] = deal.ExpectedCloseDate.Format("2006-01-02")
+	}
+	return item
+}
+
+// Render renders a subject and HTML content against merge data, generating the text version
+func (s *TemplateService) Render(subject string, content string, data map[string]interface{}) (*RenderedTemplate, error) {
+	parsedSubject, err := ParseTemplate(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+	parsedContent, err := ParseTemplate(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content: %w", err)
+	}
+
+	htmlBody := parsedContent.Render(data, true)
+	return &RenderedTemplate{
+		Subject: parsedSubject.Render(data, false),
+		HTML:    htmlBody,
+		Text:    HTMLToText(htmlBody),
+	}, nil
+}
+
+// RenderForLead renders a campaign template for a lead
+func (s *TemplateService) RenderForLead(template *models.CampaignTemplate, leadID int) (*RenderedTemplate, error) {
+	data, err := s.BuildMergeData(leadID, template.CompanyId)
+	if err != nil {
+		return nil, err
+	}
+	return s.Render(template.Subject, template.Content, data)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"crm-app/backend/models"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultTrashRetention is how long deleted records stay in the recycle bin before they are
+// purged for good, unless TRASH_RETENTION sets another duration such as "2160h"
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+const trashPurgeBatchSize = 100
+
+// TrashService lists, restores and permanently purges deleted records, and purges those that
+// have been in the recycle bin longer than the retention period
+type TrashService struct {
+	trashRepo models.TrashRepository
+	auditRepo models.AuditRepository
+	retention time.Duration
+}
+
+// NewTrashService creates a new recycle bin service, with the retention period from the
+// TRASH_RETENTION environment variable
+func NewTrashService(repos *models.CRMRepositories) *TrashService {
+	retention := DefaultTrashRetention
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			retention = parsed
+		} else {
+			log.Printf("Invalid TRASH_RETENTION %q, using %s", value, DefaultTrashRetention)
+		}
+	}
+	return &TrashService{
+		trashRepo: repos.TrashRepo,
+		auditRepo: repos.AuditRepo,
+		retention: retention,
+	}
+}
+
+// List returns a page of a company's deleted records, optionally of one type, each with the time
+// it will be purged, and the total number there are
+func (s *TrashService) List(recordType string, offset int, limit int, companyId int) ([]models.TrashItem, int64, error) {
+	items, total, err := s.trashRepo.GetTrash(recordType, offset, limit, companyId)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		s.setPurgeAt(&items[i])
+	}
+	return items, total, nil
+}
+
+// Get returns a deleted record with the time it will be purged, or nil if it is not in the
+// recycle bin
+func (s *TrashService) Get(recordType string, id int) (*models.TrashItem, error) {
+	item, err := s.trashRepo.GetTrashItem(recordType, id)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	s.setPurgeAt(item)
+	return item, nil
+}
+
+func (s *TrashService) setPurgeAt(item *models.TrashItem) {
+	purgeAt := item.DeletedAt.Add(s.retention)
+	item.PurgeAt = &purgeAt
+}
+
+// Restore restores a deleted record and the children deleted with it, recording who restored it
+func (s *TrashService) Restore(item *models.TrashItem, userID *int) error {
+	if err := s.trashRepo.RestoreRecord(item.Type, item.ID); err != nil {
+		return err
+	}
+	s.recordAudit(item, models.AuditActionRestore, userID)
+	return nil
+}
+
+// Purge permanently deletes a deleted record and everything belonging to it, recording who
+// purged it; userID is nil when the record is purged for having expired
+func (s *TrashService) Purge(item *models.TrashItem, userID *int) error {
+	if err := s.trashRepo.PurgeRecord(item.Type, item.ID); err != nil {
+		return err
+	}
+	s.recordAudit(item, models.AuditActionPurge, userID)
+	return nil
+}
+
+// recordAudit records a change to a deleted record in the audit trail. The change has been made,
+// so a failure is logged rather than returned.
+func (s *TrashService) recordAudit(item *models.TrashItem, action string, userID *int) {
+	entry := models.AuditEntry{
+		EntityType: item.Type,
+		EntityID:   item.ID,
+		Action:     action,
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+		CompanyId:  item.CompanyId,
+	}
+	if err := s.auditRepo.RecordAudit([]models.AuditEntry{entry}); err != nil {
+		log.Printf("Failed to record %s of %s %d in the audit trail: %v", action, item.Type, item.ID, err)
+	}
+}
+
+// Start purges records that have outlived the retention period now and then at each interval,
+// until the context is cancelled
+func (s *TrashService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Recycle bin purge started (interval %s, retention %s)", interval, s.retention)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := s.PurgeExpired(ctx, time.Now()); err != nil {
+			log.Printf("Recycle bin purge failed: %v", err)
+		} else if result.Total > 0 {
+			log.Printf("Recycle bin purge removed %d records", result.Total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired permanently deletes every record deleted longer ago than the retention period.
+// A record that fails to purge is logged and left for the next run.
+func (s *TrashService) PurgeExpired(ctx context.Context, now time.Time) (*models.TrashPurgeResult, error) {
+	result := &models.TrashPurgeResult{Purged: map[string]int{}}
+	cutoff := now.Add(-s.retention)
+
+	for _, recordType := range models.TrashTypes {
+		failed := map[int]bool{}
+		for {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			ids, err := s.trashRepo.GetExpiredTrash(recordType, cutoff, trashPurgeBatchSize+len(failed))
+			if err != nil {
+				return result, fmt.Errorf("failed to fetch expired %s records: %w", recordType, err)
+			}
+
+			purged := 0
+			for _, id := range ids {
+				if failed[id] {
+					continue
+				}
+				item, err := s.trashRepo.GetTrashItem(recordType, id)
+				if err == nil && item == nil {
+					continue // restored since it was fetched
+				}
+				if err == nil {
+					err = s.Purge(item, nil)
+				}
+				if err != nil {
+					log.Printf("Failed to purge %s %d: %v", recordType, id, err)
+					failed[id] = true
+					continue
+				}
+				purged++
+			}
+			result.Purged[recordType] += purged
+			result.Total += purged
+
+			if purged == 0 {
+				break
+			}
+		}
+	}
+	return result, nil
+}
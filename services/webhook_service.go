@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crm-app/backend/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookLeaseDuration = 5 * time.Minute
+	webhookBatchSize     = 50
+	webhookBaseBackoff   = time.Minute
+	webhookMaxBackoff    = 6 * time.Hour
+	webhookTimeout       = 10 * time.Second
+	webhookResponseLimit = 1000
+)
+
+// WebhookDisableAfter is how many delivery attempts in a row can fail before a webhook is disabled
+const WebhookDisableAfter = 20
+
+// Webhook request headers. The signature is the hex HMAC-SHA256, keyed with the webhook's secret,
+// of the timestamp header, a dot and the body, so receivers can reject stale or altered requests.
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderEventID   = "X-Webhook-Event-Id"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookService manages webhook subscriptions and delivers the events queued for them
+type WebhookService struct {
+	webhookRepo models.WebhookRepository
+	client      *http.Client
+	workerID    string
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repos *models.CRMRepositories) *WebhookService {
+	hostname, _ := os.Hostname()
+	return &WebhookService{
+		webhookRepo: repos.WebhookRepo,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// A redirect counts as a failed delivery, so the payload is only ever posted to the
+			// configured URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		workerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// WebhookSignature returns the signature header value of a request body sent at a Unix time
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookSecret returns a random secret for signing a webhook's payloads
+func NewWebhookSecret() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return "whsec_" + hex.EncodeToString(buf)
+}
+
+// ValidateSubscription checks a subscription's URL, events and secret, generating a secret if it
+// has none
+func ValidateSubscription(subscription *models.WebhookSubscription) error {
+	parsed, err := url.Parse(subscription.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(subscription.Events) == 0 {
+		return fmt.Errorf("events are required")
+	}
+	seen := map[string]bool{}
+	var events []string
+	for _, event := range subscription.Events {
+		if !validWebhookEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	subscription.Events = events
+	if subscription.Secret == "" {
+		subscription.Secret = NewWebhookSecret()
+	} else if len(subscription.Secret) < 16 || len(subscription.Secret) > 100 {
+		return fmt.Errorf("secret must be 16 to 100 characters")
+	}
+	return nil
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range models.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// domainWebhookEvents maps the domain events sent to webhooks to their webhook event types
+var domainWebhookEvents = map[string]string{
+	models.EventLeadCreated:      models.WebhookEventLeadCreated,
+	models.EventLeadUpdated:      models.WebhookEventLeadUpdated,
+	models.EventLeadDeleted:      models.WebhookEventLeadDeleted,
+	models.EventLeadQualified:    models.WebhookEventLeadQualified,
+	models.EventLeadAssigned:     models.WebhookEventLeadAssigned,
+	models.EventDealCreated:      models.WebhookEventDealCreated,
+	models.EventDealUpdated:      models.WebhookEventDealUpdated,
+	models.EventDealDeleted:      models.WebhookEventDealDeleted,
+	models.EventDealStageChanged: models.WebhookEventDealStageChanged,
+	models.EventDealWon:          models.WebhookEventDealWon,
+	models.EventContactCreated:   models.WebhookEventContactCreated,
+	models.EventContactUpdated:   models.WebhookEventContactUpdated,
+	models.EventContactDeleted:   models.WebhookEventContactDeleted,
+	models.EventTargetAchieved:   models.WebhookEventTargetAchieved,
+}
+
+// DomainEventTypes returns the domain event types DomainEventHandler sends to webhooks
+func (s *WebhookService) DomainEventTypes() []string {
+	types := make([]string, 0, len(domainWebhookEvents))
+	for eventType := range domainWebhookEvents {
+		types = append(types, eventType)
+	}
+	return types
+}
+
+// DomainEventHandler returns an event bus handler that queues domain events for the webhooks
+// subscribed to them. Every webhook event comes from the outbox, so it is queued if and only if
+// the change it records was committed. The webhook event keeps the domain event's ID, so an event
+// the bus delivers again is not queued twice.
+func (s *WebhookService) DomainEventHandler() EventHandler {
+	return func(event *models.OutboxEvent) error {
+		webhookType, ok := domainWebhookEvents[event.Type]
+		if !ok {
+			return nil
+		}
+		// Only load the record for the event when a webhook will receive it
+		subscribed, err := s.webhookRepo.HasSubscribers(event.CompanyId, webhookType)
+		if err != nil || !subscribed {
+			return err
+		}
+		data, err := s.domainEventData(event)
+		if err != nil {
+			return err
+		}
+		_, err = s.webhookRepo.QueueEvent(&models.WebhookEvent{
+			ID:        event.EventID,
+			Type:      webhookType,
+			CreatedAt: event.OccurredAt.UTC(),
+			CompanyId: event.CompanyId,
+			Data:      data,
+		})
+		return err
+	}
+}
+
+// domainEventData returns the data of the webhook event for a domain event: the record the event
+// is about as it is when the event is dispatched, keyed by its type, along with the previous
+// values the event recorded, such as previous_stage. A record purged since is replaced by the
+// event's own payload.
+func (s *WebhookService) domainEventData(event *models.OutboxEvent) (map[string]interface{}, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil, err
+	}
+	record, err := s.webhookRepo.GetEventRecord(event.AggregateType, event.AggregateID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{event.AggregateType: payload}
+	if record != nil {
+		data[event.AggregateType] = record
+	}
+	for key, value := range payload {
+		if strings.HasPrefix(key, "previous_") {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// Replay queues a delivery's event for delivery again, as a new delivery
+func (s *WebhookService) Replay(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	return s.webhookRepo.ReplayDelivery(delivery, time.Now())
+}
+
+// Start runs the delivery loop until the context is cancelled
+func (s *WebhookService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Webhook delivery started (worker %s, interval %s)", s.workerID, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if delivered, err := s.RunOnce(time.Now()); err != nil {
+			log.Printf("Webhook delivery run failed: %v", err)
+		} else if delivered > 0 {
+			log.Printf("Webhook delivery processed %d deliveries", delivered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and attempts the webhook deliveries that are due at the given time
+func (s *WebhookService) RunOnce(now time.Time) (int, error) {
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(s.workerID, now, now.Add(webhookLeaseDuration), webhookBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	subscriptions := map[int]*models.WebhookSubscription{}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			if subscription, err = s.webhookRepo.GetSubscriptionByID(delivery.SubscriptionID); err != nil {
+				log.Printf("Failed to load webhook %d: %v", delivery.SubscriptionID, err)
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		// A webhook disabled earlier in the run keeps its deliveries until it is enabled again
+		if subscription != nil && subscription.Active {
+			s.deliver(delivery, subscription, now)
+		}
+		if err := s.webhookRepo.ReleaseDelivery(delivery, s.workerID); err != nil {
+			log.Printf("Webhook delivery %d could not be released: %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver makes one delivery attempt, scheduling a retry with exponential backoff if it fails, and
+// disables the webhook once too many attempts in a row have failed
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery, subscription *models.WebhookSubscription, now time.Time) {
+	delivery.Attempts++
+	status, body, err := s.post(delivery, subscription, now)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+
+	succeeded := err == nil
+	disableAfter := WebhookDisableAfter
+	reason := fmt.Sprintf("%d delivery attempts in a row failed", WebhookDisableAfter)
+	if succeeded {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= delivery.MaxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(webhookBackoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+		// The receiver says the endpoint is gone for good
+		if status == http.StatusGone {
+			disableAfter = 1
+			reason = "the webhook URL responded 410 Gone"
+		}
+	}
+
+	disabled, err := s.webhookRepo.RecordDeliveryResult(subscription.ID, succeeded, disableAfter, reason, now)
+	if err != nil {
+		log.Printf("Failed to record delivery result of webhook %d: %v", subscription.ID, err)
+	}
+	if disabled {
+		subscription.Active = false
+		log.Printf("Webhook %d disabled: %s", subscription.ID, reason)
+	}
+}
+
+// post sends a delivery's payload to the webhook, returning the response status and the start of
+// the response body. Any status other than 2xx is an error.
+func (s *WebhookService) post(delivery *models.WebhookDelivery, subscription *models.WebhookSubscription, now time.Time) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CRM-Webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderEventID, delivery.EventID)
+	req.Header.Set(WebhookHeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookHeaderSignature, WebhookSignature(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	read, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	body := strings.ToValidUTF8(string(read), "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, body, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// webhookBackoff returns the delay before the next attempt, doubling per attempt up to
+// webhookMaxBackoff
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crm-app/backend/models"
+	"testing"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	const secret = "test-secret-0123456789"
+	body := []byte(`{"event":"deal.created"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		want      string
+		wantMatch bool
+	}{
+		// expected values computed with: printf '%s' '<timestamp>.<body>' | openssl dgst -sha256 -hmac <secret>
+		{"known payload", secret, 1700000000, body, "sha256=8698cb0ed20ac9e0b079f6ccfb092579c6a24fd66a56bf40e027b2d6e80c2091", true},
+		{"empty body", secret, 1700000000, nil, "sha256=76f09fb28067a0b07f97cd14755cb648d9a0a760987f0e11a0c8924778de5071", true},
+		{"other timestamp", secret, 1700000001, body, "sha256=8698cb0ed20ac9e0b079f6ccfb092579c6a24fd66a56bf40e027b2d6e80c2091", false},
+		{"other secret", "another-secret-0123456", 1700000000, body, "sha256=8698cb0ed20ac9e0b079f6ccfb092579c6a24fd66a56bf40e027b2d6e80c2091", false},
+		{"other body", secret, 1700000000, []byte(`{"event":"deal.updated"}`), "sha256=8698cb0ed20ac9e0b079f6ccfb092579c6a24fd66a56bf40e027b2d6e80c2091", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WebhookSignature(tt.secret, tt.timestamp, tt.body)
+			if (got == tt.want) != tt.wantMatch {
+				t.Errorf("WebhookSignature = %s, want match with %s: %v", got, tt.want, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	if got := webhookBackoff(0); got != webhookBaseBackoff {
+		t.Errorf("webhookBackoff(0) = %v, want %v", got, webhookBaseBackoff)
+	}
+
+	// Each attempt doubles the delay of the previous one until it reaches the cap
+	previous := webhookBackoff(1)
+	if previous != webhookBaseBackoff {
+		t.Errorf("webhookBackoff(1) = %v, want %v", previous, webhookBaseBackoff)
+	}
+	for attempts := 2; attempts <= 50; attempts++ {
+		want := previous * 2
+		if want > webhookMaxBackoff {
+			want = webhookMaxBackoff
+		}
+		got := webhookBackoff(attempts)
+		if got != want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+		previous = got
+	}
+	if previous != webhookMaxBackoff {
+		t.Errorf("webhookBackoff(50) = %v, want the %v cap", previous, webhookMaxBackoff)
+	}
+}
+
+func TestValidateSubscription(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		events     []string
+		secret     string
+		wantErr    bool
+		wantEvents []string
+	}{
+		{"valid", "https://example.com/hook", []string{models.WebhookEventDealCreated}, "0123456789abcdef", false, []string{models.WebhookEventDealCreated}},
+		{"duplicate events removed", "http://example.com/hook", []string{models.WebhookEventLeadCreated, models.WebhookEventLeadCreated}, "", false, []string{models.WebhookEventLeadCreated}},
+		{"relative url", "/hook", []string{models.WebhookEventLeadCreated}, "", true, nil},
+		{"unsupported scheme", "ftp://example.com/hook", []string{models.WebhookEventLeadCreated}, "", true, nil},
+		{"no events", "https://example.com/hook", nil, "", true, nil},
+		{"unknown event", "https://example.com/hook", []string{"lead.exploded"}, "", true, nil},
+		{"short secret", "https://example.com/hook", []string{models.WebhookEventLeadCreated}, "too-short", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription := &models.WebhookSubscription{URL: tt.url, Events: tt.events, Secret: tt.secret}
+			err := ValidateSubscription(subscription)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateSubscription error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(subscription.Events) != len(tt.wantEvents) || subscription.Events[0] != tt.wantEvents[0] {
+				t.Errorf("events = %v, want %v", subscription.Events, tt.wantEvents)
+			}
+			if tt.secret == "" && len(subscription.Secret) < 16 {
+				t.Errorf("generated secret %q is too short", subscription.Secret)
+			}
+		})
+	}
+}